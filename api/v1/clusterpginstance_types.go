@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterPgInstanceSpec defines the desired state of ClusterPgInstance
+type ClusterPgInstanceSpec struct {
+	PgInstanceSpec `json:",inline"`
+	// Namespace in which the ConfigMaps and Secrets referenced by the properties are located
+	Namespace string `json:"namespace"`
+}
+
+// ClusterPgInstanceStatus defines the observed state of ClusterPgInstance
+type ClusterPgInstanceStatus struct {
+	PgInstanceStatus `json:",inline"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Connected",type=string,JSONPath=`.status.conditions[?(@.type=="postgres.brose.bike/connected")].status`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.serverVersion`
+//+kubebuilder:printcolumn:name="Superuser",type=boolean,JSONPath=`.status.adminPrivileges.superuser`,priority=1
+//+kubebuilder:printcolumn:name="CreateRole",type=boolean,JSONPath=`.status.adminPrivileges.createRole`,priority=1
+//+kubebuilder:printcolumn:name="CreateDB",type=boolean,JSONPath=`.status.adminPrivileges.createDB`,priority=1
+//+kubebuilder:printcolumn:name="Connections",type=integer,JSONPath=`.status.connections`
+//+kubebuilder:printcolumn:name="Max Connections",type=integer,JSONPath=`.status.maxConnections`
+//+kubebuilder:printcolumn:name="Users",type=integer,JSONPath=`.status.users`
+//+kubebuilder:printcolumn:name="Databases",type=integer,JSONPath=`.status.databases`
+//+kubebuilder:printcolumn:name="Up Since",type=date,JSONPath=`.status.startTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterPgInstance is the Schema for the clusterpginstances API
+type ClusterPgInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterPgInstanceSpec   `json:"spec,omitempty"`
+	Status ClusterPgInstanceStatus `json:"status,omitempty"`
+}
+
+func (i *ClusterPgInstance) GetConditions() []metav1.Condition {
+	return i.Status.Conditions
+}
+
+func (i *ClusterPgInstance) SetConditions(conditions []metav1.Condition) {
+	i.Status.Conditions = conditions
+}
+
+// IsNamespaceAllowed returns true if resources in the given namespace are allowed to reference this instance
+func (i *ClusterPgInstance) IsNamespaceAllowed(namespace *coreV1.Namespace) (bool, error) {
+	return i.Spec.IsNamespaceAllowed(namespace)
+}
+
+// ToPgInstance converts the ClusterPgInstance into a PgInstance located in the namespace of the properties,
+// which allows to connect to both kinds in the same way
+func (i *ClusterPgInstance) ToPgInstance() *PgInstance {
+	return &PgInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: i.Spec.Namespace,
+			Name:      i.Name,
+			UID:       i.UID,
+		},
+		Spec: *i.Spec.PgInstanceSpec.DeepCopy(),
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterPgInstanceList contains a list of ClusterPgInstance
+type ClusterPgInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPgInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterPgInstance{}, &ClusterPgInstanceList{})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-clusterpginstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=clusterpginstances,verbs=create;update,versions=v1,name=vclusterpginstance.kb.io,admissionReviewVersions=v1
+
+// clusterPgInstanceValidator validates ClusterPgInstance resources before they are admitted
+type clusterPgInstanceValidator struct{}
+
+var _ webhook.CustomValidator = &clusterPgInstanceValidator{}
+
+func (i *ClusterPgInstance) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(i).
+		WithValidator(&clusterPgInstanceValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *clusterPgInstanceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	instance, ok := obj.(*ClusterPgInstance)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPgInstance but got a %T", obj)
+	}
+	return toInvalidError(string(ClusterPgInstanceKind), instance.Name, instance.Spec.validate(field.NewPath("spec")))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *clusterPgInstanceValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldInstance, ok := oldObj.(*ClusterPgInstance)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPgInstance but got a %T", oldObj)
+	}
+	instance, ok := newObj.(*ClusterPgInstance)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPgInstance but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if instance.DeletionTimestamp != nil || isSpecUnchanged(oldInstance.Spec, instance.Spec) {
+		return nil
+	}
+	return toInvalidError(string(ClusterPgInstanceKind), instance.Name, instance.Spec.validate(field.NewPath("spec")))
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *clusterPgInstanceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the connection properties of the instance and the namespace of the referenced ConfigMaps and Secrets
+func (s *ClusterPgInstanceSpec) validate(path *field.Path) field.ErrorList {
+	errs := s.PgInstanceSpec.validate(path)
+	if s.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), "the namespace of the properties is required"))
+	}
+	return errs
+}
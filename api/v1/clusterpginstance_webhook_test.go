@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("clusterPgInstanceValidator", func() {
+
+	newInstance := func() *ClusterPgInstance {
+		return &ClusterPgInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+			Spec: ClusterPgInstanceSpec{
+				PgInstanceSpec: PgInstanceSpec{
+					Hostname: PgProperty{Value: "localhost"},
+					Port:     PgProperty{Value: "5432"},
+					Username: PgProperty{Value: "admin"},
+					Password: PgProperty{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "my-secret"}, Key: "password"}},
+					SSLMode:  PgProperty{Value: "verify-full"},
+				},
+				Namespace: "postgres",
+			},
+		}
+	}
+
+	It("admits a valid instance", func() {
+		// given:
+		validator := clusterPgInstanceValidator{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newInstance())
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses the same properties as a PgInstance", func() {
+		// given:
+		validator := clusterPgInstanceValidator{}
+		instance := newInstance()
+		instance.Spec.SSLMode = PgProperty{Value: "none"}
+		instance.Spec.SSLKey = PgProperty{Value: "key"}
+		instance.Spec.CredentialProvider = &PgCredentialProvider{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), instance)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.sslMode.value"))
+		Expect(err.Error()).To(ContainSubstring("spec.sslKey"))
+		Expect(err.Error()).To(ContainSubstring("spec.credentialProvider.exec"))
+	})
+
+	It("refuses an instance without namespace", func() {
+		// given:
+		validator := clusterPgInstanceValidator{}
+		instance := newInstance()
+		instance.Spec.Namespace = ""
+		// when:
+		err := validator.ValidateCreate(context.TODO(), instance)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.namespace"))
+	})
+
+	It("admits metadata changes of an invalid instance", func() {
+		// given:
+		validator := clusterPgInstanceValidator{}
+		oldInstance := newInstance()
+		oldInstance.Spec.SSLMode = PgProperty{Value: "none"}
+		instance := oldInstance.DeepCopy()
+		instance.Labels = map[string]string{"team": "bikes"}
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldInstance, instance)
+		// then:
+		Expect(err).To(BeNil())
+	})
+})
@@ -18,9 +18,11 @@ package v1
 
 import (
 	"context"
+	"strings"
 
 	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -34,6 +36,54 @@ const (
 	PgConnectedConditionReasonConFailed    = "ConnectionFailed"
 )
 
+// PgOwnershipMarkerPrefix is the prefix of the comment, which marks an object on the instance
+// as managed by the resource with the UID following the prefix
+const PgOwnershipMarkerPrefix = "postgres.brose.bike/uid="
+
+// PgAdoptionPolicy defines how objects are handled, which already exist on the instance
+// +kubebuilder:validation:Enum=Create;Adopt;Fail
+type PgAdoptionPolicy string
+
+const (
+	// CreateAdoptionPolicy creates missing objects and adopts existing objects which are not managed by another resource
+	CreateAdoptionPolicy PgAdoptionPolicy = "Create"
+	// AdoptAdoptionPolicy adopts existing objects which are not managed by another resource, but never creates objects
+	AdoptAdoptionPolicy PgAdoptionPolicy = "Adopt"
+	// FailAdoptionPolicy creates missing objects and refuses existing objects which were not created by the resource
+	FailAdoptionPolicy PgAdoptionPolicy = "Fail"
+)
+
+// NewOwnershipMarker returns the comment which marks an object as managed by the resource with the given UID
+func NewOwnershipMarker(uid types.UID) string {
+	return PgOwnershipMarkerPrefix + string(uid)
+}
+
+// ParseOwnershipMarker returns the UID contained in a line of the given comment,
+// or an empty UID if the comment contains no ownership marker
+func ParseOwnershipMarker(comment string) types.UID {
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.HasPrefix(line, PgOwnershipMarkerPrefix) {
+			return types.UID(strings.TrimPrefix(line, PgOwnershipMarkerPrefix))
+		}
+	}
+	return ""
+}
+
+// SetOwnershipMarker returns the given comment with the ownership marker for the given UID as last line,
+// the existing comment is kept. An empty UID removes the ownership marker from the comment.
+func SetOwnershipMarker(comment string, uid types.UID) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" && !strings.HasPrefix(line, PgOwnershipMarkerPrefix) {
+			lines = append(lines, line)
+		}
+	}
+	if uid != "" {
+		lines = append(lines, NewOwnershipMarker(uid))
+	}
+	return strings.Join(lines, "\n")
+}
+
 type PgProperty struct {
 	// The value for this property
 	// +optional
@@ -46,6 +96,11 @@ type PgProperty struct {
 	SecretKeyRef *coreV1.SecretKeySelector `json:"secretKeyRef,omitempty" protobuf:"bytes,3,opt,name=secretKeyRef"`
 }
 
+// IsSet returns true if any source for the value of the property is set
+func (p *PgProperty) IsSet() bool {
+	return p.Value != "" || p.ConfigMapKeyRef != nil || p.SecretKeyRef != nil
+}
+
 func (p *PgProperty) GetPropertyValueWithDefault(ctx context.Context, r client.Reader, namespace string, name string, defaultValue string) (string, error) {
 	value, err := p.GetPropertyValue(ctx, r, namespace, name)
 	if err == nil {
@@ -97,17 +152,91 @@ func (p *PgProperty) GetPropertyValue(ctx context.Context, r client.Reader, name
 	return "", brose_errors.NewMissingPropertyValueError(name, nil)
 }
 
+// +kubebuilder:validation:Enum=PgInstance;ClusterPgInstance
+type PgInstanceKind string
+
+const (
+	NamespacedPgInstanceKind PgInstanceKind = "PgInstance"
+	ClusterPgInstanceKind    PgInstanceKind = "ClusterPgInstance"
+)
+
 // PgInstanceRef identifies the PgInstanceConnection which should be used
 type PgInstanceRef struct {
-	// Namespace defines the namespace in which the PgInstanceConnection is located
-	Namespace string `json:"namespace"`
+	// Kind defines if a PgInstance or a ClusterPgInstance is referenced, defaults to PgInstance
+	// +optional
+	Kind PgInstanceKind `json:"kind,omitempty"`
+	// Namespace defines the namespace in which the PgInstanceConnection is located,
+	// it is ignored for a ClusterPgInstance
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 	// Name identifies the PgInstanceConnection which should be used
 	Name string `json:"name"`
 }
 
+// IsClusterInstance returns true if the reference points to a ClusterPgInstance
+func (i *PgInstanceRef) IsClusterInstance() bool {
+	return i.Kind == ClusterPgInstanceKind
+}
+
+// IsReferencing returns true if the reference points to the given PgInstance
+func (i *PgInstanceRef) IsReferencing(instance *PgInstance) bool {
+	return !i.IsClusterInstance() && i.Namespace == instance.Namespace && i.Name == instance.Name
+}
+
+// IsReferencingClusterInstance returns true if the reference points to the given ClusterPgInstance
+func (i *PgInstanceRef) IsReferencingClusterInstance(instance *ClusterPgInstance) bool {
+	return i.IsClusterInstance() && i.Name == instance.Name
+}
+
 func (i *PgInstanceRef) ToNamespacedName() types.NamespacedName {
+	if i.IsClusterInstance() {
+		return types.NamespacedName{
+			Name: i.Name,
+		}
+	}
 	return types.NamespacedName{
 		Namespace: i.Namespace,
 		Name:      i.Name,
 	}
 }
+
+// PgDatabaseRef identifies the PgDatabase which should be used
+type PgDatabaseRef struct {
+	// Namespace defines the namespace in which the PgDatabase is located
+	Namespace string `json:"namespace"`
+	// Name identifies the PgDatabase which should be used
+	Name string `json:"name"`
+}
+
+func (d *PgDatabaseRef) ToNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: d.Namespace,
+		Name:      d.Name,
+	}
+}
+
+// PgJobPhase describes the state of a Job run by the operator
+type PgJobPhase string
+
+const (
+	// RunningJobPhase is used for Jobs which did not finish yet
+	RunningJobPhase PgJobPhase = "Running"
+	// SucceededJobPhase is used for Jobs which finished successfully
+	SucceededJobPhase PgJobPhase = "Succeeded"
+	// FailedJobPhase is used for Jobs which failed
+	FailedJobPhase PgJobPhase = "Failed"
+)
+
+// PgJobStatus describes the observed state of a Job run by the operator
+type PgJobStatus struct {
+	// Name contains the name of the Job
+	Name string `json:"name"`
+	// Phase contains the state of the Job
+	Phase PgJobPhase `json:"phase"`
+	// StartTime contains the time at which the Job was started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime contains the time at which the Job finished successfully
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
@@ -22,12 +22,14 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type mockReader struct {
-	callsGet int
-	proxyGet func(key client.ObjectKey, obj client.Object) error
+	callsGet  int
+	proxyGet  func(key client.ObjectKey, obj client.Object) error
+	proxyList func(list client.ObjectList) error
 }
 
 func (r *mockReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
@@ -39,6 +41,9 @@ func (r *mockReader) Get(ctx context.Context, key client.ObjectKey, obj client.O
 }
 
 func (r *mockReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if r.proxyList != nil {
+		return r.proxyList(list)
+	}
 	return nil
 }
 
@@ -189,3 +194,47 @@ func TestPgPropertyWithSecretStringData(t *testing.T) {
 		t.Errorf("Unexpected call to reader, expected 1 calls, got %d", reader.callsGet)
 	}
 }
+
+func TestParseOwnershipMarker(t *testing.T) {
+	// given
+	marker := NewOwnershipMarker("6d3b0a8e-1c1f-4d0a-9f0e-2c7c1b5e9a11")
+
+	// when
+	actual := ParseOwnershipMarker(marker)
+
+	// then
+	if diff := cmp.Diff(types.UID("6d3b0a8e-1c1f-4d0a-9f0e-2c7c1b5e9a11"), actual); diff != "" {
+		t.Errorf("UID is incorrect (-want +got):\n%s", diff)
+	}
+	// and comments of users are no markers
+	if actual := ParseOwnershipMarker("database of the payments team"); actual != "" {
+		t.Errorf("Comment was parsed as marker: %s", actual)
+	}
+}
+
+func TestSetOwnershipMarker(t *testing.T) {
+	// given
+	comment := "database of the payments team"
+
+	// when
+	actual := SetOwnershipMarker(comment, "6d3b0a8e-1c1f-4d0a-9f0e-2c7c1b5e9a11")
+
+	// then
+	expected := "database of the payments team\n" + NewOwnershipMarker("6d3b0a8e-1c1f-4d0a-9f0e-2c7c1b5e9a11")
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Comment is incorrect (-want +got):\n%s", diff)
+	}
+	if uid := ParseOwnershipMarker(actual); uid != "6d3b0a8e-1c1f-4d0a-9f0e-2c7c1b5e9a11" {
+		t.Errorf("Marker was not parsed from comment: %s", uid)
+	}
+	// and an existing marker is replaced
+	actual = SetOwnershipMarker(actual, "other-uid")
+	if diff := cmp.Diff("database of the payments team\n"+NewOwnershipMarker("other-uid"), actual); diff != "" {
+		t.Errorf("Comment is incorrect (-want +got):\n%s", diff)
+	}
+	// and an empty UID removes the marker
+	actual = SetOwnershipMarker(actual, "")
+	if diff := cmp.Diff(comment, actual); diff != "" {
+		t.Errorf("Comment is incorrect (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const PgBackupSucceededConditionType string = "pgbackup.postgres.brose.bike/succeeded"
+
+// PgBackupLabel is added to all Jobs of a PgBackup and contains the name of the PgBackup
+const PgBackupLabel = "postgres.brose.bike/pgbackup"
+
+// PgBackupFormat defines the output format of pg_dump
+// +kubebuilder:validation:Enum=custom;plain;directory;tar
+type PgBackupFormat string
+
+const (
+	// CustomBackupFormat writes a compressed archive, which can be restored with pg_restore
+	CustomBackupFormat PgBackupFormat = "custom"
+	// PlainBackupFormat writes a plain SQL script, which can be restored with psql
+	PlainBackupFormat PgBackupFormat = "plain"
+	// DirectoryBackupFormat writes a directory with one file for each table, which can be restored with pg_restore
+	DirectoryBackupFormat PgBackupFormat = "directory"
+	// TarBackupFormat writes a tar archive, which can be restored with pg_restore
+	TarBackupFormat PgBackupFormat = "tar"
+)
+
+// FileExtension returns the extension of the files written in the format
+func (f PgBackupFormat) FileExtension() string {
+	switch f {
+	case PlainBackupFormat:
+		return ".sql"
+	case DirectoryBackupFormat:
+		return ""
+	case TarBackupFormat:
+		return ".tar"
+	default:
+		return ".dump"
+	}
+}
+
+// PgBackupSpec defines the desired state of PgBackup
+type PgBackupSpec struct {
+	// Database identifies the PgDatabase which should be backed up
+	Database PgDatabaseRef `json:"database"`
+	// Schedule contains the schedule in cron format, at which backups are taken.
+	// A single backup is taken if no schedule is set.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// Format specifies the output format of pg_dump (defaults to custom)
+	// +optional
+	Format PgBackupFormat `json:"format,omitempty"`
+	// Role contains the name of the PgUser in the namespace of the resource, as which pg_dump connects to the database,
+	// e.g. the owner of the database. The operator passes the password from the Secret of the PgUser to the Jobs.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+	// PersistentVolumeClaim specifies the name of the claim in the namespace of the PgBackup, to which the backups are written
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// Retention specifies the number of backups which are kept in the claim, older backups are deleted (defaults to 0, which keeps all backups)
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retention int `json:"retention,omitempty"`
+	// Image specifies the image containing pg_dump (defaults to postgres:16)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// PgBackupStatus defines the observed state of PgBackup
+type PgBackupStatus struct {
+	// Conditions represent the current state of the backup
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// LastSuccessfulTime contains the time at which the last backup succeeded
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Jobs contains the state of the Jobs taking the backups, the newest Job comes first
+	// +optional
+	Jobs []PgJobStatus `json:"jobs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.database.name`
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Last Backup",type=date,JSONPath=`.status.lastSuccessfulTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PgBackup is the Schema for the pgbackups API
+type PgBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgBackupSpec   `json:"spec,omitempty"`
+	Status PgBackupStatus `json:"status,omitempty"`
+}
+
+func PgBackupKind() string {
+	obj := &PgBackup{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (b *PgBackup) GetConditions() []metav1.Condition {
+	return b.Status.Conditions
+}
+
+func (b *PgBackup) SetConditions(conditions []metav1.Condition) {
+	b.Status.Conditions = conditions
+}
+
+// GetFormat returns the output format and falls back to custom if none is set
+func (b *PgBackup) GetFormat() PgBackupFormat {
+	if b.Spec.Format == "" {
+		return CustomBackupFormat
+	}
+	return b.Spec.Format
+}
+
+// GetImage returns the image which should be used to take the backups
+func (b *PgBackup) GetImage() string {
+	if b.Spec.Image == "" {
+		return DefaultDumpImage
+	}
+	return b.Spec.Image
+}
+
+// GetUserId returns the id of the PgUser as which the Jobs connect to the database
+func (b *PgBackup) GetUserId() types.NamespacedName {
+	return types.NamespacedName{Namespace: b.Namespace, Name: b.Spec.Role}
+}
+
+func (b *PgBackup) GetDatabaseId() types.NamespacedName {
+	return b.Spec.Database.ToNamespacedName()
+}
+
+func (b *PgBackup) GetDatabaseIdString() string {
+	return b.Spec.Database.ToNamespacedName().String()
+}
+
+func (b *PgBackup) ToNamespacedName() string {
+	return b.Namespace + "/" + b.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgBackupList contains a list of PgBackup
+type PgBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgBackup{}, &PgBackupList{})
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PgBackup", func() {
+
+	It("falls back to the custom format", func() {
+		// given:
+		backup := PgBackup{}
+		// when:
+		format := backup.GetFormat()
+		// then:
+		Expect(format).To(Equal(CustomBackupFormat))
+		Expect(format.FileExtension()).To(Equal(".dump"))
+	})
+
+	It("writes directories without extension", func() {
+		// given:
+		backup := PgBackup{Spec: PgBackupSpec{Format: DirectoryBackupFormat}}
+		// when:
+		extension := backup.GetFormat().FileExtension()
+		// then:
+		Expect(extension).To(BeEmpty())
+	})
+})
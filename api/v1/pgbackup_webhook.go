@@ -0,0 +1,85 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgbackup,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgbackups,verbs=create;update,versions=v1,name=vpgbackup.kb.io,admissionReviewVersions=v1
+
+// pgBackupValidator validates PgBackup resources before they are admitted
+type pgBackupValidator struct{}
+
+var _ webhook.CustomValidator = &pgBackupValidator{}
+
+func (b *PgBackup) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(b).
+		WithValidator(&pgBackupValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgBackupValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	backup, ok := obj.(*PgBackup)
+	if !ok {
+		return fmt.Errorf("expected a PgBackup but got a %T", obj)
+	}
+	return toInvalidError(PgBackupKind(), backup.Name, backup.validate())
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgBackupValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldBackup, ok := oldObj.(*PgBackup)
+	if !ok {
+		return fmt.Errorf("expected a PgBackup but got a %T", oldObj)
+	}
+	backup, ok := newObj.(*PgBackup)
+	if !ok {
+		return fmt.Errorf("expected a PgBackup but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if backup.DeletionTimestamp != nil || isSpecUnchanged(oldBackup.Spec, backup.Spec) {
+		return nil
+	}
+	return toInvalidError(PgBackupKind(), backup.Name, backup.validate())
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgBackupValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the fields of the PgBackup, which do not depend on other resources
+func (b *PgBackup) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateLocalDatabaseRef(specPath.Child("database"), b.Spec.Database, b.Namespace)
+	if b.Spec.Role == "" {
+		errs = append(errs, field.Required(specPath.Child("role"), "the PgUser as which the Jobs connect is required"))
+	}
+	if b.Spec.PersistentVolumeClaim == "" {
+		errs = append(errs, field.Required(specPath.Child("persistentVolumeClaim"), "the claim is required"))
+	}
+	return errs
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -27,6 +31,18 @@ const DefaultFinalizerPgDatabase = "postgres.brose.bike/pgdatabase"
 const PgDatabaseExistsConditionType string = "pgdatabase.postgres.brose.bike/exists"
 const PgDatabaseExtensionsConditionType string = "pgdatabase.postgres.brose.bike/extensions"
 const PgDatabaseDefaultPrivilegesConditionType string = "pgdatabase.postgres.brose.bike/default-privileges"
+const PgDatabaseOptionsConditionType string = "pgdatabase.postgres.brose.bike/options"
+const PgDatabaseOwnershipConditionType string = "pgdatabase.postgres.brose.bike/ownership"
+const PgDatabaseDeletionConditionType string = "pgdatabase.postgres.brose.bike/deletion"
+const PgDatabaseCloneConditionType string = "pgdatabase.postgres.brose.bike/clone"
+const PgDatabaseSettingsConditionType string = "pgdatabase.postgres.brose.bike/settings"
+
+// DefaultDumpImage contains the image which is used to dump a database, if no image is specified
+const DefaultDumpImage = "postgres:16"
+
+// PgDatabaseCloneAllowedNamespacesAnnotation contains the comma separated namespaces, whose PgDatabases are allowed
+// to clone the annotated PgDatabase, "*" allows all namespaces
+const PgDatabaseCloneAllowedNamespacesAnnotation string = "pgdatabase.postgres.brose.bike/clone-allowed-namespaces"
 
 // +kubebuilder:validation:Enum=USAGE;CREATE
 type SchemaPrivilege string
@@ -48,6 +64,31 @@ type PgDatabaseDeletion struct {
 	Drop bool `json:"drop,omitempty"`
 	// Wait specifies if the finalizer should wait for the database to be deleted manually
 	Wait bool `json:"wait,omitempty"`
+	// Force specifies if the sessions connected to the database should be terminated before it is dropped (defaults to false)
+	Force bool `json:"force,omitempty"`
+	// Dump specifies if the database should be dumped before it is dropped
+	// +optional
+	Dump *PgDatabaseDump `json:"dump,omitempty"`
+}
+
+type PgDatabaseDump struct {
+	// Role contains the name of the PgUser in the namespace of the PgDatabase, as which pg_dump connects to the database,
+	// e.g. the owner of the database. The operator passes the password from the Secret of the PgUser to the Job.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+	// PersistentVolumeClaim specifies the name of the claim in the namespace of the PgDatabase, to which the dump is written
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// Image specifies the image containing pg_dump (defaults to postgres:16)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// GetImage returns the image which should be used to dump the database
+func (d *PgDatabaseDump) GetImage() string {
+	if d.Image == "" {
+		return DefaultDumpImage
+	}
+	return d.Image
 }
 
 type PgDatabaseDefaultPrivileges struct {
@@ -107,6 +148,67 @@ func (dp *PgDatabaseDefaultPrivileges) TypePrivilegesStr() []string {
 	return privileges
 }
 
+// +kubebuilder:validation:Enum=present;absent
+type PgDatabaseExtensionState string
+
+const (
+	// The extension is created or updated to the desired version
+	PresentExtensionState PgDatabaseExtensionState = "present"
+
+	// The extension is dropped from the database
+	AbsentExtensionState PgDatabaseExtensionState = "absent"
+)
+
+// PgDatabaseExtension represents an extension in the database
+type PgDatabaseExtension struct {
+	// Name of the extension as listed in pg_available_extensions
+	Name string `json:"name"`
+	// Version of the extension, the default version of the extension is used if empty
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Schema in which the objects of the extension are created, only applied on creation
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Cascade creates required extensions on creation and drops dependent objects on removal
+	// +optional
+	Cascade bool `json:"cascade,omitempty"`
+	// State specifies if the extension should exist in the database (defaults to present)
+	// +optional
+	State PgDatabaseExtensionState `json:"state,omitempty"`
+}
+
+func (e *PgDatabaseExtension) IsAbsent() bool {
+	return e.State == AbsentExtensionState
+}
+
+// UnmarshalJSON accepts the name of the extension as string, which was the format of extensions before
+// their version, schema and state could be specified, as well as the extension as object
+func (e *PgDatabaseExtension) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		*e = PgDatabaseExtension{}
+		return json.Unmarshal(trimmed, &e.Name)
+	}
+	// The alias has the fields, but not the methods of the extension, which prevents the recursion
+	type extension PgDatabaseExtension
+	return json.Unmarshal(data, (*extension)(e))
+}
+
+// PgDatabaseExtensionStatus contains the observed state of an extension
+type PgDatabaseExtensionStatus struct {
+	// Name of the extension
+	Name string `json:"name"`
+	// DesiredVersion is the version specified in the resource, empty for the default version
+	// +optional
+	DesiredVersion string `json:"desiredVersion,omitempty"`
+	// InstalledVersion is the version installed in the database, empty if not installed
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	// Created is true if the extension was created by the operator,
+	// only those extensions are dropped when they are removed from the resource
+	// +optional
+	Created bool `json:"created,omitempty"`
+}
+
 type PgDatabasePublicPrivileges struct {
 	// Revoke the public privileges from all database object
 	Revoke bool `json:"revoke"`
@@ -122,20 +224,142 @@ type PgDatabaseSpec struct {
 	Instance PgInstanceRef `json:"instance"`
 	// DeletionBehavior specifies what should happen when the manifest gets deleted
 	DeletionBehavior PgDatabaseDeletion `json:"deletion"`
-	// Extensions which should exist in this database
-	Extensions []string `json:"extensions,omitempty"`
+	// AdoptionPolicy defines how an already existing database is handled, defaults to Fail
+	// +optional
+	AdoptionPolicy PgAdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// Extensions which should exist in this database, either as objects or as names of extensions.
+	// The schema is validated by the webhook, as the structural schema of a CRD cannot contain both formats.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Extensions []PgDatabaseExtension `json:"extensions,omitempty"`
 	// DefaultPrivileges defines the default privileges for schemas in this database
 	DefaultPrivileges []PgDatabaseDefaultPrivileges `json:"defaultPrivileges,omitempty"`
 	// PublicPrivileges revokes and Public stuff in postgres
 	PublicPrivileges PgDatabasePublicPrivileges `json:"publicPrivileges"`
 	// PublicSchema dropped
 	PublicSchema PgDatabasePublicSchema `json:"publicSchema"`
+	// Owner is the name of the role which should own the database, it takes precedence over the owner flag of PgUsers
+	// +optional
+	Owner string `json:"owner,omitempty"`
+	// Encoding is the character set encoding of the database, it can only be set on creation
+	// +optional
+	Encoding string `json:"encoding,omitempty"`
+	// LcCollate is the collation order (LC_COLLATE) of the database, it can only be set on creation
+	// +optional
+	LcCollate string `json:"lcCollate,omitempty"`
+	// LcCtype is the character classification (LC_CTYPE) of the database, it can only be set on creation
+	// +optional
+	LcCtype string `json:"lcCtype,omitempty"`
+	// IcuLocale is the ICU locale of the database, it can only be set on creation
+	// +optional
+	IcuLocale string `json:"icuLocale,omitempty"`
+	// Template is the name of the template from which the database is created, it is only used on creation
+	// +optional
+	Template string `json:"template,omitempty"`
+	// Tablespace is the name of the default tablespace of the database, it can only be set on creation
+	// +optional
+	Tablespace string `json:"tablespace,omitempty"`
+	// ConnectionLimit limits the concurrent connections to the database, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// AllowConnections can be set to false to prevent connections to the database,
+	// the objects in the database are not updated while connections are not allowed
+	// +optional
+	AllowConnections *bool `json:"allowConnections,omitempty"`
+	// Source identifies the PgDatabase from which the database is cloned, it is only used on creation
+	// +optional
+	Source *PgDatabaseSource `json:"source,omitempty"`
+	// Settings contains the configuration settings for all sessions in the database, e.g. statement_timeout
+	// +optional
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// PgDatabaseSource describes the database from which a new database is cloned
+type PgDatabaseSource struct {
+	// Database identifies the PgDatabase which should be cloned, a PgDatabase in another namespace
+	// has to allow the namespace with the annotation pgdatabase.postgres.brose.bike/clone-allowed-namespaces
+	Database PgDatabaseRef `json:"database"`
+	// Image specifies the image containing pg_dump and pg_restore,
+	// which is used to clone a database from another instance (defaults to postgres:16)
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Role contains the name of the PgUser in the namespace of the PgDatabase, as which pg_restore connects to the database,
+	// e.g. the owner of the database. It is required to clone a database from another instance.
+	// +optional
+	Role string `json:"role,omitempty"`
+	// SourceRole contains the name of the PgUser in the namespace of the PgDatabase on the instance of the source,
+	// as which pg_dump connects to the source database. It is required to clone a database from another instance.
+	// +optional
+	SourceRole string `json:"sourceRole,omitempty"`
+	// TerminateSessions terminates the sessions connected to the source database before it is used as template,
+	// otherwise a source with connected sessions is not cloned. It is only used for a source on the same instance.
+	// +optional
+	TerminateSessions bool `json:"terminateSessions,omitempty"`
+}
+
+// GetImage returns the image which should be used to clone the database from another instance
+func (s *PgDatabaseSource) GetImage() string {
+	if s.Image == "" {
+		return DefaultDumpImage
+	}
+	return s.Image
+}
+
+// PgDatabaseCloneMethod describes how a database is cloned
+type PgDatabaseCloneMethod string
+
+const (
+	// TemplateCloneMethod creates the database with the source database as template, which requires both on the same instance
+	TemplateCloneMethod PgDatabaseCloneMethod = "Template"
+	// DumpRestoreCloneMethod restores a dump of the source database in a Job
+	DumpRestoreCloneMethod PgDatabaseCloneMethod = "DumpRestore"
+)
+
+// PgDatabaseCloneStatus describes the observed state of the clone of a database
+type PgDatabaseCloneStatus struct {
+	// Source contains the namespace and name of the cloned PgDatabase
+	Source string `json:"source"`
+	// Method contains the method used to clone the database
+	Method PgDatabaseCloneMethod `json:"method"`
+	// Phase contains the state of the clone
+	Phase PgJobPhase `json:"phase"`
+	// Job contains the name of the Job restoring the dump of the source database
+	// +optional
+	Job string `json:"job,omitempty"`
+	// SnapshotTime contains the time at which the source database was copied
+	// +optional
+	SnapshotTime *metav1.Time `json:"snapshotTime,omitempty"`
+}
+
+// GetAdoptionPolicy returns the adoption policy or Fail if none is set
+func (s *PgDatabaseSpec) GetAdoptionPolicy() PgAdoptionPolicy {
+	if s.AdoptionPolicy == "" {
+		return FailAdoptionPolicy
+	}
+	return s.AdoptionPolicy
+}
+
+// HasOptions returns true if any option of the database is specified
+func (s *PgDatabaseSpec) HasOptions() bool {
+	return s.Owner != "" || s.Encoding != "" || s.LcCollate != "" || s.LcCtype != "" || s.IcuLocale != "" ||
+		s.Template != "" || s.Tablespace != "" || s.ConnectionLimit != nil || s.AllowConnections != nil
 }
 
 // PgDatabaseStatus defines the observed state of PgDatabase
 type PgDatabaseStatus struct {
 	// Conditions represent the current connection state
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Extensions contains the observed state of the extensions managed by the operator
+	// +optional
+	Extensions []PgDatabaseExtensionStatus `json:"extensions,omitempty"`
+	// Clone contains the observed state of the clone, if the database was cloned from a source
+	// +optional
+	Clone *PgDatabaseCloneStatus `json:"clone,omitempty"`
+	// Settings contains the names of the configuration settings which were set by the operator
+	// +optional
+	Settings []string `json:"settings,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -150,6 +374,15 @@ type PgDatabase struct {
 	Status PgDatabaseStatus `json:"status,omitempty"`
 }
 
+func PgDatabaseKind() string {
+	obj := &PgDatabase{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
 func (d *PgDatabase) GetConditions() []metav1.Condition {
 	return d.Status.Conditions
 }
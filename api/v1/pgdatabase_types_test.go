@@ -15,3 +15,45 @@ limitations under the License.
 */
 
 package v1
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PgDatabaseExtension", func() {
+
+	It("gets deserialized from an object", func() {
+		// given:
+		data := `{"extensions":[{"name":"pg_trgm","version":"1.6","state":"absent"}]}`
+		spec := PgDatabaseSpec{}
+		// when:
+		err := json.Unmarshal([]byte(data), &spec)
+		// then:
+		Expect(err).To(BeNil())
+		Expect(spec.Extensions).To(Equal([]PgDatabaseExtension{{Name: "pg_trgm", Version: "1.6", State: AbsentExtensionState}}))
+	})
+
+	It("gets deserialized from the name of the extension", func() {
+		// given:
+		data := `{"extensions":["pg_trgm", {"name":"hstore"}]}`
+		spec := PgDatabaseSpec{}
+		// when:
+		err := json.Unmarshal([]byte(data), &spec)
+		// then:
+		Expect(err).To(BeNil())
+		Expect(spec.Extensions).To(Equal([]PgDatabaseExtension{{Name: "pg_trgm"}, {Name: "hstore"}}))
+	})
+
+	It("refuses other values", func() {
+		// given:
+		data := `{"extensions":[42]}`
+		spec := PgDatabaseSpec{}
+		// when:
+		err := json.Unmarshal([]byte(data), &spec)
+		// then:
+		Expect(err).NotTo(BeNil())
+	})
+})
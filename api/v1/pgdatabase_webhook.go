@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgdatabase,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgdatabases,verbs=create;update,versions=v1,name=vpgdatabase.kb.io,admissionReviewVersions=v1
+
+// pgDatabaseValidator validates PgDatabase resources before they are admitted
+type pgDatabaseValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &pgDatabaseValidator{}
+
+func (d *PgDatabase) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(d).
+		WithValidator(&pgDatabaseValidator{mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgDatabaseValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	database, ok := obj.(*PgDatabase)
+	if !ok {
+		return fmt.Errorf("expected a PgDatabase but got a %T", obj)
+	}
+	errs := database.validate()
+	duplicates, err := v.validateUniqueName(ctx, database)
+	if err != nil {
+		return err
+	}
+	return toInvalidError("PgDatabase", database.Name, append(errs, duplicates...))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgDatabaseValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldDatabase, ok := oldObj.(*PgDatabase)
+	if !ok {
+		return fmt.Errorf("expected a PgDatabase but got a %T", oldObj)
+	}
+	database, ok := newObj.(*PgDatabase)
+	if !ok {
+		return fmt.Errorf("expected a PgDatabase but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if database.DeletionTimestamp != nil || isSpecUnchanged(oldDatabase.Spec, database.Spec) {
+		return nil
+	}
+	errs := database.validate()
+	errs = append(errs, validateInstanceRefUpdate(field.NewPath("spec", "instance"), oldDatabase.Spec.Instance, database.Spec.Instance)...)
+	return toInvalidError("PgDatabase", database.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgDatabaseValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateUniqueName checks that no other PgDatabase manages a database with the same name on the instance
+func (v *pgDatabaseValidator) validateUniqueName(ctx context.Context, database *PgDatabase) (field.ErrorList, error) {
+	var databases PgDatabaseList
+	if err := v.client.List(ctx, &databases); err != nil {
+		return nil, err
+	}
+	for _, other := range databases.Items {
+		if other.UID != database.UID && other.Name == database.Name && isSameInstance(other.Spec.Instance, database.Spec.Instance) {
+			return field.ErrorList{field.Duplicate(field.NewPath("metadata", "name"), database.Name)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the fields of the PgDatabase, which do not depend on other resources
+func (d *PgDatabase) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateDatabaseName(field.NewPath("metadata", "name"), d.Name)
+	errs = append(errs, validateInstanceRef(specPath.Child("instance"), d.Spec.Instance)...)
+	if d.Spec.Owner != "" {
+		errs = append(errs, validateIdentifier(specPath.Child("owner"), d.Spec.Owner)...)
+	}
+	// Validate extensions
+	extensionNames := make(map[string]bool)
+	for i, extension := range d.Spec.Extensions {
+		extensionPath := specPath.Child("extensions").Index(i)
+		if extension.Name == "" {
+			errs = append(errs, field.Required(extensionPath.Child("name"), "the name of the extension is required"))
+		} else if extensionNames[extension.Name] {
+			errs = append(errs, field.Duplicate(extensionPath.Child("name"), extension.Name))
+		}
+		extensionNames[extension.Name] = true
+		// The CRD does not validate the extensions, because they can be given as names as well
+		if extension.State != "" && extension.State != PresentExtensionState && extension.State != AbsentExtensionState {
+			states := []string{string(PresentExtensionState), string(AbsentExtensionState)}
+			errs = append(errs, field.NotSupported(extensionPath.Child("state"), extension.State, states))
+		}
+	}
+	errs = append(errs, validateSettings(specPath.Child("settings"), d.Spec.Settings)...)
+	// Validate source
+	if d.Spec.Source != nil {
+		sourcePath := specPath.Child("source", "database")
+		if d.Spec.Template != "" {
+			errs = append(errs, field.Forbidden(specPath.Child("template"), "template and source cannot be used together"))
+		}
+		if d.Spec.Source.Database.Name == "" {
+			errs = append(errs, field.Required(sourcePath.Child("name"), "the name of the source is required"))
+		}
+		if d.Spec.Source.Database.Namespace == d.Namespace && d.Spec.Source.Database.Name == d.Name {
+			errs = append(errs, field.Invalid(sourcePath, d.Spec.Source.Database, "a database cannot be cloned from itself"))
+		}
+		// A clone from another instance requires a role for pg_dump and a role for pg_restore
+		if d.Spec.Source.Role != "" && d.Spec.Source.SourceRole == "" {
+			errs = append(errs, field.Required(specPath.Child("source", "sourceRole"), "the PgUser as which the source is dumped is required"))
+		}
+		if d.Spec.Source.SourceRole != "" && d.Spec.Source.Role == "" {
+			errs = append(errs, field.Required(specPath.Child("source", "role"), "the PgUser as which the dump is restored is required"))
+		}
+	}
+	// Validate deletion
+	deletion := d.Spec.DeletionBehavior
+	deletionPath := specPath.Child("deletion")
+	if deletion.Force && !deletion.Drop {
+		errs = append(errs, field.Invalid(deletionPath.Child("force"), deletion.Force, "force requires drop"))
+	}
+	if deletion.Dump != nil {
+		if !deletion.Drop {
+			errs = append(errs, field.Invalid(deletionPath.Child("dump"), deletion.Dump, "dump requires drop"))
+		}
+		if deletion.Dump.PersistentVolumeClaim == "" {
+			errs = append(errs, field.Required(deletionPath.Child("dump", "persistentVolumeClaim"), "the claim for the dump is required"))
+		}
+		if deletion.Dump.Role == "" {
+			errs = append(errs, field.Required(deletionPath.Child("dump", "role"), "the PgUser as which the dump is taken is required"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("pgDatabaseValidator", func() {
+
+	newDatabase := func(name string) *PgDatabase {
+		return &PgDatabase{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: PgDatabaseSpec{
+				Instance: PgInstanceRef{Kind: ClusterPgInstanceKind, Name: "instance"},
+			},
+		}
+	}
+
+	It("admits a valid database", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newDatabase("service"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses reserved database names", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newDatabase("template1"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("metadata.name"))
+	})
+
+	It("refuses duplicate names on the same instance", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			other := newDatabase("service")
+			other.Namespace = "other"
+			other.UID = "uid-other"
+			list.(*PgDatabaseList).Items = []PgDatabase{*other}
+			return nil
+		}}
+		validator := pgDatabaseValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newDatabase("service"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("admits metadata changes of an invalid database", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		oldDatabase := newDatabase("template1")
+		database := oldDatabase.DeepCopy()
+		database.Labels = map[string]string{"team": "bikes"}
+		database.Finalizers = []string{DefaultFinalizerPgDatabase}
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldDatabase, database)
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses changes of the instance", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		oldDatabase := newDatabase("service")
+		database := newDatabase("service")
+		database.Spec.Instance = PgInstanceRef{Namespace: "default", Name: "instance"}
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldDatabase, database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.instance"))
+	})
+
+	It("refuses a dump without drop", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.DeletionBehavior = PgDatabaseDeletion{Force: true, Dump: &PgDatabaseDump{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.deletion.force"))
+		Expect(err.Error()).To(ContainSubstring("spec.deletion.dump"))
+		Expect(err.Error()).To(ContainSubstring("spec.deletion.dump.persistentVolumeClaim"))
+		Expect(err.Error()).To(ContainSubstring("spec.deletion.dump.role"))
+	})
+
+	It("refuses a source together with a template", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.Template = "template0"
+		database.Spec.Source = &PgDatabaseSource{Database: PgDatabaseRef{Namespace: "default", Name: "service"}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.template"))
+		Expect(err.Error()).To(ContainSubstring("cloned from itself"))
+	})
+
+	It("refuses a source with only one of the roles", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.Source = &PgDatabaseSource{Database: PgDatabaseRef{Namespace: "default", Name: "staging"}, Role: "service-owner"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.source.sourceRole"))
+	})
+
+	It("refuses an unknown state of an extension", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.Extensions = []PgDatabaseExtension{{Name: "pg_trgm", State: "removed"}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.extensions[0].state"))
+	})
+
+	It("admits valid settings", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.Settings = map[string]string{"statement_timeout": "30s", "search_path": "app, public", "app.tenant": "brose"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses invalid and reserved settings", func() {
+		// given:
+		validator := pgDatabaseValidator{&mockReader{}}
+		database := newDatabase("service")
+		database.Spec.Settings = map[string]string{"work_mem; drop": "1MB", "role": "admin"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), database)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.settings[work_mem; drop]"))
+		Expect(err.Error()).To(ContainSubstring("spec.settings[role]"))
+	})
+})
@@ -20,10 +20,14 @@ import (
 	"context"
 	"strconv"
 
+	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const PgInstanceAccessConditionType string = "postgres.brose.bike/instance-access"
+
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // PgInstanceSpec defines the desired state of PgInstance
@@ -38,8 +42,74 @@ type PgInstanceSpec struct {
 	Password PgProperty `json:"password,omitempty"`
 	// The Maintenance Database which should be used to establish the connection, defaults to 'postgres'
 	Database PgProperty `json:"database,omitempty"`
-	// The SSLMode which should be used for the connection, defaults to 'none'
+	// The SSLMode which should be used for the connection, defaults to 'require'
 	SSLMode PgProperty `json:"sslMode,omitempty"`
+	// SSLRootCert contains the PEM encoded certificates of the authorities which are used to verify the server
+	// +optional
+	SSLRootCert PgProperty `json:"sslRootCert,omitempty"`
+	// SSLCert contains the PEM encoded client certificate which is used to authenticate the Administrator User
+	// +optional
+	SSLCert PgProperty `json:"sslCert,omitempty"`
+	// SSLKey contains the PEM encoded private key of the client certificate, it can only be read from a secret
+	// +optional
+	SSLKey PgProperty `json:"sslKey,omitempty"`
+	// SSLCRL contains the PEM encoded certificate revocation list against which the server certificates are checked
+	// +optional
+	SSLCRL PgProperty `json:"sslCRL,omitempty"`
+	// AllowedNamespaces selects the namespaces from which resources are allowed to reference this instance.
+	// A PgInstance can always be referenced from its own namespace, if no selector is given only from there.
+	// A ClusterPgInstance without selector cannot be referenced at all, an empty selector allows all namespaces.
+	// +optional
+	AllowedNamespaces *metav1.LabelSelector `json:"allowedNamespaces,omitempty"`
+	// CredentialProvider obtains short-lived credentials of the Administrator User, e.g. tokens of a cloud provider,
+	// instead of the static Password
+	// +optional
+	CredentialProvider *PgCredentialProvider `json:"credentialProvider,omitempty"`
+}
+
+// PgCredentialProvider defines how the credentials of the Administrator User are obtained
+type PgCredentialProvider struct {
+	// Exec runs an external command, which prints the credentials as JSON
+	// +optional
+	Exec *PgExecCredentialProvider `json:"exec,omitempty"`
+}
+
+// PgExecCredentialProvider runs an external command similar to the exec plugins of kubeconfig files.
+// The command has to print a JSON object with the fields token and optionally expirationTimestamp (RFC 3339),
+// the token is used as password until it expires or the authentication fails.
+// The command line has to be allowed for the namespace of the instance with the flag --credential-exec-config of the operator.
+type PgExecCredentialProvider struct {
+	// Command is the executable which is run
+	Command string `json:"command"`
+	// Args are passed to the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Env contains additional environment variables of the command
+	// +optional
+	Env []PgExecEnvVar `json:"env,omitempty"`
+	// Timeout after which the command is cancelled, defaults to 30s
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// PgExecEnvVar is an environment variable of an external command
+type PgExecEnvVar struct {
+	// Name of the environment variable
+	Name string `json:"name"`
+	// Value of the environment variable
+	Value PgProperty `json:"value"`
+}
+
+// IsNamespaceAllowed returns true if the allowed namespaces selector matches the given namespace
+func (s *PgInstanceSpec) IsNamespaceAllowed(namespace *coreV1.Namespace) (bool, error) {
+	if s.AllowedNamespaces == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(s.AllowedNamespaces)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
 }
 
 func (s *PgInstanceSpec) GetHostname(ctx context.Context, r client.Reader, namespace string) (string, error) {
@@ -71,17 +141,91 @@ func (s *PgInstanceSpec) GetDatabase(ctx context.Context, r client.Reader, names
 }
 
 func (s *PgInstanceSpec) GetSSLMode(ctx context.Context, r client.Reader, namespace string) (string, error) {
-	return s.SSLMode.GetPropertyValueWithDefault(ctx, r, namespace, "sslMode", "none")
+	return s.SSLMode.GetPropertyValueWithDefault(ctx, r, namespace, "sslMode", "require")
+}
+
+func (s *PgInstanceSpec) GetSSLRootCert(ctx context.Context, r client.Reader, namespace string) (string, error) {
+	return s.SSLRootCert.GetPropertyValueWithDefault(ctx, r, namespace, "sslRootCert", "")
+}
+
+func (s *PgInstanceSpec) GetSSLCert(ctx context.Context, r client.Reader, namespace string) (string, error) {
+	return s.SSLCert.GetPropertyValueWithDefault(ctx, r, namespace, "sslCert", "")
+}
+
+func (s *PgInstanceSpec) GetSSLKey(ctx context.Context, r client.Reader, namespace string) (string, error) {
+	return s.SSLKey.GetPropertyValueWithDefault(ctx, r, namespace, "sslKey", "")
+}
+
+func (s *PgInstanceSpec) GetSSLCRL(ctx context.Context, r client.Reader, namespace string) (string, error) {
+	return s.SSLCRL.GetPropertyValueWithDefault(ctx, r, namespace, "sslCRL", "")
+}
+
+// PgInstanceCertificateStatus describes a certificate which is used for the connection
+type PgInstanceCertificateStatus struct {
+	// Property is the name of the property which contains the certificate
+	Property string `json:"property"`
+	// Subject is the distinguished name of the certificate
+	Subject string `json:"subject"`
+	// NotAfter is the time at which the certificate expires
+	NotAfter metav1.Time `json:"notAfter"`
+}
+
+// PgInstanceAdminPrivileges describes the privileges of the administrator role on the server
+type PgInstanceAdminPrivileges struct {
+	// Superuser is true if the administrator role is a superuser
+	Superuser bool `json:"superuser"`
+	// CreateRole is true if the administrator role has the CREATEROLE attribute
+	CreateRole bool `json:"createRole"`
+	// CreateDB is true if the administrator role has the CREATEDB attribute
+	CreateDB bool `json:"createDB"`
 }
 
 // PgInstanceStatus defines the observed state of PgInstance
 type PgInstanceStatus struct {
 	// Conditions represent the current connection state
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// ServerVersion is the version of the Postgres server
+	// +optional
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// AdminPrivileges are the privileges of the administrator role
+	// +optional
+	AdminPrivileges *PgInstanceAdminPrivileges `json:"adminPrivileges,omitempty"`
+	// StartTime is the time at which the Postgres server was started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Connections is the number of currently open client connections
+	// +optional
+	Connections int `json:"connections,omitempty"`
+	// MaxConnections is the maximum number of concurrent connections of the server
+	// +optional
+	MaxConnections int `json:"maxConnections,omitempty"`
+	// Users is the number of PgUser objects which reference this instance
+	// +optional
+	Users int `json:"users,omitempty"`
+	// Databases is the number of PgDatabase objects which reference this instance
+	// +optional
+	Databases int `json:"databases,omitempty"`
+	// Certificates contains the expiry of the root and client certificates used for the connection
+	// +optional
+	Certificates []PgInstanceCertificateStatus `json:"certificates,omitempty"`
+	// LastProbeTime is the time at which the server was probed successfully for the last time
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Connected",type=string,JSONPath=`.status.conditions[?(@.type=="postgres.brose.bike/connected")].status`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.serverVersion`
+//+kubebuilder:printcolumn:name="Superuser",type=boolean,JSONPath=`.status.adminPrivileges.superuser`,priority=1
+//+kubebuilder:printcolumn:name="CreateRole",type=boolean,JSONPath=`.status.adminPrivileges.createRole`,priority=1
+//+kubebuilder:printcolumn:name="CreateDB",type=boolean,JSONPath=`.status.adminPrivileges.createDB`,priority=1
+//+kubebuilder:printcolumn:name="Connections",type=integer,JSONPath=`.status.connections`
+//+kubebuilder:printcolumn:name="Max Connections",type=integer,JSONPath=`.status.maxConnections`
+//+kubebuilder:printcolumn:name="Users",type=integer,JSONPath=`.status.users`
+//+kubebuilder:printcolumn:name="Databases",type=integer,JSONPath=`.status.databases`
+//+kubebuilder:printcolumn:name="Up Since",type=date,JSONPath=`.status.startTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PgInstance is the Schema for the pginstances API
 type PgInstance struct {
@@ -100,6 +244,14 @@ func (i *PgInstance) SetConditions(conditions []metav1.Condition) {
 	i.Status.Conditions = conditions
 }
 
+// IsNamespaceAllowed returns true if resources in the given namespace are allowed to reference this instance
+func (i *PgInstance) IsNamespaceAllowed(namespace *coreV1.Namespace) (bool, error) {
+	if namespace.Name == i.Namespace {
+		return true, nil
+	}
+	return i.Spec.IsNamespaceAllowed(namespace)
+}
+
 //+kubebuilder:object:root=true
 
 // PgInstanceList contains a list of PgInstance
@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -159,4 +160,105 @@ var _ = Describe("PgInstanceSpec", func() {
 		Expect(database).To(Equal("database+hash"))
 		Expect(sslmode).To(Equal("sslmode+hash"))
 	})
+
+	It("requires ssl if no sslMode is set", func() {
+		// given:
+		instanceSpec := PgInstanceSpec{}
+		// and:
+		ctx := context.TODO()
+		r := mockReader{}
+		// when:
+		sslmode, err := instanceSpec.GetSSLMode(ctx, &r, "default")
+		// then:
+		Expect(err).To(BeNil())
+		Expect(sslmode).To(Equal("require"))
+	})
+})
+
+var _ = Describe("PgInstance namespace access", func() {
+
+	teamNamespace := v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "team",
+			Labels: map[string]string{"team": "payments"},
+		},
+	}
+	otherNamespace := v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "other",
+		},
+	}
+
+	It("allows the own namespace without selector", func() {
+		// given:
+		instance := PgInstance{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "instance"}}
+		// when:
+		ownAllowed, err0 := instance.IsNamespaceAllowed(&teamNamespace)
+		otherAllowed, err1 := instance.IsNamespaceAllowed(&otherNamespace)
+		// then:
+		Expect(err0).To(BeNil())
+		Expect(err1).To(BeNil())
+		Expect(ownAllowed).To(BeTrue())
+		Expect(otherAllowed).To(BeFalse())
+	})
+
+	It("allows namespaces matching the selector", func() {
+		// given:
+		instance := PgInstance{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "postgres", Name: "instance"},
+			Spec: PgInstanceSpec{
+				AllowedNamespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			},
+		}
+		// when:
+		teamAllowed, err0 := instance.IsNamespaceAllowed(&teamNamespace)
+		otherAllowed, err1 := instance.IsNamespaceAllowed(&otherNamespace)
+		// then:
+		Expect(err0).To(BeNil())
+		Expect(err1).To(BeNil())
+		Expect(teamAllowed).To(BeTrue())
+		Expect(otherAllowed).To(BeFalse())
+	})
+
+	It("refuses all namespaces for a cluster instance without selector", func() {
+		// given:
+		instance := ClusterPgInstance{ObjectMeta: metav1.ObjectMeta{Name: "instance"}}
+		// when:
+		allowed, err := instance.IsNamespaceAllowed(&teamNamespace)
+		// then:
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+	})
+
+	It("allows all namespaces for a cluster instance with empty selector", func() {
+		// given:
+		instance := ClusterPgInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance"},
+			Spec: ClusterPgInstanceSpec{
+				PgInstanceSpec: PgInstanceSpec{AllowedNamespaces: &metav1.LabelSelector{}},
+			},
+		}
+		// when:
+		allowed, err := instance.IsNamespaceAllowed(&otherNamespace)
+		// then:
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("converts a cluster instance into an instance in the properties namespace", func() {
+		// given:
+		instance := ClusterPgInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance"},
+			Spec: ClusterPgInstanceSpec{
+				PgInstanceSpec: PgInstanceSpec{Hostname: PgProperty{Value: "hostname"}},
+				Namespace:      "postgres",
+			},
+		}
+		// when:
+		converted := instance.ToPgInstance()
+		// then:
+		Expect(converted.Namespace).To(Equal("postgres"))
+		Expect(converted.Name).To(Equal("instance"))
+		Expect(converted.Spec.Hostname.Value).To(Equal("hostname"))
+	})
 })
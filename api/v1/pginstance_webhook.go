@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pginstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pginstances,verbs=create;update,versions=v1,name=vpginstance.kb.io,admissionReviewVersions=v1
+
+// pgInstanceValidator validates PgInstance resources before they are admitted
+type pgInstanceValidator struct{}
+
+var _ webhook.CustomValidator = &pgInstanceValidator{}
+
+func (i *PgInstance) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(i).
+		WithValidator(&pgInstanceValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgInstanceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	instance, ok := obj.(*PgInstance)
+	if !ok {
+		return fmt.Errorf("expected a PgInstance but got a %T", obj)
+	}
+	return toInvalidError("PgInstance", instance.Name, instance.Spec.validate(field.NewPath("spec")))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgInstanceValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldInstance, ok := oldObj.(*PgInstance)
+	if !ok {
+		return fmt.Errorf("expected a PgInstance but got a %T", oldObj)
+	}
+	instance, ok := newObj.(*PgInstance)
+	if !ok {
+		return fmt.Errorf("expected a PgInstance but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if instance.DeletionTimestamp != nil || isSpecUnchanged(oldInstance.Spec, instance.Spec) {
+		return nil
+	}
+	return toInvalidError("PgInstance", instance.Name, instance.Spec.validate(field.NewPath("spec")))
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgInstanceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the connection properties and the namespace selector of the instance
+func (s *PgInstanceSpec) validate(path *field.Path) field.ErrorList {
+	errs := validateProperty(path.Child("host"), s.Hostname, true)
+	errs = append(errs, validatePortProperty(path.Child("port"), s.Port)...)
+	errs = append(errs, validateProperty(path.Child("username"), s.Username, true)...)
+	if s.CredentialProvider != nil {
+		errs = append(errs, s.CredentialProvider.validate(path.Child("credentialProvider"))...)
+		if s.Password.IsSet() {
+			errs = append(errs, field.Forbidden(path.Child("password"), "the password cannot be set together with a credential provider"))
+		}
+	} else {
+		errs = append(errs, validateProperty(path.Child("password"), s.Password, true)...)
+	}
+	errs = append(errs, validateProperty(path.Child("database"), s.Database, false)...)
+	errs = append(errs, validateSSLModeProperty(path.Child("sslMode"), s.SSLMode)...)
+	errs = append(errs, validateProperty(path.Child("sslRootCert"), s.SSLRootCert, false)...)
+	errs = append(errs, validateProperty(path.Child("sslCert"), s.SSLCert, false)...)
+	errs = append(errs, validateProperty(path.Child("sslKey"), s.SSLKey, false)...)
+	errs = append(errs, validateProperty(path.Child("sslCRL"), s.SSLCRL, false)...)
+	// Private keys must not be stored in plain text
+	if s.SSLKey.Value != "" || s.SSLKey.ConfigMapKeyRef != nil {
+		errs = append(errs, field.Forbidden(path.Child("sslKey"), "the private key can only be read from a secret"))
+	}
+	if s.SSLCert.IsSet() != s.SSLKey.IsSet() {
+		errs = append(errs, field.Required(path.Child("sslKey"), "sslCert and sslKey have to be set together"))
+	}
+	if s.AllowedNamespaces != nil {
+		if _, err := metav1.LabelSelectorAsSelector(s.AllowedNamespaces); err != nil {
+			errs = append(errs, field.Invalid(path.Child("allowedNamespaces"), s.AllowedNamespaces, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validate checks that exactly one provider is configured
+func (p *PgCredentialProvider) validate(path *field.Path) field.ErrorList {
+	if p.Exec == nil {
+		return field.ErrorList{field.Required(path.Child("exec"), "a provider is required")}
+	}
+	execPath := path.Child("exec")
+	var errs field.ErrorList
+	if p.Exec.Command == "" {
+		errs = append(errs, field.Required(execPath.Child("command"), "the command is required"))
+	}
+	if p.Exec.Timeout != nil && p.Exec.Timeout.Duration < 0 {
+		errs = append(errs, field.Invalid(execPath.Child("timeout"), p.Exec.Timeout.String(), "the timeout cannot be negative"))
+	}
+	for i, env := range p.Exec.Env {
+		if env.Name == "" {
+			errs = append(errs, field.Required(execPath.Child("env").Index(i).Child("name"), "the name is required"))
+		}
+		errs = append(errs, validateProperty(execPath.Child("env").Index(i).Child("value"), env.Value, false)...)
+	}
+	return errs
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFinalizerPgPublication contains the name for the default finalizer
+// of the PgPublication resource
+const DefaultFinalizerPgPublication = "postgres.brose.bike/pgpublication"
+const PgPublicationExistsConditionType string = "pgpublication.postgres.brose.bike/exists"
+
+// +kubebuilder:validation:Enum=insert;update;delete;truncate
+type PgPublicationOperation string
+
+const (
+	InsertPublicationOperation   PgPublicationOperation = "insert"
+	UpdatePublicationOperation   PgPublicationOperation = "update"
+	DeletePublicationOperation   PgPublicationOperation = "delete"
+	TruncatePublicationOperation PgPublicationOperation = "truncate"
+)
+
+type PgPublicationTable struct {
+	// Schema contains the schema of the table (defaults to public)
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Name contains the name of the table
+	Name string `json:"name"`
+}
+
+// GetQualifiedName returns the name of the table prefixed with its schema
+func (t *PgPublicationTable) GetQualifiedName() string {
+	if t.Schema == "" {
+		return "public." + t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// PgPublicationSpec defines the desired state of PgPublication
+type PgPublicationSpec struct {
+	// Database identifies the PgDatabase in which the publication should be managed
+	Database PgDatabaseRef `json:"database"`
+	// Name contains the name of the publication in the database, defaults to the name of the resource
+	// +optional
+	Name string `json:"name,omitempty"`
+	// AllTables publishes all tables of the database, including tables created in the future.
+	// It cannot be combined with tables and cannot be changed after creation.
+	// +optional
+	AllTables bool `json:"allTables,omitempty"`
+	// Tables contains the published tables
+	// +optional
+	Tables []PgPublicationTable `json:"tables,omitempty"`
+	// Operations contains the published operations (defaults to all operations)
+	// +optional
+	Operations []PgPublicationOperation `json:"operations,omitempty"`
+}
+
+// PgPublicationStatus defines the observed state of PgPublication
+type PgPublicationStatus struct {
+	// Conditions represent the current connection state
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Tables contains the schema qualified names of the published tables,
+	// it is empty for publications of all tables
+	// +optional
+	Tables []string `json:"tables,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// PgPublication is the Schema for the pgpublications API
+type PgPublication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgPublicationSpec   `json:"spec,omitempty"`
+	Status PgPublicationStatus `json:"status,omitempty"`
+}
+
+func PgPublicationKind() string {
+	obj := &PgPublication{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (p *PgPublication) GetConditions() []metav1.Condition {
+	return p.Status.Conditions
+}
+
+func (p *PgPublication) SetConditions(conditions []metav1.Condition) {
+	p.Status.Conditions = conditions
+}
+
+// GetPublicationName returns the name of the publication in the database
+func (p *PgPublication) GetPublicationName() string {
+	if p.Spec.Name != "" {
+		return p.Spec.Name
+	}
+	return p.Name
+}
+
+// GetTableNames returns the schema qualified names of the published tables
+func (p *PgPublication) GetTableNames() []string {
+	names := []string{}
+	for _, table := range p.Spec.Tables {
+		names = append(names, table.GetQualifiedName())
+	}
+	return names
+}
+
+// GetOperations returns the names of the published operations
+func (p *PgPublication) GetOperations() []string {
+	operations := []string{}
+	for _, operation := range p.Spec.Operations {
+		operations = append(operations, string(operation))
+	}
+	return operations
+}
+
+func (p *PgPublication) GetDatabaseId() types.NamespacedName {
+	return p.Spec.Database.ToNamespacedName()
+}
+
+func (p *PgPublication) GetDatabaseIdString() string {
+	return p.Spec.Database.ToNamespacedName().String()
+}
+
+func (p *PgPublication) ToNamespacedName() string {
+	return p.Namespace + "/" + p.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgPublicationList contains a list of PgPublication
+type PgPublicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgPublication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgPublication{}, &PgPublicationList{})
+}
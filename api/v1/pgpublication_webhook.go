@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgpublication,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgpublications,verbs=create;update,versions=v1,name=vpgpublication.kb.io,admissionReviewVersions=v1
+
+// pgPublicationValidator validates PgPublication resources before they are admitted
+type pgPublicationValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &pgPublicationValidator{}
+
+func (p *PgPublication) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(p).
+		WithValidator(&pgPublicationValidator{mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgPublicationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	publication, ok := obj.(*PgPublication)
+	if !ok {
+		return fmt.Errorf("expected a PgPublication but got a %T", obj)
+	}
+	errs := publication.validate()
+	duplicates, err := v.validateUniqueName(ctx, publication)
+	if err != nil {
+		return err
+	}
+	return toInvalidError(PgPublicationKind(), publication.Name, append(errs, duplicates...))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgPublicationValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldPublication, ok := oldObj.(*PgPublication)
+	if !ok {
+		return fmt.Errorf("expected a PgPublication but got a %T", oldObj)
+	}
+	publication, ok := newObj.(*PgPublication)
+	if !ok {
+		return fmt.Errorf("expected a PgPublication but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if publication.DeletionTimestamp != nil || isSpecUnchanged(oldPublication.Spec, publication.Spec) {
+		return nil
+	}
+	specPath := field.NewPath("spec")
+	errs := publication.validate()
+	if oldPublication.Spec.Database != publication.Spec.Database {
+		errs = append(errs, field.Forbidden(specPath.Child("database"), "the database cannot be changed"))
+	}
+	if oldPublication.GetPublicationName() != publication.GetPublicationName() {
+		errs = append(errs, field.Forbidden(specPath.Child("name"), "the name cannot be changed"))
+	}
+	if oldPublication.Spec.AllTables != publication.Spec.AllTables {
+		errs = append(errs, field.Forbidden(specPath.Child("allTables"), "allTables cannot be changed"))
+	}
+	return toInvalidError(PgPublicationKind(), publication.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgPublicationValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateUniqueName checks that no other PgPublication manages a publication with the same name in the database
+func (v *pgPublicationValidator) validateUniqueName(ctx context.Context, publication *PgPublication) (field.ErrorList, error) {
+	var publications PgPublicationList
+	if err := v.client.List(ctx, &publications); err != nil {
+		return nil, err
+	}
+	for _, other := range publications.Items {
+		if other.UID != publication.UID && other.GetPublicationName() == publication.GetPublicationName() && other.Spec.Database == publication.Spec.Database {
+			return field.ErrorList{field.Duplicate(field.NewPath("spec", "name"), publication.GetPublicationName())}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the fields of the PgPublication, which do not depend on other resources
+func (p *PgPublication) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateIdentifier(specPath.Child("name"), p.GetPublicationName())
+	errs = append(errs, validateLocalDatabaseRef(specPath.Child("database"), p.Spec.Database, p.Namespace)...)
+	if p.Spec.AllTables && len(p.Spec.Tables) > 0 {
+		errs = append(errs, field.Forbidden(specPath.Child("tables"), "tables and allTables cannot be used together"))
+	}
+	tableNames := make(map[string]bool)
+	for i, table := range p.Spec.Tables {
+		tablePath := specPath.Child("tables").Index(i)
+		if table.Name == "" {
+			errs = append(errs, field.Required(tablePath.Child("name"), "the name of the table is required"))
+		} else if tableNames[table.GetQualifiedName()] {
+			errs = append(errs, field.Duplicate(tablePath, table.GetQualifiedName()))
+		}
+		tableNames[table.GetQualifiedName()] = true
+	}
+	return errs
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("pgPublicationValidator", func() {
+
+	newPublication := func(name string) *PgPublication {
+		return &PgPublication{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: PgPublicationSpec{
+				Database: PgDatabaseRef{Namespace: "default", Name: "service"},
+				Tables:   []PgPublicationTable{{Name: "orders"}, {Schema: "sales", Name: "orders"}},
+			},
+		}
+	}
+
+	It("admits a valid publication", func() {
+		// given:
+		validator := pgPublicationValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newPublication("orders"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses tables together with all tables", func() {
+		// given:
+		validator := pgPublicationValidator{&mockReader{}}
+		publication := newPublication("orders")
+		publication.Spec.AllTables = true
+		// when:
+		err := validator.ValidateCreate(context.TODO(), publication)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.tables"))
+	})
+
+	It("refuses duplicate tables", func() {
+		// given:
+		validator := pgPublicationValidator{&mockReader{}}
+		publication := newPublication("orders")
+		publication.Spec.Tables = append(publication.Spec.Tables, PgPublicationTable{Schema: "public", Name: "orders"})
+		// when:
+		err := validator.ValidateCreate(context.TODO(), publication)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.tables[2]"))
+	})
+
+	It("refuses a database in another namespace", func() {
+		// given:
+		validator := pgPublicationValidator{&mockReader{}}
+		publication := newPublication("orders")
+		publication.Spec.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), publication)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.database.namespace"))
+	})
+
+	It("refuses duplicate names in the same database", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			other := newPublication("other")
+			other.Spec.Name = "orders"
+			list.(*PgPublicationList).Items = []PgPublication{*other}
+			return nil
+		}}
+		validator := pgPublicationValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newPublication("orders"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("refuses changes of all tables", func() {
+		// given:
+		validator := pgPublicationValidator{&mockReader{}}
+		oldPublication := newPublication("orders")
+		oldPublication.Spec.Tables = nil
+		publication := oldPublication.DeepCopy()
+		publication.Spec.AllTables = true
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldPublication, publication)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.allTables"))
+	})
+})
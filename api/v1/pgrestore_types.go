@@ -0,0 +1,138 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const PgRestoreSucceededConditionType string = "pgrestore.postgres.brose.bike/succeeded"
+
+// PgRestoreSpec defines the desired state of PgRestore
+type PgRestoreSpec struct {
+	// Database identifies the PgDatabase into which the backup should be restored
+	Database PgDatabaseRef `json:"database"`
+	// Role contains the name of the PgUser in the namespace of the resource, as which pg_restore connects to the database,
+	// e.g. the owner of the database. The operator passes the password from the Secret of the PgUser to the Jobs.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+	// PersistentVolumeClaim specifies the name of the claim in the namespace of the PgRestore, which contains the backup
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// File contains the path of the backup relative to the root of the claim
+	File string `json:"file"`
+	// Format specifies the format of the backup (defaults to custom)
+	// +optional
+	Format PgBackupFormat `json:"format,omitempty"`
+	// Clean specifies if the objects contained in the backup should be dropped before they are restored (defaults to false),
+	// it is not supported for backups in the plain format
+	// +optional
+	Clean bool `json:"clean,omitempty"`
+	// Image specifies the image containing pg_restore and psql (defaults to postgres:16)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// PgRestoreStatus defines the observed state of PgRestore
+type PgRestoreStatus struct {
+	// Conditions represent the current state of the restore
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Job contains the state of the Job restoring the backup
+	// +optional
+	Job *PgJobStatus `json:"job,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.database.name`
+//+kubebuilder:printcolumn:name="File",type=string,JSONPath=`.spec.file`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.job.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PgRestore is the Schema for the pgrestores API
+type PgRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgRestoreSpec   `json:"spec,omitempty"`
+	Status PgRestoreStatus `json:"status,omitempty"`
+}
+
+func PgRestoreKind() string {
+	obj := &PgRestore{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (r *PgRestore) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+func (r *PgRestore) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+// GetFormat returns the format of the backup and falls back to custom if none is set
+func (r *PgRestore) GetFormat() PgBackupFormat {
+	if r.Spec.Format == "" {
+		return CustomBackupFormat
+	}
+	return r.Spec.Format
+}
+
+// GetImage returns the image which should be used to restore the backup
+func (r *PgRestore) GetImage() string {
+	if r.Spec.Image == "" {
+		return DefaultDumpImage
+	}
+	return r.Spec.Image
+}
+
+// GetUserId returns the id of the PgUser as which the Jobs connect to the database
+func (r *PgRestore) GetUserId() types.NamespacedName {
+	return types.NamespacedName{Namespace: r.Namespace, Name: r.Spec.Role}
+}
+
+func (r *PgRestore) GetDatabaseId() types.NamespacedName {
+	return r.Spec.Database.ToNamespacedName()
+}
+
+func (r *PgRestore) GetDatabaseIdString() string {
+	return r.Spec.Database.ToNamespacedName().String()
+}
+
+func (r *PgRestore) ToNamespacedName() string {
+	return r.Namespace + "/" + r.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgRestoreList contains a list of PgRestore
+type PgRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgRestore{}, &PgRestoreList{})
+}
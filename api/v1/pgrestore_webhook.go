@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgrestore,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgrestores,verbs=create;update,versions=v1,name=vpgrestore.kb.io,admissionReviewVersions=v1
+
+// pgRestoreValidator validates PgRestore resources before they are admitted
+type pgRestoreValidator struct{}
+
+var _ webhook.CustomValidator = &pgRestoreValidator{}
+
+func (r *PgRestore) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&pgRestoreValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgRestoreValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	restore, ok := obj.(*PgRestore)
+	if !ok {
+		return fmt.Errorf("expected a PgRestore but got a %T", obj)
+	}
+	return toInvalidError(PgRestoreKind(), restore.Name, restore.validate())
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgRestoreValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldRestore, ok := oldObj.(*PgRestore)
+	if !ok {
+		return fmt.Errorf("expected a PgRestore but got a %T", oldObj)
+	}
+	restore, ok := newObj.(*PgRestore)
+	if !ok {
+		return fmt.Errorf("expected a PgRestore but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if restore.DeletionTimestamp != nil || isSpecUnchanged(oldRestore.Spec, restore.Spec) {
+		return nil
+	}
+	return toInvalidError(PgRestoreKind(), restore.Name, restore.validate())
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgRestoreValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the fields of the PgRestore, which do not depend on other resources
+func (r *PgRestore) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateLocalDatabaseRef(specPath.Child("database"), r.Spec.Database, r.Namespace)
+	if r.Spec.Role == "" {
+		errs = append(errs, field.Required(specPath.Child("role"), "the PgUser as which the Jobs connect is required"))
+	}
+	if r.Spec.PersistentVolumeClaim == "" {
+		errs = append(errs, field.Required(specPath.Child("persistentVolumeClaim"), "the claim is required"))
+	}
+	if r.Spec.File == "" {
+		errs = append(errs, field.Required(specPath.Child("file"), "the file of the backup is required"))
+	}
+	return errs
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("pgRestoreValidator", func() {
+
+	newRestore := func() *PgRestore {
+		return &PgRestore{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "service"},
+			Spec: PgRestoreSpec{
+				Database:              PgDatabaseRef{Namespace: "default", Name: "service"},
+				Role:                  "service-owner",
+				PersistentVolumeClaim: "backups",
+				File:                  "nightly-20260101T020000Z.dump",
+			},
+		}
+	}
+
+	It("admits a valid restore", func() {
+		// given:
+		validator := pgRestoreValidator{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newRestore())
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses a database in another namespace", func() {
+		// given:
+		validator := pgRestoreValidator{}
+		restore := newRestore()
+		restore.Spec.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), restore)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.database.namespace"))
+	})
+
+	It("refuses a restore without role", func() {
+		// given:
+		validator := pgRestoreValidator{}
+		restore := newRestore()
+		restore.Spec.Role = ""
+		// when:
+		err := validator.ValidateCreate(context.TODO(), restore)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.role"))
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFinalizerPgRole contains the name for the default finalizer
+// of the PgRole resource
+const DefaultFinalizerPgRole = "postgres.brose.bike/pgrole"
+const PgRoleExistsConditionType string = "pgrole.postgres.brose.bike/exists"
+const PgRoleMembershipsConditionType string = "pgrole.postgres.brose.bike/memberships"
+const PgRoleDeletionConditionType string = "pgrole.postgres.brose.bike/deletion"
+const PgRoleOwnershipConditionType string = "pgrole.postgres.brose.bike/ownership"
+
+// PgRoleMembership represents the membership of a role in a group role
+type PgRoleMembership struct {
+	// Name contains the name of the group role
+	Name string `json:"name"`
+	// Admin allows the member to grant the group role to other roles (defaults to false)
+	// +optional
+	Admin *bool `json:"admin,omitempty"`
+	// Inherit specifies if the member inherits the privileges of the group role (defaults to true)
+	// Postgres versions before 16 only support inherited memberships.
+	// +optional
+	Inherit *bool `json:"inherit,omitempty"`
+}
+
+func (m *PgRoleMembership) IsAdmin() bool {
+	return m.Admin != nil && *m.Admin
+}
+
+func (m *PgRoleMembership) IsInherit() bool {
+	return m.Inherit == nil || *m.Inherit
+}
+
+// PgRoleSpec defines the desired state of PgRole
+type PgRoleSpec struct {
+	// Instance identifies the PgInstanceConnection which should be used
+	Instance PgInstanceRef `json:"instance"`
+	// MemberOf contains the group roles in which this role should be a member
+	// +optional
+	MemberOf []PgRoleMembership `json:"memberOf,omitempty"`
+	// AdoptionPolicy defines how an already existing role is handled, defaults to Fail
+	// +optional
+	AdoptionPolicy PgAdoptionPolicy `json:"adoptionPolicy,omitempty"`
+}
+
+// GetAdoptionPolicy returns the adoption policy or Fail if none is set
+func (s *PgRoleSpec) GetAdoptionPolicy() PgAdoptionPolicy {
+	if s.AdoptionPolicy == "" {
+		return FailAdoptionPolicy
+	}
+	return s.AdoptionPolicy
+}
+
+// PgRoleStatus defines the observed state of PgRole
+type PgRoleStatus struct {
+	// Conditions represent the current connection state
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Memberships contains the names of the group roles which were granted by the operator
+	// +optional
+	Memberships []string `json:"memberships,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// PgRole is the Schema for the pgroles API
+type PgRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgRoleSpec   `json:"spec,omitempty"`
+	Status PgRoleStatus `json:"status,omitempty"`
+}
+
+func PgRoleKind() string {
+	obj := &PgRole{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (r *PgRole) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+func (r *PgRole) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+func (r *PgRole) GetMemberships() []string {
+	return r.Status.Memberships
+}
+
+func (r *PgRole) SetMemberships(memberships []string) {
+	r.Status.Memberships = memberships
+}
+
+func (r *PgRole) GetInstanceId() types.NamespacedName {
+	return r.Spec.Instance.ToNamespacedName()
+}
+
+func (r *PgRole) GetInstanceIdString() string {
+	return r.Spec.Instance.ToNamespacedName().String()
+}
+
+func (r *PgRole) ToNamespacedName() string {
+	return r.Namespace + "/" + r.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgRoleList contains a list of PgRole
+type PgRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgRole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgRole{}, &PgRoleList{})
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgrole,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgroles,verbs=create;update,versions=v1,name=vpgrole.kb.io,admissionReviewVersions=v1
+
+// pgRoleValidator validates PgRole resources before they are admitted
+type pgRoleValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &pgRoleValidator{}
+
+func (r *PgRole) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&pgRoleValidator{mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgRoleValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	role, ok := obj.(*PgRole)
+	if !ok {
+		return fmt.Errorf("expected a PgRole but got a %T", obj)
+	}
+	errs := role.validate()
+	duplicates, err := v.validateUniqueName(ctx, role)
+	if err != nil {
+		return err
+	}
+	return toInvalidError(PgRoleKind(), role.Name, append(errs, duplicates...))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgRoleValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldRole, ok := oldObj.(*PgRole)
+	if !ok {
+		return fmt.Errorf("expected a PgRole but got a %T", oldObj)
+	}
+	role, ok := newObj.(*PgRole)
+	if !ok {
+		return fmt.Errorf("expected a PgRole but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if role.DeletionTimestamp != nil || isSpecUnchanged(oldRole.Spec, role.Spec) {
+		return nil
+	}
+	errs := role.validate()
+	errs = append(errs, validateInstanceRefUpdate(field.NewPath("spec", "instance"), oldRole.Spec.Instance, role.Spec.Instance)...)
+	return toInvalidError(PgRoleKind(), role.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgRoleValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateUniqueName checks that no other PgRole or PgUser manages a role with the same name on the instance,
+// which includes the alternate login roles of the password rotation
+func (v *pgRoleValidator) validateUniqueName(ctx context.Context, role *PgRole) (field.ErrorList, error) {
+	path := field.NewPath("metadata", "name")
+	var roles PgRoleList
+	if err := v.client.List(ctx, &roles); err != nil {
+		return nil, err
+	}
+	for _, other := range roles.Items {
+		if other.UID != role.UID && other.Name == role.Name && isSameInstance(other.Spec.Instance, role.Spec.Instance) {
+			return field.ErrorList{field.Duplicate(path, role.Name)}, nil
+		}
+	}
+	var users PgUserList
+	if err := v.client.List(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, other := range users.Items {
+		if other.hasRoleName(role.Name) && isSameInstance(other.Spec.Instance, role.Spec.Instance) {
+			return field.ErrorList{field.Duplicate(path, role.Name)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the fields of the PgRole, which do not depend on other resources
+func (r *PgRole) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateRoleName(field.NewPath("metadata", "name"), r.Name)
+	errs = append(errs, validateInstanceRef(specPath.Child("instance"), r.Spec.Instance)...)
+	errs = append(errs, validateMemberships(specPath.Child("memberOf"), r.Spec.MemberOf, r.Name)...)
+	return errs
+}
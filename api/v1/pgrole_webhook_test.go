@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("pgRoleValidator", func() {
+
+	newRole := func(name string) *PgRole {
+		return &PgRole{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: PgRoleSpec{
+				Instance: PgInstanceRef{Namespace: "default", Name: "instance"},
+			},
+		}
+	}
+
+	It("admits a valid role", func() {
+		// given:
+		validator := pgRoleValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newRole("readers"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses reserved role names", func() {
+		// given:
+		validator := pgRoleValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newRole("pg_monitor"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("metadata.name"))
+	})
+
+	It("refuses memberships in predefined and reserved roles", func() {
+		// given:
+		validator := pgRoleValidator{&mockReader{}}
+		role := newRole("readers")
+		role.Spec.MemberOf = []PgRoleMembership{{Name: "pg_read_server_files"}, {Name: "postgres"}, {Name: "readers"}, {Name: "writers"}, {Name: "writers"}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), role)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.memberOf[0].name"))
+		Expect(err.Error()).To(ContainSubstring("spec.memberOf[1].name"))
+		Expect(err.Error()).To(ContainSubstring("spec.memberOf[2].name"))
+		Expect(err.Error()).To(ContainSubstring("spec.memberOf[4].name"))
+		Expect(err.Error()).NotTo(ContainSubstring("spec.memberOf[3].name"))
+	})
+
+	It("refuses a role with the name of a user on the same instance", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if users, ok := list.(*PgUserList); ok {
+				users.Items = []PgUser{{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "readers"},
+					Spec:       PgUserSpec{Instance: PgInstanceRef{Namespace: "default", Name: "instance"}},
+				}}
+			}
+			return nil
+		}}
+		validator := pgRoleValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newRole("readers"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("refuses the name of the alternate login role of a user on the same instance", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if users, ok := list.(*PgUserList); ok {
+				users.Items = []PgUser{{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "readers"},
+					Spec: PgUserSpec{
+						Instance: PgInstanceRef{Namespace: "default", Name: "instance"},
+						Rotation: &PgUserRotation{},
+					},
+				}}
+			}
+			return nil
+		}}
+		validator := pgRoleValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newRole("readers_alt"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("refuses changes of the instance", func() {
+		// given:
+		validator := pgRoleValidator{&mockReader{}}
+		oldRole := newRole("readers")
+		role := oldRole.DeepCopy()
+		role.Spec.Instance.Name = "other"
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldRole, role)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.instance"))
+	})
+})
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFinalizerPgSchema contains the name for the default finalizer
+// of the PgSchema resource
+const DefaultFinalizerPgSchema = "postgres.brose.bike/pgschema"
+const PgSchemaExistsConditionType string = "pgschema.postgres.brose.bike/exists"
+const PgSchemaOwnerConditionType string = "pgschema.postgres.brose.bike/owner"
+
+// +kubebuilder:validation:Enum=Drop;Retain;Cascade
+type PgSchemaDeletionPolicy string
+
+const (
+	// Drops the schema, fails if the schema still contains objects
+	DropSchemaDeletionPolicy PgSchemaDeletionPolicy = "Drop"
+
+	// Keeps the schema and all contained objects in the database
+	RetainSchemaDeletionPolicy PgSchemaDeletionPolicy = "Retain"
+
+	// Drops the schema and all objects contained in the schema
+	CascadeSchemaDeletionPolicy PgSchemaDeletionPolicy = "Cascade"
+)
+
+type PgSchemaDeletion struct {
+	// Policy specifies what should happen with the schema on deletion (defaults to Retain)
+	// +optional
+	Policy PgSchemaDeletionPolicy `json:"policy,omitempty"`
+}
+
+// PgSchemaSpec defines the desired state of PgSchema
+type PgSchemaSpec struct {
+	// Database identifies the PgDatabase in which the schema should be managed
+	Database PgDatabaseRef `json:"database"`
+	// Name contains the name of the schema in the database, defaults to the name of the resource
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Owner contains the name of the role which should own the schema
+	// +optional
+	Owner string `json:"owner,omitempty"`
+	// DeletionBehavior specifies what should happen when the manifest gets deleted
+	// +optional
+	DeletionBehavior PgSchemaDeletion `json:"deletion,omitempty"`
+}
+
+// PgSchemaStatus defines the observed state of PgSchema
+type PgSchemaStatus struct {
+	// Conditions represent the current connection state
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// PgSchema is the Schema for the pgschemas API
+type PgSchema struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgSchemaSpec   `json:"spec,omitempty"`
+	Status PgSchemaStatus `json:"status,omitempty"`
+}
+
+func PgSchemaKind() string {
+	obj := &PgSchema{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (s *PgSchema) GetConditions() []metav1.Condition {
+	return s.Status.Conditions
+}
+
+func (s *PgSchema) SetConditions(conditions []metav1.Condition) {
+	s.Status.Conditions = conditions
+}
+
+// GetSchemaName returns the name of the schema in the database
+func (s *PgSchema) GetSchemaName() string {
+	if s.Spec.Name != "" {
+		return s.Spec.Name
+	}
+	return s.Name
+}
+
+// GetDeletionPolicy returns the deletion policy and falls back to Retain if none is set
+func (s *PgSchema) GetDeletionPolicy() PgSchemaDeletionPolicy {
+	if s.Spec.DeletionBehavior.Policy == "" {
+		return RetainSchemaDeletionPolicy
+	}
+	return s.Spec.DeletionBehavior.Policy
+}
+
+func (s *PgSchema) GetDatabaseId() types.NamespacedName {
+	return s.Spec.Database.ToNamespacedName()
+}
+
+func (s *PgSchema) GetDatabaseIdString() string {
+	return s.Spec.Database.ToNamespacedName().String()
+}
+
+func (s *PgSchema) ToNamespacedName() string {
+	return s.Namespace + "/" + s.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgSchemaList contains a list of PgSchema
+type PgSchemaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgSchema `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgSchema{}, &PgSchemaList{})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgschema,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgschemas,verbs=create;update,versions=v1,name=vpgschema.kb.io,admissionReviewVersions=v1
+
+// pgSchemaValidator validates PgSchema resources before they are admitted
+type pgSchemaValidator struct{}
+
+var _ webhook.CustomValidator = &pgSchemaValidator{}
+
+func (s *PgSchema) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(s).
+		WithValidator(&pgSchemaValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgSchemaValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	schema, ok := obj.(*PgSchema)
+	if !ok {
+		return fmt.Errorf("expected a PgSchema but got a %T", obj)
+	}
+	return toInvalidError(PgSchemaKind(), schema.Name, schema.validate())
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgSchemaValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldSchema, ok := oldObj.(*PgSchema)
+	if !ok {
+		return fmt.Errorf("expected a PgSchema but got a %T", oldObj)
+	}
+	schema, ok := newObj.(*PgSchema)
+	if !ok {
+		return fmt.Errorf("expected a PgSchema but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if schema.DeletionTimestamp != nil || isSpecUnchanged(oldSchema.Spec, schema.Spec) {
+		return nil
+	}
+	errs := schema.validate()
+	if oldSchema.Spec.Database != schema.Spec.Database {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "database"), "the database cannot be changed"))
+	}
+	if oldSchema.GetSchemaName() != schema.GetSchemaName() {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "name"), "the name of the schema cannot be changed"))
+	}
+	return toInvalidError(PgSchemaKind(), schema.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgSchemaValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the fields of the PgSchema, which do not depend on other resources
+func (s *PgSchema) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	namePath := specPath.Child("name")
+	if s.Spec.Name == "" {
+		namePath = field.NewPath("metadata", "name")
+	}
+	errs := validateSchemaName(namePath, s.GetSchemaName())
+	errs = append(errs, validateLocalDatabaseRef(specPath.Child("database"), s.Spec.Database, s.Namespace)...)
+	if s.Spec.Owner != "" {
+		errs = append(errs, validateIdentifier(specPath.Child("owner"), s.Spec.Owner)...)
+	}
+	return errs
+}
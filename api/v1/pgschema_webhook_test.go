@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("pgSchemaValidator", func() {
+
+	newSchema := func(name string) *PgSchema {
+		return &PgSchema{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: PgSchemaSpec{
+				Database: PgDatabaseRef{Namespace: "default", Name: "service"},
+			},
+		}
+	}
+
+	It("admits a valid schema", func() {
+		// given:
+		validator := pgSchemaValidator{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newSchema("orders"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses reserved schema names", func() {
+		// given:
+		validator := pgSchemaValidator{}
+		schema := newSchema("catalog")
+		schema.Spec.Name = "pg_catalog"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), schema)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.name"))
+	})
+
+	It("refuses a database in another namespace", func() {
+		// given:
+		validator := pgSchemaValidator{}
+		schema := newSchema("orders")
+		schema.Spec.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), schema)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.database.namespace"))
+	})
+
+	It("refuses changes of the schema name", func() {
+		// given:
+		validator := pgSchemaValidator{}
+		oldSchema := newSchema("orders")
+		schema := oldSchema.DeepCopy()
+		schema.Spec.Name = "sales"
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldSchema, schema)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.name"))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const PgScriptAppliedConditionType string = "pgscript.postgres.brose.bike/applied"
+
+type PgScriptStep struct {
+	// Name identifies the script, it is recorded together with the checksum of the script
+	Name string `json:"name"`
+	// SQL contains the statements of the script, either inline or from a key of a ConfigMap or Secret
+	SQL PgProperty `json:"sql"`
+	// RerunOnChange runs the script again if its checksum differs from the applied script,
+	// otherwise a changed script is refused
+	// +optional
+	RerunOnChange bool `json:"rerunOnChange,omitempty"`
+}
+
+// PgScriptSpec defines the desired state of PgScript
+type PgScriptSpec struct {
+	// Database identifies the PgDatabase in which the scripts should be executed
+	Database PgDatabaseRef `json:"database"`
+	// Role contains the name of the PgUser in the namespace of the script, as which the scripts are executed.
+	// The operator logs in with the password from the Secret of the PgUser,
+	// users with the superuser or createrole attribute are refused.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+	// Scripts contains the scripts in the order of their execution
+	// +kubebuilder:validation:MinItems=1
+	Scripts []PgScriptStep `json:"scripts"`
+}
+
+type PgScriptStepStatus struct {
+	// Name identifies the script
+	Name string `json:"name"`
+	// Checksum contains the SHA-256 checksum of the applied script
+	Checksum string `json:"checksum"`
+	// AppliedTime contains the time at which the script was applied
+	AppliedTime metav1.Time `json:"appliedTime"`
+}
+
+// PgScriptStatus defines the observed state of PgScript
+type PgScriptStatus struct {
+	// Conditions represent the current connection state
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Scripts contains the applied scripts
+	// +optional
+	Scripts []PgScriptStepStatus `json:"scripts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.database.name`
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="pgscript.postgres.brose.bike/applied")].status`
+
+// PgScript is the Schema for the pgscripts API
+type PgScript struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgScriptSpec   `json:"spec,omitempty"`
+	Status PgScriptStatus `json:"status,omitempty"`
+}
+
+func PgScriptKind() string {
+	obj := &PgScript{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (s *PgScript) GetConditions() []metav1.Condition {
+	return s.Status.Conditions
+}
+
+func (s *PgScript) SetConditions(conditions []metav1.Condition) {
+	s.Status.Conditions = conditions
+}
+
+// IsReferencingConfigMap returns true if any script is read from the ConfigMap with the given name
+func (s *PgScript) IsReferencingConfigMap(name string) bool {
+	for _, script := range s.Spec.Scripts {
+		if script.SQL.ConfigMapKeyRef != nil && script.SQL.ConfigMapKeyRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReferencingSecret returns true if any script is read from the Secret with the given name
+func (s *PgScript) IsReferencingSecret(name string) bool {
+	for _, script := range s.Spec.Scripts {
+		if script.SQL.SecretKeyRef != nil && script.SQL.SecretKeyRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserId returns the id of the PgUser as which the scripts are executed
+func (s *PgScript) GetUserId() types.NamespacedName {
+	return types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.Role}
+}
+
+func (s *PgScript) GetDatabaseId() types.NamespacedName {
+	return s.Spec.Database.ToNamespacedName()
+}
+
+func (s *PgScript) GetDatabaseIdString() string {
+	return s.Spec.Database.ToNamespacedName().String()
+}
+
+func (s *PgScript) ToNamespacedName() string {
+	return s.Namespace + "/" + s.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgScriptList contains a list of PgScript
+type PgScriptList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgScript `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgScript{}, &PgScriptList{})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgscript,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgscripts,verbs=create;update,versions=v1,name=vpgscript.kb.io,admissionReviewVersions=v1
+
+// pgScriptValidator validates PgScript resources before they are admitted
+type pgScriptValidator struct{}
+
+var _ webhook.CustomValidator = &pgScriptValidator{}
+
+func (s *PgScript) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(s).
+		WithValidator(&pgScriptValidator{}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgScriptValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	script, ok := obj.(*PgScript)
+	if !ok {
+		return fmt.Errorf("expected a PgScript but got a %T", obj)
+	}
+	return toInvalidError(PgScriptKind(), script.Name, script.validate())
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgScriptValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldScript, ok := oldObj.(*PgScript)
+	if !ok {
+		return fmt.Errorf("expected a PgScript but got a %T", oldObj)
+	}
+	script, ok := newObj.(*PgScript)
+	if !ok {
+		return fmt.Errorf("expected a PgScript but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if script.DeletionTimestamp != nil || isSpecUnchanged(oldScript.Spec, script.Spec) {
+		return nil
+	}
+	errs := script.validate()
+	if oldScript.Spec.Database != script.Spec.Database {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "database"), "the database cannot be changed"))
+	}
+	return toInvalidError(PgScriptKind(), script.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgScriptValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate checks the fields of the PgScript, which do not depend on other resources
+func (s *PgScript) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateLocalDatabaseRef(specPath.Child("database"), s.Spec.Database, s.Namespace)
+	if s.Spec.Role == "" {
+		errs = append(errs, field.Required(specPath.Child("role"), "the PgUser as which the scripts are executed is required"))
+	} else {
+		errs = append(errs, validateIdentifier(specPath.Child("role"), s.Spec.Role)...)
+	}
+	if len(s.Spec.Scripts) == 0 {
+		errs = append(errs, field.Required(specPath.Child("scripts"), "at least one script is required"))
+	}
+	scriptNames := make(map[string]bool)
+	for i, script := range s.Spec.Scripts {
+		scriptPath := specPath.Child("scripts").Index(i)
+		if script.Name == "" {
+			errs = append(errs, field.Required(scriptPath.Child("name"), "the name of the script is required"))
+		} else if scriptNames[script.Name] {
+			errs = append(errs, field.Duplicate(scriptPath.Child("name"), script.Name))
+		}
+		scriptNames[script.Name] = true
+		errs = append(errs, validateProperty(scriptPath.Child("sql"), script.SQL, true)...)
+	}
+	return errs
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("pgScriptValidator", func() {
+
+	newScript := func() *PgScript {
+		return &PgScript{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "migrations"},
+			Spec: PgScriptSpec{
+				Database: PgDatabaseRef{Namespace: "default", Name: "service"},
+				Role:     "service-owner",
+				Scripts: []PgScriptStep{
+					{Name: "001-orders", SQL: PgProperty{Value: "create table orders (id integer);"}},
+					{Name: "002-items", SQL: PgProperty{ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{
+						LocalObjectReference: coreV1.LocalObjectReference{Name: "migrations"},
+						Key:                  "002-items.sql",
+					}}},
+				},
+			},
+		}
+	}
+
+	It("admits a valid script", func() {
+		// given:
+		validator := pgScriptValidator{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newScript())
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses a database in another namespace", func() {
+		// given:
+		validator := pgScriptValidator{}
+		script := newScript()
+		script.Spec.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), script)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.database.namespace"))
+	})
+
+	It("refuses duplicate script names", func() {
+		// given:
+		validator := pgScriptValidator{}
+		script := newScript()
+		script.Spec.Scripts[1].Name = "001-orders"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), script)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.scripts[1].name"))
+	})
+
+	It("refuses a script without sql", func() {
+		// given:
+		validator := pgScriptValidator{}
+		script := newScript()
+		script.Spec.Scripts[0].SQL = PgProperty{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), script)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.scripts[0].sql"))
+	})
+
+	It("refuses a script without role", func() {
+		// given:
+		validator := pgScriptValidator{}
+		script := newScript()
+		script.Spec.Role = ""
+		// when:
+		err := validator.ValidateCreate(context.TODO(), script)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.role"))
+	})
+})
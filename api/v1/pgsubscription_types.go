@@ -0,0 +1,214 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFinalizerPgSubscription contains the name for the default finalizer
+// of the PgSubscription resource
+const DefaultFinalizerPgSubscription = "postgres.brose.bike/pgsubscription"
+const PgSubscriptionExistsConditionType string = "pgsubscription.postgres.brose.bike/exists"
+const PgSubscriptionSourceConditionType string = "pgsubscription.postgres.brose.bike/source"
+const PgSubscriptionReplicatingConditionType string = "pgsubscription.postgres.brose.bike/replicating"
+
+type PgSubscriptionSource struct {
+	// Database identifies the PgDatabase which contains the publications
+	// +optional
+	Database *PgDatabaseRef `json:"database,omitempty"`
+	// Instance identifies the instance of the publisher, if the database is not managed by a PgDatabase
+	// +optional
+	Instance *PgInstanceRef `json:"instance,omitempty"`
+	// DatabaseName contains the name of the database on the instance, it is required together with instance
+	// +optional
+	DatabaseName string `json:"databaseName,omitempty"`
+	// Role contains the name of the PgUser in the namespace of the subscription,
+	// which is used to connect to the publisher and has to be a role of the instance of the publisher
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+	// SSLRootCert contains the path of the root certificate on the server of the subscriber,
+	// which is used to verify the publisher
+	// +optional
+	SSLRootCert string `json:"sslRootCert,omitempty"`
+	// SSLCRL contains the path of the certificate revocation list on the server of the subscriber,
+	// against which the certificate of the publisher is checked
+	// +optional
+	SSLCRL string `json:"sslCRL,omitempty"`
+}
+
+type PgSubscriptionSlot struct {
+	// Name contains the name of the replication slot on the publisher (defaults to the name of the subscription)
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Create creates the replication slot on the publisher when the subscription is created (defaults to true)
+	// +optional
+	Create *bool `json:"create,omitempty"`
+	// Retain keeps the replication slot on the publisher when the subscription is deleted
+	// +optional
+	Retain bool `json:"retain,omitempty"`
+}
+
+// PgSubscriptionSpec defines the desired state of PgSubscription
+type PgSubscriptionSpec struct {
+	// Database identifies the PgDatabase in which the subscription should be managed
+	Database PgDatabaseRef `json:"database"`
+	// Name contains the name of the subscription in the database, defaults to the name of the resource
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Source identifies the database of the publisher
+	Source PgSubscriptionSource `json:"source"`
+	// Publications contains the names of the subscribed publications
+	// +kubebuilder:validation:MinItems=1
+	Publications []string `json:"publications"`
+	// Enabled can be set to false to stop the replication (defaults to true)
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// CopyData copies the existing data of the published tables on creation (defaults to true)
+	// +optional
+	CopyData *bool `json:"copyData,omitempty"`
+	// Slot specifies the handling of the replication slot on the publisher
+	// +optional
+	Slot PgSubscriptionSlot `json:"slot,omitempty"`
+}
+
+type PgSubscriptionTableStatus struct {
+	// Name contains the schema qualified name of the table
+	Name string `json:"name"`
+	// State contains the synchronization state of the table
+	State string `json:"state"`
+}
+
+// PgSubscriptionStatus defines the observed state of PgSubscription
+type PgSubscriptionStatus struct {
+	// Conditions represent the current connection state
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Enabled is true if the subscription replicates
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// ReceivedLsn contains the last write-ahead log location received from the publisher
+	// +optional
+	ReceivedLsn string `json:"receivedLsn,omitempty"`
+	// LatestEndLsn contains the last write-ahead log location reported to the publisher
+	// +optional
+	LatestEndLsn string `json:"latestEndLsn,omitempty"`
+	// LatestEndTime contains the time of the last location reported to the publisher
+	// +optional
+	LatestEndTime *metav1.Time `json:"latestEndTime,omitempty"`
+	// LagBytes contains the number of bytes the replication slot is behind the publisher
+	// +optional
+	LagBytes *int64 `json:"lagBytes,omitempty"`
+	// Tables contains the synchronization state of the subscribed tables
+	// +optional
+	Tables []PgSubscriptionTableStatus `json:"tables,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.database.name`
+//+kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.status.enabled`
+//+kubebuilder:printcolumn:name="Lag",type=integer,JSONPath=`.status.lagBytes`
+
+// PgSubscription is the Schema for the pgsubscriptions API
+type PgSubscription struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PgSubscriptionSpec   `json:"spec,omitempty"`
+	Status PgSubscriptionStatus `json:"status,omitempty"`
+}
+
+func PgSubscriptionKind() string {
+	obj := &PgSubscription{}
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Pointer {
+		panic("All types must be pointers to structs.")
+	}
+	return t.Elem().Name()
+}
+
+func (s *PgSubscription) GetConditions() []metav1.Condition {
+	return s.Status.Conditions
+}
+
+func (s *PgSubscription) SetConditions(conditions []metav1.Condition) {
+	s.Status.Conditions = conditions
+}
+
+// GetSubscriptionName returns the name of the subscription in the database
+func (s *PgSubscription) GetSubscriptionName() string {
+	if s.Spec.Name != "" {
+		return s.Spec.Name
+	}
+	return s.Name
+}
+
+// GetSlotName returns the name of the replication slot on the publisher
+func (s *PgSubscription) GetSlotName() string {
+	if s.Spec.Slot.Name != "" {
+		return s.Spec.Slot.Name
+	}
+	return s.GetSubscriptionName()
+}
+
+// IsEnabled returns true if the subscription should replicate
+func (s *PgSubscription) IsEnabled() bool {
+	return s.Spec.Enabled == nil || *s.Spec.Enabled
+}
+
+// IsCopyData returns true if the existing data should be copied on creation
+func (s *PgSubscription) IsCopyData() bool {
+	return s.Spec.CopyData == nil || *s.Spec.CopyData
+}
+
+// IsCreateSlot returns true if the replication slot should be created on creation
+func (s *PgSubscription) IsCreateSlot() bool {
+	return s.Spec.Slot.Create == nil || *s.Spec.Slot.Create
+}
+
+// GetUserId returns the id of the PgUser, which is used to connect to the publisher
+func (s *PgSubscription) GetUserId() types.NamespacedName {
+	return types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.Source.Role}
+}
+
+func (s *PgSubscription) GetDatabaseId() types.NamespacedName {
+	return s.Spec.Database.ToNamespacedName()
+}
+
+func (s *PgSubscription) GetDatabaseIdString() string {
+	return s.Spec.Database.ToNamespacedName().String()
+}
+
+func (s *PgSubscription) ToNamespacedName() string {
+	return s.Namespace + "/" + s.Name
+}
+
+//+kubebuilder:object:root=true
+
+// PgSubscriptionList contains a list of PgSubscription
+type PgSubscriptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PgSubscription `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PgSubscription{}, &PgSubscriptionList{})
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pgsubscription,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgsubscriptions,verbs=create;update,versions=v1,name=vpgsubscription.kb.io,admissionReviewVersions=v1
+
+// pgSubscriptionValidator validates PgSubscription resources before they are admitted
+type pgSubscriptionValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &pgSubscriptionValidator{}
+
+func (s *PgSubscription) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(s).
+		WithValidator(&pgSubscriptionValidator{mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgSubscriptionValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	subscription, ok := obj.(*PgSubscription)
+	if !ok {
+		return fmt.Errorf("expected a PgSubscription but got a %T", obj)
+	}
+	errs := subscription.validate()
+	duplicates, err := v.validateUniqueName(ctx, subscription)
+	if err != nil {
+		return err
+	}
+	return toInvalidError(PgSubscriptionKind(), subscription.Name, append(errs, duplicates...))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgSubscriptionValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldSubscription, ok := oldObj.(*PgSubscription)
+	if !ok {
+		return fmt.Errorf("expected a PgSubscription but got a %T", oldObj)
+	}
+	subscription, ok := newObj.(*PgSubscription)
+	if !ok {
+		return fmt.Errorf("expected a PgSubscription but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if subscription.DeletionTimestamp != nil || isSpecUnchanged(oldSubscription.Spec, subscription.Spec) {
+		return nil
+	}
+	specPath := field.NewPath("spec")
+	errs := subscription.validate()
+	if oldSubscription.Spec.Database != subscription.Spec.Database {
+		errs = append(errs, field.Forbidden(specPath.Child("database"), "the database cannot be changed"))
+	}
+	if oldSubscription.GetSubscriptionName() != subscription.GetSubscriptionName() {
+		errs = append(errs, field.Forbidden(specPath.Child("name"), "the name cannot be changed"))
+	}
+	if oldSubscription.GetSlotName() != subscription.GetSlotName() {
+		errs = append(errs, field.Forbidden(specPath.Child("slot", "name"), "the name of the slot cannot be changed"))
+	}
+	return toInvalidError(PgSubscriptionKind(), subscription.Name, errs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgSubscriptionValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateUniqueName checks that no other PgSubscription manages a subscription with the same name in the database
+func (v *pgSubscriptionValidator) validateUniqueName(ctx context.Context, subscription *PgSubscription) (field.ErrorList, error) {
+	var subscriptions PgSubscriptionList
+	if err := v.client.List(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	for _, other := range subscriptions.Items {
+		if other.UID != subscription.UID && other.GetSubscriptionName() == subscription.GetSubscriptionName() && other.Spec.Database == subscription.Spec.Database {
+			return field.ErrorList{field.Duplicate(field.NewPath("spec", "name"), subscription.GetSubscriptionName())}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the fields of the PgSubscription, which do not depend on other resources
+func (s *PgSubscription) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateIdentifier(specPath.Child("name"), s.GetSubscriptionName())
+	errs = append(errs, validateIdentifier(specPath.Child("slot", "name"), s.GetSlotName())...)
+	errs = append(errs, validateLocalDatabaseRef(specPath.Child("database"), s.Spec.Database, s.Namespace)...)
+	// Validate source
+	source := s.Spec.Source
+	sourcePath := specPath.Child("source")
+	if (source.Database == nil) == (source.Instance == nil) {
+		errs = append(errs, field.Invalid(sourcePath, source, "exactly one of database and instance is required"))
+	}
+	if source.Database != nil {
+		errs = append(errs, validateLocalDatabaseRef(sourcePath.Child("database"), *source.Database, s.Namespace)...)
+		if *source.Database == s.Spec.Database {
+			errs = append(errs, field.Invalid(sourcePath.Child("database"), *source.Database, "a database cannot subscribe to itself"))
+		}
+		if source.DatabaseName != "" {
+			errs = append(errs, field.Forbidden(sourcePath.Child("databaseName"), "databaseName can only be used together with instance"))
+		}
+	}
+	if source.Instance != nil {
+		errs = append(errs, validateInstanceRef(sourcePath.Child("instance"), *source.Instance)...)
+		if source.DatabaseName == "" {
+			errs = append(errs, field.Required(sourcePath.Child("databaseName"), "the name of the database is required together with instance"))
+		}
+	}
+	if source.Role == "" {
+		errs = append(errs, field.Required(sourcePath.Child("role"), "the PgUser which connects to the publisher is required"))
+	}
+	errs = append(errs, validateAbsolutePath(sourcePath.Child("sslRootCert"), source.SSLRootCert)...)
+	errs = append(errs, validateAbsolutePath(sourcePath.Child("sslCRL"), source.SSLCRL)...)
+	// Validate publications
+	publicationNames := make(map[string]bool)
+	for i, name := range s.Spec.Publications {
+		publicationPath := specPath.Child("publications").Index(i)
+		if name == "" {
+			errs = append(errs, field.Required(publicationPath, "the name of the publication is required"))
+		} else if publicationNames[name] {
+			errs = append(errs, field.Duplicate(publicationPath, name))
+		}
+		errs = append(errs, validateIdentifier(publicationPath, name)...)
+		publicationNames[name] = true
+	}
+	if len(s.Spec.Publications) == 0 {
+		errs = append(errs, field.Required(specPath.Child("publications"), "at least one publication is required"))
+	}
+	return errs
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("pgSubscriptionValidator", func() {
+
+	newSubscription := func(name string) *PgSubscription {
+		return &PgSubscription{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: PgSubscriptionSpec{
+				Database: PgDatabaseRef{Namespace: "default", Name: "replica"},
+				Source: PgSubscriptionSource{
+					Database: &PgDatabaseRef{Namespace: "default", Name: "service"},
+					Role:     "replicator",
+				},
+				Publications: []string{"orders"},
+			},
+		}
+	}
+
+	It("admits a valid subscription", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newSubscription("orders"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("admits a subscription to an instance", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source = PgSubscriptionSource{
+			Instance:     &PgInstanceRef{Kind: ClusterPgInstanceKind, Name: "legacy"},
+			DatabaseName: "service",
+			Role:         "replicator",
+			SSLRootCert:  "/etc/postgresql/root.crt",
+		}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses a source with database and instance", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source.Instance = &PgInstanceRef{Kind: ClusterPgInstanceKind, Name: "legacy"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("exactly one of database and instance"))
+	})
+
+	It("refuses an instance without database name", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source = PgSubscriptionSource{
+			Instance: &PgInstanceRef{Kind: ClusterPgInstanceKind, Name: "legacy"},
+			Role:     "replicator",
+		}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.source.databaseName"))
+	})
+
+	It("refuses a database in another namespace", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.database.namespace"))
+	})
+
+	It("refuses a source database in another namespace", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source.Database.Namespace = "other"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.source.database.namespace"))
+	})
+
+	It("refuses a source without role", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source.Role = ""
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.source.role"))
+	})
+
+	It("refuses a relative path of the root certificate", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Source.SSLRootCert = "root.crt"
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.source.sslRootCert"))
+	})
+
+	It("refuses a subscription without publications", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		subscription := newSubscription("orders")
+		subscription.Spec.Publications = nil
+		// when:
+		err := validator.ValidateCreate(context.TODO(), subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.publications"))
+	})
+
+	It("refuses changes of the slot name", func() {
+		// given:
+		validator := pgSubscriptionValidator{&mockReader{}}
+		oldSubscription := newSubscription("orders")
+		subscription := oldSubscription.DeepCopy()
+		subscription.Spec.Slot.Name = "other"
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldSubscription, subscription)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.slot.name"))
+	})
+})
@@ -18,7 +18,10 @@ package v1
 
 import (
 	"reflect"
+	"strings"
+	"text/template"
 
+	"github.com/brose-ebike/postgres-operator/pkg/security"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -26,6 +29,25 @@ import (
 const DefaultFinalizerPgUser = "postgres.brose.bike/pgloginrole"
 const PgUserExistsConditionType string = "pguser.postgres.brose.bike/exists"
 const PgUserDatabasesExistsConditionType string = "pguser.postgres.brose.bike/databases"
+const PgUserMembershipsConditionType string = "pguser.postgres.brose.bike/memberships"
+const PgUserAttributesConditionType string = "pguser.postgres.brose.bike/attributes"
+const PgUserRotationConditionType string = "pguser.postgres.brose.bike/rotation"
+const PgUserSecretConditionType string = "pguser.postgres.brose.bike/secret"
+const PgUserOwnershipConditionType string = "pguser.postgres.brose.bike/ownership"
+const PgUserDeletionConditionType string = "pguser.postgres.brose.bike/deletion"
+const PgUserSettingsConditionType string = "pguser.postgres.brose.bike/settings"
+const PgUserPasswordPolicyConditionType string = "pguser.postgres.brose.bike/password-policy"
+const PgUserPasswordSecretConditionType string = "pguser.postgres.brose.bike/password-secret"
+
+// PgUserRotatePasswordAnnotation triggers a password rotation whenever its value changes
+const PgUserRotatePasswordAnnotation string = "pguser.postgres.brose.bike/rotate-password"
+
+// PgPasswordSecretAllowedNamespacesAnnotation contains the comma separated namespaces, whose PgUsers are allowed
+// to read their password from the annotated Secret, "*" allows all namespaces
+const PgPasswordSecretAllowedNamespacesAnnotation string = "postgres.brose.bike/allowed-namespaces"
+
+// PgUserAlternateRoleSuffix is appended to the name of the user to get the name of the alternate login role
+const PgUserAlternateRoleSuffix string = "_alt"
 
 // +kubebuilder:validation:Enum=CONNECT;CREATE
 type DatabasePrivilege string
@@ -38,28 +60,302 @@ const (
 	CreateDatabasePrivilege DatabasePrivilege = "CREATE"
 )
 
+// +kubebuilder:validation:Enum=spring;django;dotnet
+type PgUserSecretPreset string
+
+const (
+	// Keys for Spring Boot: SPRING_DATASOURCE_URL, SPRING_DATASOURCE_USERNAME and SPRING_DATASOURCE_PASSWORD
+	SpringSecretPreset PgUserSecretPreset = "spring"
+
+	// Keys for Django with dj-database-url: DATABASE_URL
+	DjangoSecretPreset PgUserSecretPreset = "django"
+
+	// Keys for .NET with Npgsql: ConnectionStrings__DefaultConnection
+	DotnetSecretPreset PgUserSecretPreset = "dotnet"
+)
+
+// PgUserSecretTemplateFuncs contains the functions, which are available in the templates of a PgUserSecret
+var PgUserSecretTemplateFuncs = template.FuncMap{
+	"replace": strings.ReplaceAll,
+	"quote":   QuoteConnectionStringValue,
+}
+
+// QuoteConnectionStringValue wraps the value in double quotes and doubles the contained double quotes,
+// so values containing ; or = can be used in key-value connection strings like the ones of Npgsql
+func QuoteConnectionStringValue(value string) string {
+	return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+}
+
 // PgLoginRoleSecret identifies the PgLoginRoleSecret which should be used
 type PgUserSecret struct {
 	// Name identifies the PgLoginRoleSecret which should be used
 	Name string `json:"name,omitempty"`
+	// Preset selects a built-in layout of the secret for a common framework
+	// +optional
+	Preset PgUserSecretPreset `json:"preset,omitempty"`
+	// Templates maps keys of the secret to Go templates, which are rendered with the fields
+	// .Host, .Port, .User, .Password, .Database and .SSLMode and the URL-escaped fields
+	// .UserURL, .PasswordURL and .DatabaseURL and the functions replace and quote. Templates override keys of the preset.
+	// +optional
+	Templates map[string]string `json:"templates,omitempty"`
+	// Database is the database used in the templates, defaults to the first database of the user
+	// +optional
+	Database string `json:"database,omitempty"`
+}
+
+// HasLayout returns true if the secret uses a preset or templates instead of the default layout
+func (s *PgUserSecret) HasLayout() bool {
+	return s.Preset != "" || len(s.Templates) > 0
 }
 
 // PgUserDatabase represents the database a user would like to connect to
 type PgUserDatabase struct {
 	// Name contains the Database Name on the postgres instance
 	Name string `json:"name,omitempty"`
-	// Owner is the optional value which allows to set this user as owner of a database
+	// Owner is the optional value which allows to set this user as owner of a database,
+	// it is ignored if a PgDatabase on the same instance sets the owner of the database
 	// +optional
 	Owner *bool `json:"owner,omitempty"`
 	// Privileges contains the names of the privileges the user needs on the database
 	Privileges []DatabasePrivilege `json:"privileges"`
-	// TODO add schemas, tables, etc.
+	// Schemas contains the privileges the user needs on schemas in the database
+	// +optional
+	Schemas []PgUserSchema `json:"schemas,omitempty"`
+	// Settings contains the configuration settings for the sessions of the user in the database,
+	// which override the settings of the user
+	// +optional
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 func (d *PgUserDatabase) IsOwner() bool {
 	return d.Owner != nil && *d.Owner
 }
 
+// PgUserSchema represents the privileges a user needs on a schema and the objects within
+type PgUserSchema struct {
+	// Name contains the name of the schema in the database
+	Name string `json:"name"`
+	// Privileges contains the names of the privileges the user needs on the schema
+	// +optional
+	Privileges []SchemaPrivilege `json:"privileges,omitempty"`
+	// TablePrivileges contains the privileges the user needs on all tables in the schema
+	// +optional
+	TablePrivileges []TablePrivilege `json:"tablePrivileges,omitempty"`
+	// SequencePrivileges contains the privileges the user needs on all sequences in the schema
+	// +optional
+	SequencePrivileges []SequencePrivilege `json:"sequencePrivileges,omitempty"`
+	// FunctionPrivileges contains the privileges the user needs on all functions in the schema
+	// +optional
+	FunctionPrivileges []FunctionPrivilege `json:"functionPrivileges,omitempty"`
+	// Tables contains the privileges the user needs on specific tables in the schema
+	// +optional
+	Tables []PgUserTable `json:"tables,omitempty"`
+}
+
+func (s *PgUserSchema) PrivilegesStr() []string {
+	privileges := make([]string, len(s.Privileges))
+	for i := range s.Privileges {
+		privileges[i] = string(s.Privileges[i])
+	}
+	return privileges
+}
+
+func (s *PgUserSchema) TablePrivilegesStr() []string {
+	privileges := make([]string, len(s.TablePrivileges))
+	for i := range s.TablePrivileges {
+		privileges[i] = string(s.TablePrivileges[i])
+	}
+	return privileges
+}
+
+func (s *PgUserSchema) SequencePrivilegesStr() []string {
+	privileges := make([]string, len(s.SequencePrivileges))
+	for i := range s.SequencePrivileges {
+		privileges[i] = string(s.SequencePrivileges[i])
+	}
+	return privileges
+}
+
+func (s *PgUserSchema) FunctionPrivilegesStr() []string {
+	privileges := make([]string, len(s.FunctionPrivileges))
+	for i := range s.FunctionPrivileges {
+		privileges[i] = string(s.FunctionPrivileges[i])
+	}
+	return privileges
+}
+
+// PgUserTable represents the privileges a user needs on a specific table
+type PgUserTable struct {
+	// Name contains the name of the table in the schema
+	Name string `json:"name"`
+	// Privileges contains the names of the privileges the user needs on the table
+	Privileges []TablePrivilege `json:"privileges"`
+}
+
+func (t *PgUserTable) PrivilegesStr() []string {
+	privileges := make([]string, len(t.Privileges))
+	for i := range t.Privileges {
+		privileges[i] = string(t.Privileges[i])
+	}
+	return privileges
+}
+
+// PgUserAttributes contains the role attributes of a user.
+// Attributes which are not set are not managed by the operator.
+type PgUserAttributes struct {
+	// CreateDatabase allows the user to create databases (CREATEDB)
+	// +optional
+	CreateDatabase *bool `json:"createDatabase,omitempty"`
+	// CreateRole allows the user to create, alter and drop other roles (CREATEROLE)
+	// +optional
+	CreateRole *bool `json:"createRole,omitempty"`
+	// Replication allows the user to initiate streaming replication (REPLICATION)
+	// +optional
+	Replication *bool `json:"replication,omitempty"`
+	// BypassRLS allows the user to bypass every row level security policy (BYPASSRLS)
+	// +optional
+	BypassRLS *bool `json:"bypassRLS,omitempty"`
+	// ConnectionLimit limits the concurrent connections of the user, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// ValidUntil is the time after which the password of the user is no longer valid
+	// +optional
+	ValidUntil *metav1.Time `json:"validUntil,omitempty"`
+}
+
+// PgUserRotation defines when and how the password of a user is rotated
+type PgUserRotation struct {
+	// Interval after which the password is rotated, e.g. "720h".
+	// Without an interval the password is only rotated on demand via the annotation
+	// pguser.postgres.brose.bike/rotate-password
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// GracePeriod specifies how long the previous credentials stay valid after a rotation, e.g. "1h".
+	// With a grace period the credentials alternate between the role of the user and an alternate login role
+	// named <user>_alt, which acts as the user. Without a grace period the password of the user is replaced.
+	// +optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+func (r *PgUserRotation) HasGracePeriod() bool {
+	return r.GracePeriod != nil && r.GracePeriod.Duration > 0
+}
+
+// PgUserPasswordSecretRef identifies the key of a Secret, which contains the password of a user
+type PgUserPasswordSecretRef struct {
+	// Namespace of the Secret, defaults to the namespace of the user.
+	// A Secret in another namespace has to allow the namespace of the user
+	// in the annotation postgres.brose.bike/allowed-namespaces
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the Secret
+	Name string `json:"name"`
+	// Key of the password in the Secret, defaults to password
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// GetKey returns the key of the password or password if none is set
+func (r *PgUserPasswordSecretRef) GetKey() string {
+	if r.Key == "" {
+		return "password"
+	}
+	return r.Key
+}
+
+// PgPasswordCharacterClass is a class of characters which is contained in generated passwords
+// +kubebuilder:validation:Enum=Lowercase;Uppercase;Digits;Symbols
+type PgPasswordCharacterClass string
+
+const (
+	LowercasePasswordCharacterClass PgPasswordCharacterClass = "Lowercase"
+	UppercasePasswordCharacterClass PgPasswordCharacterClass = "Uppercase"
+	DigitsPasswordCharacterClass    PgPasswordCharacterClass = "Digits"
+	SymbolsPasswordCharacterClass   PgPasswordCharacterClass = "Symbols"
+)
+
+// PgPasswordPolicy defines how the passwords of a user are generated,
+// unset fields are taken from the operator-wide password policy
+type PgPasswordPolicy struct {
+	// MinLength is the minimum length of generated passwords
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinLength int `json:"minLength,omitempty"`
+	// MaxLength is the maximum length of generated passwords
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxLength int `json:"maxLength,omitempty"`
+	// CharacterClasses contains the classes of characters used in generated passwords,
+	// every password contains at least one character of every class
+	// +optional
+	CharacterClasses []PgPasswordCharacterClass `json:"characterClasses,omitempty"`
+	// Symbols contains the characters of the class Symbols
+	// +optional
+	Symbols string `json:"symbols,omitempty"`
+	// MinSymbols is the minimum number of symbols in generated passwords, which implies the class Symbols
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSymbols int `json:"minSymbols,omitempty"`
+	// ExcludedCharacters contains characters which are never used in generated passwords, e.g. "0O1lI"
+	// +optional
+	ExcludedCharacters string `json:"excludedCharacters,omitempty"`
+}
+
+// ToPasswordPolicy converts the policy, a nil policy results in an empty policy
+func (p *PgPasswordPolicy) ToPasswordPolicy() security.PasswordPolicy {
+	if p == nil {
+		return security.PasswordPolicy{}
+	}
+	classes := []security.CharacterClass{}
+	for _, class := range p.CharacterClasses {
+		classes = append(classes, security.CharacterClass(class))
+	}
+	return security.PasswordPolicy{
+		MinLength:          p.MinLength,
+		MaxLength:          p.MaxLength,
+		CharacterClasses:   classes,
+		Symbols:            p.Symbols,
+		MinSymbols:         p.MinSymbols,
+		ExcludedCharacters: p.ExcludedCharacters,
+	}
+}
+
+// PgUserDeletionPolicy defines what happens to the role when the PgUser is deleted
+// +kubebuilder:validation:Enum=Drop;Retain;Disable;Reassign
+type PgUserDeletionPolicy string
+
+const (
+	// DropUserDeletionPolicy drops all objects owned by the role and the role itself
+	DropUserDeletionPolicy PgUserDeletionPolicy = "Drop"
+	// RetainUserDeletionPolicy keeps the role as it is
+	RetainUserDeletionPolicy PgUserDeletionPolicy = "Retain"
+	// DisableUserDeletionPolicy keeps the role, but removes its password and login attribute
+	DisableUserDeletionPolicy PgUserDeletionPolicy = "Disable"
+	// ReassignUserDeletionPolicy reassigns all objects owned by the role to another role and drops the role
+	ReassignUserDeletionPolicy PgUserDeletionPolicy = "Reassign"
+)
+
+type PgUserDeletion struct {
+	// Policy specifies what should happen to the role on deletion (defaults to Drop)
+	// +optional
+	Policy PgUserDeletionPolicy `json:"policy,omitempty"`
+	// ReassignTo is the name of the role which receives the objects owned by the user, required for the policy Reassign
+	// +optional
+	ReassignTo string `json:"reassignTo,omitempty"`
+	// RetainSecret specifies if the secret should be kept on deletion (defaults to false)
+	// +optional
+	RetainSecret bool `json:"retainSecret,omitempty"`
+}
+
+// GetPolicy returns the deletion policy or Drop if none is set
+func (d *PgUserDeletion) GetPolicy() PgUserDeletionPolicy {
+	if d.Policy == "" {
+		return DropUserDeletionPolicy
+	}
+	return d.Policy
+}
+
 // PgUserSpec defines the desired state of PgUser
 type PgUserSpec struct {
 	// Instance identifies the PgInstanceConnection which should be used
@@ -68,6 +364,39 @@ type PgUserSpec struct {
 	Secret *PgUserSecret `json:"secret,omitempty"`
 	// Databases is an example field of PgLoginRole
 	Databases []PgUserDatabase `json:"databases,omitempty"`
+	// MemberOf contains the group roles in which this user should be a member
+	// +optional
+	MemberOf []PgRoleMembership `json:"memberOf,omitempty"`
+	// Attributes contains the role attributes of the user
+	// +optional
+	Attributes *PgUserAttributes `json:"attributes,omitempty"`
+	// Rotation enables the rotation of the password of the user
+	// +optional
+	Rotation *PgUserRotation `json:"rotation,omitempty"`
+	// PasswordPolicy defines how the passwords of the user are generated
+	// +optional
+	PasswordPolicy *PgPasswordPolicy `json:"passwordPolicy,omitempty"`
+	// PasswordSecretRef identifies a Secret, from which the password of the user is read instead of generating one.
+	// The password is applied whenever the Secret changes, the Secret of the user still contains the connection details.
+	// +optional
+	PasswordSecretRef *PgUserPasswordSecretRef `json:"passwordSecretRef,omitempty"`
+	// AdoptionPolicy defines how an already existing role is handled, defaults to Fail
+	// +optional
+	AdoptionPolicy PgAdoptionPolicy `json:"adoptionPolicy,omitempty"`
+	// DeletionBehavior specifies what should happen to the role and the secret when the manifest gets deleted
+	// +optional
+	DeletionBehavior PgUserDeletion `json:"deletion,omitempty"`
+	// Settings contains the configuration settings for all sessions of the user, e.g. search_path
+	// +optional
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// GetAdoptionPolicy returns the adoption policy or Fail if none is set
+func (s *PgUserSpec) GetAdoptionPolicy() PgAdoptionPolicy {
+	if s.AdoptionPolicy == "" {
+		return FailAdoptionPolicy
+	}
+	return s.AdoptionPolicy
 }
 
 // PgUserStatus defines the observed state of PgUser
@@ -77,6 +406,45 @@ type PgUserStatus struct {
 	// - postgres.brose.bike/login-role-exists true if login role exists false if not
 	// - postgres.brose.bike/connected true if the instance is reachable false if not
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+	// Memberships contains the names of the group roles which were granted by the operator
+	// +optional
+	Memberships []string `json:"memberships,omitempty"`
+	// LastRotationTime is the time of the last password rotation
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// LastRotationRequest is the value of the rotate-password annotation which was handled last
+	// +optional
+	LastRotationRequest string `json:"lastRotationRequest,omitempty"`
+	// ActiveRole is the login role whose credentials are stored in the secret, empty for the role of the user
+	// +optional
+	ActiveRole string `json:"activeRole,omitempty"`
+	// PreviousRole is the login role whose credentials are valid until the grace period ends
+	// +optional
+	PreviousRole string `json:"previousRole,omitempty"`
+	// Settings contains the names of the configuration settings which were set by the operator
+	// +optional
+	Settings []PgUserSettingsStatus `json:"settings,omitempty"`
+	// Schemas contains the names of the schemas on which the operator granted privileges
+	// +optional
+	Schemas []PgUserSchemaStatus `json:"schemas,omitempty"`
+}
+
+// PgUserSettingsStatus contains the names of the configuration settings which were set by the operator
+// for the sessions of the user in a database
+type PgUserSettingsStatus struct {
+	// Database contains the name of the database, empty for the settings in all databases
+	// +optional
+	Database string `json:"database,omitempty"`
+	// Names contains the names of the configuration settings
+	Names []string `json:"names"`
+}
+
+// PgUserSchemaStatus contains the names of the schemas in a database on which the operator granted privileges
+type PgUserSchemaStatus struct {
+	// Database contains the name of the database
+	Database string `json:"database"`
+	// Names contains the names of the schemas
+	Names []string `json:"names"`
 }
 
 //+kubebuilder:object:root=true
@@ -108,6 +476,44 @@ func (u *PgUser) SetConditions(conditions []metav1.Condition) {
 	u.Status.Conditions = conditions
 }
 
+func (u *PgUser) GetMemberships() []string {
+	return u.Status.Memberships
+}
+
+func (u *PgUser) SetMemberships(memberships []string) {
+	u.Status.Memberships = memberships
+}
+
+// ActiveRoleName returns the name of the login role whose credentials are currently in use
+func (u *PgUser) ActiveRoleName() string {
+	if u.Status.ActiveRole == "" {
+		return u.Name
+	}
+	return u.Status.ActiveRole
+}
+
+// AlternateRoleName returns the name of the login role which is used alternately during password rotations
+func (u *PgUser) AlternateRoleName() string {
+	return u.Name + PgUserAlternateRoleSuffix
+}
+
+// GetPasswordSecretId returns the namespaced name of the referenced password Secret
+func (u *PgUser) GetPasswordSecretId() types.NamespacedName {
+	if u.Spec.PasswordSecretRef == nil {
+		return types.NamespacedName{}
+	}
+	namespace := u.Spec.PasswordSecretRef.Namespace
+	if namespace == "" {
+		namespace = u.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: u.Spec.PasswordSecretRef.Name}
+}
+
+// IsReferencingPasswordSecret returns true if the user reads its password from the given Secret
+func (u *PgUser) IsReferencingPasswordSecret(secret types.NamespacedName) bool {
+	return u.Spec.PasswordSecretRef != nil && u.GetPasswordSecretId() == secret
+}
+
 func (u *PgUser) GetInstanceId() types.NamespacedName {
 	return u.Spec.Instance.ToNamespacedName()
 }
@@ -21,6 +21,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var _ = Describe("PgUserDatabase", func() {
@@ -100,3 +101,32 @@ var _ = Describe("PgUserDatabase", func() {
 		Expect(instanceSpec2.IsOwner()).To(BeTrue())
 	})
 })
+
+var _ = Describe("PgUser", func() {
+
+	It("references a password secret in its own namespace by default", func() {
+		// given:
+		user := PgUser{}
+		user.Namespace = "team"
+		user.Spec.PasswordSecretRef = &PgUserPasswordSecretRef{Name: "vendor-password"}
+		// when:
+		secretId := user.GetPasswordSecretId()
+		// then:
+		Expect(secretId.String()).To(Equal("team/vendor-password"))
+		Expect(user.Spec.PasswordSecretRef.GetKey()).To(Equal("password"))
+		Expect(user.IsReferencingPasswordSecret(secretId)).To(BeTrue())
+	})
+
+	It("references a password secret in another namespace", func() {
+		// given:
+		user := PgUser{}
+		user.Namespace = "team"
+		user.Spec.PasswordSecretRef = &PgUserPasswordSecretRef{Namespace: "vendor", Name: "vendor-password", Key: "pw"}
+		// when:
+		secretId := user.GetPasswordSecretId()
+		// then:
+		Expect(secretId.String()).To(Equal("vendor/vendor-password"))
+		Expect(user.Spec.PasswordSecretRef.GetKey()).To(Equal("pw"))
+		Expect(user.IsReferencingPasswordSecret(types.NamespacedName{Namespace: "team", Name: "vendor-password"})).To(BeFalse())
+	})
+})
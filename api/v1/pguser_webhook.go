@@ -0,0 +1,284 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/brose-ebike/postgres-operator/pkg/security"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+//+kubebuilder:webhook:path=/validate-postgres-brose-bike-v1-pguser,mutating=false,failurePolicy=fail,sideEffects=None,groups=postgres.brose.bike,resources=pgusers,verbs=create;update,versions=v1,name=vpguser.kb.io,admissionReviewVersions=v1
+
+// pgUserValidator validates PgUser resources before they are admitted
+type pgUserValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &pgUserValidator{}
+
+func (u *PgUser) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(u).
+		WithValidator(&pgUserValidator{mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *pgUserValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	user, ok := obj.(*PgUser)
+	if !ok {
+		return fmt.Errorf("expected a PgUser but got a %T", obj)
+	}
+	errs := user.validate()
+	duplicates, err := v.validateUniqueName(ctx, user)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, duplicates...)
+	alternateErrs, err := v.validateAlternateRoleName(ctx, user)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, alternateErrs...)
+	reassignErrs, err := v.validateReassignTo(ctx, user)
+	if err != nil {
+		return err
+	}
+	return toInvalidError(PgUserKind(), user.Name, append(errs, reassignErrs...))
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *pgUserValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldUser, ok := oldObj.(*PgUser)
+	if !ok {
+		return fmt.Errorf("expected a PgUser but got a %T", oldObj)
+	}
+	user, ok := newObj.(*PgUser)
+	if !ok {
+		return fmt.Errorf("expected a PgUser but got a %T", newObj)
+	}
+	// Allow the removal of finalizers and changes of the metadata of invalid resources
+	if user.DeletionTimestamp != nil || isSpecUnchanged(oldUser.Spec, user.Spec) {
+		return nil
+	}
+	errs := user.validate()
+	errs = append(errs, validateInstanceRefUpdate(field.NewPath("spec", "instance"), oldUser.Spec.Instance, user.Spec.Instance)...)
+	alternateErrs, err := v.validateAlternateRoleName(ctx, user)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, alternateErrs...)
+	reassignErrs, err := v.validateReassignTo(ctx, user)
+	if err != nil {
+		return err
+	}
+	return toInvalidError(PgUserKind(), user.Name, append(errs, reassignErrs...))
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *pgUserValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateUniqueName checks that no other PgUser or PgRole manages a role with the same name on the instance,
+// which includes the alternate login roles of the password rotation
+func (v *pgUserValidator) validateUniqueName(ctx context.Context, user *PgUser) (field.ErrorList, error) {
+	path := field.NewPath("metadata", "name")
+	var users PgUserList
+	if err := v.client.List(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, other := range users.Items {
+		if other.UID != user.UID && other.hasRoleName(user.Name) && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return field.ErrorList{field.Duplicate(path, user.Name)}, nil
+		}
+	}
+	var roles PgRoleList
+	if err := v.client.List(ctx, &roles); err != nil {
+		return nil, err
+	}
+	for _, other := range roles.Items {
+		if other.Name == user.Name && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return field.ErrorList{field.Duplicate(path, user.Name)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateAlternateRoleName checks that no other PgUser or PgRole manages a role with the name
+// of the alternate login role of the password rotation on the instance
+func (v *pgUserValidator) validateAlternateRoleName(ctx context.Context, user *PgUser) (field.ErrorList, error) {
+	if user.Spec.Rotation == nil {
+		return nil, nil
+	}
+	path := field.NewPath("spec", "rotation")
+	roleName := user.AlternateRoleName()
+	var users PgUserList
+	if err := v.client.List(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, other := range users.Items {
+		if other.UID != user.UID && other.hasRoleName(roleName) && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return field.ErrorList{field.Forbidden(path, "the alternate login role "+roleName+" is managed by the PgUser "+other.Namespace+"/"+other.Name)}, nil
+		}
+	}
+	var roles PgRoleList
+	if err := v.client.List(ctx, &roles); err != nil {
+		return nil, err
+	}
+	for _, other := range roles.Items {
+		if other.Name == roleName && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return field.ErrorList{field.Forbidden(path, "the alternate login role "+roleName+" is managed by the PgRole "+other.Namespace+"/"+other.Name)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// hasRoleName returns true if the login role or the alternate login role of the user has the given name
+func (u *PgUser) hasRoleName(roleName string) bool {
+	return u.Name == roleName || (u.Spec.Rotation != nil && u.AlternateRoleName() == roleName)
+}
+
+// validateReassignTo checks that the role, which receives the objects of the user on deletion,
+// is managed by another PgUser or a PgRole in the namespace of the user on the same instance
+func (v *pgUserValidator) validateReassignTo(ctx context.Context, user *PgUser) (field.ErrorList, error) {
+	reassignTo := user.Spec.DeletionBehavior.ReassignTo
+	if user.Spec.DeletionBehavior.GetPolicy() != ReassignUserDeletionPolicy || reassignTo == "" || reassignTo == user.Name {
+		return nil, nil
+	}
+	var users PgUserList
+	if err := v.client.List(ctx, &users, client.InNamespace(user.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, other := range users.Items {
+		if other.Name == reassignTo && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return nil, nil
+		}
+	}
+	var roles PgRoleList
+	if err := v.client.List(ctx, &roles, client.InNamespace(user.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, other := range roles.Items {
+		if other.Name == reassignTo && isSameInstance(other.Spec.Instance, user.Spec.Instance) {
+			return nil, nil
+		}
+	}
+	message := "the role has to be managed by a PgUser or PgRole in the namespace " + user.Namespace + " on the same instance"
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "deletion", "reassignTo"), message)}, nil
+}
+
+// validate checks the fields of the PgUser, which do not depend on other resources
+func (u *PgUser) validate() field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateRoleName(field.NewPath("metadata", "name"), u.Name)
+	// Postgres truncates longer names, so the alternate login role of the rotation would not end with its suffix
+	if maxLength := maxIdentifierLength - len(PgUserAlternateRoleSuffix); u.Spec.Rotation != nil && len(u.Name) > maxLength {
+		errs = append(errs, field.TooLong(field.NewPath("metadata", "name"), u.Name, maxLength))
+	}
+	errs = append(errs, validateInstanceRef(specPath.Child("instance"), u.Spec.Instance)...)
+	// Validate secret
+	secretPath := specPath.Child("secret")
+	if u.Spec.Secret == nil {
+		errs = append(errs, field.Required(secretPath, "the secret is required"))
+	} else {
+		if u.Spec.Secret.Name == "" {
+			errs = append(errs, field.Required(secretPath.Child("name"), "the name of the secret is required"))
+		}
+		for key, text := range u.Spec.Secret.Templates {
+			if _, err := template.New(key).Funcs(PgUserSecretTemplateFuncs).Parse(text); err != nil {
+				errs = append(errs, field.Invalid(secretPath.Child("templates").Key(key), text, err.Error()))
+			}
+		}
+	}
+	// Validate databases
+	databaseNames := make(map[string]bool)
+	for i, database := range u.Spec.Databases {
+		databasePath := specPath.Child("databases").Index(i).Child("name")
+		if database.Name == "" {
+			errs = append(errs, field.Required(databasePath, "the name of the database is required"))
+		} else if databaseNames[database.Name] {
+			errs = append(errs, field.Duplicate(databasePath, database.Name))
+		}
+		databaseNames[database.Name] = true
+		errs = append(errs, validateSettings(specPath.Child("databases").Index(i).Child("settings"), database.Settings)...)
+	}
+	errs = append(errs, validateSettings(specPath.Child("settings"), u.Spec.Settings)...)
+	errs = append(errs, validateMemberships(specPath.Child("memberOf"), u.Spec.MemberOf, u.Name)...)
+	// Validate rotation
+	if u.Spec.Rotation != nil {
+		rotationPath := specPath.Child("rotation")
+		if u.Spec.Rotation.Interval != nil && u.Spec.Rotation.Interval.Duration < 0 {
+			errs = append(errs, field.Invalid(rotationPath.Child("interval"), u.Spec.Rotation.Interval.String(), "the interval cannot be negative"))
+		}
+		if u.Spec.Rotation.GracePeriod != nil && u.Spec.Rotation.GracePeriod.Duration < 0 {
+			errs = append(errs, field.Invalid(rotationPath.Child("gracePeriod"), u.Spec.Rotation.GracePeriod.String(), "the grace period cannot be negative"))
+		}
+	}
+	// Validate password policy
+	if u.Spec.PasswordPolicy != nil {
+		policy := u.Spec.PasswordPolicy.ToPasswordPolicy().WithDefaults(security.DefaultPasswordPolicy)
+		if err := policy.Validate(); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("passwordPolicy"), u.Spec.PasswordPolicy, err.Error()))
+		}
+	}
+	// Validate password secret
+	if u.Spec.PasswordSecretRef != nil {
+		refPath := specPath.Child("passwordSecretRef")
+		if u.Spec.PasswordSecretRef.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), "the name of the secret is required"))
+		}
+		// The Secret of the user is written by the operator and cannot be the source of the password at the same time
+		if u.Spec.Secret != nil && u.IsReferencingPasswordSecret(types.NamespacedName{Namespace: u.Namespace, Name: u.Spec.Secret.Name}) {
+			errs = append(errs, field.Invalid(refPath, u.GetPasswordSecretId().String(), "the password cannot be read from the secret of the user"))
+		}
+		if u.Spec.Rotation != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("rotation"), "the password cannot be rotated if it is read from a secret"))
+		}
+		if u.Spec.PasswordPolicy != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("passwordPolicy"), "the password is not generated if it is read from a secret"))
+		}
+	}
+	// Validate deletion
+	deletion := u.Spec.DeletionBehavior
+	reassignToPath := specPath.Child("deletion", "reassignTo")
+	if deletion.GetPolicy() == ReassignUserDeletionPolicy {
+		if deletion.ReassignTo == "" {
+			errs = append(errs, field.Required(reassignToPath, "the role which receives the owned objects is required for the policy Reassign"))
+		} else if deletion.ReassignTo == u.Name {
+			errs = append(errs, field.Invalid(reassignToPath, deletion.ReassignTo, "the objects cannot be reassigned to the user itself"))
+		} else {
+			errs = append(errs, validateIdentifier(reassignToPath, deletion.ReassignTo)...)
+			if strings.HasPrefix(deletion.ReassignTo, "pg_") || hasReservedRoleName(deletion.ReassignTo) {
+				errs = append(errs, field.Forbidden(reassignToPath, "the objects cannot be reassigned to predefined or reserved roles"))
+			}
+		}
+	} else if deletion.ReassignTo != "" {
+		errs = append(errs, field.Forbidden(reassignToPath, "the role can only be set for the policy Reassign"))
+	}
+	return errs
+}
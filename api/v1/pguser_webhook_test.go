@@ -0,0 +1,309 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("pgUserValidator", func() {
+
+	newUser := func(name string) *PgUser {
+		return &PgUser{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name)},
+			Spec: PgUserSpec{
+				Instance: PgInstanceRef{Namespace: "default", Name: "instance"},
+				Secret:   &PgUserSecret{Name: "credentials"},
+			},
+		}
+	}
+
+	It("admits a valid user", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newUser("service"))
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses a user without secret", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.Secret = nil
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.secret"))
+	})
+
+	It("refuses reserved role names", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		// when:
+		errPostgres := validator.ValidateCreate(context.TODO(), newUser("postgres"))
+		errPrefix := validator.ValidateCreate(context.TODO(), newUser("pg_monitor"))
+		// then:
+		Expect(apierrors.IsInvalid(errPostgres)).To(BeTrue())
+		Expect(apierrors.IsInvalid(errPrefix)).To(BeTrue())
+	})
+
+	It("refuses memberships in predefined roles", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.MemberOf = []PgRoleMembership{{Name: "readers"}, {Name: "pg_execute_server_program"}}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.memberOf[1].name"))
+		Expect(err.Error()).NotTo(ContainSubstring("spec.memberOf[0].name"))
+	})
+
+	It("refuses invalid secret templates", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.Secret.Templates = map[string]string{"url": "{{.Host"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.secret.templates[url]"))
+	})
+
+	It("refuses the policy Reassign without role", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.DeletionBehavior = PgUserDeletion{Policy: ReassignUserDeletionPolicy}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.deletion.reassignTo"))
+	})
+
+	It("admits the policy Reassign with a role managed in the namespace", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if roles, ok := list.(*PgRoleList); ok {
+				roles.Items = []PgRole{{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "service_owner"},
+					Spec:       PgRoleSpec{Instance: PgInstanceRef{Namespace: "default", Name: "instance"}},
+				}}
+			}
+			return nil
+		}}
+		validator := pgUserValidator{&r}
+		user := newUser("service")
+		user.Spec.DeletionBehavior = PgUserDeletion{Policy: ReassignUserDeletionPolicy, ReassignTo: "service_owner"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses the policy Reassign with a reserved or unmanaged role", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		reserved := newUser("service")
+		reserved.Spec.DeletionBehavior = PgUserDeletion{Policy: ReassignUserDeletionPolicy, ReassignTo: "postgres"}
+		unmanaged := newUser("service")
+		unmanaged.Spec.DeletionBehavior = PgUserDeletion{Policy: ReassignUserDeletionPolicy, ReassignTo: "other_tenant"}
+		// when:
+		errReserved := validator.ValidateCreate(context.TODO(), reserved)
+		errUnmanaged := validator.ValidateCreate(context.TODO(), unmanaged)
+		// then:
+		Expect(apierrors.IsInvalid(errReserved)).To(BeTrue())
+		Expect(errReserved.Error()).To(ContainSubstring("predefined or reserved roles"))
+		Expect(apierrors.IsInvalid(errUnmanaged)).To(BeTrue())
+		Expect(errUnmanaged.Error()).To(ContainSubstring("spec.deletion.reassignTo"))
+	})
+
+	It("admits a satisfiable password policy", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.PasswordPolicy = &PgPasswordPolicy{
+			MinLength:          16,
+			CharacterClasses:   []PgPasswordCharacterClass{LowercasePasswordCharacterClass, DigitsPasswordCharacterClass},
+			MinSymbols:         2,
+			ExcludedCharacters: "0Ol1",
+		}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses an unsatisfiable password policy", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.PasswordPolicy = &PgPasswordPolicy{
+			CharacterClasses:   []PgPasswordCharacterClass{DigitsPasswordCharacterClass},
+			ExcludedCharacters: "0123456789",
+		}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.passwordPolicy"))
+	})
+
+	It("refuses a password secret together with a rotation", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.PasswordSecretRef = &PgUserPasswordSecretRef{Name: "vendor-password"}
+		user.Spec.Rotation = &PgUserRotation{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.rotation"))
+	})
+
+	It("refuses the secret of the user as password secret", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.PasswordSecretRef = &PgUserPasswordSecretRef{Name: "credentials"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.passwordSecretRef"))
+
+		// and:
+		user.Spec.PasswordSecretRef.Namespace = "vendor"
+		Expect(validator.ValidateCreate(context.TODO(), user)).To(BeNil())
+	})
+
+	It("refuses a password secret without name", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser("service")
+		user.Spec.PasswordSecretRef = &PgUserPasswordSecretRef{Key: "password"}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.passwordSecretRef.name"))
+	})
+
+	It("refuses duplicate names on the same instance", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if users, ok := list.(*PgUserList); ok {
+				other := newUser("service")
+				other.Namespace = "other"
+				other.UID = "uid-other"
+				users.Items = []PgUser{*other}
+			}
+			return nil
+		}}
+		validator := pgUserValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newUser("service"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("refuses the name of the alternate login role of another user on the same instance", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if users, ok := list.(*PgUserList); ok {
+				other := newUser("service")
+				other.UID = "uid-other"
+				other.Spec.Rotation = &PgUserRotation{}
+				users.Items = []PgUser{*other}
+			}
+			return nil
+		}}
+		validator := pgUserValidator{&r}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), newUser("service_alt"))
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+	})
+
+	It("refuses a rotation if the alternate login role is managed by a role", func() {
+		// given:
+		r := mockReader{proxyList: func(list client.ObjectList) error {
+			if roles, ok := list.(*PgRoleList); ok {
+				roles.Items = []PgRole{{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "service_alt"},
+					Spec:       PgRoleSpec{Instance: PgInstanceRef{Namespace: "default", Name: "instance"}},
+				}}
+			}
+			return nil
+		}}
+		validator := pgUserValidator{&r}
+		oldUser := newUser("service")
+		user := newUser("service")
+		user.Spec.Rotation = &PgUserRotation{}
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldUser, user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.rotation"))
+	})
+
+	It("refuses names longer than 59 characters with a rotation", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		user := newUser(strings.Repeat("a", 60))
+		user.Spec.Rotation = &PgUserRotation{}
+		// when:
+		err := validator.ValidateCreate(context.TODO(), user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("metadata.name"))
+
+		// and:
+		user.Name = strings.Repeat("a", 59)
+		Expect(validator.ValidateCreate(context.TODO(), user)).To(BeNil())
+	})
+
+	It("refuses changes of the instance", func() {
+		// given:
+		validator := pgUserValidator{&mockReader{}}
+		oldUser := newUser("service")
+		user := newUser("service")
+		user.Spec.Instance.Name = "other-instance"
+		// when:
+		err := validator.ValidateUpdate(context.TODO(), oldUser, user)
+		// then:
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.instance"))
+	})
+})
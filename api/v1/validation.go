@@ -0,0 +1,271 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// maxIdentifierLength is the maximum length of an identifier in Postgres
+const maxIdentifierLength = 63
+
+// reservedRoleNames cannot be used as name of a role
+var reservedRoleNames = []string{"postgres", "public", "none", "current_role", "current_user", "session_user"}
+
+// reservedDatabaseNames cannot be used as name of a database
+var reservedDatabaseNames = []string{"postgres", "template0", "template1"}
+
+// supportedSSLModes contains the libpq ssl modes which are supported by the operator
+var supportedSSLModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+// settingNamePattern matches the names of configuration settings including custom settings like app.tenant
+var settingNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// reservedSettingNames cannot be set by the operator, because they change the identity of the session
+var reservedSettingNames = []string{"role", "session_authorization"}
+
+// validateRoleName checks that the given name can be used for a role managed by the operator
+func validateRoleName(path *field.Path, name string) field.ErrorList {
+	errs := validateIdentifier(path, name)
+	if strings.HasPrefix(name, "pg_") {
+		errs = append(errs, field.Invalid(path, name, "role names starting with pg_ are reserved"))
+	}
+	if hasReservedRoleName(name) {
+		errs = append(errs, field.Invalid(path, name, "the role name is reserved"))
+	}
+	return errs
+}
+
+// hasReservedRoleName returns true if the given name is one of the reserved role names
+func hasReservedRoleName(name string) bool {
+	for _, reserved := range reservedRoleNames {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMemberships checks the group roles of a role, predefined and reserved roles cannot be granted
+func validateMemberships(path *field.Path, memberships []PgRoleMembership, roleName string) field.ErrorList {
+	errs := field.ErrorList{}
+	names := make(map[string]bool)
+	for i, membership := range memberships {
+		namePath := path.Index(i).Child("name")
+		if membership.Name == "" {
+			errs = append(errs, field.Required(namePath, "the name of the group role is required"))
+			continue
+		}
+		if names[membership.Name] {
+			errs = append(errs, field.Duplicate(namePath, membership.Name))
+		}
+		names[membership.Name] = true
+		if membership.Name == roleName {
+			errs = append(errs, field.Invalid(namePath, membership.Name, "a role cannot be a member of itself"))
+		}
+		if strings.HasPrefix(membership.Name, "pg_") {
+			errs = append(errs, field.Forbidden(namePath, "predefined roles cannot be granted"))
+		}
+		if hasReservedRoleName(membership.Name) {
+			errs = append(errs, field.Forbidden(namePath, "reserved roles cannot be granted"))
+		}
+	}
+	return errs
+}
+
+// validateDatabaseName checks that the given name can be used for a database managed by the operator
+func validateDatabaseName(path *field.Path, name string) field.ErrorList {
+	errs := validateIdentifier(path, name)
+	for _, reserved := range reservedDatabaseNames {
+		if name == reserved {
+			errs = append(errs, field.Invalid(path, name, "the database name is reserved"))
+		}
+	}
+	return errs
+}
+
+// validateSchemaName checks that the given name can be used for a schema managed by the operator
+func validateSchemaName(path *field.Path, name string) field.ErrorList {
+	errs := validateIdentifier(path, name)
+	if strings.HasPrefix(name, "pg_") {
+		errs = append(errs, field.Invalid(path, name, "schema names starting with pg_ are reserved"))
+	}
+	if name == "information_schema" {
+		errs = append(errs, field.Invalid(path, name, "the schema name is reserved"))
+	}
+	return errs
+}
+
+func validateIdentifier(path *field.Path, name string) field.ErrorList {
+	if len(name) > maxIdentifierLength {
+		return field.ErrorList{field.TooLong(path, name, maxIdentifierLength)}
+	}
+	return nil
+}
+
+// validateSettings checks that the names of the configuration settings are valid and not reserved
+func validateSettings(path *field.Path, settings map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+	// Sort the names to report the errors in a stable order
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lowerNames := make(map[string]bool)
+	for _, name := range names {
+		lowerName := strings.ToLower(name)
+		if !settingNamePattern.MatchString(name) {
+			errs = append(errs, field.Invalid(path.Key(name), name, "the name of the setting is invalid"))
+		} else if lowerNames[lowerName] {
+			// Names of settings are case insensitive
+			errs = append(errs, field.Duplicate(path.Key(name), name))
+		}
+		for _, reserved := range reservedSettingNames {
+			if lowerName == reserved {
+				errs = append(errs, field.Forbidden(path.Key(name), "the setting cannot be managed by the operator"))
+			}
+		}
+		lowerNames[lowerName] = true
+	}
+	return errs
+}
+
+// validateAbsolutePath checks that the given path is empty or absolute
+func validateAbsolutePath(path *field.Path, value string) field.ErrorList {
+	if value != "" && !strings.HasPrefix(value, "/") {
+		return field.ErrorList{field.Invalid(path, value, "the path has to be absolute")}
+	}
+	return nil
+}
+
+// validateInstanceRef checks that the reference identifies an instance
+func validateInstanceRef(path *field.Path, ref PgInstanceRef) field.ErrorList {
+	errs := field.ErrorList{}
+	if ref.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "the name of the instance is required"))
+	}
+	if !ref.IsClusterInstance() && ref.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), "the namespace of a PgInstance is required"))
+	}
+	return errs
+}
+
+// validateDatabaseRef checks that the reference identifies a PgDatabase
+func validateDatabaseRef(path *field.Path, ref PgDatabaseRef) field.ErrorList {
+	errs := field.ErrorList{}
+	if ref.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "the name of the database is required"))
+	}
+	if ref.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), "the namespace of the database is required"))
+	}
+	return errs
+}
+
+// validateLocalDatabaseRef checks the reference to a database, which has to be in the given namespace
+func validateLocalDatabaseRef(path *field.Path, ref PgDatabaseRef, namespace string) field.ErrorList {
+	errs := validateDatabaseRef(path, ref)
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		errs = append(errs, field.Forbidden(path.Child("namespace"), "the database has to be in the namespace "+namespace))
+	}
+	return errs
+}
+
+// isSpecUnchanged returns true if an update did not change the spec of a resource,
+// resources which became invalid, e.g. by a new validation, can be labeled or finalized anyway
+func isSpecUnchanged(oldSpec interface{}, newSpec interface{}) bool {
+	return equality.Semantic.DeepEqual(oldSpec, newSpec)
+}
+
+// validateInstanceRefUpdate checks that the referenced instance was not changed
+func validateInstanceRefUpdate(path *field.Path, oldRef PgInstanceRef, newRef PgInstanceRef) field.ErrorList {
+	if oldRef.IsClusterInstance() != newRef.IsClusterInstance() || oldRef.ToNamespacedName() != newRef.ToNamespacedName() {
+		return field.ErrorList{field.Forbidden(path, "the instance cannot be changed")}
+	}
+	return nil
+}
+
+// isSameInstance returns true if both references identify the same instance
+func isSameInstance(ref PgInstanceRef, other PgInstanceRef) bool {
+	return ref.IsClusterInstance() == other.IsClusterInstance() && ref.ToNamespacedName() == other.ToNamespacedName()
+}
+
+// validateProperty checks that at most one source is set for the property
+// and that a source is set if the property is required
+func validateProperty(path *field.Path, property PgProperty, required bool) field.ErrorList {
+	sources := 0
+	if property.Value != "" {
+		sources += 1
+	}
+	if property.ConfigMapKeyRef != nil {
+		sources += 1
+	}
+	if property.SecretKeyRef != nil {
+		sources += 1
+	}
+	if sources > 1 {
+		return field.ErrorList{field.Invalid(path, property, "only one of value, configMapKeyRef and secretKeyRef can be set")}
+	}
+	if sources == 0 && required {
+		return field.ErrorList{field.Required(path, "one of value, configMapKeyRef and secretKeyRef is required")}
+	}
+	return nil
+}
+
+// validatePortProperty checks that the value of the property is a valid port
+func validatePortProperty(path *field.Path, property PgProperty) field.ErrorList {
+	errs := validateProperty(path, property, false)
+	if property.Value == "" {
+		return errs
+	}
+	port, err := strconv.Atoi(property.Value)
+	if err != nil || port < 1 || port > 65535 {
+		errs = append(errs, field.Invalid(path.Child("value"), property.Value, "the port must be a number between 1 and 65535"))
+	}
+	return errs
+}
+
+// validateSSLModeProperty checks that the value of the property is a supported ssl mode
+func validateSSLModeProperty(path *field.Path, property PgProperty) field.ErrorList {
+	errs := validateProperty(path, property, false)
+	if property.Value == "" {
+		return errs
+	}
+	for _, mode := range supportedSSLModes {
+		if property.Value == mode {
+			return errs
+		}
+	}
+	return append(errs, field.NotSupported(path.Child("value"), property.Value, supportedSSLModes))
+}
+
+// toInvalidError converts the given field errors into an invalid error, nil if there are no errors
+func toInvalidError(kind string, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: kind}, name, errs)
+}
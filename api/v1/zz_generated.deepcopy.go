@@ -24,9 +24,228 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPgInstance) DeepCopyInto(out *ClusterPgInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPgInstance.
+func (in *ClusterPgInstance) DeepCopy() *ClusterPgInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPgInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterPgInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPgInstanceList) DeepCopyInto(out *ClusterPgInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterPgInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPgInstanceList.
+func (in *ClusterPgInstanceList) DeepCopy() *ClusterPgInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPgInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterPgInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPgInstanceSpec) DeepCopyInto(out *ClusterPgInstanceSpec) {
+	*out = *in
+	in.PgInstanceSpec.DeepCopyInto(&out.PgInstanceSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPgInstanceSpec.
+func (in *ClusterPgInstanceSpec) DeepCopy() *ClusterPgInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPgInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPgInstanceStatus) DeepCopyInto(out *ClusterPgInstanceStatus) {
+	*out = *in
+	in.PgInstanceStatus.DeepCopyInto(&out.PgInstanceStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPgInstanceStatus.
+func (in *ClusterPgInstanceStatus) DeepCopy() *ClusterPgInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPgInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackup) DeepCopyInto(out *PgBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackup.
+func (in *PgBackup) DeepCopy() *PgBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackupList) DeepCopyInto(out *PgBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackupList.
+func (in *PgBackupList) DeepCopy() *PgBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackupSpec) DeepCopyInto(out *PgBackupSpec) {
+	*out = *in
+	out.Database = in.Database
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackupSpec.
+func (in *PgBackupSpec) DeepCopy() *PgBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackupStatus) DeepCopyInto(out *PgBackupStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]PgJobStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackupStatus.
+func (in *PgBackupStatus) DeepCopy() *PgBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgCredentialProvider) DeepCopyInto(out *PgCredentialProvider) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(PgExecCredentialProvider)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgCredentialProvider.
+func (in *PgCredentialProvider) DeepCopy() *PgCredentialProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(PgCredentialProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgDatabase) DeepCopyInto(out *PgDatabase) {
 	*out = *in
@@ -54,6 +273,25 @@ func (in *PgDatabase) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseCloneStatus) DeepCopyInto(out *PgDatabaseCloneStatus) {
+	*out = *in
+	if in.SnapshotTime != nil {
+		in, out := &in.SnapshotTime, &out.SnapshotTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseCloneStatus.
+func (in *PgDatabaseCloneStatus) DeepCopy() *PgDatabaseCloneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseCloneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgDatabaseDefaultPrivileges) DeepCopyInto(out *PgDatabaseDefaultPrivileges) {
 	*out = *in
@@ -102,6 +340,11 @@ func (in *PgDatabaseDefaultPrivileges) DeepCopy() *PgDatabaseDefaultPrivileges {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgDatabaseDeletion) DeepCopyInto(out *PgDatabaseDeletion) {
 	*out = *in
+	if in.Dump != nil {
+		in, out := &in.Dump, &out.Dump
+		*out = new(PgDatabaseDump)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseDeletion.
@@ -114,6 +357,51 @@ func (in *PgDatabaseDeletion) DeepCopy() *PgDatabaseDeletion {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseDump) DeepCopyInto(out *PgDatabaseDump) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseDump.
+func (in *PgDatabaseDump) DeepCopy() *PgDatabaseDump {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseDump)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseExtension) DeepCopyInto(out *PgDatabaseExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseExtension.
+func (in *PgDatabaseExtension) DeepCopy() *PgDatabaseExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseExtensionStatus) DeepCopyInto(out *PgDatabaseExtensionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseExtensionStatus.
+func (in *PgDatabaseExtensionStatus) DeepCopy() *PgDatabaseExtensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseExtensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgDatabaseList) DeepCopyInto(out *PgDatabaseList) {
 	*out = *in
@@ -176,14 +464,45 @@ func (in *PgDatabasePublicSchema) DeepCopy() *PgDatabasePublicSchema {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseRef) DeepCopyInto(out *PgDatabaseRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseRef.
+func (in *PgDatabaseRef) DeepCopy() *PgDatabaseRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgDatabaseSource) DeepCopyInto(out *PgDatabaseSource) {
+	*out = *in
+	out.Database = in.Database
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseSource.
+func (in *PgDatabaseSource) DeepCopy() *PgDatabaseSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PgDatabaseSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgDatabaseSpec) DeepCopyInto(out *PgDatabaseSpec) {
 	*out = *in
 	out.Instance = in.Instance
-	out.DeletionBehavior = in.DeletionBehavior
+	in.DeletionBehavior.DeepCopyInto(&out.DeletionBehavior)
 	if in.Extensions != nil {
 		in, out := &in.Extensions, &out.Extensions
-		*out = make([]string, len(*in))
+		*out = make([]PgDatabaseExtension, len(*in))
 		copy(*out, *in)
 	}
 	if in.DefaultPrivileges != nil {
@@ -195,6 +514,28 @@ func (in *PgDatabaseSpec) DeepCopyInto(out *PgDatabaseSpec) {
 	}
 	out.PublicPrivileges = in.PublicPrivileges
 	out.PublicSchema = in.PublicSchema
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.AllowConnections != nil {
+		in, out := &in.AllowConnections, &out.AllowConnections
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(PgDatabaseSource)
+		**out = **in
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseSpec.
@@ -217,6 +558,21 @@ func (in *PgDatabaseStatus) DeepCopyInto(out *PgDatabaseStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]PgDatabaseExtensionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(PgDatabaseCloneStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgDatabaseStatus.
@@ -224,13 +580,930 @@ func (in *PgDatabaseStatus) DeepCopy() *PgDatabaseStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PgDatabaseStatus)
+	out := new(PgDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgExecCredentialProvider) DeepCopyInto(out *PgExecCredentialProvider) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]PgExecEnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgExecCredentialProvider.
+func (in *PgExecCredentialProvider) DeepCopy() *PgExecCredentialProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(PgExecCredentialProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgExecEnvVar) DeepCopyInto(out *PgExecEnvVar) {
+	*out = *in
+	in.Value.DeepCopyInto(&out.Value)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgExecEnvVar.
+func (in *PgExecEnvVar) DeepCopy() *PgExecEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(PgExecEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstance) DeepCopyInto(out *PgInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstance.
+func (in *PgInstance) DeepCopy() *PgInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceAdminPrivileges) DeepCopyInto(out *PgInstanceAdminPrivileges) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceAdminPrivileges.
+func (in *PgInstanceAdminPrivileges) DeepCopy() *PgInstanceAdminPrivileges {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceAdminPrivileges)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceCertificateStatus) DeepCopyInto(out *PgInstanceCertificateStatus) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceCertificateStatus.
+func (in *PgInstanceCertificateStatus) DeepCopy() *PgInstanceCertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceCertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceList) DeepCopyInto(out *PgInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceList.
+func (in *PgInstanceList) DeepCopy() *PgInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceRef) DeepCopyInto(out *PgInstanceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceRef.
+func (in *PgInstanceRef) DeepCopy() *PgInstanceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceSpec) DeepCopyInto(out *PgInstanceSpec) {
+	*out = *in
+	in.Hostname.DeepCopyInto(&out.Hostname)
+	in.Port.DeepCopyInto(&out.Port)
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+	in.Database.DeepCopyInto(&out.Database)
+	in.SSLMode.DeepCopyInto(&out.SSLMode)
+	in.SSLRootCert.DeepCopyInto(&out.SSLRootCert)
+	in.SSLCert.DeepCopyInto(&out.SSLCert)
+	in.SSLKey.DeepCopyInto(&out.SSLKey)
+	in.SSLCRL.DeepCopyInto(&out.SSLCRL)
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialProvider != nil {
+		in, out := &in.CredentialProvider, &out.CredentialProvider
+		*out = new(PgCredentialProvider)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceSpec.
+func (in *PgInstanceSpec) DeepCopy() *PgInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgInstanceStatus) DeepCopyInto(out *PgInstanceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdminPrivileges != nil {
+		in, out := &in.AdminPrivileges, &out.AdminPrivileges
+		*out = new(PgInstanceAdminPrivileges)
+		**out = **in
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]PgInstanceCertificateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceStatus.
+func (in *PgInstanceStatus) DeepCopy() *PgInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgJobStatus) DeepCopyInto(out *PgJobStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgJobStatus.
+func (in *PgJobStatus) DeepCopy() *PgJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPasswordPolicy) DeepCopyInto(out *PgPasswordPolicy) {
+	*out = *in
+	if in.CharacterClasses != nil {
+		in, out := &in.CharacterClasses, &out.CharacterClasses
+		*out = make([]PgPasswordCharacterClass, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPasswordPolicy.
+func (in *PgPasswordPolicy) DeepCopy() *PgPasswordPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPasswordPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgProperty) DeepCopyInto(out *PgProperty) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgProperty.
+func (in *PgProperty) DeepCopy() *PgProperty {
+	if in == nil {
+		return nil
+	}
+	out := new(PgProperty)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPublication) DeepCopyInto(out *PgPublication) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPublication.
+func (in *PgPublication) DeepCopy() *PgPublication {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPublication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgPublication) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPublicationList) DeepCopyInto(out *PgPublicationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgPublication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPublicationList.
+func (in *PgPublicationList) DeepCopy() *PgPublicationList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPublicationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgPublicationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPublicationSpec) DeepCopyInto(out *PgPublicationSpec) {
+	*out = *in
+	out.Database = in.Database
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]PgPublicationTable, len(*in))
+		copy(*out, *in)
+	}
+	if in.Operations != nil {
+		in, out := &in.Operations, &out.Operations
+		*out = make([]PgPublicationOperation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPublicationSpec.
+func (in *PgPublicationSpec) DeepCopy() *PgPublicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPublicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPublicationStatus) DeepCopyInto(out *PgPublicationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPublicationStatus.
+func (in *PgPublicationStatus) DeepCopy() *PgPublicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPublicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgPublicationTable) DeepCopyInto(out *PgPublicationTable) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgPublicationTable.
+func (in *PgPublicationTable) DeepCopy() *PgPublicationTable {
+	if in == nil {
+		return nil
+	}
+	out := new(PgPublicationTable)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRestore) DeepCopyInto(out *PgRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRestore.
+func (in *PgRestore) DeepCopy() *PgRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRestoreList) DeepCopyInto(out *PgRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRestoreList.
+func (in *PgRestoreList) DeepCopy() *PgRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRestoreSpec) DeepCopyInto(out *PgRestoreSpec) {
+	*out = *in
+	out.Database = in.Database
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRestoreSpec.
+func (in *PgRestoreSpec) DeepCopy() *PgRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRestoreStatus) DeepCopyInto(out *PgRestoreStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(PgJobStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRestoreStatus.
+func (in *PgRestoreStatus) DeepCopy() *PgRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRole) DeepCopyInto(out *PgRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRole.
+func (in *PgRole) DeepCopy() *PgRole {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRoleList) DeepCopyInto(out *PgRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRoleList.
+func (in *PgRoleList) DeepCopy() *PgRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRoleMembership) DeepCopyInto(out *PgRoleMembership) {
+	*out = *in
+	if in.Admin != nil {
+		in, out := &in.Admin, &out.Admin
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Inherit != nil {
+		in, out := &in.Inherit, &out.Inherit
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRoleMembership.
+func (in *PgRoleMembership) DeepCopy() *PgRoleMembership {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRoleMembership)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRoleSpec) DeepCopyInto(out *PgRoleSpec) {
+	*out = *in
+	out.Instance = in.Instance
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]PgRoleMembership, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRoleSpec.
+func (in *PgRoleSpec) DeepCopy() *PgRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgRoleStatus) DeepCopyInto(out *PgRoleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Memberships != nil {
+		in, out := &in.Memberships, &out.Memberships
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgRoleStatus.
+func (in *PgRoleStatus) DeepCopy() *PgRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSchema) DeepCopyInto(out *PgSchema) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSchema.
+func (in *PgSchema) DeepCopy() *PgSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgSchema) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSchemaDeletion) DeepCopyInto(out *PgSchemaDeletion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSchemaDeletion.
+func (in *PgSchemaDeletion) DeepCopy() *PgSchemaDeletion {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSchemaDeletion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSchemaList) DeepCopyInto(out *PgSchemaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgSchema, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSchemaList.
+func (in *PgSchemaList) DeepCopy() *PgSchemaList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSchemaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgSchemaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSchemaSpec) DeepCopyInto(out *PgSchemaSpec) {
+	*out = *in
+	out.Database = in.Database
+	out.DeletionBehavior = in.DeletionBehavior
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSchemaSpec.
+func (in *PgSchemaSpec) DeepCopy() *PgSchemaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSchemaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSchemaStatus) DeepCopyInto(out *PgSchemaStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSchemaStatus.
+func (in *PgSchemaStatus) DeepCopy() *PgSchemaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSchemaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScript) DeepCopyInto(out *PgScript) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScript.
+func (in *PgScript) DeepCopy() *PgScript {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgScript) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScriptList) DeepCopyInto(out *PgScriptList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PgScript, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScriptList.
+func (in *PgScriptList) DeepCopy() *PgScriptList {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScriptList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PgScriptList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScriptSpec) DeepCopyInto(out *PgScriptSpec) {
+	*out = *in
+	out.Database = in.Database
+	if in.Scripts != nil {
+		in, out := &in.Scripts, &out.Scripts
+		*out = make([]PgScriptStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScriptSpec.
+func (in *PgScriptSpec) DeepCopy() *PgScriptSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScriptSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScriptStatus) DeepCopyInto(out *PgScriptStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Scripts != nil {
+		in, out := &in.Scripts, &out.Scripts
+		*out = make([]PgScriptStepStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScriptStatus.
+func (in *PgScriptStatus) DeepCopy() *PgScriptStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScriptStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScriptStep) DeepCopyInto(out *PgScriptStep) {
+	*out = *in
+	in.SQL.DeepCopyInto(&out.SQL)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScriptStep.
+func (in *PgScriptStep) DeepCopy() *PgScriptStep {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScriptStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgScriptStepStatus) DeepCopyInto(out *PgScriptStepStatus) {
+	*out = *in
+	in.AppliedTime.DeepCopyInto(&out.AppliedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgScriptStepStatus.
+func (in *PgScriptStepStatus) DeepCopy() *PgScriptStepStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgScriptStepStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgInstance) DeepCopyInto(out *PgInstance) {
+func (in *PgSubscription) DeepCopyInto(out *PgSubscription) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -238,18 +1511,18 @@ func (in *PgInstance) DeepCopyInto(out *PgInstance) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstance.
-func (in *PgInstance) DeepCopy() *PgInstance {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscription.
+func (in *PgSubscription) DeepCopy() *PgSubscription {
 	if in == nil {
 		return nil
 	}
-	out := new(PgInstance)
+	out := new(PgSubscription)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PgInstance) DeepCopyObject() runtime.Object {
+func (in *PgSubscription) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -257,31 +1530,31 @@ func (in *PgInstance) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgInstanceList) DeepCopyInto(out *PgInstanceList) {
+func (in *PgSubscriptionList) DeepCopyInto(out *PgSubscriptionList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PgInstance, len(*in))
+		*out = make([]PgSubscription, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceList.
-func (in *PgInstanceList) DeepCopy() *PgInstanceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionList.
+func (in *PgSubscriptionList) DeepCopy() *PgSubscriptionList {
 	if in == nil {
 		return nil
 	}
-	out := new(PgInstanceList)
+	out := new(PgSubscriptionList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PgInstanceList) DeepCopyObject() runtime.Object {
+func (in *PgSubscriptionList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -289,43 +1562,85 @@ func (in *PgInstanceList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgInstanceRef) DeepCopyInto(out *PgInstanceRef) {
+func (in *PgSubscriptionSlot) DeepCopyInto(out *PgSubscriptionSlot) {
 	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(bool)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceRef.
-func (in *PgInstanceRef) DeepCopy() *PgInstanceRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionSlot.
+func (in *PgSubscriptionSlot) DeepCopy() *PgSubscriptionSlot {
 	if in == nil {
 		return nil
 	}
-	out := new(PgInstanceRef)
+	out := new(PgSubscriptionSlot)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgInstanceSpec) DeepCopyInto(out *PgInstanceSpec) {
+func (in *PgSubscriptionSource) DeepCopyInto(out *PgSubscriptionSource) {
 	*out = *in
-	in.Hostname.DeepCopyInto(&out.Hostname)
-	in.Port.DeepCopyInto(&out.Port)
-	in.Username.DeepCopyInto(&out.Username)
-	in.Password.DeepCopyInto(&out.Password)
-	in.Database.DeepCopyInto(&out.Database)
-	in.SSLMode.DeepCopyInto(&out.SSLMode)
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(PgDatabaseRef)
+		**out = **in
+	}
+	if in.Instance != nil {
+		in, out := &in.Instance, &out.Instance
+		*out = new(PgInstanceRef)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceSpec.
-func (in *PgInstanceSpec) DeepCopy() *PgInstanceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionSource.
+func (in *PgSubscriptionSource) DeepCopy() *PgSubscriptionSource {
 	if in == nil {
 		return nil
 	}
-	out := new(PgInstanceSpec)
+	out := new(PgSubscriptionSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgInstanceStatus) DeepCopyInto(out *PgInstanceStatus) {
+func (in *PgSubscriptionSpec) DeepCopyInto(out *PgSubscriptionSpec) {
+	*out = *in
+	out.Database = in.Database
+	in.Source.DeepCopyInto(&out.Source)
+	if in.Publications != nil {
+		in, out := &in.Publications, &out.Publications
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CopyData != nil {
+		in, out := &in.CopyData, &out.CopyData
+		*out = new(bool)
+		**out = **in
+	}
+	in.Slot.DeepCopyInto(&out.Slot)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionSpec.
+func (in *PgSubscriptionSpec) DeepCopy() *PgSubscriptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgSubscriptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgSubscriptionStatus) DeepCopyInto(out *PgSubscriptionStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -334,39 +1649,43 @@ func (in *PgInstanceStatus) DeepCopyInto(out *PgInstanceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LatestEndTime != nil {
+		in, out := &in.LatestEndTime, &out.LatestEndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LagBytes != nil {
+		in, out := &in.LagBytes, &out.LagBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]PgSubscriptionTableStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgInstanceStatus.
-func (in *PgInstanceStatus) DeepCopy() *PgInstanceStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionStatus.
+func (in *PgSubscriptionStatus) DeepCopy() *PgSubscriptionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PgInstanceStatus)
+	out := new(PgSubscriptionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PgProperty) DeepCopyInto(out *PgProperty) {
+func (in *PgSubscriptionTableStatus) DeepCopyInto(out *PgSubscriptionTableStatus) {
 	*out = *in
-	if in.ConfigMapKeyRef != nil {
-		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
-		*out = new(corev1.ConfigMapKeySelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.SecretKeyRef != nil {
-		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(corev1.SecretKeySelector)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgProperty.
-func (in *PgProperty) DeepCopy() *PgProperty {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgSubscriptionTableStatus.
+func (in *PgSubscriptionTableStatus) DeepCopy() *PgSubscriptionTableStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PgProperty)
+	out := new(PgSubscriptionTableStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -398,6 +1717,50 @@ func (in *PgUser) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserAttributes) DeepCopyInto(out *PgUserAttributes) {
+	*out = *in
+	if in.CreateDatabase != nil {
+		in, out := &in.CreateDatabase, &out.CreateDatabase
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CreateRole != nil {
+		in, out := &in.CreateRole, &out.CreateRole
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BypassRLS != nil {
+		in, out := &in.BypassRLS, &out.BypassRLS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.ValidUntil != nil {
+		in, out := &in.ValidUntil, &out.ValidUntil
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserAttributes.
+func (in *PgUserAttributes) DeepCopy() *PgUserAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgUserDatabase) DeepCopyInto(out *PgUserDatabase) {
 	*out = *in
@@ -411,6 +1774,20 @@ func (in *PgUserDatabase) DeepCopyInto(out *PgUserDatabase) {
 		*out = make([]DatabasePrivilege, len(*in))
 		copy(*out, *in)
 	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]PgUserSchema, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserDatabase.
@@ -423,6 +1800,21 @@ func (in *PgUserDatabase) DeepCopy() *PgUserDatabase {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserDeletion) DeepCopyInto(out *PgUserDeletion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserDeletion.
+func (in *PgUserDeletion) DeepCopy() *PgUserDeletion {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserDeletion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgUserList) DeepCopyInto(out *PgUserList) {
 	*out = *in
@@ -455,9 +1847,118 @@ func (in *PgUserList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserPasswordSecretRef) DeepCopyInto(out *PgUserPasswordSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserPasswordSecretRef.
+func (in *PgUserPasswordSecretRef) DeepCopy() *PgUserPasswordSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserPasswordSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserRotation) DeepCopyInto(out *PgUserRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserRotation.
+func (in *PgUserRotation) DeepCopy() *PgUserRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserSchema) DeepCopyInto(out *PgUserSchema) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]SchemaPrivilege, len(*in))
+		copy(*out, *in)
+	}
+	if in.TablePrivileges != nil {
+		in, out := &in.TablePrivileges, &out.TablePrivileges
+		*out = make([]TablePrivilege, len(*in))
+		copy(*out, *in)
+	}
+	if in.SequencePrivileges != nil {
+		in, out := &in.SequencePrivileges, &out.SequencePrivileges
+		*out = make([]SequencePrivilege, len(*in))
+		copy(*out, *in)
+	}
+	if in.FunctionPrivileges != nil {
+		in, out := &in.FunctionPrivileges, &out.FunctionPrivileges
+		*out = make([]FunctionPrivilege, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]PgUserTable, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserSchema.
+func (in *PgUserSchema) DeepCopy() *PgUserSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserSchemaStatus) DeepCopyInto(out *PgUserSchemaStatus) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserSchemaStatus.
+func (in *PgUserSchemaStatus) DeepCopy() *PgUserSchemaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserSchemaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgUserSecret) DeepCopyInto(out *PgUserSecret) {
 	*out = *in
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserSecret.
@@ -470,6 +1971,26 @@ func (in *PgUserSecret) DeepCopy() *PgUserSecret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserSettingsStatus) DeepCopyInto(out *PgUserSettingsStatus) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserSettingsStatus.
+func (in *PgUserSettingsStatus) DeepCopy() *PgUserSettingsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserSettingsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PgUserSpec) DeepCopyInto(out *PgUserSpec) {
 	*out = *in
@@ -477,7 +1998,7 @@ func (in *PgUserSpec) DeepCopyInto(out *PgUserSpec) {
 	if in.Secret != nil {
 		in, out := &in.Secret, &out.Secret
 		*out = new(PgUserSecret)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Databases != nil {
 		in, out := &in.Databases, &out.Databases
@@ -486,6 +2007,41 @@ func (in *PgUserSpec) DeepCopyInto(out *PgUserSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]PgRoleMembership, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = new(PgUserAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(PgUserRotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PasswordPolicy != nil {
+		in, out := &in.PasswordPolicy, &out.PasswordPolicy
+		*out = new(PgPasswordPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(PgUserPasswordSecretRef)
+		**out = **in
+	}
+	out.DeletionBehavior = in.DeletionBehavior
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserSpec.
@@ -508,6 +2064,29 @@ func (in *PgUserStatus) DeepCopyInto(out *PgUserStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Memberships != nil {
+		in, out := &in.Memberships, &out.Memberships
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make([]PgUserSettingsStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]PgUserSchemaStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserStatus.
@@ -519,3 +2098,23 @@ func (in *PgUserStatus) DeepCopy() *PgUserStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgUserTable) DeepCopyInto(out *PgUserTable) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]TablePrivilege, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgUserTable.
+func (in *PgUserTable) DeepCopy() *PgUserTable {
+	if in == nil {
+		return nil
+	}
+	out := new(PgUserTable)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ClusterPgInstanceReconciler", func() {
+
+	var reconciler *ClusterPgInstanceReconciler
+	var connectedInstance *apiV1.PgInstance
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Create Reconciler
+		reconciler = &ClusterPgInstanceReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error) {
+				if instance.Name == "cluster-failure" {
+					return nil, errors.New("Connection Failure")
+				}
+				connectedInstance = instance
+				return &pgConnectorMock{}, nil
+			},
+		}
+
+		// Create instances
+		for _, name := range []string{"cluster-dummy", "cluster-failure"} {
+			instance := apiV1.ClusterPgInstance{
+				TypeMeta: metaV1.TypeMeta{
+					APIVersion: "postgres.brose.bike/v1",
+					Kind:       "ClusterPgInstance",
+				},
+				ObjectMeta: metaV1.ObjectMeta{
+					Name: name,
+				},
+				Spec: apiV1.ClusterPgInstanceSpec{
+					PgInstanceSpec: apiV1.PgInstanceSpec{
+						Hostname: apiV1.PgProperty{Value: "localhost"},
+						Port:     apiV1.PgProperty{Value: "5432"},
+						Username: apiV1.PgProperty{Value: "admin"},
+						Password: apiV1.PgProperty{Value: "password"},
+					},
+					Namespace: "default",
+				},
+			}
+			err := k8sClient.Create(ctx, &instance)
+			Expect(err).To(BeNil())
+		}
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("reconciles on create of ClusterPgInstance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name: "cluster-dummy",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(Equal(instanceProbeInterval))
+		Expect(connectedInstance.Namespace).To(Equal("default"))
+
+		// and
+		var instance apiV1.ClusterPgInstance
+		err = k8sClient.Get(ctx, request.NamespacedName, &instance)
+		Expect(err).To(BeNil())
+		Expect(instance.Status.Conditions).To(HaveLen(1))
+		Expect(instance.Status.Conditions[0].Status).To(Equal(metaV1.ConditionTrue))
+		Expect(instance.Status.ServerVersion).To(Equal("14.5"))
+		Expect(instance.Status.AdminPrivileges).ToNot(BeNil())
+		Expect(instance.Status.AdminPrivileges.CreateRole).To(BeTrue())
+		Expect(instance.Status.MaxConnections).To(Equal(100))
+		Expect(instance.Status.LastProbeTime).ToNot(BeNil())
+	})
+
+	It("counts the dependents of ClusterPgInstance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		database := apiV1.PgDatabase{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace: "default",
+				Name:      "dependent",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Kind: apiV1.ClusterPgInstanceKind,
+					Name: "cluster-dummy",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &database)).To(Succeed())
+		namespaced := apiV1.PgDatabase{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace: "default",
+				Name:      "namespaced",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "cluster-dummy",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &namespaced)).To(Succeed())
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name: "cluster-dummy",
+			},
+		}
+
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		var instance apiV1.ClusterPgInstance
+		err = k8sClient.Get(ctx, request.NamespacedName, &instance)
+		Expect(err).To(BeNil())
+		Expect(instance.Status.Databases).To(Equal(1))
+		Expect(instance.Status.Users).To(BeZero())
+	})
+
+	It("handles connection failures", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name: "cluster-failure",
+			},
+		}
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+
+		// and
+		var instance apiV1.ClusterPgInstance
+		err = k8sClient.Get(ctx, request.NamespacedName, &instance)
+		Expect(err).To(BeNil())
+		Expect(instance.Status.Conditions).To(HaveLen(1))
+		Expect(instance.Status.Conditions[0].Status).To(Equal(metaV1.ConditionFalse))
+	})
+})
@@ -27,16 +27,44 @@ import (
 type PgConnectionFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error)
 
 type PgDatabaseAPI interface {
+	pgapi.PgConnector
 	pgapi.PgDatabaseAPI
 	pgapi.PgSchemaAPI
+	pgapi.PgSettingsAPI
 }
 
 type PgRoleAPI interface {
 	pgapi.PgConnector
 	pgapi.PgRoleAPI
 	pgapi.PgDatabaseAPI
+	pgapi.PgSchemaAPI
+	pgapi.PgSettingsAPI
+}
+
+type PgSchemaAPI interface {
+	pgapi.PgRoleAPI
+	pgapi.PgDatabaseAPI
+	pgapi.PgSchemaAPI
+}
+
+type PgReplicationAPI interface {
+	pgapi.PgConnector
+	pgapi.PgDatabaseAPI
+	pgapi.PgReplicationAPI
+}
+
+type PgScriptAPI interface {
+	pgapi.PgConnector
+	pgapi.PgDatabaseAPI
+	pgapi.PgScriptAPI
 }
 
 type PgDatabaseAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgDatabaseAPI, error)
 
 type PgRoleAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgRoleAPI, error)
+
+type PgSchemaAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgSchemaAPI, error)
+
+type PgReplicationAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgReplicationAPI, error)
+
+type PgScriptAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgScriptAPI, error)
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+)
+
+// getInstance fetches the PgInstance or ClusterPgInstance referenced by the given object
+// and checks if the namespace of the object is allowed to use it.
+// A ClusterPgInstance is returned as PgInstance located in the namespace of its properties.
+// If the namespace is not allowed the access condition of the object is set to false.
+func getInstance(
+	ctx context.Context,
+	r client.Reader,
+	w client.StatusWriter,
+	obj ObjectWithConditions,
+	ref apiV1.PgInstanceRef,
+) (*apiV1.PgInstance, error) {
+	logger := log.FromContext(ctx)
+	instanceId := ref.ToNamespacedName()
+
+	// Fetch the namespace of the referencing object
+	var namespace coreV1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetNamespace()}, &namespace); err != nil {
+		logger.Error(err, "Unable to fetch Namespace", "namespace", obj.GetNamespace())
+		return nil, err
+	}
+
+	// Fetch Instance
+	var instance *apiV1.PgInstance
+	var allowed bool
+	if ref.IsClusterInstance() {
+		var clusterInstance apiV1.ClusterPgInstance
+		exists, err := getResource(ctx, r, instanceId, &clusterInstance)
+		if err != nil {
+			logger.Error(err, "Unable to fetch ClusterPgInstance", "instance", instanceId.Name)
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("ClusterPgInstance " + instanceId.Name + " does not exist")
+		}
+		if allowed, err = clusterInstance.IsNamespaceAllowed(&namespace); err != nil {
+			return nil, err
+		}
+		instance = clusterInstance.ToPgInstance()
+	} else {
+		var namespacedInstance apiV1.PgInstance
+		exists, err := getResource(ctx, r, instanceId, &namespacedInstance)
+		if err != nil {
+			logger.Error(err, "Unable to fetch PgInstance", "instance", instanceId.String())
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("PgInstance " + instanceId.String() + " does not exist")
+		}
+		if allowed, err = namespacedInstance.IsNamespaceAllowed(&namespace); err != nil {
+			return nil, err
+		}
+		instance = &namespacedInstance
+	}
+
+	// Refuse instances which are not allowed in the namespace of the object
+	if !allowed {
+		err := errors.New("Namespace " + namespace.Name + " is not allowed to use the instance " + instanceId.String())
+		logger.Error(err, "Instance access refused", "instance", instanceId.String())
+		if err := setCondition(ctx, w, obj, apiV1.PgInstanceAccessConditionType, false, "NamespaceNotAllowed", err.Error()); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	// Remove the refusal after access was granted
+	if meta.FindStatusCondition(obj.GetConditions(), apiV1.PgInstanceAccessConditionType) != nil {
+		if err := removeCondition(ctx, w, obj, apiV1.PgInstanceAccessConditionType); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
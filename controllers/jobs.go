@@ -0,0 +1,226 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// jobSecretMountPath contains the path at which the Secret of a Job is mounted,
+// because libpq only reads certificates and keys from files
+const jobSecretMountPath = "/etc/postgres-operator"
+
+// jobSecretFileMode contains the permissions of the files in the mounted Secret
+var jobSecretFileMode int32 = 0440
+
+// jobOwnerReferences returns the owner references for the Jobs and Secrets created for the given resource
+func jobOwnerReferences(obj client.Object, kind string) []metaV1.OwnerReference {
+	return []metaV1.OwnerReference{
+		{
+			APIVersion:         apiV1.GroupVersion.String(),
+			BlockOwnerDeletion: &cTrue,
+			Controller:         &cTrue,
+			Kind:               kind,
+			Name:               obj.GetName(),
+			UID:                obj.GetUID(),
+		},
+	}
+}
+
+// createOrUpdateConnectionSecret writes the connection details of the given database with the credentials
+// of the given login as environment variables of libpq to the Secret with the given key.
+// The client certificate of the instance is not passed, since it authenticates the user of the instance.
+func createOrUpdateConnectionSecret(
+	ctx context.Context,
+	c client.Client,
+	key types.NamespacedName,
+	owners []metaV1.OwnerReference,
+	connStr pgapi.PgConnectionString,
+	login pgapi.PgLogin,
+	database string,
+) error {
+	data := map[string][]byte{
+		"PGHOST":     []byte(connStr.Hostname()),
+		"PGPORT":     []byte(strconv.Itoa(connStr.Port())),
+		"PGUSER":     []byte(login.Username),
+		"PGPASSWORD": []byte(login.Password),
+		"PGSSLMODE":  []byte(connStr.SSLMode()),
+		"PGDATABASE": []byte(database),
+	}
+	for parameter, file := range addTLSFiles(data, "", withoutClientCertificate(connStr.TLSConfig())) {
+		data["PG"+strings.ToUpper(parameter)] = []byte(file)
+	}
+	return createOrUpdateJobSecret(ctx, c, key, owners, data)
+}
+
+// createOrUpdateJobSecret writes the given data to the Secret with the given key
+func createOrUpdateJobSecret(
+	ctx context.Context,
+	c client.Client,
+	key types.NamespacedName,
+	owners []metaV1.OwnerReference,
+	data map[string][]byte,
+) error {
+	secret := coreV1.Secret{}
+	exists, err := getResource(ctx, c, key, &secret)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		secret = coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace:       key.Namespace,
+				Name:            key.Name,
+				OwnerReferences: owners,
+			},
+			Data: data,
+		}
+		return c.Create(ctx, &secret)
+	}
+	secret.OwnerReferences = owners
+	secret.Data = data
+	return c.Update(ctx, &secret)
+}
+
+// addTLSFiles adds the certificates and keys of the given config with the given prefix to the data of a Job Secret
+// and returns the libpq parameters, which reference the files in the mounted Secret
+func addTLSFiles(data map[string][]byte, prefix string, config pgapi.PgTLSConfig) map[string]string {
+	files := map[string]string{}
+	add := func(parameter string, name string, content string) {
+		if content == "" {
+			return
+		}
+		data[prefix+name] = []byte(content)
+		files[parameter] = path.Join(jobSecretMountPath, prefix+name)
+	}
+	add("sslrootcert", "root.crt", config.RootCert)
+	add("sslcert", "postgresql.crt", config.Cert)
+	add("sslkey", "postgresql.key", config.Key)
+	add("sslcrl", "root.crl", config.CRL)
+	return files
+}
+
+// withoutClientCertificate removes the client certificate and key of the instance from the given configuration,
+// which must not be passed to Jobs in the namespaces of the tenants
+func withoutClientCertificate(config pgapi.PgTLSConfig) pgapi.PgTLSConfig {
+	config.Cert, config.Key = "", ""
+	return config
+}
+
+// toConnInfo returns the connection details of the given database as a libpq connection string with the given login,
+// the given files are added as parameters for the certificates and keys
+func toConnInfo(connStr pgapi.PgConnectionString, login pgapi.PgLogin, database string, files map[string]string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	parameters := []string{
+		"host='" + quote.Replace(connStr.Hostname()) + "'",
+		"port=" + strconv.Itoa(connStr.Port()),
+		"user='" + quote.Replace(login.Username) + "'",
+		"password='" + quote.Replace(login.Password) + "'",
+		"dbname='" + quote.Replace(database) + "'",
+	}
+	if connStr.SSLMode() != "" {
+		parameters = append(parameters, "sslmode='"+quote.Replace(connStr.SSLMode())+"'")
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parameters = append(parameters, name+"='"+quote.Replace(files[name])+"'")
+	}
+	return strings.Join(parameters, " ")
+}
+
+// newJobPodSpec returns the specification of a pod, which runs the given command with the connection details
+// from the given Secret and the given claim mounted at the given path.
+// If no claim is given, an empty directory is mounted instead.
+// The Secret is mounted as well, to provide the certificates and keys of the connection.
+func newJobPodSpec(name string, image string, command []string, secretName string, claimName string, mountPath string) coreV1.PodSpec {
+	volumeSource := coreV1.VolumeSource{EmptyDir: &coreV1.EmptyDirVolumeSource{}}
+	if claimName != "" {
+		volumeSource = coreV1.VolumeSource{
+			PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		}
+	}
+	return coreV1.PodSpec{
+		RestartPolicy: coreV1.RestartPolicyNever,
+		Containers: []coreV1.Container{
+			{
+				Name:    name,
+				Image:   image,
+				Command: command,
+				EnvFrom: []coreV1.EnvFromSource{
+					{SecretRef: &coreV1.SecretEnvSource{LocalObjectReference: coreV1.LocalObjectReference{Name: secretName}}},
+				},
+				VolumeMounts: []coreV1.VolumeMount{
+					{Name: "data", MountPath: mountPath},
+					{Name: "connection", MountPath: jobSecretMountPath, ReadOnly: true},
+				},
+			},
+		},
+		Volumes: []coreV1.Volume{
+			{Name: "data", VolumeSource: volumeSource},
+			{Name: "connection", VolumeSource: coreV1.VolumeSource{
+				// libpq refuses private keys which are accessible by others
+				Secret: &coreV1.SecretVolumeSource{SecretName: secretName, DefaultMode: &jobSecretFileMode},
+			}},
+		},
+	}
+}
+
+// getJobStatus returns the observed state of the given Job
+func getJobStatus(job *batchV1.Job) apiV1.PgJobStatus {
+	status := apiV1.PgJobStatus{
+		Name:           job.Name,
+		Phase:          apiV1.RunningJobPhase,
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+	}
+	if job.Status.Succeeded > 0 {
+		status.Phase = apiV1.SucceededJobPhase
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchV1.JobFailed && condition.Status == coreV1.ConditionTrue {
+			status.Phase = apiV1.FailedJobPhase
+		}
+	}
+	return status
+}
+
+// getJobFailure returns the message of the failed condition of the given Job
+func getJobFailure(job *batchV1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchV1.JobFailed && condition.Status == coreV1.ConditionTrue {
+			return condition.Message
+		}
+	}
+	return ""
+}
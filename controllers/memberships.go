@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// ObjectWithMemberships is a resource which tracks the group roles granted by the operator in its status
+type ObjectWithMemberships interface {
+	ObjectWithConditions
+	GetMemberships() []string
+	SetMemberships(memberships []string)
+}
+
+// updateMemberships grants all desired group roles to the given role and revokes all group roles,
+// which were granted by the operator before, but are not desired anymore.
+// Memberships which were granted manually are not touched.
+// Only group roles managed by a PgRole or PgUser in the namespace of the resource on the same instance are granted,
+// the role of the operator, superusers and predefined roles are refused.
+func updateMemberships(
+	ctx context.Context,
+	c client.Client,
+	obj ObjectWithMemberships,
+	pgApi PgRoleAPI,
+	roleName string,
+	instance *apiV1.PgInstanceRef,
+	desired []apiV1.PgRoleMembership,
+	conditionType string,
+) error {
+	logger := log.FromContext(ctx)
+	r := c.Status()
+	previous := obj.GetMemberships()
+	// Memberships are not managed for this role
+	if len(desired) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	// Refuse group roles, which the resource is not allowed to use,
+	// memberships in these roles, which were granted before, are revoked below
+	allowed := make([]apiV1.PgRoleMembership, 0, len(desired))
+	refusals := make([]string, 0)
+	for _, membership := range desired {
+		message, err := checkPrivilegedRole(pgApi, membership.Name)
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			managed, err := isRoleManagedInNamespace(ctx, c, obj.GetNamespace(), instance, membership.Name)
+			if err != nil {
+				return err
+			}
+			if !managed {
+				message = "The role " + membership.Name + " is not managed by a PgRole or PgUser in the namespace " + obj.GetNamespace()
+			}
+		}
+		if message != "" {
+			refusals = append(refusals, message)
+			continue
+		}
+		allowed = append(allowed, membership)
+	}
+	desired = allowed
+
+	// Check if all group roles exist
+	missingRoles := make([]string, 0)
+	for _, membership := range desired {
+		exists, err := pgApi.IsRoleExisting(membership.Name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			missingRoles = append(missingRoles, membership.Name)
+		}
+	}
+	if len(missingRoles) > 0 {
+		err := errors.New("The instance does not contain the roles: " + strings.Join(missingRoles, ","))
+		if err := setCondition(ctx, r, obj, conditionType, false, "RolesMissing", err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+
+	// Fetch current memberships
+	currentMemberships, err := pgApi.GetRoleMemberships(roleName)
+	if err != nil {
+		logger.Error(err, "Unable to query memberships of role "+roleName)
+		return err
+	}
+	current := make(map[string]pgapi.PgRoleMembership)
+	for _, membership := range currentMemberships {
+		current[membership.Group] = membership
+	}
+
+	// Grant desired memberships
+	granted := make([]string, 0)
+	for _, membership := range desired {
+		existing, found := current[membership.Name]
+		if found && existing.Admin == membership.IsAdmin() && existing.Inherit == membership.IsInherit() {
+			granted = append(granted, membership.Name)
+			continue
+		}
+		// Options can only be changed by granting the membership again
+		if found {
+			if err := pgApi.RevokeRoleMembership(roleName, membership.Name); err != nil {
+				return err
+			}
+		}
+		if err := pgApi.GrantRoleMembership(roleName, membership.Name, membership.IsAdmin(), membership.IsInherit()); err != nil {
+			logger.Error(err, "Unable to grant role "+membership.Name+" to role "+roleName)
+			if err := setCondition(ctx, r, obj, conditionType, false, "GrantFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+		granted = append(granted, membership.Name)
+	}
+
+	// Revoke memberships which are not desired anymore
+	for _, groupName := range previous {
+		if hasElement(granted, groupName) {
+			continue
+		}
+		if _, found := current[groupName]; !found {
+			continue
+		}
+		if err := pgApi.RevokeRoleMembership(roleName, groupName); err != nil {
+			logger.Error(err, "Unable to revoke role "+groupName+" from role "+roleName)
+			if err := setCondition(ctx, r, obj, conditionType, false, "RevokeFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	// Persist the granted memberships
+	if !equalElements(previous, granted) {
+		obj.SetMemberships(granted)
+		if err := r.Update(ctx, obj); err != nil {
+			return err
+		}
+	}
+	if len(refusals) > 0 {
+		err := errors.New(strings.Join(refusals, ", "))
+		if err := setCondition(ctx, r, obj, conditionType, false, "RoleNotAllowed", err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+	return setCondition(ctx, r, obj, conditionType, true, "MembershipsApplied", "-")
+}
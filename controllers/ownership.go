@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+)
+
+// checkOwnership decides if the given resource is allowed to manage an existing object with the given comment.
+// It returns true if the object is not marked as managed by the resource yet and has to be adopted.
+// If the resource is not allowed to manage the object, the reason and an error explaining the refusal are returned.
+// Objects, which the resource already reported as existing in the given condition, were created by a version
+// of the operator without ownership markers and are adopted regardless of the policy.
+func checkOwnership(obj ObjectWithConditions, policy apiV1.PgAdoptionPolicy, comment string, description string, existsConditionType string) (bool, string, error) {
+	owner := apiV1.ParseOwnershipMarker(comment)
+	if owner == obj.GetUID() {
+		return false, "", nil
+	}
+	if owner != "" {
+		return false, "ManagedByOtherResource", fmt.Errorf("%s is already managed by the resource with the UID %s", description, owner)
+	}
+	if policy == apiV1.FailAdoptionPolicy && !meta.IsStatusConditionTrue(obj.GetConditions(), existsConditionType) {
+		return false, "ExistsUnmanaged", fmt.Errorf("%s already exists and was not created by this resource, the adoption policy %s refuses to adopt it", description, policy)
+	}
+	return true, "", nil
+}
+
+// checkMissingOwnership returns an error if a missing object must not be created with the given adoption policy
+func checkMissingOwnership(policy apiV1.PgAdoptionPolicy, description string) (string, error) {
+	if policy == apiV1.AdoptAdoptionPolicy {
+		return "NotExisting", errors.New(description + " does not exist and the adoption policy " + string(policy) + " does not create it")
+	}
+	return "", nil
+}
+
+// refuseOwnership sets the ownership condition of the given resource to false and returns the given error
+func refuseOwnership(ctx context.Context, w client.StatusWriter, obj ObjectWithConditions, conditionType string, reason string, err error) error {
+	if err := setCondition(ctx, w, obj, conditionType, false, reason, err.Error()); err != nil {
+		return err
+	}
+	return err
+}
+
+// acceptOwnership removes the ownership condition of the given resource after the object was accepted
+func acceptOwnership(ctx context.Context, w client.StatusWriter, obj ObjectWithConditions, conditionType string) error {
+	if meta.FindStatusCondition(obj.GetConditions(), conditionType) != nil {
+		return removeCondition(ctx, w, obj, conditionType)
+	}
+	return nil
+}
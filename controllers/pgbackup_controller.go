@@ -0,0 +1,376 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// backupMountPath contains the path at which the claim of the backups is mounted
+const backupMountPath = "/backup"
+
+// backupTimestampPattern matches the timestamp in the file names of the backups
+const backupTimestampPattern = "[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]T[0-9][0-9][0-9][0-9][0-9][0-9]Z"
+
+// backupCredentialsRefreshInterval specifies how often the credentials of scheduled backups are refreshed
+const backupCredentialsRefreshInterval = 5 * time.Minute
+
+// backupJobHistoryLimit limits the number of Jobs which are reported in the status of a PgBackup
+const backupJobHistoryLimit = 5
+
+// PgBackupReconciler reconciles a PgBackup object
+type PgBackupReconciler struct {
+	client.Client
+	Scheme              *runtime.Scheme
+	PgConnectionFactory PgConnectionFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgbackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgbackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgbackups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var backup apiV1.PgBackup
+	exists, err := getResource(ctx, r, req.NamespacedName, &backup)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgBackup", "backup", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted, the Jobs and Secrets are removed by the garbage collector
+	if !exists || backup.DeletionTimestamp != nil {
+		logger.Info("Deleted PgBackup", "backup", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be backed up
+	if backup.Spec.Database.Namespace != backup.Namespace {
+		message := "The PgDatabase " + backup.GetDatabaseIdString() + " is not in the namespace " + backup.Namespace
+		if err := setCondition(ctx, r.Status(), &backup, apiV1.PgBackupSucceededConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, backup.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", backup.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The PgDatabase " + backup.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &backup, apiV1.PgBackupSucceededConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "backup", backup.ToNamespacedName(), "database", backup.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &backup, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Read the credentials of the role
+	_, login, reason, err := readUserLogin(ctx, r, backup.GetUserId())
+	if err != nil {
+		logger.Error(err, "Unable to read the credentials of the role", "backup", backup.ToNamespacedName(), "role", backup.Spec.Role)
+		if reason != "" {
+			if err := setCondition(ctx, r.Status(), &backup, apiV1.PgBackupSucceededConditionType, false, reason, err.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Minute}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update the credentials used by the Jobs
+	secretKey := types.NamespacedName{Namespace: backup.Namespace, Name: backupSecretName(&backup)}
+	owners := jobOwnerReferences(&backup, apiV1.PgBackupKind())
+	if err := createOrUpdateConnectionSecret(ctx, r.Client, secretKey, owners, pgApi.ConnectionString(), login, database.Name); err != nil {
+		logger.Error(err, "Unable to update backup Secret", "backup", backup.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Create the Job or the CronJob taking the backups
+	if err := r.createOrUpdateJobs(ctx, &backup); err != nil {
+		logger.Error(err, "Unable to update backup Jobs", "backup", backup.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Track the state of the Jobs
+	if err := r.updateStatus(ctx, &backup); err != nil {
+		logger.Error(err, "Unable to update status", "backup", backup.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	logger.Info("Processed backup", "backup", backup.ToNamespacedName(), "database", backup.GetDatabaseIdString())
+
+	// The credentials are refreshed periodically for the Jobs of the CronJob, e.g. after the password was rotated
+	if backup.Spec.Schedule != "" {
+		return ctrl.Result{RequeueAfter: backupCredentialsRefreshInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgConnectionFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgBackup{}).
+		Owns(&batchV1.CronJob{}).
+		// The Jobs of a CronJob are not owned by the PgBackup, therefore they are mapped by their label
+		Watches(&source.Kind{Type: &batchV1.Job{}}, handler.EnqueueRequestsFromMapFunc(mapBackupJob)).
+		Complete(r)
+}
+
+// mapBackupJob maps a Job to the PgBackup referenced in its labels
+func mapBackupJob(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[apiV1.PgBackupLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+func (r *PgBackupReconciler) createPgApi(ctx context.Context, backup *apiV1.PgBackup, database *apiV1.PgDatabase) (pgapi.PgConnector, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), backup, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgConnectionFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), backup, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "backup", backup.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), backup, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "backup", backup.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+// createOrUpdateJobs creates the Job of a single backup, or creates and updates the CronJob of scheduled backups
+func (r *PgBackupReconciler) createOrUpdateJobs(ctx context.Context, backup *apiV1.PgBackup) error {
+	logger := log.FromContext(ctx)
+	key := types.NamespacedName{Namespace: backup.Namespace, Name: backupJobName(backup)}
+	owners := jobOwnerReferences(backup, apiV1.PgBackupKind())
+
+	cronJob := batchV1.CronJob{}
+	cronJobExists, err := getResource(ctx, r, key, &cronJob)
+	if err != nil {
+		return err
+	}
+
+	// Take a single backup
+	if backup.Spec.Schedule == "" {
+		if cronJobExists {
+			if err := r.Delete(ctx, &cronJob); err != nil {
+				return err
+			}
+			logger.Info("Deleted backup CronJob", "backup", backup.ToNamespacedName(), "cronjob", key.String())
+		}
+		job := batchV1.Job{}
+		exists, err := getResource(ctx, r, key, &job)
+		if err != nil || exists {
+			return err
+		}
+		job = batchV1.Job{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace:       key.Namespace,
+				Name:            key.Name,
+				Labels:          backupJobLabels(backup),
+				OwnerReferences: owners,
+			},
+			Spec: newBackupJobSpec(backup),
+		}
+		if err := r.Create(ctx, &job); err != nil {
+			return err
+		}
+		logger.Info("Created backup Job", "backup", backup.ToNamespacedName(), "job", key.String())
+		return nil
+	}
+
+	// Take scheduled backups
+	spec := batchV1.CronJobSpec{
+		Schedule:          backup.Spec.Schedule,
+		ConcurrencyPolicy: batchV1.ForbidConcurrent,
+		JobTemplate: batchV1.JobTemplateSpec{
+			ObjectMeta: metaV1.ObjectMeta{Labels: backupJobLabels(backup)},
+			Spec:       newBackupJobSpec(backup),
+		},
+	}
+	if !cronJobExists {
+		cronJob = batchV1.CronJob{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace:       key.Namespace,
+				Name:            key.Name,
+				OwnerReferences: owners,
+			},
+			Spec: spec,
+		}
+		if err := r.Create(ctx, &cronJob); err != nil {
+			return err
+		}
+		logger.Info("Created backup CronJob", "backup", backup.ToNamespacedName(), "cronjob", key.String())
+		return nil
+	}
+	cronJob.OwnerReferences = owners
+	cronJob.Spec = spec
+	return r.Update(ctx, &cronJob)
+}
+
+// updateStatus reports the state of the Jobs of the backup
+func (r *PgBackupReconciler) updateStatus(ctx context.Context, backup *apiV1.PgBackup) error {
+	jobs := batchV1.JobList{}
+	err := r.List(ctx, &jobs, client.InNamespace(backup.Namespace), client.MatchingLabels(backupJobLabels(backup)))
+	if err != nil {
+		return err
+	}
+	// Newest Jobs first
+	sort.Slice(jobs.Items, func(i, j int) bool {
+		return jobs.Items[j].CreationTimestamp.Before(&jobs.Items[i].CreationTimestamp)
+	})
+
+	backup.Status.Jobs = []apiV1.PgJobStatus{}
+	for i := range jobs.Items {
+		status := getJobStatus(&jobs.Items[i])
+		if status.Phase == apiV1.SucceededJobPhase && status.CompletionTime != nil {
+			if backup.Status.LastSuccessfulTime == nil || backup.Status.LastSuccessfulTime.Before(status.CompletionTime) {
+				backup.Status.LastSuccessfulTime = status.CompletionTime
+			}
+		}
+		if len(backup.Status.Jobs) < backupJobHistoryLimit {
+			backup.Status.Jobs = append(backup.Status.Jobs, status)
+		}
+	}
+
+	// Report the state of the newest Job
+	if len(jobs.Items) == 0 {
+		putCondition(backup, apiV1.PgBackupSucceededConditionType, false, "BackupPending", "Waiting for the first backup")
+		return r.Status().Update(ctx, backup)
+	}
+	newest := &jobs.Items[0]
+	switch getJobStatus(newest).Phase {
+	case apiV1.SucceededJobPhase:
+		putCondition(backup, apiV1.PgBackupSucceededConditionType, true, "BackupSucceeded", "Job "+newest.Name+" succeeded")
+	case apiV1.FailedJobPhase:
+		message := fmt.Sprintf("Job %s failed: %s", newest.Name, getJobFailure(newest))
+		putCondition(backup, apiV1.PgBackupSucceededConditionType, false, "BackupFailed", message)
+	default:
+		putCondition(backup, apiV1.PgBackupSucceededConditionType, false, "BackupRunning", "Job "+newest.Name+" is running")
+	}
+	return r.Status().Update(ctx, backup)
+}
+
+// backupJobName returns the name of the Job or CronJob taking the backups
+func backupJobName(backup *apiV1.PgBackup) string {
+	return backup.Name + "-backup"
+}
+
+// backupSecretName returns the name of the Secret containing the credentials used by the backup Jobs
+func backupSecretName(backup *apiV1.PgBackup) string {
+	return backup.Name + "-backup-credentials"
+}
+
+func backupJobLabels(backup *apiV1.PgBackup) map[string]string {
+	return map[string]string{apiV1.PgBackupLabel: backup.Name}
+}
+
+// newBackupJobSpec returns the specification of the Job which takes a backup and deletes the backups exceeding the retention
+func newBackupJobSpec(backup *apiV1.PgBackup) batchV1.JobSpec {
+	format := backup.GetFormat()
+	backoffLimit := int32(2)
+	prefix := backupMountPath + "/" + backup.Name + "-"
+	script := []string{
+		"set -e",
+		fmt.Sprintf(`file="%s$(date -u +%%Y%%m%%dT%%H%%M%%SZ)%s"`, prefix, format.FileExtension()),
+		fmt.Sprintf(`pg_dump --format=%s --file="$file"`, format),
+		`echo "Written backup $file"`,
+	}
+	if backup.Spec.Retention > 0 {
+		// Only files with the exact timestamp pattern are deleted, which excludes the backups of PgBackups
+		// whose names start with the name of this PgBackup. The timestamp sorts the backups by their age.
+		pattern := prefix + backupTimestampPattern + format.FileExtension()
+		script = append(script, fmt.Sprintf(
+			`ls -1d %s 2>/dev/null | sort -r | tail -n +%d | while read -r old; do rm -rf "$old"; echo "Deleted backup $old"; done`,
+			pattern, backup.Spec.Retention+1,
+		))
+	}
+	command := []string{"/bin/sh", "-c", strings.Join(script, "\n")}
+	return batchV1.JobSpec{
+		BackoffLimit: &backoffLimit,
+		Template: coreV1.PodTemplateSpec{
+			ObjectMeta: metaV1.ObjectMeta{Labels: backupJobLabels(backup)},
+			Spec:       newJobPodSpec("pg-dump", backup.GetImage(), command, backupSecretName(backup), backup.Spec.PersistentVolumeClaim, backupMountPath),
+		},
+	}
+}
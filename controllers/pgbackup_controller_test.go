@@ -0,0 +1,330 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// createJobTestFixtures creates the instance, the database and the user with its Secret referenced by backups and restores
+func createJobTestFixtures(ctx context.Context) {
+	instance := apiV1.PgInstance{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "instance",
+		},
+		Spec: apiV1.PgInstanceSpec{
+			Hostname: apiV1.PgProperty{Value: "localhost"},
+			Port:     apiV1.PgProperty{Value: "5432"},
+			Username: apiV1.PgProperty{Value: "admin"},
+			Password: apiV1.PgProperty{Value: "password"},
+		},
+	}
+	err := k8sClient.Create(ctx, &instance)
+	Expect(err).To(BeNil())
+	database := apiV1.PgDatabase{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "dummy",
+		},
+		Spec: apiV1.PgDatabaseSpec{
+			Instance: apiV1.PgInstanceRef{
+				Namespace: "default",
+				Name:      "instance",
+			},
+			DefaultPrivileges: []apiV1.PgDatabaseDefaultPrivileges{},
+			Extensions:        []apiV1.PgDatabaseExtension{},
+			DeletionBehavior:  apiV1.PgDatabaseDeletion{},
+			PublicPrivileges:  apiV1.PgDatabasePublicPrivileges{},
+			PublicSchema:      apiV1.PgDatabasePublicSchema{},
+		},
+	}
+	err = k8sClient.Create(ctx, &database)
+	Expect(err).To(BeNil())
+	user := apiV1.PgUser{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "dummy-owner",
+		},
+		Spec: apiV1.PgUserSpec{
+			Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "instance"},
+			Secret:   &apiV1.PgUserSecret{Name: "dummy-owner-credentials"},
+		},
+	}
+	err = k8sClient.Create(ctx, &user)
+	Expect(err).To(BeNil())
+	secret := coreV1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "dummy-owner-credentials",
+		},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	err = k8sClient.Create(ctx, &secret)
+	Expect(err).To(BeNil())
+}
+
+// deleteJobTestFixtures deletes the Jobs, CronJobs and Secrets, which are not removed by a garbage collector in the test environment
+func deleteJobTestFixtures(ctx context.Context, secrets ...string) {
+	opts := []client.DeleteAllOfOption{client.InNamespace("default"), client.PropagationPolicy(v1.DeletePropagationBackground)}
+	err := k8sClient.DeleteAllOf(ctx, &batchV1.Job{}, opts...)
+	Expect(err).To(BeNil())
+	err = k8sClient.DeleteAllOf(ctx, &batchV1.CronJob{}, opts...)
+	Expect(err).To(BeNil())
+	for _, name := range append(secrets, "dummy-owner-credentials") {
+		err = client.IgnoreNotFound(k8sClient.Delete(ctx, &coreV1.Secret{ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: name}}))
+		Expect(err).To(BeNil())
+	}
+}
+
+var _ = Describe("PgBackupReconciler", func() {
+
+	var reconciler *PgBackupReconciler
+
+	newBackup := func(schedule string) *apiV1.PgBackup {
+		return &apiV1.PgBackup{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "nightly",
+			},
+			Spec: apiV1.PgBackupSpec{
+				Database: apiV1.PgDatabaseRef{
+					Namespace: "default",
+					Name:      "dummy",
+				},
+				Schedule:              schedule,
+				Role:                  "dummy-owner",
+				PersistentVolumeClaim: "backups",
+				Retention:             3,
+			},
+		}
+	}
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "nightly",
+		},
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Create Reconciler
+		reconciler = &PgBackupReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error) {
+				return &pgConnectorMock{}, nil
+			},
+		}
+		createJobTestFixtures(ctx)
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		deleteJobTestFixtures(ctx, "nightly-backup-credentials")
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("creates a Job for a single backup", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		err := k8sClient.Create(ctx, newBackup(""))
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-backup"}, &job)
+		Expect(err).To(BeNil())
+		Expect(job.Labels[apiV1.PgBackupLabel]).To(Equal("nightly"))
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("backups"))
+		Expect(podSpec.Containers[0].EnvFrom[0].SecretRef.Name).To(Equal("nightly-backup-credentials"))
+		Expect(podSpec.Volumes[1].Secret.SecretName).To(Equal("nightly-backup-credentials"))
+		script := podSpec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring("pg_dump --format=custom"))
+		Expect(strings.Contains(script, "tail -n +4")).To(BeTrue())
+		Expect(script).To(ContainSubstring("/backup/nightly-" + backupTimestampPattern + ".dump"))
+
+		// and
+		secret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-backup-credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data["PGDATABASE"])).To(Equal("dummy"))
+		Expect(string(secret.Data["PGUSER"])).To(Equal("dummy-owner"))
+		Expect(string(secret.Data["PGPASSWORD"])).To(Equal("secret"))
+
+		// and
+		backup := apiV1.PgBackup{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &backup)
+		Expect(err).To(BeNil())
+		Expect(backup.Status.Jobs).To(HaveLen(1))
+		Expect(backup.Status.Jobs[0].Phase).To(Equal(apiV1.RunningJobPhase))
+		condition := meta.FindStatusCondition(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)
+		Expect(condition.Reason).To(Equal("BackupRunning"))
+	})
+
+	It("creates a CronJob for scheduled backups", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		err := k8sClient.Create(ctx, newBackup("0 2 * * *"))
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(Equal(backupCredentialsRefreshInterval))
+		cronJob := batchV1.CronJob{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-backup"}, &cronJob)
+		Expect(err).To(BeNil())
+		Expect(cronJob.Spec.Schedule).To(Equal("0 2 * * *"))
+		Expect(cronJob.Spec.JobTemplate.Labels[apiV1.PgBackupLabel]).To(Equal("nightly"))
+
+		// and
+		backup := apiV1.PgBackup{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &backup)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)
+		Expect(condition.Reason).To(Equal("BackupPending"))
+	})
+
+	It("reports succeeded backups", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		err := k8sClient.Create(ctx, newBackup(""))
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-backup"}, &job)
+		Expect(err).To(BeNil())
+		now := v1.Now()
+		job.Status.StartTime = &now
+		job.Status.CompletionTime = &now
+		job.Status.Succeeded = 1
+		err = k8sClient.Status().Update(ctx, &job)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		backup := apiV1.PgBackup{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &backup)
+		Expect(err).To(BeNil())
+		Expect(backup.Status.LastSuccessfulTime).ToNot(BeNil())
+		Expect(backup.Status.Jobs[0].Phase).To(Equal(apiV1.SucceededJobPhase))
+		Expect(meta.IsStatusConditionTrue(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)).To(BeTrue())
+	})
+
+	It("reports a missing database", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		backup := newBackup("")
+		backup.Spec.Database.Name = "missing"
+		err := k8sClient.Create(ctx, backup)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		err = k8sClient.Get(ctx, request.NamespacedName, backup)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)
+		Expect(condition.Reason).To(Equal("DatabaseMissing"))
+	})
+
+	It("refuses a database in another namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		backup := newBackup("")
+		backup.Spec.Database.Namespace = "other"
+		err := k8sClient.Create(ctx, backup)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		err = k8sClient.Get(ctx, request.NamespacedName, backup)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)
+		Expect(condition.Reason).To(Equal("DatabaseNotAllowed"))
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-backup"}, &job)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("reports a missing user", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		backup := newBackup("")
+		backup.Spec.Role = "missing"
+		err := k8sClient.Create(ctx, backup)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		err = k8sClient.Get(ctx, request.NamespacedName, backup)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(backup.Status.Conditions, apiV1.PgBackupSucceededConditionType)
+		Expect(condition.Reason).To(Equal("RoleMissing"))
+	})
+})
@@ -0,0 +1,355 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// cloneMountPath contains the path at which the working directory of the clone Job is mounted
+const cloneMountPath = "/work"
+
+// cloneJobName returns the name of the Job and the Secret, which are used to clone the given database
+func cloneJobName(database *apiV1.PgDatabase) string {
+	return database.Name + "-clone"
+}
+
+// isSameInstance returns true if both references point to the same instance
+func isSameInstance(a *apiV1.PgInstanceRef, b *apiV1.PgInstanceRef) bool {
+	return a.IsClusterInstance() == b.IsClusterInstance() && a.ToNamespacedName() == b.ToNamespacedName()
+}
+
+// cloneDatabase creates the database as a clone of the configured source.
+// A source on the same instance is used as template, a source on another instance
+// is copied by a Job running pg_dump and pg_restore into the newly created database.
+// The clone is recorded in the status before the database is created, so that an interrupted clone can be completed.
+func (r *PgDatabaseReconciler) cloneDatabase(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase, options pgapi.PgDatabaseOptions) error {
+	logger := log.FromContext(ctx)
+	source, err := r.getCloneSource(ctx, database)
+	if err != nil {
+		return err
+	}
+	sourceId := database.Spec.Source.Database.ToNamespacedName()
+
+	// Clone on the same instance
+	if isSameInstance(&database.Spec.Instance, &source.Spec.Instance) {
+		if err := r.checkCloneSource(ctx, pgApi, database, source); err != nil {
+			return err
+		}
+		// A database with connected sessions cannot be used as template,
+		// the sessions belong to the users of the source and are only terminated on request
+		if !database.Spec.Source.TerminateSessions {
+			count, err := pgApi.GetDatabaseSessionCount(source.Name)
+			if err != nil {
+				logger.Error(err, "Unable to query sessions of source database "+source.Name)
+				return err
+			}
+			if count > 0 {
+				err := fmt.Errorf("The source database %s is used by %d sessions", source.Name, count)
+				if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "SourceInUse", err.Error()); err != nil {
+					return err
+				}
+				return err
+			}
+		}
+		database.Status.Clone = &apiV1.PgDatabaseCloneStatus{
+			Source: sourceId.String(),
+			Method: apiV1.TemplateCloneMethod,
+			Phase:  apiV1.RunningJobPhase,
+		}
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, false, "CloneRunning", "Cloning from "+sourceId.String())
+		if err := r.Status().Update(ctx, database); err != nil {
+			return err
+		}
+		if database.Spec.Source.TerminateSessions {
+			if err := pgApi.TerminateDatabaseSessions(source.Name); err != nil {
+				logger.Error(err, "Unable to terminate sessions of source database "+source.Name)
+				return r.failClone(ctx, database, err)
+			}
+		}
+		options.Template = source.Name
+		snapshotTime := metaV1.Now()
+		if err := pgApi.CreateDatabaseWithOptions(database.Name, options); err != nil {
+			logger.Error(err, "Unable to clone database "+database.Name+" from "+source.Name)
+			return r.failClone(ctx, database, err)
+		}
+		logger.Info("Cloned database "+database.Name+" from template", "source", sourceId.String())
+		database.Status.Clone.Phase = apiV1.SucceededJobPhase
+		database.Status.Clone.SnapshotTime = &snapshotTime
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, true, "CloneSucceeded", "Cloned from "+sourceId.String())
+		return r.Status().Update(ctx, database)
+	}
+
+	// Clone from another instance
+	sourceApi, err := r.createSourceApi(ctx, database, source)
+	if err != nil {
+		return err
+	}
+	if err := r.checkCloneSource(ctx, sourceApi, database, source); err != nil {
+		return err
+	}
+	if _, _, err := r.readCloneLogins(ctx, database, source); err != nil {
+		return err
+	}
+	key := types.NamespacedName{Namespace: database.Namespace, Name: cloneJobName(database)}
+	database.Status.Clone = &apiV1.PgDatabaseCloneStatus{
+		Source: sourceId.String(),
+		Method: apiV1.DumpRestoreCloneMethod,
+		Phase:  apiV1.RunningJobPhase,
+		Job:    key.Name,
+	}
+	putCondition(database, apiV1.PgDatabaseCloneConditionType, false, "CloneRunning", "Waiting for clone Job "+key.String()+" to succeed")
+	if err := r.Status().Update(ctx, database); err != nil {
+		return err
+	}
+	if err := pgApi.CreateDatabaseWithOptions(database.Name, options); err != nil {
+		logger.Error(err, "Unable to create database "+database.Name)
+		return r.failClone(ctx, database, err)
+	}
+	logger.Info("Created database " + database.Name + " for clone")
+	return r.createCloneJob(ctx, pgApi, sourceApi, database, source, key)
+}
+
+// getCloneSource fetches the source PgDatabase of the clone and checks that the source allows the namespace of the database
+func (r *PgDatabaseReconciler) getCloneSource(ctx context.Context, database *apiV1.PgDatabase) (*apiV1.PgDatabase, error) {
+	logger := log.FromContext(ctx)
+	sourceId := database.Spec.Source.Database.ToNamespacedName()
+
+	var source apiV1.PgDatabase
+	exists, err := getResource(ctx, r, sourceId, &source)
+	if err != nil {
+		logger.Error(err, "Unable to fetch source PgDatabase", "database", database.Name, "source", sourceId.String())
+		return nil, err
+	}
+	if !exists {
+		err := errors.New("The source PgDatabase " + sourceId.String() + " does not exist")
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "SourceMissing", err.Error()); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if source.Namespace != database.Namespace && !isNamespaceAllowedByAnnotation(&source, apiV1.PgDatabaseCloneAllowedNamespacesAnnotation, database.Namespace) {
+		err := errors.New("The source PgDatabase " + sourceId.String() + " does not allow clones in namespace " + database.Namespace)
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "SourceNotAllowed", err.Error()); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+// createSourceApi connects to the instance of the source database
+func (r *PgDatabaseReconciler) createSourceApi(ctx context.Context, database *apiV1.PgDatabase, source *apiV1.PgDatabase) (PgDatabaseAPI, error) {
+	sourceInstance, err := getInstance(ctx, r, r.Status(), database, source.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+	sourceApi, err := r.PgDatabaseAPIFactory(ctx, r, sourceInstance)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to connect to source instance", "database", database.Name, "source", source.ToNamespacedName())
+		return nil, err
+	}
+	return sourceApi, nil
+}
+
+// createCloneJob creates the Secret and the Job, which copy the source database into the database.
+// The Job connects with the logins of PgUsers, the login and the client certificate of the instances are not passed to the Job.
+func (r *PgDatabaseReconciler) createCloneJob(ctx context.Context, pgApi PgDatabaseAPI, sourceApi PgDatabaseAPI, database *apiV1.PgDatabase, source *apiV1.PgDatabase, key types.NamespacedName) error {
+	logger := log.FromContext(ctx)
+	sourceLogin, targetLogin, err := r.readCloneLogins(ctx, database, source)
+	if err != nil {
+		return err
+	}
+	owners := jobOwnerReferences(database, apiV1.PgDatabaseKind())
+	data := map[string][]byte{}
+	sourceConnStr, targetConnStr := sourceApi.ConnectionString(), pgApi.ConnectionString()
+	sourceFiles := addTLSFiles(data, "source-", withoutClientCertificate(sourceConnStr.TLSConfig()))
+	targetFiles := addTLSFiles(data, "target-", withoutClientCertificate(targetConnStr.TLSConfig()))
+	data["SOURCE_DSN"] = []byte(toConnInfo(sourceConnStr, sourceLogin, source.Name, sourceFiles))
+	data["TARGET_DSN"] = []byte(toConnInfo(targetConnStr, targetLogin, database.Name, targetFiles))
+	if err := createOrUpdateJobSecret(ctx, r.Client, key, owners, data); err != nil {
+		logger.Error(err, "Unable to create clone Secret", "database", database.Name)
+		return err
+	}
+	job := newCloneJob(database, key, owners)
+	if err := r.Create(ctx, &job); err != nil {
+		logger.Error(err, "Unable to create clone Job", "database", database.Name)
+		return err
+	}
+	logger.Info("Created clone Job", "database", database.Name, "job", key.String())
+	return nil
+}
+
+// readCloneLogins returns the logins of the PgUsers, as which the clone Job dumps the source and restores the dump.
+// Both PgUsers have to be in the namespace of the database and on the instance of the database to which they connect.
+func (r *PgDatabaseReconciler) readCloneLogins(ctx context.Context, database *apiV1.PgDatabase, source *apiV1.PgDatabase) (pgapi.PgLogin, pgapi.PgLogin, error) {
+	spec := database.Spec.Source
+	if spec.Role == "" || spec.SourceRole == "" {
+		err := errors.New("A clone from another instance requires the PgUsers source.role and source.sourceRole")
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "RoleMissing", err.Error()); err != nil {
+			return pgapi.PgLogin{}, pgapi.PgLogin{}, err
+		}
+		return pgapi.PgLogin{}, pgapi.PgLogin{}, err
+	}
+	sourceLogin, err := r.readCloneLogin(ctx, database, spec.SourceRole, &source.Spec.Instance)
+	if err != nil {
+		return pgapi.PgLogin{}, pgapi.PgLogin{}, err
+	}
+	targetLogin, err := r.readCloneLogin(ctx, database, spec.Role, &database.Spec.Instance)
+	if err != nil {
+		return pgapi.PgLogin{}, pgapi.PgLogin{}, err
+	}
+	return sourceLogin, targetLogin, nil
+}
+
+// readCloneLogin returns the login of the PgUser with the given name in the namespace of the database,
+// which has to be a user of the given instance
+func (r *PgDatabaseReconciler) readCloneLogin(ctx context.Context, database *apiV1.PgDatabase, role string, instance *apiV1.PgInstanceRef) (pgapi.PgLogin, error) {
+	userId := types.NamespacedName{Namespace: database.Namespace, Name: role}
+	user, login, reason, err := readUserLogin(ctx, r, userId)
+	if err == nil && !isSameInstance(&user.Spec.Instance, instance) {
+		reason = "RoleNotAllowed"
+		err = errors.New("The PgUser " + userId.String() + " is not a user of the instance " + instance.ToNamespacedName().String())
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to read the credentials of the role", "database", database.Name, "role", role)
+		if reason != "" {
+			if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, reason, err.Error()); err != nil {
+				return pgapi.PgLogin{}, err
+			}
+		}
+		return pgapi.PgLogin{}, err
+	}
+	return login, nil
+}
+
+// failClone marks the clone as failed and returns the given error
+func (r *PgDatabaseReconciler) failClone(ctx context.Context, database *apiV1.PgDatabase, err error) error {
+	database.Status.Clone.Phase = apiV1.FailedJobPhase
+	if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "CloneFailed", err.Error()); err != nil {
+		return err
+	}
+	return err
+}
+
+// checkCloneSource returns an error if the source database does not exist on its instance
+func (r *PgDatabaseReconciler) checkCloneSource(ctx context.Context, sourceApi PgDatabaseAPI, database *apiV1.PgDatabase, source *apiV1.PgDatabase) error {
+	exists, err := sourceApi.IsDatabaseExisting(source.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err := errors.New("The source database " + source.Name + " does not exist on instance " + source.GetInstanceIdString())
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseCloneConditionType, false, "SourceMissing", err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+	return nil
+}
+
+// handleClone completes a clone, which was interrupted after the database was created,
+// and updates the clone status from the Job restoring the source database.
+// It returns true if the clone is still running.
+func (r *PgDatabaseReconciler) handleClone(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) (bool, error) {
+	clone := database.Status.Clone
+	if clone == nil || clone.Phase != apiV1.RunningJobPhase {
+		return false, nil
+	}
+
+	// The database was created from the template
+	if clone.Method == apiV1.TemplateCloneMethod {
+		clone.Phase = apiV1.SucceededJobPhase
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, true, "CloneSucceeded", "Cloned from "+clone.Source)
+		return false, r.Status().Update(ctx, database)
+	}
+
+	key := types.NamespacedName{Namespace: database.Namespace, Name: clone.Job}
+	job := batchV1.Job{}
+	exists, err := getResource(ctx, r, key, &job)
+	if err != nil {
+		return false, err
+	}
+	// The database was created, but the Job was not
+	if !exists && database.Spec.Source != nil {
+		source, err := r.getCloneSource(ctx, database)
+		if err != nil {
+			return false, err
+		}
+		sourceApi, err := r.createSourceApi(ctx, database, source)
+		if err != nil {
+			return false, err
+		}
+		return true, r.createCloneJob(ctx, pgApi, sourceApi, database, source, key)
+	}
+	if !exists {
+		clone.Phase = apiV1.FailedJobPhase
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, false, "CloneFailed", "The source was removed before the clone Job "+key.String()+" was created")
+		return false, r.Status().Update(ctx, database)
+	}
+
+	status := getJobStatus(&job)
+	switch status.Phase {
+	case apiV1.SucceededJobPhase:
+		clone.Phase = apiV1.SucceededJobPhase
+		clone.SnapshotTime = status.StartTime
+		if clone.SnapshotTime == nil {
+			clone.SnapshotTime = &job.CreationTimestamp
+		}
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, true, "CloneSucceeded", "Cloned from "+clone.Source)
+		return false, r.Status().Update(ctx, database)
+	case apiV1.FailedJobPhase:
+		clone.Phase = apiV1.FailedJobPhase
+		message := fmt.Sprintf("Clone Job %s failed: %s", key.String(), getJobFailure(&job))
+		putCondition(database, apiV1.PgDatabaseCloneConditionType, false, "CloneFailed", message)
+		return false, r.Status().Update(ctx, database)
+	}
+	return true, nil
+}
+
+// newCloneJob creates the Job which copies the source database into the database
+func newCloneJob(database *apiV1.PgDatabase, key types.NamespacedName, owners []metaV1.OwnerReference) batchV1.Job {
+	backoffLimit := int32(0)
+	file := cloneMountPath + "/clone.dump"
+	script := "set -e\n" +
+		"pg_dump --format=custom --file=" + file + " --dbname=\"$SOURCE_DSN\"\n" +
+		"pg_restore --no-owner --no-acl --dbname=\"$TARGET_DSN\" " + file + "\n"
+	command := []string{"/bin/sh", "-c", script}
+	podSpec := newJobPodSpec("pg-clone", database.Spec.Source.GetImage(), command, key.Name, "", cloneMountPath)
+	return batchV1.Job{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:       key.Namespace,
+			Name:            key.Name,
+			OwnerReferences: owners,
+		},
+		Spec: batchV1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     coreV1.PodTemplateSpec{Spec: podSpec},
+		},
+	}
+}
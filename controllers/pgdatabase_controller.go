@@ -20,8 +20,11 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"time"
+	"unicode"
 
+	batchV1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -29,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
 	"github.com/brose-ebike/postgres-operator/pkg/services"
 )
 
@@ -42,8 +46,12 @@ type PgDatabaseReconciler struct {
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases/finalizers,verbs=update
-//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -87,6 +95,16 @@ func (r *PgDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	// Add the finalizer before the database is created
+	if !controllerutil.ContainsFinalizer(&database, apiV1.DefaultFinalizerPgDatabase) {
+		controllerutil.AddFinalizer(&database, apiV1.DefaultFinalizerPgDatabase)
+		err = r.Update(ctx, &database)
+		if err != nil {
+			logger.Error(err, "Failed to update finalizers", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
+			return ctrl.Result{RequeueAfter: time.Second}, err
+		}
+	}
+
 	// Create Database if not exist
 	if err := r.createDatabaseIfNotExists(ctx, pgApi, &database); err != nil {
 		logger.Error(err, "Unable to create Database", "database", database.Name, "instance", database.GetInstanceIdString())
@@ -98,6 +116,34 @@ func (r *PgDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
+	// Wait for the clone of the database
+	if running, err := r.handleClone(ctx, pgApi, &database); err != nil {
+		logger.Error(err, "Unable to update clone status", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	} else if running {
+		logger.Info("Waiting for clone of database", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
+		return ctrl.Result{}, nil
+	}
+
+	// Update Database Options
+	allowsConnections, err := r.handleOptions(ctx, pgApi, &database)
+	if err != nil {
+		logger.Error(err, "Unable to update options", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update Database Settings
+	if err := r.handleSettings(ctx, pgApi, &database); err != nil {
+		logger.Error(err, "Unable to update settings", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// The objects in the database can only be managed while connections to the database are allowed
+	if !allowsConnections {
+		logger.Info("Skipped objects in database, because connections are not allowed", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
+		return ctrl.Result{}, nil
+	}
+
 	// Install Extensions if missing
 	if err := r.handleExtensions(ctx, pgApi, &database); err != nil {
 		logger.Error(err, "Unable to create extensions", "database", database.Name, "instance", database.GetInstanceIdString())
@@ -131,14 +177,10 @@ func (r *PgDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
-	// Check if finalizer exists
-	if !controllerutil.ContainsFinalizer(&database, apiV1.DefaultFinalizerPgDatabase) {
-		controllerutil.AddFinalizer(&database, apiV1.DefaultFinalizerPgDatabase)
-		err = r.Update(ctx, &database)
-		if err != nil {
-			logger.Error(err, "Failed to update finalizers", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
-			return ctrl.Result{RequeueAfter: time.Second}, err
-		}
+	// Disallow connections after the objects in the database were updated
+	if err := r.disallowConnections(ctx, pgApi, &database); err != nil {
+		logger.Error(err, "Unable to disallow connections", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
 	logger.Info("Processed database", "database", database.ToNamespacedName(), "instance", database.GetInstanceIdString())
@@ -155,6 +197,7 @@ func (r *PgDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&apiV1.PgDatabase{}).
+		Owns(&batchV1.Job{}).
 		Complete(r)
 }
 
@@ -162,18 +205,15 @@ func (r *PgDatabaseReconciler) createPgApi(ctx context.Context, database *apiV1.
 	logger := log.FromContext(ctx)
 
 	// Fetch Instance
-	instanceId := database.GetInstanceId()
-	var instance apiV1.PgInstance
-	exists, err := getResource(ctx, r, instanceId, &instance)
-	if !exists || err != nil {
-		logger.Error(err, "Unable to fetch PgInstance", "instance", instanceId.String())
+	instance, err := getInstance(ctx, r, r.Status(), database, database.Spec.Instance)
+	if err != nil {
 		return nil, err
 	}
 
 	// Connect to Instance
-	pgApi, err := r.PgDatabaseAPIFactory(ctx, r, &instance)
+	pgApi, err := r.PgDatabaseAPIFactory(ctx, r, instance)
 	if err != nil {
-		logger.Error(err, "Unable to connect", "instance", instanceId)
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
 		// Update connection status
 		if err := setCondition(ctx, r.Status(), database, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
 			logger.Error(err, "Unable to update condition", "database", database.ToNamespacedName())
@@ -194,19 +234,42 @@ func (r *PgDatabaseReconciler) finalize(ctx context.Context, database *apiV1.PgD
 	logger := log.FromContext(ctx)
 
 	if database.Spec.DeletionBehavior.Drop {
-		exists, err := pgApi.IsDatabaseExisting(database.Name)
+		managed, err := r.isDatabaseManaged(pgApi, database)
 		if err != nil {
 			logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
 			return err
 		}
-		if exists {
-			if err := pgApi.DeleteDatabase(database.Name); err != nil {
+		if managed {
+			deletion := database.Spec.DeletionBehavior
+			if deletion.Dump != nil {
+				dumped, err := r.dumpDatabase(ctx, pgApi, database)
+				if err != nil {
+					return err
+				}
+				if !dumped {
+					logger.Info("Waiting for dump before database is dropped", "database", database.Name)
+					return nil
+				}
+			}
+			deleteDatabase := pgApi.DeleteDatabase
+			if deletion.Force {
+				deleteDatabase = pgApi.ForceDeleteDatabase
+			}
+			if err := deleteDatabase(database.Name); err != nil {
 				logger.Error(err, "Unable to remove database", "database", database.Name, "instance", database.GetInstanceIdString())
+				if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseDeletionConditionType, false, "DropFailed", err.Error()); err != nil {
+					return err
+				}
+				return err
+			}
+			// Update Database Exists Condition
+			if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseExistsConditionType, false, "DatabaseMissing", "Database was deleted"); err != nil {
 				return err
 			}
 		}
-		// Update Database Exists Condition
-		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseExistsConditionType, false, "DatabaseMissing", "Database was deleted"); err != nil {
+	}
+	if !database.Spec.DeletionBehavior.Drop {
+		if err := r.releaseDatabase(ctx, pgApi, database); err != nil {
 			return err
 		}
 	}
@@ -236,6 +299,8 @@ func (r *PgDatabaseReconciler) finalize(ctx context.Context, database *apiV1.PgD
 func (r *PgDatabaseReconciler) createDatabaseIfNotExists(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) error {
 	logger := log.FromContext(ctx)
 	databaseName := database.Name
+	policy := database.Spec.GetAdoptionPolicy()
+	description := "Database " + databaseName
 
 	exists, err := pgApi.IsDatabaseExisting(databaseName)
 	if err != nil {
@@ -244,29 +309,326 @@ func (r *PgDatabaseReconciler) createDatabaseIfNotExists(ctx context.Context, pg
 	}
 
 	// create database
+	comment := ""
 	if !exists {
-		if err := pgApi.CreateDatabase(databaseName); err != nil {
-			logger.Error(err, "Unable to create database "+databaseName)
+		if reason, err := checkMissingOwnership(policy, description); err != nil {
+			return refuseOwnership(ctx, r.Status(), database, apiV1.PgDatabaseOwnershipConditionType, reason, err)
+		}
+		options := pgapi.PgDatabaseOptions{
+			Encoding:        database.Spec.Encoding,
+			LcCollate:       database.Spec.LcCollate,
+			LcCtype:         database.Spec.LcCtype,
+			IcuLocale:       database.Spec.IcuLocale,
+			Template:        database.Spec.Template,
+			Tablespace:      database.Spec.Tablespace,
+			ConnectionLimit: database.Spec.ConnectionLimit,
+			Owner:           database.Spec.Owner,
+			// Connections are disallowed after the objects in the database were created
+		}
+		if database.Spec.Source != nil {
+			if err := r.cloneDatabase(ctx, pgApi, database, options); err != nil {
+				logger.Error(err, "Unable to clone database "+databaseName)
+				return err
+			}
+		} else {
+			if err := pgApi.CreateDatabaseWithOptions(databaseName, options); err != nil {
+				logger.Error(err, "Unable to create database "+databaseName)
+				return err
+			}
+			logger.Info("Created database " + databaseName)
+		}
+	} else {
+		comment, err = pgApi.GetDatabaseComment(databaseName)
+		if err != nil {
+			logger.Error(err, "Unable to query comment of database "+databaseName)
+			return err
+		}
+		adopt, reason, err := checkOwnership(database, policy, comment, description, apiV1.PgDatabaseExistsConditionType)
+		if err != nil {
+			logger.Error(err, "Refused to manage database "+databaseName)
+			return refuseOwnership(ctx, r.Status(), database, apiV1.PgDatabaseOwnershipConditionType, reason, err)
+		}
+		if !adopt {
+			return acceptOwnership(ctx, r.Status(), database, apiV1.PgDatabaseOwnershipConditionType)
+		}
+		logger.Info("Adopting existing database " + databaseName)
+	}
+
+	// Mark database as managed by this resource and keep the existing comment
+	if err := pgApi.UpdateDatabaseComment(databaseName, apiV1.SetOwnershipMarker(comment, database.UID)); err != nil {
+		logger.Error(err, "Unable to mark database "+databaseName)
+		return err
+	}
+	return acceptOwnership(ctx, r.Status(), database, apiV1.PgDatabaseOwnershipConditionType)
+}
+
+// isDatabaseManaged returns true if the database exists and is marked as managed by this resource.
+// The finalizer is added before the ownership is checked, so a database managed by another resource
+// or refused by the adoption policy must not be dropped.
+func (r *PgDatabaseReconciler) isDatabaseManaged(pgApi PgDatabaseAPI, database *apiV1.PgDatabase) (bool, error) {
+	exists, err := pgApi.IsDatabaseExisting(database.Name)
+	if err != nil || !exists {
+		return false, err
+	}
+	comment, err := pgApi.GetDatabaseComment(database.Name)
+	if err != nil {
+		return false, err
+	}
+	return apiV1.ParseOwnershipMarker(comment) == database.UID, nil
+}
+
+// releaseDatabase removes the ownership marker of this resource from the database,
+// which allows other resources to adopt the retained database
+func (r *PgDatabaseReconciler) releaseDatabase(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) error {
+	logger := log.FromContext(ctx)
+	exists, err := pgApi.IsDatabaseExisting(database.Name)
+	if err != nil || !exists {
+		return err
+	}
+	comment, err := pgApi.GetDatabaseComment(database.Name)
+	if err != nil {
+		return err
+	}
+	if apiV1.ParseOwnershipMarker(comment) != database.UID {
+		return nil
+	}
+	if err := pgApi.UpdateDatabaseComment(database.Name, apiV1.SetOwnershipMarker(comment, "")); err != nil {
+		logger.Error(err, "Unable to release database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return err
+	}
+	return nil
+}
+
+// handleSettings sets the configuration settings of the database and resets the settings which were removed
+func (r *PgDatabaseReconciler) handleSettings(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) error {
+	// Settings are not managed for this database
+	if len(database.Spec.Settings) == 0 && len(database.Status.Settings) == 0 {
+		return nil
+	}
+
+	names, err := updateSettings(ctx, pgApi, database.Name, "", database.Spec.Settings, database.Status.Settings)
+	if err != nil {
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseSettingsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+
+	// Persist the names of the settings
+	if !equalElements(database.Status.Settings, names) {
+		database.Status.Settings = names
+		if err := r.Status().Update(ctx, database); err != nil {
 			return err
 		}
-		logger.Info("Created database " + databaseName)
+	}
+	return setCondition(ctx, r.Status(), database, apiV1.PgDatabaseSettingsConditionType, true, "SettingsApplied", "-")
+}
+
+// handleOptions updates the owner and the mutable options of the database and compares the immutable options.
+// It returns false if connections to the database are not allowed.
+func (r *PgDatabaseReconciler) handleOptions(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) (bool, error) {
+	logger := log.FromContext(ctx)
+	// Options are not managed for this database
+	if !database.Spec.HasOptions() {
+		return true, nil
+	}
+
+	// Update owner, the PgDatabase takes precedence over PgUsers which own the database
+	if database.Spec.Owner != "" {
+		currentOwner, err := pgApi.GetDatabaseOwner(database.Name)
+		if err != nil {
+			return false, err
+		}
+		if currentOwner != database.Spec.Owner {
+			if err := pgApi.UpdateDatabaseOwner(database.Name, database.Spec.Owner); err != nil {
+				logger.Error(err, "Unable to update database owner", "database", database.Name)
+				if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseOptionsConditionType, false, "OwnerUpdateFailed", err.Error()); err != nil {
+					return false, err
+				}
+				return false, err
+			}
+		}
+	}
+
+	// Update mutable options, connections are only disallowed after the objects in the database were updated
+	options := pgapi.PgDatabaseOptions{
+		ConnectionLimit: database.Spec.ConnectionLimit,
+	}
+	if database.Spec.AllowConnections != nil && *database.Spec.AllowConnections {
+		options.AllowConnections = database.Spec.AllowConnections
+	}
+	if err := pgApi.UpdateDatabaseOptions(database.Name, options); err != nil {
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseOptionsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	// Compare immutable options, mismatches cannot be fixed by the operator
+	current, err := pgApi.GetDatabaseOptions(database.Name)
+	if err != nil {
+		return false, err
+	}
+	allowsConnections := current.AllowConnections == nil || *current.AllowConnections
+	// The encoding has various aliases, e.g. UTF-8 and UTF8
+	encoding := database.Spec.Encoding
+	if encoding != "" {
+		name, err := pgApi.GetEncodingName(encoding)
+		if err != nil {
+			return false, err
+		}
+		if name != "" {
+			encoding = name
+		}
+	}
+	mismatches := make([]string, 0)
+	compare := func(name string, desired string, actual string) {
+		if desired != "" && !strings.EqualFold(desired, actual) {
+			mismatches = append(mismatches, name+" is '"+actual+"' instead of '"+desired+"'")
+		}
+	}
+	compareLocale := func(name string, desired string, actual string) {
+		if desired != "" && normalizeLocale(desired) != normalizeLocale(actual) {
+			mismatches = append(mismatches, name+" is '"+actual+"' instead of '"+desired+"'")
+		}
+	}
+	compare("encoding", encoding, current.Encoding)
+	compareLocale("lcCollate", database.Spec.LcCollate, current.LcCollate)
+	compareLocale("lcCtype", database.Spec.LcCtype, current.LcCtype)
+	compareLocale("icuLocale", database.Spec.IcuLocale, current.IcuLocale)
+	compare("tablespace", database.Spec.Tablespace, current.Tablespace)
+	if len(mismatches) > 0 {
+		message := "Immutable options differ: " + strings.Join(mismatches, ", ")
+		logger.Info(message, "database", database.ToNamespacedName())
+		return allowsConnections, setCondition(ctx, r.Status(), database, apiV1.PgDatabaseOptionsConditionType, false, "ImmutableOptionMismatch", message)
+	}
+	return allowsConnections, setCondition(ctx, r.Status(), database, apiV1.PgDatabaseOptionsConditionType, true, "OptionsApplied", "-")
+}
+
+// disallowConnections disallows connections to the database, if it is configured
+func (r *PgDatabaseReconciler) disallowConnections(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) error {
+	if database.Spec.AllowConnections == nil || *database.Spec.AllowConnections {
+		return nil
+	}
+	options := pgapi.PgDatabaseOptions{AllowConnections: database.Spec.AllowConnections}
+	if err := pgApi.UpdateDatabaseOptions(database.Name, options); err != nil {
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseOptionsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+			return err
+		}
+		return err
 	}
 	return nil
 }
 
+// normalizeLocale returns the given locale name with a normalized codeset, e.g. en_US.utf8 for en_US.UTF-8,
+// because the C library accepts both spellings for the same locale
+func normalizeLocale(locale string) string {
+	name, codeset, found := strings.Cut(locale, ".")
+	if !found {
+		return strings.ToLower(locale)
+	}
+	codeset, modifier, _ := strings.Cut(codeset, "@")
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, codeset)
+	if modifier != "" {
+		normalized += "@" + modifier
+	}
+	return strings.ToLower(name + "." + normalized)
+}
+
 func (r *PgDatabaseReconciler) handleExtensions(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) error {
+	logger := log.FromContext(ctx)
+	failed := func(reason string, err error) error {
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseExtensionsConditionType, false, reason, err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+
+	created := make(map[string]bool)
+	for _, previous := range database.Status.Extensions {
+		created[previous.Name] = previous.Created
+	}
+	desired := make([]string, 0)
+	statuses := make([]apiV1.PgDatabaseExtensionStatus, 0)
 	for _, extension := range database.Spec.Extensions {
-		exists, err := pgApi.IsDatabaseExtensionPresent(database.Name, extension)
+		desired = append(desired, extension.Name)
+		installedVersion, err := pgApi.GetDatabaseExtensionVersion(database.Name, extension.Name)
 		if err != nil {
 			return err
 		}
-		if exists {
+		// Drop extensions which should not exist
+		if extension.IsAbsent() {
+			if installedVersion == "" {
+				continue
+			}
+			if err := pgApi.DeleteDatabaseExtension(database.Name, extension.Name, extension.Cascade); err != nil {
+				logger.Error(err, "Unable to drop extension "+extension.Name+" in database "+database.Name)
+				return failed("ExtensionFailed", err)
+			}
 			continue
 		}
-		if err := pgApi.CreateDatabaseExtension(database.Name, extension); err != nil {
-			reason := "MissingExtension-" + extension
-			message := "The database extension " + extension + " cannot be created\n" + err.Error()
-			setCondition(ctx, r.Status(), database, apiV1.PgDatabaseExtensionsConditionType, false, reason, message)
+		// Check if the desired version can be installed
+		if installedVersion == "" || (extension.Version != "" && extension.Version != installedVersion) {
+			available, err := pgApi.IsDatabaseExtensionAvailable(database.Name, extension.Name, extension.Version)
+			if err != nil {
+				return err
+			}
+			if !available {
+				err := errors.New("The extension " + extension.Name + " is not available in version '" + extension.Version + "' on the instance")
+				return failed("ExtensionNotAvailable", err)
+			}
+		}
+		// Create or update the extension
+		if installedVersion == "" {
+			err = pgApi.CreateDatabaseExtensionWithOptions(database.Name, extension.Name, extension.Version, extension.Schema, extension.Cascade)
+			created[extension.Name] = err == nil
+		} else if extension.Version != "" && extension.Version != installedVersion {
+			err = pgApi.UpdateDatabaseExtension(database.Name, extension.Name, extension.Version)
+		}
+		if err != nil {
+			logger.Error(err, "Unable to apply extension "+extension.Name+" in database "+database.Name)
+			return failed("ExtensionFailed", err)
+		}
+		installedVersion, err = pgApi.GetDatabaseExtensionVersion(database.Name, extension.Name)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, apiV1.PgDatabaseExtensionStatus{
+			Name:             extension.Name,
+			DesiredVersion:   extension.Version,
+			InstalledVersion: installedVersion,
+			Created:          created[extension.Name],
+		})
+	}
+
+	// Drop extensions which were created by the operator, but were removed from the resource,
+	// extensions which existed before are kept
+	for _, previous := range database.Status.Extensions {
+		if hasElement(desired, previous.Name) || !previous.Created {
+			continue
+		}
+		installedVersion, err := pgApi.GetDatabaseExtensionVersion(database.Name, previous.Name)
+		if err != nil {
+			return err
+		}
+		if installedVersion == "" {
+			continue
+		}
+		if err := pgApi.DeleteDatabaseExtension(database.Name, previous.Name, false); err != nil {
+			logger.Error(err, "Unable to drop extension "+previous.Name+" in database "+database.Name)
+			return failed("ExtensionFailed", err)
+		}
+	}
+
+	// Persist the observed extensions
+	if !reflect.DeepEqual(database.Status.Extensions, statuses) && (len(database.Status.Extensions) > 0 || len(statuses) > 0) {
+		database.Status.Extensions = statuses
+		if err := r.Status().Update(ctx, database); err != nil {
 			return err
 		}
 	}
@@ -19,12 +19,16 @@ package controllers
 import (
 	"context"
 	"errors"
+	"strings"
 
 	kErrors "k8s.io/apimachinery/pkg/api/errors"
 
 	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,11 +41,49 @@ type dummyDB struct {
 	schemas map[string]string
 }
 
+// pgSettingsMock stores the configuration settings by database and role
+type pgSettingsMock struct {
+	settings           map[string]map[string]string
+	callsUpdateSetting int
+	callsResetSetting  int
+}
+
+func (m *pgSettingsMock) GetSettings(databaseName string, roleName string) (map[string]string, error) {
+	settings := make(map[string]string)
+	for name, value := range m.settings[databaseName+"/"+roleName] {
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+func (m *pgSettingsMock) UpdateSetting(databaseName string, roleName string, name string, value string) error {
+	m.callsUpdateSetting += 1
+	if m.settings == nil {
+		m.settings = make(map[string]map[string]string)
+	}
+	key := databaseName + "/" + roleName
+	if m.settings[key] == nil {
+		m.settings[key] = make(map[string]string)
+	}
+	m.settings[key][name] = value
+	return nil
+}
+
+func (m *pgSettingsMock) ResetSetting(databaseName string, roleName string, name string) error {
+	m.callsResetSetting += 1
+	delete(m.settings[databaseName+"/"+roleName], name)
+	return nil
+}
+
 type pgDatabaseMock struct {
+	pgConnectorMock
+	pgSettingsMock
 	databases                         map[string]dummyDB
 	callsIsDatabaseExisting           int
 	callsCreateDatabase               int
 	callsDeleteDatabase               int
+	callsForceDeleteDatabase          int
+	callsTerminateDatabaseSessions    int
 	callsGetDatabaseOwner             int
 	callsUpdateDatabaseOwner          int
 	callsResetDatabaseOwner           int
@@ -49,6 +91,8 @@ type pgDatabaseMock struct {
 	callsIsSchemaInDatabase           int
 	callsCreateSchema                 int
 	callsDeleteSchema                 int
+	callsDeleteSchemaCascade          int
+	callsUpdateSchemaOwner            int
 	callsUpdateDefaultPrivileges      int
 	callsDeleteAllPrivilegesOnSchema  int
 	callsIsDatabaseExtensionPresent   int
@@ -58,6 +102,17 @@ type pgDatabaseMock struct {
 	callsMakeSchemaUseable            int
 	callsUpdateSchemaPrivileges       int
 	callsGetSchemaOwner               int
+	callsUpdateTablePrivileges        int
+	callsGetDatabaseOptions           int
+	callsUpdateDatabaseOptions        int
+	callsCreateExtensionWithOptions   int
+	callsUpdateDatabaseExtension      int
+	callsDeleteDatabaseExtension      int
+	options                           map[string]pgapi.PgDatabaseOptions
+	extensions                        map[string]string
+	unavailableExtensions             []string
+	comments                          map[string]string
+	sessions                          map[string]int
 }
 
 func (m *pgDatabaseMock) IsDatabaseExisting(databaseName string) (bool, error) {
@@ -77,12 +132,67 @@ func (m *pgDatabaseMock) CreateDatabase(databaseName string) error {
 	return nil
 }
 
+func (m *pgDatabaseMock) CreateDatabaseWithOptions(databaseName string, options pgapi.PgDatabaseOptions) error {
+	if err := m.CreateDatabase(databaseName); err != nil {
+		return err
+	}
+	if m.options == nil {
+		m.options = make(map[string]pgapi.PgDatabaseOptions)
+	}
+	m.options[databaseName] = options
+	if options.Owner != "" {
+		m.databases[databaseName] = dummyDB{owner: options.Owner}
+	}
+	return nil
+}
+
+func (m *pgDatabaseMock) GetEncodingName(encoding string) (string, error) {
+	return strings.ToUpper(strings.ReplaceAll(encoding, "-", "")), nil
+}
+
+func (m *pgDatabaseMock) GetDatabaseOptions(databaseName string) (pgapi.PgDatabaseOptions, error) {
+	m.callsGetDatabaseOptions += 1
+	return m.options[databaseName], nil
+}
+
+func (m *pgDatabaseMock) UpdateDatabaseOptions(databaseName string, options pgapi.PgDatabaseOptions) error {
+	m.callsUpdateDatabaseOptions += 1
+	if m.options == nil {
+		m.options = make(map[string]pgapi.PgDatabaseOptions)
+	}
+	current := m.options[databaseName]
+	if options.ConnectionLimit != nil {
+		current.ConnectionLimit = options.ConnectionLimit
+	}
+	if options.AllowConnections != nil {
+		current.AllowConnections = options.AllowConnections
+	}
+	m.options[databaseName] = current
+	return nil
+}
+
 func (m *pgDatabaseMock) DeleteDatabase(databaseName string) error {
 	m.callsDeleteDatabase += 1
 	delete(m.databases, databaseName)
 	return nil
 }
 
+func (m *pgDatabaseMock) ForceDeleteDatabase(databaseName string) error {
+	m.callsForceDeleteDatabase += 1
+	delete(m.databases, databaseName)
+	return nil
+}
+
+func (m *pgDatabaseMock) GetDatabaseSessionCount(databaseName string) (int, error) {
+	return m.sessions[databaseName], nil
+}
+
+func (m *pgDatabaseMock) TerminateDatabaseSessions(databaseName string) error {
+	m.callsTerminateDatabaseSessions += 1
+	delete(m.sessions, databaseName)
+	return nil
+}
+
 func (m *pgDatabaseMock) GetDatabaseOwner(databaseName string) (string, error) {
 	m.callsGetDatabaseOwner += 1
 	value, exists := m.databases[databaseName]
@@ -92,6 +202,18 @@ func (m *pgDatabaseMock) GetDatabaseOwner(databaseName string) (string, error) {
 	return value.owner, nil
 }
 
+func (m *pgDatabaseMock) GetDatabaseComment(databaseName string) (string, error) {
+	return m.comments[databaseName], nil
+}
+
+func (m *pgDatabaseMock) UpdateDatabaseComment(databaseName string, comment string) error {
+	if m.comments == nil {
+		m.comments = map[string]string{}
+	}
+	m.comments[databaseName] = comment
+	return nil
+}
+
 func (m *pgDatabaseMock) UpdateDatabaseOwner(databaseName string, roleName string) error {
 	m.callsUpdateDatabaseOwner += 1
 	value, exists := m.databases[databaseName]
@@ -148,6 +270,24 @@ func (m *pgDatabaseMock) DeleteSchema(databaseName string, schemaName string) er
 	return nil
 }
 
+func (m *pgDatabaseMock) DeleteSchemaCascade(databaseName string, schemaName string) error {
+	m.callsDeleteSchemaCascade += 1
+	_, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	return nil
+}
+
+func (m *pgDatabaseMock) UpdateSchemaOwner(databaseName string, schemaName string, roleName string) error {
+	m.callsUpdateSchemaOwner += 1
+	_, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	return nil
+}
+
 func (m *pgDatabaseMock) UpdateDefaultPrivileges(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
 	m.callsUpdateDefaultPrivileges += 1
 	_, exists := m.databases[databaseName]
@@ -176,6 +316,38 @@ func (m *pgDatabaseMock) CreateDatabaseExtension(databaseName string, extension
 	return nil
 }
 
+func (m *pgDatabaseMock) GetDatabaseExtensionVersion(databaseName string, extension string) (string, error) {
+	return m.extensions[databaseName+"/"+extension], nil
+}
+
+func (m *pgDatabaseMock) IsDatabaseExtensionAvailable(databaseName string, extension string, version string) (bool, error) {
+	return !hasElement(m.unavailableExtensions, extension), nil
+}
+
+func (m *pgDatabaseMock) CreateDatabaseExtensionWithOptions(databaseName string, extension string, version string, schema string, cascade bool) error {
+	m.callsCreateExtensionWithOptions += 1
+	if m.extensions == nil {
+		m.extensions = make(map[string]string)
+	}
+	if version == "" {
+		version = "1.0"
+	}
+	m.extensions[databaseName+"/"+extension] = version
+	return nil
+}
+
+func (m *pgDatabaseMock) UpdateDatabaseExtension(databaseName string, extension string, version string) error {
+	m.callsUpdateDatabaseExtension += 1
+	m.extensions[databaseName+"/"+extension] = version
+	return nil
+}
+
+func (m *pgDatabaseMock) DeleteDatabaseExtension(databaseName string, extension string, cascade bool) error {
+	m.callsDeleteDatabaseExtension += 1
+	delete(m.extensions, databaseName+"/"+extension)
+	return nil
+}
+
 func (m *pgDatabaseMock) UpdatePrivilegesOnAllObjects(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
 	m.callsUpdatePrivilegesOnAllObjects += 1
 	return nil
@@ -196,6 +368,11 @@ func (m *pgDatabaseMock) UpdateSchemaPrivileges(databaseName string, schemaName
 	return nil
 }
 
+func (m *pgDatabaseMock) UpdateTablePrivileges(databaseName string, schemaName string, tableName string, roleName string, privileges []string) error {
+	m.callsUpdateTablePrivileges += 1
+	return nil
+}
+
 func (m *pgDatabaseMock) GetSchemaOwner(databaseName string, schemaName string) (string, error) {
 	m.callsGetSchemaOwner += 1
 	return "", nil
@@ -266,7 +443,7 @@ var _ = Describe("PgInstanceReconciler", func() {
 						Name:      "instance",
 					},
 					DefaultPrivileges: []apiV1.PgDatabaseDefaultPrivileges{},
-					Extensions:        []string{},
+					Extensions:        []apiV1.PgDatabaseExtension{},
 					DeletionBehavior: apiV1.PgDatabaseDeletion{
 						Drop: false,
 						Wait: false,
@@ -335,16 +512,27 @@ var _ = Describe("PgInstanceReconciler", func() {
 		Expect(mock.callsCreateDatabase).To(Equal(1))
 	})
 
-	It("reconciles on delete of PgDatabase", func() {
+	It("reconciles options of PgDatabase", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		// given
 		request := reconcile.Request{
 			NamespacedName: types.NamespacedName{
 				Namespace: "default",
-				Name:      "missing",
+				Name:      "dummy",
 			},
 		}
+		connectionLimit := 20
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Owner = "service_user"
+		database.Spec.Encoding = "UTF8"
+		database.Spec.Template = "template0"
+		database.Spec.ConnectionLimit = &connectionLimit
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
 		// when
 		result, err := reconciler.Reconcile(ctx, request)
 
@@ -353,10 +541,22 @@ var _ = Describe("PgInstanceReconciler", func() {
 		Expect(result.RequeueAfter).To(BeZero())
 
 		// and
-		Expect(nil).To(BeNil())
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		optionsCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOptionsConditionType)
+		Expect(optionsCondition.Status).To(Equal(v1.ConditionTrue))
+
+		// and
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.callsUpdateDatabaseOwner).To(BeZero())
+		Expect(mock.databases["dummy"].owner).To(Equal("service_user"))
+		Expect(mock.options["dummy"].Encoding).To(Equal("UTF8"))
+		Expect(mock.options["dummy"].Template).To(Equal("template0"))
+		Expect(*mock.options["dummy"].ConnectionLimit).To(Equal(20))
 	})
 
-	It("reconciles on finalize of PgDatabase", func() {
+	It("reports mismatches of immutable options", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		// given
@@ -366,26 +566,907 @@ var _ = Describe("PgInstanceReconciler", func() {
 				Name:      "dummy",
 			},
 		}
-		_, err := reconciler.Reconcile(ctx, request)
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["dummy"] = dummyDB{owner: "pgadmin"}
+		mock.options = map[string]pgapi.PgDatabaseOptions{
+			"dummy": {Encoding: "SQL_ASCII"},
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		database.Spec.Encoding = "UTF8"
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(mock.callsCreateDatabase).To(BeZero())
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		optionsCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOptionsConditionType)
+		Expect(optionsCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(optionsCondition.Reason).To(Equal("ImmutableOptionMismatch"))
+	})
+
+	It("accepts aliases of immutable options", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["dummy"] = dummyDB{owner: "pgadmin"}
+		mock.options = map[string]pgapi.PgDatabaseOptions{
+			"dummy": {Encoding: "UTF8", LcCollate: "en_US.utf8", LcCtype: "en_US.utf8"},
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		database.Spec.Encoding = "utf-8"
+		database.Spec.LcCollate = "en_US.UTF-8"
+		database.Spec.LcCtype = "en_US.UTF-8"
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
 		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
 
 		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		optionsCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOptionsConditionType)
+		Expect(optionsCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("disallows connections after the objects of PgDatabase were updated", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		allowConnections := false
 		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.AllowConnections = &allowConnections
+		database.Spec.Extensions = []apiV1.PgDatabaseExtension{{Name: "uuid-ossp"}}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.callsCreateExtensionWithOptions).To(Equal(1))
+		Expect(*mock.options["dummy"].AllowConnections).To(BeFalse())
+
+		// when
+		database = apiV1.PgDatabase{}
 		err = k8sClient.Get(ctx, request.NamespacedName, &database)
 		Expect(err).To(BeNil())
-		err = k8sClient.Delete(ctx, &database)
+		database.Spec.Extensions = append(database.Spec.Extensions, apiV1.PgDatabaseExtension{Name: "pg_trgm"})
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then the objects in the database are skipped
+		Expect(err).To(BeNil())
+		Expect(mock.callsCreateExtensionWithOptions).To(Equal(1))
+		Expect(*mock.options["dummy"].AllowConnections).To(BeFalse())
+	})
+
+	It("reconciles settings of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		// a manually set setting is not touched
+		mock.settings = map[string]map[string]string{"dummy/": {"work_mem": "4MB"}}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Settings = map[string]string{"statement_timeout": "30s", "search_path": "app, public"}
+		err = k8sClient.Update(ctx, &database)
 		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		Expect(mock.settings["dummy/"]).To(HaveLen(3))
 
 		// when
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Settings = map[string]string{"search_path": "app"}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
 		result, err := reconciler.Reconcile(ctx, request)
 
 		// then
 		Expect(err).To(BeNil())
 		Expect(result.RequeueAfter).To(BeZero())
+		Expect(mock.settings["dummy/"]).To(Equal(map[string]string{"work_mem": "4MB", "search_path": "app"}))
+		Expect(mock.callsResetSetting).To(Equal(1))
 
 		// and
 		database = apiV1.PgDatabase{}
 		err = k8sClient.Get(ctx, request.NamespacedName, &database)
-		Expect(kErrors.IsNotFound(err)).To(BeTrue())
+		Expect(err).To(BeNil())
+		Expect(database.Status.Settings).To(Equal([]string{"search_path"}))
+		settingsCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseSettingsConditionType)
+		Expect(settingsCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("reconciles extensions of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.extensions = map[string]string{
+			"dummy/pg_trgm": "1.5",
+			"dummy/hstore":  "1.8",
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Extensions = []apiV1.PgDatabaseExtension{
+			{Name: "uuid-ossp"},
+			{Name: "pg_trgm", Version: "1.6"},
+			{Name: "hstore", State: apiV1.AbsentExtensionState},
+		}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(mock.callsCreateExtensionWithOptions).To(Equal(1))
+		Expect(mock.callsUpdateDatabaseExtension).To(Equal(1))
+		Expect(mock.callsDeleteDatabaseExtension).To(Equal(1))
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		extensionCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseExtensionsConditionType)
+		Expect(extensionCondition.Status).To(Equal(v1.ConditionTrue))
+		Expect(database.Status.Extensions).To(ConsistOf(
+			apiV1.PgDatabaseExtensionStatus{Name: "uuid-ossp", InstalledVersion: "1.0", Created: true},
+			apiV1.PgDatabaseExtensionStatus{Name: "pg_trgm", DesiredVersion: "1.6", InstalledVersion: "1.6"},
+		))
+
+		// when
+		database.Spec.Extensions = nil
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsDeleteDatabaseExtension).To(Equal(2))
+		Expect(mock.extensions).NotTo(HaveKey("dummy/uuid-ossp"))
+		Expect(mock.extensions).To(HaveKeyWithValue("dummy/pg_trgm", "1.6"))
+	})
+
+	It("reports unavailable extensions of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.unavailableExtensions = []string{"postgis"}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Extensions = []apiV1.PgDatabaseExtension{{Name: "postgis"}}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(mock.callsCreateExtensionWithOptions).To(BeZero())
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		extensionCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseExtensionsConditionType)
+		Expect(extensionCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(extensionCondition.Reason).To(Equal("ExtensionNotAvailable"))
+	})
+
+	It("adopts an existing database of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["dummy"] = dummyDB{}
+		mock.comments = map[string]string{"dummy": "Shared database"}
+
+		// and
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsCreateDatabase).To(BeZero())
+		Expect(mock.comments["dummy"]).To(Equal("Shared database\n" + apiV1.NewOwnershipMarker(database.UID)))
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOwnershipConditionType)).To(BeNil())
+	})
+
+	It("refuses a database managed by another PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["dummy"] = dummyDB{}
+		mock.comments = map[string]string{"dummy": apiV1.NewOwnershipMarker("other-uid")}
+
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(mock.comments["dummy"]).To(Equal(apiV1.NewOwnershipMarker("other-uid")))
+
+		// and
+		database := apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("ManagedByOtherResource"))
+	})
+
+	It("refuses an unmanaged database by default", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["dummy"] = dummyDB{}
+
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(mock.comments["dummy"]).To(BeEmpty())
+
+		// and
+		database := apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("ExistsUnmanaged"))
+	})
+
+	It("does not create a missing database with adoption policy Adopt", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.AdoptionPolicy = apiV1.AdoptAdoptionPolicy
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.callsCreateDatabase).To(BeZero())
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("NotExisting"))
+	})
+
+	It("reconciles on delete of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "missing",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		Expect(nil).To(BeNil())
+	})
+
+	It("reconciles on finalize of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		database := apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		database = apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(kErrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("force drops the database on finalize of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.DeletionBehavior = apiV1.PgDatabaseDeletion{Drop: true, Force: true}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.callsForceDeleteDatabase).To(Equal(1))
+		Expect(mock.callsDeleteDatabase).To(BeZero())
+	})
+
+	It("dumps the database before it is dropped on finalize of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "dummy-owner"},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "instance"},
+				Secret:   &apiV1.PgUserSecret{Name: "dummy-owner-credentials"},
+			},
+		}
+		err = k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+		secret := coreV1.Secret{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "dummy-owner-credentials"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}
+		err = k8sClient.Create(ctx, &secret)
+		Expect(err).To(BeNil())
+		database.Spec.DeletionBehavior = apiV1.PgDatabaseDeletion{
+			Drop: true,
+			Dump: &apiV1.PgDatabaseDump{Role: "dummy-owner", PersistentVolumeClaim: "dumps"},
+		}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.callsDeleteDatabase).To(BeZero())
+		jobKey := types.NamespacedName{Namespace: "default", Name: "dummy-dump"}
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).To(BeNil())
+		Expect(job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("dumps"))
+		dumpSecret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, jobKey, &dumpSecret)
+		Expect(err).To(BeNil())
+		Expect(string(dumpSecret.Data["PGUSER"])).To(Equal("dummy-owner"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseDeletionConditionType)
+		Expect(condition.Reason).To(Equal("DumpRunning"))
+
+		// when
+		job.Status.Succeeded = 1
+		err = k8sClient.Status().Update(ctx, &job)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsDeleteDatabase).To(Equal(1))
+
+		// cleanup
+		err = k8sClient.Delete(ctx, &job, client.PropagationPolicy(v1.DeletePropagationBackground))
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &coreV1.Secret{ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "dummy-dump"}})
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &secret)
+		Expect(err).To(BeNil())
+	})
+
+	It("clones a PgDatabase from a source on the same instance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "default", Name: "dummy"},
+		})
+		Expect(err).To(BeNil())
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy-copy",
+			},
+		}
+		database := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "dummy-copy",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Source: &apiV1.PgDatabaseSource{
+					Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "dummy"},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		mock := pgApiMock.(*pgDatabaseMock)
+		Expect(mock.options["dummy-copy"].Template).To(Equal("dummy"))
+
+		// and
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		Expect(database.Status.Clone).NotTo(BeNil())
+		Expect(database.Status.Clone.Method).To(Equal(apiV1.TemplateCloneMethod))
+		Expect(database.Status.Clone.Phase).To(Equal(apiV1.SucceededJobPhase))
+		Expect(database.Status.Clone.SnapshotTime).NotTo(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("clones a PgDatabase from a source on another instance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		instance := apiV1.PgInstance{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "other",
+			},
+			Spec: apiV1.PgInstanceSpec{
+				Hostname: apiV1.PgProperty{Value: "remote"},
+				Port:     apiV1.PgProperty{Value: "5432"},
+				Username: apiV1.PgProperty{Value: "admin"},
+				Password: apiV1.PgProperty{Value: "password"},
+			},
+		}
+		err := k8sClient.Create(ctx, &instance)
+		Expect(err).To(BeNil())
+		source := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "remote",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "other",
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, &source)
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["remote"] = dummyDB{owner: "pgadmin"}
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Source = &apiV1.PgDatabaseSource{
+			Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "remote"},
+		}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(mock.databases).NotTo(HaveKey("dummy"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Reason).To(Equal("RoleMissing"))
+
+		// given
+		for _, user := range []apiV1.PgUser{
+			{
+				ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "remote-reader"},
+				Spec: apiV1.PgUserSpec{
+					Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "other"},
+					Secret:   &apiV1.PgUserSecret{Name: "remote-reader-credentials"},
+				},
+			},
+			{
+				ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "dummy-owner"},
+				Spec: apiV1.PgUserSpec{
+					Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "instance"},
+					Secret:   &apiV1.PgUserSecret{Name: "dummy-owner-credentials"},
+				},
+			},
+		} {
+			user := user
+			err = k8sClient.Create(ctx, &user)
+			Expect(err).To(BeNil())
+			secret := coreV1.Secret{
+				ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: user.Spec.Secret.Name},
+				Data:       map[string][]byte{"password": []byte("secret")},
+			}
+			err = k8sClient.Create(ctx, &secret)
+			Expect(err).To(BeNil())
+		}
+		database.Spec.Source.Role = "remote-reader"
+		database.Spec.Source.SourceRole = "dummy-owner"
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(mock.databases).NotTo(HaveKey("dummy"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		condition = meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Reason).To(Equal("RoleNotAllowed"))
+
+		// given
+		database.Spec.Source.Role = "dummy-owner"
+		database.Spec.Source.SourceRole = "remote-reader"
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		jobKey := types.NamespacedName{Namespace: "default", Name: "dummy-clone"}
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).To(BeNil())
+		Expect(job.Spec.Template.Spec.Volumes[0].EmptyDir).NotTo(BeNil())
+		cloneSecret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, jobKey, &cloneSecret)
+		Expect(err).To(BeNil())
+		Expect(string(cloneSecret.Data["SOURCE_DSN"])).To(ContainSubstring("user='remote-reader'"))
+		Expect(string(cloneSecret.Data["TARGET_DSN"])).To(ContainSubstring("user='dummy-owner'"))
+		Expect(string(cloneSecret.Data["SOURCE_DSN"])).NotTo(ContainSubstring("admin"))
+		Expect(string(cloneSecret.Data["TARGET_DSN"])).NotTo(ContainSubstring("admin"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		Expect(database.Status.Clone.Method).To(Equal(apiV1.DumpRestoreCloneMethod))
+		Expect(database.Status.Clone.Phase).To(Equal(apiV1.RunningJobPhase))
+		condition = meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Reason).To(Equal("CloneRunning"))
+		Expect(database.Finalizers).To(HaveLen(1))
+
+		// when
+		err = k8sClient.Delete(ctx, &job, client.PropagationPolicy(v1.DeletePropagationBackground))
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		job = batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).To(BeNil())
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		Expect(database.Status.Clone.Phase).To(Equal(apiV1.RunningJobPhase))
+
+		// when
+		job.Status.Succeeded = 1
+		err = k8sClient.Status().Update(ctx, &job)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		Expect(database.Status.Clone.Phase).To(Equal(apiV1.SucceededJobPhase))
+		Expect(database.Status.Clone.SnapshotTime).NotTo(BeNil())
+		condition = meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+		Expect(database.Finalizers).To(HaveLen(1))
+
+		// cleanup
+		deleteJobTestFixtures(ctx, "dummy-clone", "remote-reader-credentials")
+	})
+
+	It("does not clone a source on the same instance with connected sessions", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "default", Name: "dummy"},
+		})
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.sessions = map[string]int{"dummy": 2}
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy-copy",
+			},
+		}
+		database := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "dummy-copy",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Source: &apiV1.PgDatabaseSource{
+					Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "dummy"},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(mock.databases).NotTo(HaveKey("dummy-copy"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Reason).To(Equal("SourceInUse"))
+
+		// when
+		database.Spec.Source.TerminateSessions = true
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsTerminateDatabaseSessions).To(Equal(1))
+		Expect(mock.options["dummy-copy"].Template).To(Equal("dummy"))
+	})
+
+	It("clones a source in another namespace only if the namespace is allowed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		namespace := coreV1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "clone-source"}}
+		err := k8sClient.Create(ctx, &namespace)
+		if err != nil {
+			Expect(kErrors.IsAlreadyExists(err)).To(BeTrue())
+		}
+		source := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "clone-source",
+				Name:      "source",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, &source)
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgDatabaseMock)
+		mock.databases["source"] = dummyDB{owner: "pgadmin"}
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		database.Spec.Source = &apiV1.PgDatabaseSource{
+			Database: apiV1.PgDatabaseRef{Namespace: "clone-source", Name: "source"},
+		}
+		err = k8sClient.Update(ctx, &database)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(mock.databases).NotTo(HaveKey("dummy"))
+		err = k8sClient.Get(ctx, request.NamespacedName, &database)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, apiV1.PgDatabaseCloneConditionType)
+		Expect(condition.Reason).To(Equal("SourceNotAllowed"))
+
+		// when
+		source.Annotations = map[string]string{apiV1.PgDatabaseCloneAllowedNamespacesAnnotation: "default"}
+		err = k8sClient.Update(ctx, &source)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.options["dummy"].Template).To(Equal("source"))
+
+		// cleanup
+		err = k8sClient.Delete(ctx, &source)
+		Expect(err).To(BeNil())
 	})
 })
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// dumpMountPath contains the path at which the claim of the dump is mounted
+const dumpMountPath = "/dump"
+
+// dumpJobName returns the name of the Job and the Secret, which are used to dump the given database
+func dumpJobName(database *apiV1.PgDatabase) string {
+	return database.Name + "-dump"
+}
+
+// dumpDatabase runs a Job which dumps the database into the configured claim.
+// It returns true if the Job succeeded, false if the Job is still running.
+func (r *PgDatabaseReconciler) dumpDatabase(ctx context.Context, pgApi PgDatabaseAPI, database *apiV1.PgDatabase) (bool, error) {
+	logger := log.FromContext(ctx)
+	key := types.NamespacedName{Namespace: database.Namespace, Name: dumpJobName(database)}
+	owners := jobOwnerReferences(database, apiV1.PgDatabaseKind())
+
+	job := batchV1.Job{}
+	exists, err := getResource(ctx, r, key, &job)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		userId := types.NamespacedName{Namespace: database.Namespace, Name: database.Spec.DeletionBehavior.Dump.Role}
+		_, login, reason, err := readUserLogin(ctx, r, userId)
+		if err != nil {
+			logger.Error(err, "Unable to read the credentials of the role", "database", database.Name, "role", database.Spec.DeletionBehavior.Dump.Role)
+			if reason != "" {
+				if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseDeletionConditionType, false, reason, err.Error()); err != nil {
+					return false, err
+				}
+			}
+			return false, err
+		}
+		// pg_dump has to connect to the database, which is dropped afterwards anyway
+		allowConnections := true
+		if err := pgApi.UpdateDatabaseOptions(database.Name, pgapi.PgDatabaseOptions{AllowConnections: &allowConnections}); err != nil {
+			logger.Error(err, "Unable to allow connections for the dump", "database", database.Name)
+			return false, err
+		}
+		if err := createOrUpdateConnectionSecret(ctx, r.Client, key, owners, pgApi.ConnectionString(), login, database.Name); err != nil {
+			logger.Error(err, "Unable to create dump Secret", "database", database.Name)
+			return false, err
+		}
+		job = newDumpJob(database, key, owners)
+		if err := r.Create(ctx, &job); err != nil {
+			logger.Error(err, "Unable to create dump Job", "database", database.Name)
+			return false, err
+		}
+		logger.Info("Created dump Job", "database", database.Name, "job", key.String())
+	}
+
+	switch getJobStatus(&job).Phase {
+	case apiV1.SucceededJobPhase:
+		return true, nil
+	case apiV1.FailedJobPhase:
+		err := fmt.Errorf("Dump Job %s failed: %s", key.String(), getJobFailure(&job))
+		if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseDeletionConditionType, false, "DumpFailed", err.Error()); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+	message := fmt.Sprintf("Waiting for dump Job %s to succeed", key.String())
+	if err := setCondition(ctx, r.Status(), database, apiV1.PgDatabaseDeletionConditionType, false, "DumpRunning", message); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// newDumpJob creates the Job which dumps the database into the configured claim
+func newDumpJob(database *apiV1.PgDatabase, key types.NamespacedName, owners []metaV1.OwnerReference) batchV1.Job {
+	dump := database.Spec.DeletionBehavior.Dump
+	backoffLimit := int32(2)
+	timestamp := metaV1.Now()
+	if database.DeletionTimestamp != nil {
+		timestamp = *database.DeletionTimestamp
+	}
+	file := fmt.Sprintf("%s/%s-%s.dump", dumpMountPath, database.Name, timestamp.UTC().Format("20060102T150405Z"))
+	command := []string{"pg_dump", "--format=custom", "--file=" + file}
+	return batchV1.Job{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:       key.Namespace,
+			Name:            key.Name,
+			OwnerReferences: owners,
+		},
+		Spec: batchV1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: coreV1.PodTemplateSpec{
+				Spec: newJobPodSpec("pg-dump", dump.GetImage(), command, key.Name, dump.PersistentVolumeClaim, dumpMountPath),
+			},
+		},
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,6 +31,9 @@ import (
 	"github.com/brose-ebike/postgres-operator/pkg/services"
 )
 
+// instanceProbeInterval defines how often a connected instance is probed again
+const instanceProbeInterval = 5 * time.Minute
+
 // PgInstanceReconciler reconciles a PgInstance object
 type PgInstanceReconciler struct {
 	client.Client
@@ -40,6 +44,7 @@ type PgInstanceReconciler struct {
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pginstances,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pginstances/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pginstances/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers;pgdatabases,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 
@@ -86,9 +91,16 @@ func (r *PgInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
+	// Update server information and dependents
+	if err := r.updateStatus(ctx, pgApi, &instance); err != nil {
+		logger.Error(err, "Unable to update status", "instance", req.NamespacedName.String())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
 	logger.Info("Processed instance", "instance", req.NamespacedName.String())
 
-	return ctrl.Result{}, nil
+	// Probe the instance periodically to detect outages
+	return ctrl.Result{RequeueAfter: instanceProbeInterval}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -125,3 +137,114 @@ func (r *PgInstanceReconciler) createPgApi(ctx context.Context, instance *apiV1.
 	}
 	return pgApi, nil
 }
+
+func (r *PgInstanceReconciler) updateStatus(ctx context.Context, pgApi pgapi.PgConnector, instance *apiV1.PgInstance) error {
+	return updateInstanceStatus(ctx, r, r.Status(), pgApi, instance, &instance.Status, func(ref *apiV1.PgInstanceRef) bool {
+		return ref.IsReferencing(instance)
+	})
+}
+
+// updateInstanceStatus probes the server of the given PgInstance or ClusterPgInstance and stores the server information
+// and the number of dependent resources, which are selected by the given function, in the given status of the instance
+func updateInstanceStatus(
+	ctx context.Context,
+	r client.Reader,
+	w client.StatusWriter,
+	pgApi pgapi.PgConnector,
+	obj ObjectWithConditions,
+	status *apiV1.PgInstanceStatus,
+	isReferencing func(ref *apiV1.PgInstanceRef) bool,
+) error {
+	// Query server information
+	info, err := pgApi.GetServerInfo()
+	if err != nil {
+		if err := setCondition(ctx, w, obj, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+
+	// Count dependent resources
+	users, databases, err := countInstanceDependents(ctx, r, isReferencing)
+	if err != nil {
+		return err
+	}
+
+	// Report the expiry of the certificates
+	certificates, err := getCertificateStatus(pgApi.ConnectionString())
+	if err != nil {
+		return err
+	}
+
+	now := metaV1.Now()
+	status.ServerVersion = info.Version
+	status.AdminPrivileges = &apiV1.PgInstanceAdminPrivileges{
+		Superuser:  info.Superuser,
+		CreateRole: info.CreateRole,
+		CreateDB:   info.CreateDB,
+	}
+	status.StartTime = &metaV1.Time{Time: info.StartTime}
+	status.Connections = info.Connections
+	status.MaxConnections = info.MaxConnections
+	status.Users = users
+	status.Databases = databases
+	status.Certificates = certificates
+	status.LastProbeTime = &now
+	return w.Update(ctx, obj)
+}
+
+// countInstanceDependents returns the number of PgUser and PgDatabase objects in all namespaces which reference the instance
+func countInstanceDependents(ctx context.Context, r client.Reader, isReferencing func(ref *apiV1.PgInstanceRef) bool) (int, int, error) {
+	var users apiV1.PgUserList
+	if err := r.List(ctx, &users); err != nil {
+		return 0, 0, err
+	}
+	var databases apiV1.PgDatabaseList
+	if err := r.List(ctx, &databases); err != nil {
+		return 0, 0, err
+	}
+
+	userCount := 0
+	for _, user := range users.Items {
+		if isReferencing(&user.Spec.Instance) {
+			userCount++
+		}
+	}
+	databaseCount := 0
+	for _, database := range databases.Items {
+		if isReferencing(&database.Spec.Instance) {
+			databaseCount++
+		}
+	}
+	return userCount, databaseCount, nil
+}
+
+// getCertificateStatus returns the expiry of the root and client certificates used for the connection
+func getCertificateStatus(connectionString pgapi.PgConnectionString) ([]apiV1.PgInstanceCertificateStatus, error) {
+	tlsConfig := connectionString.TLSConfig()
+	sources := []struct {
+		property string
+		data     string
+	}{
+		{"sslRootCert", tlsConfig.RootCert},
+		{"sslCert", tlsConfig.Cert},
+	}
+	var result []apiV1.PgInstanceCertificateStatus
+	for _, source := range sources {
+		if source.data == "" {
+			continue
+		}
+		certificates, err := pgapi.ParseCertificates(source.data)
+		if err != nil {
+			return nil, err
+		}
+		for _, certificate := range certificates {
+			result = append(result, apiV1.PgInstanceCertificateStatus{
+				Property: source.property,
+				Subject:  certificate.Subject.String(),
+				NotAfter: metaV1.NewTime(certificate.NotAfter),
+			})
+		}
+	}
+	return result, nil
+}
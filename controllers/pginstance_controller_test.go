@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"errors"
+	"time"
 
 	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
 	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
@@ -46,6 +47,18 @@ func (a *pgConnectorMock) ConnectionString() pgapi.PgConnectionString {
 	return pgapi.PgConnectionString{}
 }
 
+func (a *pgConnectorMock) GetServerInfo() (pgapi.PgServerInfo, error) {
+	return pgapi.PgServerInfo{
+		Version:        "14.5",
+		Superuser:      false,
+		CreateRole:     true,
+		CreateDB:       true,
+		StartTime:      time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+		Connections:    5,
+		MaxConnections: 100,
+	}, nil
+}
+
 var _ = Describe("PgInstanceReconciler", func() {
 
 	var pgApiMock pgapi.PgConnector
@@ -139,7 +152,7 @@ var _ = Describe("PgInstanceReconciler", func() {
 
 		// then
 		Expect(err).To(BeNil())
-		Expect(result.RequeueAfter).To(BeZero())
+		Expect(result.RequeueAfter).To(Equal(instanceProbeInterval))
 
 		// and
 		var instance apiV1.PgInstance
@@ -147,6 +160,65 @@ var _ = Describe("PgInstanceReconciler", func() {
 		Expect(err).To(BeNil())
 		Expect(instance.Status.Conditions).To(HaveLen(1))
 		Expect(instance.Status.Conditions[0].Status).To(Equal(metaV1.ConditionTrue))
+		Expect(instance.Status.ServerVersion).To(Equal("14.5"))
+		Expect(instance.Status.AdminPrivileges).ToNot(BeNil())
+		Expect(instance.Status.AdminPrivileges.Superuser).To(BeFalse())
+		Expect(instance.Status.AdminPrivileges.CreateRole).To(BeTrue())
+		Expect(instance.Status.AdminPrivileges.CreateDB).To(BeTrue())
+		Expect(instance.Status.StartTime).ToNot(BeNil())
+		Expect(instance.Status.Connections).To(Equal(5))
+		Expect(instance.Status.MaxConnections).To(Equal(100))
+		Expect(instance.Status.LastProbeTime).ToNot(BeNil())
+	})
+
+	It("counts the dependents of PgInstance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		database := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "dependent",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "dummy",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &database)).To(Succeed())
+		other := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "other",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "failure",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &other)).To(Succeed())
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+
+		// and
+		var instance apiV1.PgInstance
+		err = k8sClient.Get(ctx, request.NamespacedName, &instance)
+		Expect(err).To(BeNil())
+		Expect(instance.Status.Databases).To(Equal(1))
+		Expect(instance.Status.Users).To(Equal(0))
 	})
 
 	It("reconciles on delete of PgInstance", func() {
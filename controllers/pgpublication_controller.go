@@ -0,0 +1,280 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// PgPublicationReconciler reconciles a PgPublication object
+type PgPublicationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	PgReplicationAPIFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgpublications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgpublications/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgpublications/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgPublicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var publication apiV1.PgPublication
+	exists, err := getResource(ctx, r, req.NamespacedName, &publication)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgPublication", "publication", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted
+	if !exists {
+		logger.Info("Deleted PgPublication", "publication", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be used, they may belong to another tenant of the instance
+	if publication.Spec.Database.Namespace != publication.Namespace {
+		if publication.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &publication)
+		}
+		message := "The PgDatabase " + publication.GetDatabaseIdString() + " is not in the namespace " + publication.Namespace
+		if err := setCondition(ctx, r.Status(), &publication, apiV1.PgPublicationExistsConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, publication.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", publication.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		// Nothing is left to clean up, if the database resource is gone
+		if publication.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &publication)
+		}
+		message := "The PgDatabase " + publication.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &publication, apiV1.PgPublicationExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "publication", publication.ToNamespacedName(), "database", publication.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &publication, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Handle finalizing
+	if publication.DeletionTimestamp != nil {
+		if err := r.finalize(ctx, &publication, &database, pgApi); err != nil {
+			logger.Info("Unable to finalize", "publication", req.NamespacedName.String(), "database", publication.GetDatabaseIdString())
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		// Exit and do not reconcile anymore
+		return ctrl.Result{}, nil
+	}
+
+	// Check if database exists on the instance
+	exists, err = pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The database " + database.Name + " does not exist on the instance"
+		if err := setCondition(ctx, r.Status(), &publication, apiV1.PgPublicationExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create or update Publication
+	if err := r.createOrUpdatePublication(ctx, pgApi, &publication, &database); err != nil {
+		logger.Error(err, "Unable to update publication", "publication", publication.GetPublicationName(), "database", database.Name)
+		if err := setCondition(ctx, r.Status(), &publication, apiV1.PgPublicationExistsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Check if finalizer exists
+	if !controllerutil.ContainsFinalizer(&publication, apiV1.DefaultFinalizerPgPublication) {
+		controllerutil.AddFinalizer(&publication, apiV1.DefaultFinalizerPgPublication)
+		err = r.Update(ctx, &publication)
+		if err != nil {
+			logger.Error(err, "Failed to update finalizers", "publication", publication.ToNamespacedName())
+			return ctrl.Result{RequeueAfter: time.Second}, err
+		}
+	}
+
+	logger.Info("Processed publication", "publication", publication.ToNamespacedName(), "database", publication.GetDatabaseIdString())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgPublicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgReplicationAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgReplicationAPI, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgPublication{}).
+		Complete(r)
+}
+
+func (r *PgPublicationReconciler) createPgApi(ctx context.Context, publication *apiV1.PgPublication, database *apiV1.PgDatabase) (PgReplicationAPI, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), publication, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgReplicationAPIFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), publication, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "publication", publication.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), publication, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "publication", publication.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+func (r *PgPublicationReconciler) finalize(ctx context.Context, publication *apiV1.PgPublication, database *apiV1.PgDatabase, pgApi PgReplicationAPI) error {
+	logger := log.FromContext(ctx)
+	publicationName := publication.GetPublicationName()
+
+	exists, err := pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return err
+	}
+	if exists {
+		if err := pgApi.DeletePublication(database.Name, publicationName); err != nil {
+			logger.Error(err, "Unable to remove publication", "publication", publicationName, "database", database.Name)
+			if err := setCondition(ctx, r.Status(), publication, apiV1.PgPublicationExistsConditionType, true, "DeletionFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	return r.removeFinalizer(ctx, publication)
+}
+
+func (r *PgPublicationReconciler) removeFinalizer(ctx context.Context, publication *apiV1.PgPublication) error {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(publication, apiV1.DefaultFinalizerPgPublication) {
+		return nil
+	}
+	controllerutil.RemoveFinalizer(publication, apiV1.DefaultFinalizerPgPublication)
+	if err := r.Update(ctx, publication); err != nil {
+		logger.Error(err, "Failed to update finalizers")
+		return err
+	}
+	logger.Info("Removed finalizer, publication resource can now be deleted")
+	return nil
+}
+
+// createOrUpdatePublication creates the publication or aligns the tables and operations of an existing publication
+func (r *PgPublicationReconciler) createOrUpdatePublication(ctx context.Context, pgApi PgReplicationAPI, publication *apiV1.PgPublication, database *apiV1.PgDatabase) error {
+	logger := log.FromContext(ctx)
+	publicationName := publication.GetPublicationName()
+	options := pgapi.PgPublicationOptions{
+		AllTables:  publication.Spec.AllTables,
+		Tables:     publication.GetTableNames(),
+		Operations: publication.GetOperations(),
+	}
+
+	exists, err := pgApi.IsPublicationExisting(database.Name, publicationName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := pgApi.CreatePublication(database.Name, publicationName, options); err != nil {
+			return err
+		}
+		logger.Info("Created publication " + publicationName + " in database " + database.Name)
+	} else {
+		current, err := pgApi.GetPublicationOptions(database.Name, publicationName)
+		if err != nil {
+			return err
+		}
+		// Publications of all tables cannot be converted
+		if current.AllTables != options.AllTables {
+			if err := pgApi.DeletePublication(database.Name, publicationName); err != nil {
+				return err
+			}
+			if err := pgApi.CreatePublication(database.Name, publicationName, options); err != nil {
+				return err
+			}
+			logger.Info("Recreated publication " + publicationName + " in database " + database.Name)
+		} else if err := pgApi.UpdatePublication(database.Name, publicationName, options); err != nil {
+			return err
+		}
+	}
+
+	// Update status
+	current, err := pgApi.GetPublicationOptions(database.Name, publicationName)
+	if err != nil {
+		return err
+	}
+	publication.Status.Tables = current.Tables
+	putCondition(publication, apiV1.PgPublicationExistsConditionType, true, "PublicationExists", "-")
+	return r.Status().Update(ctx, publication)
+}
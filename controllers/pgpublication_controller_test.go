@@ -0,0 +1,259 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type pgReplicationMock struct {
+	*pgDatabaseMock
+	publications            map[string]pgapi.PgPublicationOptions
+	subscriptions           map[string]pgapi.PgSubscriptionOptions
+	subscriptionTables      map[string]string
+	slotLag                 int64
+	callsUpdatePublication  int
+	callsUpdateSubscription int
+	callsDeleteSubscription int
+	dropSlot                bool
+}
+
+func newPgReplicationMock(databases ...string) *pgReplicationMock {
+	mock := &pgReplicationMock{
+		pgDatabaseMock:     &pgDatabaseMock{databases: make(map[string]dummyDB)},
+		publications:       make(map[string]pgapi.PgPublicationOptions),
+		subscriptions:      make(map[string]pgapi.PgSubscriptionOptions),
+		subscriptionTables: make(map[string]string),
+	}
+	for _, database := range databases {
+		mock.databases[database] = dummyDB{owner: "pgadmin"}
+	}
+	return mock
+}
+
+func (m *pgReplicationMock) IsPublicationExisting(databaseName string, publicationName string) (bool, error) {
+	_, exists := m.publications[publicationName]
+	return exists, nil
+}
+
+func (m *pgReplicationMock) GetPublicationOptions(databaseName string, publicationName string) (pgapi.PgPublicationOptions, error) {
+	return m.publications[publicationName], nil
+}
+
+func (m *pgReplicationMock) CreatePublication(databaseName string, publicationName string, options pgapi.PgPublicationOptions) error {
+	m.publications[publicationName] = options
+	return nil
+}
+
+func (m *pgReplicationMock) UpdatePublication(databaseName string, publicationName string, options pgapi.PgPublicationOptions) error {
+	m.callsUpdatePublication += 1
+	m.publications[publicationName] = options
+	return nil
+}
+
+func (m *pgReplicationMock) DeletePublication(databaseName string, publicationName string) error {
+	delete(m.publications, publicationName)
+	return nil
+}
+
+func (m *pgReplicationMock) IsSubscriptionExisting(databaseName string, subscriptionName string) (bool, error) {
+	_, exists := m.subscriptions[subscriptionName]
+	return exists, nil
+}
+
+func (m *pgReplicationMock) CreateSubscription(databaseName string, subscriptionName string, options pgapi.PgSubscriptionOptions) error {
+	m.subscriptions[subscriptionName] = options
+	return nil
+}
+
+func (m *pgReplicationMock) UpdateSubscription(databaseName string, subscriptionName string, options pgapi.PgSubscriptionOptions) error {
+	m.callsUpdateSubscription += 1
+	m.subscriptions[subscriptionName] = options
+	return nil
+}
+
+func (m *pgReplicationMock) DeleteSubscription(databaseName string, subscriptionName string, dropSlot bool) error {
+	m.callsDeleteSubscription += 1
+	m.dropSlot = dropSlot
+	delete(m.subscriptions, subscriptionName)
+	return nil
+}
+
+func (m *pgReplicationMock) GetSubscriptionState(databaseName string, subscriptionName string) (pgapi.PgSubscriptionState, error) {
+	options := m.subscriptions[subscriptionName]
+	return pgapi.PgSubscriptionState{
+		Enabled:  options.Enabled,
+		SlotName: options.SlotName,
+		Tables:   m.subscriptionTables,
+	}, nil
+}
+
+func (m *pgReplicationMock) GetReplicationSlotLag(slotName string) (int64, bool, error) {
+	return m.slotLag, true, nil
+}
+
+var _ = Describe("PgPublicationReconciler", func() {
+
+	var pgApiMock *pgReplicationMock
+	var reconciler *PgPublicationReconciler
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "orders",
+		},
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pgApiMock = newPgReplicationMock("dummy")
+
+		// Create Reconciler
+		reconciler = &PgPublicationReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgReplicationAPI, error) {
+				return pgApiMock, nil
+			},
+		}
+		createJobTestFixtures(ctx)
+
+		publication := apiV1.PgPublication{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "orders",
+			},
+			Spec: apiV1.PgPublicationSpec{
+				Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "dummy"},
+				Tables:   []apiV1.PgPublicationTable{{Name: "orders"}},
+			},
+		}
+		err := k8sClient.Create(ctx, &publication)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		deleteJobTestFixtures(ctx)
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("creates the publication", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.publications["orders"].Tables).To(Equal([]string{"public.orders"}))
+
+		// and
+		publication := apiV1.PgPublication{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).To(BeNil())
+		Expect(publication.Finalizers).To(HaveLen(1))
+		Expect(publication.Status.Tables).To(Equal([]string{"public.orders"}))
+		condition := meta.FindStatusCondition(publication.Status.Conditions, apiV1.PgPublicationExistsConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("refuses a database in another namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		publication := apiV1.PgPublication{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).To(BeNil())
+		publication.Spec.Database.Namespace = "tenant"
+		err = k8sClient.Update(ctx, &publication)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.publications).To(BeEmpty())
+		err = k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(publication.Status.Conditions, apiV1.PgPublicationExistsConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("DatabaseNotAllowed"))
+	})
+
+	It("updates the tables of the publication", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		publication := apiV1.PgPublication{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).To(BeNil())
+		publication.Spec.Tables = append(publication.Spec.Tables, apiV1.PgPublicationTable{Schema: "sales", Name: "orders"})
+		publication.Spec.Operations = []apiV1.PgPublicationOperation{apiV1.InsertPublicationOperation}
+		err = k8sClient.Update(ctx, &publication)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsUpdatePublication).To(Equal(1))
+		Expect(pgApiMock.publications["orders"].Tables).To(Equal([]string{"public.orders", "sales.orders"}))
+		Expect(pgApiMock.publications["orders"].Operations).To(Equal([]string{"insert"}))
+	})
+
+	It("drops the publication on finalize", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		publication := apiV1.PgPublication{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &publication)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.publications).To(BeEmpty())
+		err = k8sClient.Get(ctx, request.NamespacedName, &publication)
+		Expect(err).NotTo(BeNil())
+	})
+})
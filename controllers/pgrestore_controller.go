@@ -0,0 +1,257 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// restoreMountPath contains the path at which the claim of the backup is mounted
+const restoreMountPath = "/restore"
+
+// PgRestoreReconciler reconciles a PgRestore object
+type PgRestoreReconciler struct {
+	client.Client
+	Scheme              *runtime.Scheme
+	PgConnectionFactory PgConnectionFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgrestores,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgrestores/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgrestores/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var restore apiV1.PgRestore
+	exists, err := getResource(ctx, r, req.NamespacedName, &restore)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgRestore", "restore", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted, the Job and Secret are removed by the garbage collector
+	if !exists || restore.DeletionTimestamp != nil {
+		logger.Info("Deleted PgRestore", "restore", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be restored
+	if restore.Spec.Database.Namespace != restore.Namespace {
+		message := "The PgDatabase " + restore.GetDatabaseIdString() + " is not in the namespace " + restore.Namespace
+		if err := setCondition(ctx, r.Status(), &restore, apiV1.PgRestoreSucceededConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Track the state of an existing Job
+	key := types.NamespacedName{Namespace: restore.Namespace, Name: restoreJobName(&restore)}
+	job := batchV1.Job{}
+	exists, err = getResource(ctx, r, key, &job)
+	if err != nil {
+		logger.Error(err, "Unable to fetch restore Job", "restore", restore.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if exists {
+		if err := r.updateStatus(ctx, &restore, &job); err != nil {
+			logger.Error(err, "Unable to update status", "restore", restore.ToNamespacedName())
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	// A backup is restored only once
+	if restore.Status.Job != nil {
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, restore.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", restore.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The PgDatabase " + restore.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &restore, apiV1.PgRestoreSucceededConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "restore", restore.ToNamespacedName(), "database", restore.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &restore, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Read the credentials of the role
+	_, login, reason, err := readUserLogin(ctx, r, restore.GetUserId())
+	if err != nil {
+		logger.Error(err, "Unable to read the credentials of the role", "restore", restore.ToNamespacedName(), "role", restore.Spec.Role)
+		if reason != "" {
+			if err := setCondition(ctx, r.Status(), &restore, apiV1.PgRestoreSucceededConditionType, false, reason, err.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Minute}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Create the Job restoring the backup
+	owners := jobOwnerReferences(&restore, apiV1.PgRestoreKind())
+	if err := createOrUpdateConnectionSecret(ctx, r.Client, key, owners, pgApi.ConnectionString(), login, database.Name); err != nil {
+		logger.Error(err, "Unable to update restore Secret", "restore", restore.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	job = newRestoreJob(&restore, database.Name, key, owners)
+	if err := r.Create(ctx, &job); err != nil {
+		logger.Error(err, "Unable to create restore Job", "restore", restore.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	logger.Info("Created restore Job", "restore", restore.ToNamespacedName(), "job", key.String())
+
+	if err := r.updateStatus(ctx, &restore, &job); err != nil {
+		logger.Error(err, "Unable to update status", "restore", restore.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	logger.Info("Processed restore", "restore", restore.ToNamespacedName(), "database", restore.GetDatabaseIdString())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgConnectionFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgRestore{}).
+		Owns(&batchV1.Job{}).
+		Complete(r)
+}
+
+func (r *PgRestoreReconciler) createPgApi(ctx context.Context, restore *apiV1.PgRestore, database *apiV1.PgDatabase) (pgapi.PgConnector, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), restore, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgConnectionFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), restore, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "restore", restore.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), restore, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "restore", restore.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+// updateStatus reports the state of the Job restoring the backup
+func (r *PgRestoreReconciler) updateStatus(ctx context.Context, restore *apiV1.PgRestore, job *batchV1.Job) error {
+	status := getJobStatus(job)
+	restore.Status.Job = &status
+	switch status.Phase {
+	case apiV1.SucceededJobPhase:
+		putCondition(restore, apiV1.PgRestoreSucceededConditionType, true, "RestoreSucceeded", "Job "+job.Name+" succeeded")
+	case apiV1.FailedJobPhase:
+		message := fmt.Sprintf("Job %s failed: %s", job.Name, getJobFailure(job))
+		putCondition(restore, apiV1.PgRestoreSucceededConditionType, false, "RestoreFailed", message)
+	default:
+		putCondition(restore, apiV1.PgRestoreSucceededConditionType, false, "RestoreRunning", "Job "+job.Name+" is running")
+	}
+	return r.Status().Update(ctx, restore)
+}
+
+// restoreJobName returns the name of the Job and the Secret, which are used to restore the backup
+func restoreJobName(restore *apiV1.PgRestore) string {
+	return restore.Name + "-restore"
+}
+
+// newRestoreJob returns the Job which restores the backup into the given database
+func newRestoreJob(restore *apiV1.PgRestore, database string, key types.NamespacedName, owners []metaV1.OwnerReference) batchV1.Job {
+	// A failed restore is not retried, since it may have been applied partially
+	backoffLimit := int32(0)
+	file := path.Join(restoreMountPath, path.Clean("/"+restore.Spec.File))
+	command := []string{"psql", "--set=ON_ERROR_STOP=1", "--file=" + file}
+	if format := restore.GetFormat(); format != apiV1.PlainBackupFormat {
+		command = []string{"pg_restore", "--dbname=" + database, "--format=" + string(format)}
+		if restore.Spec.Clean {
+			command = append(command, "--clean", "--if-exists")
+		}
+		command = append(command, file)
+	}
+	return batchV1.Job{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:       key.Namespace,
+			Name:            key.Name,
+			OwnerReferences: owners,
+		},
+		Spec: batchV1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: coreV1.PodTemplateSpec{
+				Spec: newJobPodSpec("pg-restore", restore.GetImage(), command, key.Name, restore.Spec.PersistentVolumeClaim, restoreMountPath),
+			},
+		},
+	}
+}
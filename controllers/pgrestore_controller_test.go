@@ -0,0 +1,200 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("PgRestoreReconciler", func() {
+
+	var reconciler *PgRestoreReconciler
+
+	newRestore := func() *apiV1.PgRestore {
+		return &apiV1.PgRestore{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "rollback",
+			},
+			Spec: apiV1.PgRestoreSpec{
+				Database: apiV1.PgDatabaseRef{
+					Namespace: "default",
+					Name:      "dummy",
+				},
+				Role:                  "dummy-owner",
+				PersistentVolumeClaim: "backups",
+				File:                  "nightly-20230101T020000Z.dump",
+				Clean:                 true,
+			},
+		}
+	}
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "rollback",
+		},
+	}
+	jobKey := types.NamespacedName{Namespace: "default", Name: "rollback-restore"}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Create Reconciler
+		reconciler = &PgRestoreReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgConnector, error) {
+				return &pgConnectorMock{}, nil
+			},
+		}
+		createJobTestFixtures(ctx)
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		deleteJobTestFixtures(ctx, "rollback-restore")
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("creates a Job restoring the backup", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		err := k8sClient.Create(ctx, newRestore())
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).To(BeNil())
+		Expect(*job.Spec.BackoffLimit).To(BeZero())
+		Expect(job.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{
+			"pg_restore", "--dbname=dummy", "--format=custom", "--clean", "--if-exists", "/restore/nightly-20230101T020000Z.dump",
+		}))
+
+		// and
+		restore := apiV1.PgRestore{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &restore)
+		Expect(err).To(BeNil())
+		Expect(restore.Status.Job.Phase).To(Equal(apiV1.RunningJobPhase))
+		condition := meta.FindStatusCondition(restore.Status.Conditions, apiV1.PgRestoreSucceededConditionType)
+		Expect(condition.Reason).To(Equal("RestoreRunning"))
+	})
+
+	It("reports failed restores", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		err := k8sClient.Create(ctx, newRestore())
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).To(BeNil())
+		job.Status.Failed = 1
+		job.Status.Conditions = []batchV1.JobCondition{
+			{Type: batchV1.JobFailed, Status: coreV1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"},
+		}
+		err = k8sClient.Status().Update(ctx, &job)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		restore := apiV1.PgRestore{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &restore)
+		Expect(err).To(BeNil())
+		Expect(restore.Status.Job.Phase).To(Equal(apiV1.FailedJobPhase))
+		condition := meta.FindStatusCondition(restore.Status.Conditions, apiV1.PgRestoreSucceededConditionType)
+		Expect(condition.Reason).To(Equal("RestoreFailed"))
+		Expect(condition.Message).To(ContainSubstring("backoff limit"))
+	})
+
+	It("restores a backup only once", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		restore := newRestore()
+		err := k8sClient.Create(ctx, restore)
+		Expect(err).To(BeNil())
+		restore.Status.Job = &apiV1.PgJobStatus{Name: "rollback-restore", Phase: apiV1.SucceededJobPhase}
+		err = k8sClient.Status().Update(ctx, restore)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		job := batchV1.Job{}
+		exists, err := getResource(ctx, k8sClient, jobKey, &job)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("refuses a database in another namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		restore := newRestore()
+		restore.Spec.Database.Namespace = "other"
+		err := k8sClient.Create(ctx, restore)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		err = k8sClient.Get(ctx, request.NamespacedName, restore)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(restore.Status.Conditions, apiV1.PgRestoreSucceededConditionType)
+		Expect(condition.Reason).To(Equal("DatabaseNotAllowed"))
+		job := batchV1.Job{}
+		err = k8sClient.Get(ctx, jobKey, &job)
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,271 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// PgRoleReconciler reconciles a PgRole object
+type PgRoleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	PgRoleAPIFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgroles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgroles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgroles/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var role apiV1.PgRole
+	exists, err := getResource(ctx, r, req.NamespacedName, &role)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgRole", "role", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted
+	if !exists {
+		logger.Info("Deleted PgRole", "role", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &role)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Handle finalizing
+	if role.DeletionTimestamp != nil {
+		if err := r.finalize(ctx, &role, pgApi); err != nil {
+			logger.Info("Unable to finalize", "role", req.NamespacedName.String(), "instance", role.GetInstanceIdString())
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		// Exit and do not reconcile anymore
+		return ctrl.Result{}, nil
+	}
+
+	// Handle create / update
+	if err := r.createGroupRoleIfNotExists(ctx, pgApi, &role); err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	// Update Role Exists Condition
+	if err := setCondition(ctx, r.Status(), &role, apiV1.PgRoleExistsConditionType, true, "RoleExists", "-"); err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update memberships in group roles
+	if err := updateMemberships(ctx, r.Client, &role, pgApi, role.Name, &role.Spec.Instance, role.Spec.MemberOf, apiV1.PgRoleMembershipsConditionType); err != nil {
+		logger.Error(err, "Unable to update memberships", "role", role.ToNamespacedName(), "instance", role.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Check if finalizer exists
+	if !controllerutil.ContainsFinalizer(&role, apiV1.DefaultFinalizerPgRole) {
+		controllerutil.AddFinalizer(&role, apiV1.DefaultFinalizerPgRole)
+		err = r.Update(ctx, &role)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Second}, err
+		}
+	}
+
+	logger.Info("Processed role", "role", role.ToNamespacedName(), "instance", role.GetInstanceIdString())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgRoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgRoleAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgRoleAPI, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgRole{}).
+		Complete(r)
+}
+
+func (r *PgRoleReconciler) createPgApi(ctx context.Context, role *apiV1.PgRole) (PgRoleAPI, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), role, role.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgRoleAPIFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", instance.Namespace+"/"+instance.Name)
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), role, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "instance", instance.Namespace+"/"+instance.Name)
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), role, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "instance", instance.Namespace+"/"+instance.Name)
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+func (r *PgRoleReconciler) finalize(ctx context.Context, role *apiV1.PgRole, pgApi PgRoleAPI) error {
+	logger := log.FromContext(ctx)
+
+	// Delete only if role exists
+	exists, err := pgApi.IsRoleExisting(role.Name)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to check role`s existence %s from %s", role.Name, role.GetInstanceIdString()))
+		return err
+	}
+
+	// Delete only if role is managed by this resource
+	if exists {
+		comment, err := pgApi.GetRoleComment(role.Name)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query comment of role %s", role.Name))
+			return err
+		}
+		exists = apiV1.ParseOwnershipMarker(comment) == role.UID
+	}
+
+	// The role of the operator and superusers are kept, even if they were adopted before they were refused
+	if exists {
+		message, err := checkPrivilegedRole(pgApi, role.Name)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query role %s", role.Name))
+			return err
+		}
+		if message != "" {
+			logger.Info(fmt.Sprintf("Keeping role %s: %s", role.Name, message))
+			exists = false
+		}
+	}
+
+	if exists {
+		if err := pgApi.DeleteRole(role.Name); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to remove role %s from %s", role.Name, role.GetInstanceIdString()))
+			return reportRoleCleanup(ctx, r.Status(), role, apiV1.PgRoleDeletionConditionType, err)
+		}
+	}
+
+	// Update Role Exists Condition
+	if err := setCondition(ctx, r.Status(), role, apiV1.PgRoleExistsConditionType, false, "MissingRole", "-"); err != nil {
+		logger.Error(err, "Unable to update condition")
+		return err
+	}
+
+	// Remove finalizer
+	controllerutil.RemoveFinalizer(role, apiV1.DefaultFinalizerPgRole)
+	if err := r.Update(ctx, role); err != nil {
+		return err
+	}
+
+	// Exit after finalizer was removed
+	return nil
+}
+
+func (r *PgRoleReconciler) createGroupRoleIfNotExists(ctx context.Context, pgApi PgRoleAPI, role *apiV1.PgRole) error {
+	logger := log.FromContext(ctx)
+	roleName := role.Name
+	policy := role.Spec.GetAdoptionPolicy()
+	description := "Role " + roleName
+
+	exists, err := pgApi.IsRoleExisting(roleName)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to query role %s", roleName))
+		return err
+	}
+
+	// create roles
+	comment := ""
+	if !exists {
+		if reason, err := checkMissingOwnership(policy, description); err != nil {
+			return refuseOwnership(ctx, r.Status(), role, apiV1.PgRoleOwnershipConditionType, reason, err)
+		}
+		if err := pgApi.CreateGroupRole(roleName); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to create role %s", roleName))
+			return err
+		}
+		logger.Info(fmt.Sprintf("Created role %s", roleName))
+	} else {
+		// The role of the operator and superusers are never managed by a resource, even if it is marked as their owner
+		message, err := checkPrivilegedRole(pgApi, roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query role %s", roleName))
+			return err
+		}
+		if message != "" {
+			err := errors.New(message + " and cannot be managed by a PgRole")
+			logger.Error(err, fmt.Sprintf("Refused to manage role %s", roleName))
+			return refuseOwnership(ctx, r.Status(), role, apiV1.PgRoleOwnershipConditionType, "PrivilegedRole", err)
+		}
+		comment, err = pgApi.GetRoleComment(roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query comment of role %s", roleName))
+			return err
+		}
+		adopt, reason, err := checkOwnership(role, policy, comment, description, apiV1.PgRoleExistsConditionType)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Refused to manage role %s", roleName))
+			return refuseOwnership(ctx, r.Status(), role, apiV1.PgRoleOwnershipConditionType, reason, err)
+		}
+		if !adopt {
+			return acceptOwnership(ctx, r.Status(), role, apiV1.PgRoleOwnershipConditionType)
+		}
+		logger.Info(fmt.Sprintf("Adopting existing role %s", roleName))
+	}
+
+	// Mark role as managed by this resource and keep the existing comment
+	if err := pgApi.UpdateRoleComment(roleName, apiV1.SetOwnershipMarker(comment, role.UID)); err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to mark role %s", roleName))
+		return err
+	}
+	return acceptOwnership(ctx, r.Status(), role, apiV1.PgRoleOwnershipConditionType)
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("PgRoleReconciler", func() {
+
+	var pgApiMock *pgRoleMock
+	var reconciler *PgRoleReconciler
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Create ApiMock
+		pgApiMock = &pgRoleMock{
+			databases: map[string]dummyDB{},
+			roles: map[string]bool{
+				"readers": true,
+			},
+		}
+
+		// Create Reconciler
+		reconciler = &PgRoleReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgRoleAPI, error) {
+				if instance.Name == "failure" {
+					return nil, errors.New("Connection Failure")
+				}
+				return pgApiMock, nil
+			},
+		}
+
+		// Create dummy
+		createDummy := func() {
+			instance := apiV1.PgInstance{
+				TypeMeta: v1.TypeMeta{
+					APIVersion: "postgres.brose.bike/v1",
+					Kind:       "PgInstance",
+				},
+				ObjectMeta: v1.ObjectMeta{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Spec: apiV1.PgInstanceSpec{
+					Hostname: apiV1.PgProperty{Value: "localhost"},
+					Port:     apiV1.PgProperty{Value: "5432"},
+					Username: apiV1.PgProperty{Value: "admin"},
+					Password: apiV1.PgProperty{Value: "password"},
+				},
+				Status: apiV1.PgInstanceStatus{},
+			}
+			err := k8sClient.Create(ctx, &instance)
+			Expect(err).To(BeNil())
+		}
+		createDummy()
+		// Create role
+		createRole := func() {
+			role := apiV1.PgRole{
+				TypeMeta: v1.TypeMeta{
+					APIVersion: "postgres.brose.bike/v1",
+					Kind:       "PgRole",
+				},
+				ObjectMeta: v1.ObjectMeta{
+					Namespace: "default",
+					Name:      "dummy",
+				},
+				Spec: apiV1.PgRoleSpec{
+					Instance: apiV1.PgInstanceRef{
+						Namespace: "default",
+						Name:      "instance",
+					},
+					MemberOf: []apiV1.PgRoleMembership{
+						{Name: "readers"},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, &role)
+			Expect(err).To(BeNil())
+			// The group role is managed in the same namespace
+			group := apiV1.PgRole{
+				ObjectMeta: v1.ObjectMeta{
+					Namespace: "default",
+					Name:      "readers",
+				},
+				Spec: apiV1.PgRoleSpec{
+					Instance: apiV1.PgInstanceRef{
+						Namespace: "default",
+						Name:      "instance",
+					},
+				},
+			}
+			err = k8sClient.Create(ctx, &group)
+			Expect(err).To(BeNil())
+		}
+		createRole()
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("reconciles on create of PgRole", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		var role apiV1.PgRole
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		Expect(role.Status.Conditions).To(HaveLen(3))
+		existsCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleExistsConditionType)
+		Expect(existsCondition.Status).To(Equal(v1.ConditionTrue))
+		membershipsCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleMembershipsConditionType)
+		Expect(membershipsCondition.Status).To(Equal(v1.ConditionTrue))
+		Expect(role.Status.Memberships).To(Equal([]string{"readers"}))
+		Expect(role.Finalizers).To(HaveLen(1))
+
+		// and
+		Expect(pgApiMock.callsCreateGroupRole).To(Equal(1))
+		Expect(pgApiMock.callsGrantRoleMembership).To(Equal(1))
+	})
+
+	It("revokes memberships which are not desired anymore", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		role := apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		role.Spec.MemberOf = []apiV1.PgRoleMembership{}
+		err = k8sClient.Update(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsRevokeRoleMembership).To(Equal(1))
+		Expect(pgApiMock.memberships["dummy"]).To(BeEmpty())
+
+		// and
+		role = apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		Expect(role.Status.Memberships).To(BeEmpty())
+	})
+
+	It("handles missing group roles", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		delete(pgApiMock.roles, "readers")
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		_, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+
+		// and
+		var role apiV1.PgRole
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		membershipsCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleMembershipsConditionType)
+		Expect(membershipsCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(membershipsCondition.Reason).To(Equal("RolesMissing"))
+	})
+
+	It("refuses memberships in roles which are not managed in the namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		pgApiMock.roles["admin"] = true
+		pgApiMock.roles["root"] = true
+		pgApiMock.roles["other_tenant"] = true
+		pgApiMock.superusers = map[string]bool{"root": true}
+		role := apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		role.Spec.MemberOf = []apiV1.PgRoleMembership{{Name: "admin"}, {Name: "root"}, {Name: "pg_read_server_files"}, {Name: "other_tenant"}}
+		err = k8sClient.Update(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("admin is used by the operator"))
+		Expect(err.Error()).To(ContainSubstring("root is a superuser"))
+		Expect(err.Error()).To(ContainSubstring("pg_read_server_files is a predefined role"))
+		Expect(err.Error()).To(ContainSubstring("other_tenant is not managed"))
+		Expect(pgApiMock.callsGrantRoleMembership).To(Equal(1))
+		Expect(pgApiMock.callsRevokeRoleMembership).To(Equal(1))
+
+		// and
+		role = apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		Expect(role.Status.Memberships).To(BeEmpty())
+		membershipsCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleMembershipsConditionType)
+		Expect(membershipsCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(membershipsCondition.Reason).To(Equal("RoleNotAllowed"))
+	})
+
+	It("reconciles on finalize of PgRole", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		role := apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsDeleteRole).To(Equal(1))
+	})
+
+	It("keeps a superuser on finalize of PgRole", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		pgApiMock.superusers = map[string]bool{"dummy": true}
+
+		// and
+		role := apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsDeleteRole).To(BeZero())
+		Expect(pgApiMock.roles["dummy"]).To(BeTrue())
+	})
+
+	It("refuses an existing role which was not created by PgRole by default", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		pgApiMock.roles["dummy"] = true
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(pgApiMock.callsGrantRoleMembership).To(BeZero())
+		Expect(pgApiMock.comments["dummy"]).To(BeEmpty())
+
+		// and
+		var role apiV1.PgRole
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("ExistsUnmanaged"))
+	})
+
+	It("adopts an existing role with adoption policy Create and keeps its comment", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		pgApiMock.roles["dummy"] = true
+		pgApiMock.comments = map[string]string{"dummy": "Group of the readers"}
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		role := apiV1.PgRole{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		role.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		err = k8sClient.Update(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsCreateGroupRole).To(BeZero())
+		Expect(pgApiMock.comments["dummy"]).To(Equal("Group of the readers\n" + apiV1.NewOwnershipMarker(role.UID)))
+	})
+
+	It("refuses to adopt a superuser with PgRole", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		pgApiMock.roles["dummy"] = true
+		pgApiMock.superusers = map[string]bool{"dummy": true}
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		role := apiV1.PgRole{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		role.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		err = k8sClient.Update(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(pgApiMock.callsGrantRoleMembership).To(BeZero())
+
+		// and
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(role.Status.Conditions, apiV1.PgRoleOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("PrivilegedRole"))
+	})
+
+	It("keeps a role which is not managed by PgRole on finalize", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		pgApiMock.comments["dummy"] = apiV1.NewOwnershipMarker("other-uid")
+
+		// and
+		role := apiV1.PgRole{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &role)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &role)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsDeleteRole).To(BeZero())
+	})
+})
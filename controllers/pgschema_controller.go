@@ -0,0 +1,313 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// PgSchemaReconciler reconciles a PgSchema object
+type PgSchemaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	PgSchemaAPIFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgschemas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgschemas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgschemas/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var schema apiV1.PgSchema
+	exists, err := getResource(ctx, r, req.NamespacedName, &schema)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgSchema", "schema", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted
+	if !exists {
+		logger.Info("Deleted PgSchema", "schema", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be used, they may belong to another tenant of the instance
+	if schema.Spec.Database.Namespace != schema.Namespace {
+		if schema.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &schema)
+		}
+		message := "The PgDatabase " + schema.GetDatabaseIdString() + " is not in the namespace " + schema.Namespace
+		if err := setCondition(ctx, r.Status(), &schema, apiV1.PgSchemaExistsConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, schema.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", schema.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		// Nothing is left to clean up, if the database resource is gone
+		if schema.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &schema)
+		}
+		message := "The PgDatabase " + schema.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &schema, apiV1.PgSchemaExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "schema", schema.ToNamespacedName(), "database", schema.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &schema, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Handle finalizing
+	if schema.DeletionTimestamp != nil {
+		if err := r.finalize(ctx, &schema, &database, pgApi); err != nil {
+			logger.Info("Unable to finalize", "schema", req.NamespacedName.String(), "database", schema.GetDatabaseIdString())
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		// Exit and do not reconcile anymore
+		return ctrl.Result{}, nil
+	}
+
+	// Check if database exists on the instance
+	exists, err = pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The database " + database.Name + " does not exist on the instance"
+		if err := setCondition(ctx, r.Status(), &schema, apiV1.PgSchemaExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create Schema if not exist
+	if err := r.createSchemaIfNotExists(ctx, pgApi, &schema, &database); err != nil {
+		logger.Error(err, "Unable to create schema", "schema", schema.GetSchemaName(), "database", database.Name)
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update Schema Exists Condition
+	if err := setCondition(ctx, r.Status(), &schema, apiV1.PgSchemaExistsConditionType, true, "SchemaExists", "-"); err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update Owner
+	if err := r.handleOwner(ctx, pgApi, &schema, &database); err != nil {
+		logger.Error(err, "Unable to update schema owner", "schema", schema.GetSchemaName(), "database", database.Name)
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Check if finalizer exists
+	if !controllerutil.ContainsFinalizer(&schema, apiV1.DefaultFinalizerPgSchema) {
+		controllerutil.AddFinalizer(&schema, apiV1.DefaultFinalizerPgSchema)
+		err = r.Update(ctx, &schema)
+		if err != nil {
+			logger.Error(err, "Failed to update finalizers", "schema", schema.ToNamespacedName())
+			return ctrl.Result{RequeueAfter: time.Second}, err
+		}
+	}
+
+	logger.Info("Processed schema", "schema", schema.ToNamespacedName(), "database", schema.GetDatabaseIdString())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgSchemaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgSchemaAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgSchemaAPI, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgSchema{}).
+		Complete(r)
+}
+
+func (r *PgSchemaReconciler) createPgApi(ctx context.Context, schema *apiV1.PgSchema, database *apiV1.PgDatabase) (PgSchemaAPI, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), schema, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgSchemaAPIFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), schema, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "schema", schema.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), schema, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "schema", schema.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+func (r *PgSchemaReconciler) finalize(ctx context.Context, schema *apiV1.PgSchema, database *apiV1.PgDatabase, pgApi PgSchemaAPI) error {
+	logger := log.FromContext(ctx)
+	schemaName := schema.GetSchemaName()
+
+	policy := schema.GetDeletionPolicy()
+	if policy == apiV1.DropSchemaDeletionPolicy || policy == apiV1.CascadeSchemaDeletionPolicy {
+		exists, err := pgApi.IsDatabaseExisting(database.Name)
+		if err != nil {
+			logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+			return err
+		}
+		if exists {
+			exists, err = pgApi.IsSchemaInDatabase(database.Name, schemaName)
+			if err != nil {
+				logger.Error(err, "Unable to query schema", "schema", schemaName, "database", database.Name)
+				return err
+			}
+		}
+		if exists && policy == apiV1.CascadeSchemaDeletionPolicy {
+			err = pgApi.DeleteSchemaCascade(database.Name, schemaName)
+		} else if exists {
+			err = pgApi.DeleteSchema(database.Name, schemaName)
+		}
+		if err != nil {
+			logger.Error(err, "Unable to remove schema", "schema", schemaName, "database", database.Name)
+			if err := setCondition(ctx, r.Status(), schema, apiV1.PgSchemaExistsConditionType, true, "DeletionFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+		// Update Schema Exists Condition
+		if err := setCondition(ctx, r.Status(), schema, apiV1.PgSchemaExistsConditionType, false, "SchemaMissing", "Schema was deleted"); err != nil {
+			return err
+		}
+	}
+	return r.removeFinalizer(ctx, schema)
+}
+
+func (r *PgSchemaReconciler) removeFinalizer(ctx context.Context, schema *apiV1.PgSchema) error {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(schema, apiV1.DefaultFinalizerPgSchema) {
+		return nil
+	}
+	controllerutil.RemoveFinalizer(schema, apiV1.DefaultFinalizerPgSchema)
+	if err := r.Update(ctx, schema); err != nil {
+		logger.Error(err, "Failed to update finalizers")
+		return err
+	}
+	logger.Info("Removed finalizer, schema resource can now be deleted")
+	return nil
+}
+
+func (r *PgSchemaReconciler) createSchemaIfNotExists(ctx context.Context, pgApi PgSchemaAPI, schema *apiV1.PgSchema, database *apiV1.PgDatabase) error {
+	logger := log.FromContext(ctx)
+	schemaName := schema.GetSchemaName()
+
+	exists, err := pgApi.IsSchemaInDatabase(database.Name, schemaName)
+	if err != nil {
+		logger.Error(err, "Unable to query schema "+schemaName+" in database "+database.Name)
+		return err
+	}
+
+	// create schema
+	if !exists {
+		if err := pgApi.CreateSchema(database.Name, schemaName); err != nil {
+			logger.Error(err, "Unable to create schema "+schemaName+" in database "+database.Name)
+			return err
+		}
+		logger.Info("Created schema " + schemaName + " in database " + database.Name)
+	}
+	return nil
+}
+
+func (r *PgSchemaReconciler) handleOwner(ctx context.Context, pgApi PgSchemaAPI, schema *apiV1.PgSchema, database *apiV1.PgDatabase) error {
+	schemaName := schema.GetSchemaName()
+	owner := schema.Spec.Owner
+	// Keep the current owner if none is specified
+	if owner == "" {
+		return nil
+	}
+
+	exists, err := pgApi.IsRoleExisting(owner)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err := errors.New("The role " + owner + " does not exist on the instance")
+		if err := setCondition(ctx, r.Status(), schema, apiV1.PgSchemaOwnerConditionType, false, "OwnerMissing", err.Error()); err != nil {
+			return err
+		}
+		return err
+	}
+
+	currentOwner, err := pgApi.GetSchemaOwner(database.Name, schemaName)
+	if err != nil {
+		return err
+	}
+	if currentOwner != owner {
+		if err := pgApi.UpdateSchemaOwner(database.Name, schemaName, owner); err != nil {
+			if err := setCondition(ctx, r.Status(), schema, apiV1.PgSchemaOwnerConditionType, false, "OwnerUpdateFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	return setCondition(ctx, r.Status(), schema, apiV1.PgSchemaOwnerConditionType, true, "OwnerApplied", "-")
+}
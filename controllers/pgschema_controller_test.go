@@ -0,0 +1,453 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// pgSchemaMock extends the pgDatabaseMock with role handling
+// and keeps track of the schemas in the dummy databases
+type pgSchemaMock struct {
+	*pgDatabaseMock
+	roles                   map[string]bool
+	callsIsRoleExisting     int
+	callsCreateRole         int
+	callsDeleteRole         int
+	callsUpdateUserPassword int
+}
+
+func (m *pgSchemaMock) CreateGroupRole(name string) error {
+	m.roles[name] = true
+	return nil
+}
+
+func (m *pgSchemaMock) GetRoleMemberships(roleName string) ([]pgapi.PgRoleMembership, error) {
+	return []pgapi.PgRoleMembership{}, nil
+}
+
+func (m *pgSchemaMock) GrantRoleMembership(roleName string, groupName string, admin bool, inherit bool) error {
+	return nil
+}
+
+func (m *pgSchemaMock) RevokeRoleMembership(roleName string, groupName string) error {
+	return nil
+}
+
+func (m *pgSchemaMock) GetRoleAttributes(name string) (pgapi.PgRoleAttributes, error) {
+	return pgapi.PgRoleAttributes{}, nil
+}
+
+func (m *pgSchemaMock) GetRoleComment(name string) (string, error) {
+	return "", nil
+}
+
+func (m *pgSchemaMock) UpdateRoleComment(name string, comment string) error {
+	return nil
+}
+
+func (m *pgSchemaMock) UpdateRoleAttributes(name string, attributes pgapi.PgRoleAttributes) error {
+	return nil
+}
+
+func (m *pgSchemaMock) IsRoleExisting(roleName string) (bool, error) {
+	m.callsIsRoleExisting += 1
+	_, exists := m.roles[roleName]
+	return exists, nil
+}
+
+func (m *pgSchemaMock) IsRoleSuperuser(roleName string) (bool, error) {
+	return false, nil
+}
+
+func (m *pgSchemaMock) CreateRole(name string) error {
+	m.callsCreateRole += 1
+	m.roles[name] = true
+	return nil
+}
+
+func (m *pgSchemaMock) DeleteRole(name string) error {
+	m.callsDeleteRole += 1
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *pgSchemaMock) ReassignAndDeleteRole(name string, newOwner string) error {
+	m.callsDeleteRole += 1
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *pgSchemaMock) TerminateRoleSessions(name string) error {
+	return nil
+}
+
+func (m *pgSchemaMock) UpdateUserPassword(name string, password string) error {
+	m.callsUpdateUserPassword += 1
+	return nil
+}
+
+func (m *pgSchemaMock) DisableRoleLogin(name string) error {
+	return nil
+}
+
+func (m *pgSchemaMock) UpdateRoleSessionRole(name string, sessionRole string) error {
+	return nil
+}
+
+func (m *pgSchemaMock) IsSchemaInDatabase(databaseName string, schemaName string) (bool, error) {
+	m.callsIsSchemaInDatabase += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return false, errors.New("Database does not exist")
+	}
+	_, exists = database.schemas[schemaName]
+	return exists, nil
+}
+
+func (m *pgSchemaMock) CreateSchema(databaseName string, schemaName string) error {
+	m.callsCreateSchema += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	database.schemas[schemaName] = database.owner
+	return nil
+}
+
+func (m *pgSchemaMock) DeleteSchema(databaseName string, schemaName string) error {
+	m.callsDeleteSchema += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	delete(database.schemas, schemaName)
+	return nil
+}
+
+func (m *pgSchemaMock) DeleteSchemaCascade(databaseName string, schemaName string) error {
+	m.callsDeleteSchemaCascade += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	delete(database.schemas, schemaName)
+	return nil
+}
+
+func (m *pgSchemaMock) GetSchemaOwner(databaseName string, schemaName string) (string, error) {
+	m.callsGetSchemaOwner += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return "", errors.New("Database does not exist")
+	}
+	return database.schemas[schemaName], nil
+}
+
+func (m *pgSchemaMock) UpdateSchemaOwner(databaseName string, schemaName string, roleName string) error {
+	m.callsUpdateSchemaOwner += 1
+	database, exists := m.databases[databaseName]
+	if !exists {
+		return errors.New("Database does not exist")
+	}
+	database.schemas[schemaName] = roleName
+	return nil
+}
+
+var _ = Describe("PgSchemaReconciler", func() {
+
+	var pgApiMock *pgSchemaMock
+	var reconciler *PgSchemaReconciler
+
+	createSchema := func(ctx context.Context, owner string, policy apiV1.PgSchemaDeletionPolicy) {
+		schema := apiV1.PgSchema{
+			TypeMeta: v1.TypeMeta{
+				APIVersion: "postgres.brose.bike/v1",
+				Kind:       "PgSchema",
+			},
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+			Spec: apiV1.PgSchemaSpec{
+				Database: apiV1.PgDatabaseRef{
+					Namespace: "default",
+					Name:      "testdb",
+				},
+				Name:  "service",
+				Owner: owner,
+				DeletionBehavior: apiV1.PgSchemaDeletion{
+					Policy: policy,
+				},
+			},
+			Status: apiV1.PgSchemaStatus{},
+		}
+		err := k8sClient.Create(ctx, &schema)
+		Expect(err).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Create ApiMock
+		pgApiMock = &pgSchemaMock{
+			pgDatabaseMock: &pgDatabaseMock{
+				databases: map[string]dummyDB{
+					"testdb": {
+						owner:   "pgadmin",
+						schemas: make(map[string]string),
+					},
+				},
+			},
+			roles: map[string]bool{
+				"pgadmin":     true,
+				"schemaowner": true,
+			},
+		}
+
+		// Create Reconciler
+		reconciler = &PgSchemaReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgSchemaAPI, error) {
+				if instance.Name == "failure" {
+					return nil, errors.New("Connection Failure")
+				}
+				return pgApiMock, nil
+			},
+		}
+
+		// Create instance
+		instance := apiV1.PgInstance{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "instance",
+			},
+			Spec: apiV1.PgInstanceSpec{
+				Hostname: apiV1.PgProperty{Value: "localhost"},
+				Port:     apiV1.PgProperty{Value: "5432"},
+				Username: apiV1.PgProperty{Value: "admin"},
+				Password: apiV1.PgProperty{Value: "password"},
+			},
+		}
+		err := k8sClient.Create(ctx, &instance)
+		Expect(err).To(BeNil())
+
+		// Create database
+		database := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "testdb",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, &database)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("reconciles on create of PgSchema", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		createSchema(ctx, "schemaowner", apiV1.RetainSchemaDeletionPolicy)
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		var schema apiV1.PgSchema
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		Expect(schema.Status.Conditions).To(HaveLen(3))
+		Expect(schema.Finalizers).To(HaveLen(1))
+		// and Schema Exists Condition is true
+		schemaCondition := meta.FindStatusCondition(schema.Status.Conditions, apiV1.PgSchemaExistsConditionType)
+		Expect(schemaCondition.Status).To(Equal(v1.ConditionTrue))
+		// and Owner Condition is true
+		ownerCondition := meta.FindStatusCondition(schema.Status.Conditions, apiV1.PgSchemaOwnerConditionType)
+		Expect(ownerCondition.Status).To(Equal(v1.ConditionTrue))
+
+		// and
+		Expect(pgApiMock.callsCreateSchema).To(Equal(1))
+		Expect(pgApiMock.callsUpdateSchemaOwner).To(Equal(1))
+		Expect(pgApiMock.databases["testdb"].schemas["service"]).To(Equal("schemaowner"))
+	})
+
+	It("refuses databases of other namespaces", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		createSchema(ctx, "schemaowner", apiV1.RetainSchemaDeletionPolicy)
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		schema := apiV1.PgSchema{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		schema.Spec.Database.Namespace = "tenant"
+		err = k8sClient.Update(ctx, &schema)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsCreateSchema).To(BeZero())
+
+		// and
+		schema = apiV1.PgSchema{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		schemaCondition := meta.FindStatusCondition(schema.Status.Conditions, apiV1.PgSchemaExistsConditionType)
+		Expect(schemaCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(schemaCondition.Reason).To(Equal("DatabaseNotAllowed"))
+	})
+
+	It("reports a missing owner", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		createSchema(ctx, "missingowner", apiV1.RetainSchemaDeletionPolicy)
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+
+		// and
+		var schema apiV1.PgSchema
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		ownerCondition := meta.FindStatusCondition(schema.Status.Conditions, apiV1.PgSchemaOwnerConditionType)
+		Expect(ownerCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownerCondition.Reason).To(Equal("OwnerMissing"))
+		Expect(pgApiMock.callsUpdateSchemaOwner).To(BeZero())
+	})
+
+	It("reconciles on finalize of PgSchema with cascade", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		createSchema(ctx, "", apiV1.CascadeSchemaDeletionPolicy)
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		schema := apiV1.PgSchema{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &schema)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsDeleteSchemaCascade).To(Equal(1))
+		Expect(pgApiMock.callsDeleteSchema).To(BeZero())
+		Expect(pgApiMock.databases["testdb"].schemas).ToNot(HaveKey("service"))
+
+		// and
+		schema = apiV1.PgSchema{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(kErrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("retains the schema on finalize of PgSchema", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		createSchema(ctx, "", "")
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		schema := apiV1.PgSchema{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &schema)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &schema)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsDeleteSchemaCascade).To(BeZero())
+		Expect(pgApiMock.callsDeleteSchema).To(BeZero())
+		Expect(pgApiMock.databases["testdb"].schemas).To(HaveKey("service"))
+	})
+})
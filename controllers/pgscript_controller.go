@@ -0,0 +1,292 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// PgScriptReconciler reconciles a PgScript object
+type PgScriptReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	PgScriptAPIFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgscripts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgscripts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgscripts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgScriptReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var script apiV1.PgScript
+	exists, err := getResource(ctx, r, req.NamespacedName, &script)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgScript", "script", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted, the tracking records remain in the database
+	if !exists || script.DeletionTimestamp != nil {
+		logger.Info("Deleted PgScript", "script", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be used, they may belong to another tenant of the instance
+	if script.Spec.Database.Namespace != script.Namespace {
+		message := "The PgDatabase " + script.GetDatabaseIdString() + " is not in the namespace " + script.Namespace
+		if err := setCondition(ctx, r.Status(), &script, apiV1.PgScriptAppliedConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, script.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", script.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The PgDatabase " + script.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &script, apiV1.PgScriptAppliedConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "script", script.ToNamespacedName(), "database", script.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &script, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Check if database exists on the instance
+	exists, err = pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The database " + database.Name + " does not exist on the instance"
+		if err := setCondition(ctx, r.Status(), &script, apiV1.PgScriptAppliedConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Read the credentials of the role
+	login, err := r.readLogin(ctx, &script)
+	if err != nil {
+		logger.Error(err, "Unable to read the credentials of the role", "script", script.ToNamespacedName(), "role", script.Spec.Role)
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Apply scripts
+	if err := r.applyScripts(ctx, pgApi, &script, &database, login); err != nil {
+		logger.Error(err, "Unable to apply scripts", "script", script.ToNamespacedName(), "database", database.Name)
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	logger.Info("Processed script", "script", script.ToNamespacedName(), "database", script.GetDatabaseIdString())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgScriptReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgScriptAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgScriptAPI, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgScript{}).
+		// Changed sources of the scripts are applied immediately
+		Watches(&source.Kind{Type: &coreV1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.mapScriptSource)).
+		Watches(&source.Kind{Type: &coreV1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.mapScriptSource)).
+		Complete(r)
+}
+
+// mapScriptSource maps a ConfigMap or Secret to the PgScripts in the same namespace which read scripts from it
+func (r *PgScriptReconciler) mapScriptSource(obj client.Object) []reconcile.Request {
+	var scripts apiV1.PgScriptList
+	if err := r.List(context.Background(), &scripts, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Log.Error(err, "Unable to list PgScripts", "namespace", obj.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, script := range scripts.Items {
+		var referenced bool
+		switch obj.(type) {
+		case *coreV1.ConfigMap:
+			referenced = script.IsReferencingConfigMap(obj.GetName())
+		case *coreV1.Secret:
+			referenced = script.IsReferencingSecret(obj.GetName())
+		}
+		if referenced {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: script.Namespace, Name: script.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *PgScriptReconciler) createPgApi(ctx context.Context, script *apiV1.PgScript, database *apiV1.PgDatabase) (PgScriptAPI, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), script, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgScriptAPIFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), script, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "script", script.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), script, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "script", script.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+// readLogin reads the credentials of the PgUser as which the scripts are executed from its Secret.
+// Users which are allowed to create roles are refused, since the scripts could escalate their privileges.
+func (r *PgScriptReconciler) readLogin(ctx context.Context, script *apiV1.PgScript) (pgapi.PgLogin, error) {
+	userId := script.GetUserId()
+	user, login, reason, err := readUserLogin(ctx, r, userId)
+	if err != nil && reason == "" {
+		return pgapi.PgLogin{}, err
+	}
+	if err == nil && user.Spec.Attributes != nil && user.Spec.Attributes.CreateRole != nil && *user.Spec.Attributes.CreateRole {
+		reason, err = "RoleNotAllowed", errors.New("The PgUser "+userId.String()+" has the createrole attribute and cannot execute scripts")
+	}
+	if err != nil {
+		if err := setCondition(ctx, r.Status(), script, apiV1.PgScriptAppliedConditionType, false, reason, err.Error()); err != nil {
+			return pgapi.PgLogin{}, err
+		}
+		return pgapi.PgLogin{}, err
+	}
+	return login, nil
+}
+
+// applyScripts executes the scripts in their order, which were not applied yet.
+// The execution stops at the first script which fails, cannot be read or was changed after it was applied.
+func (r *PgScriptReconciler) applyScripts(ctx context.Context, pgApi PgScriptAPI, script *apiV1.PgScript, database *apiV1.PgDatabase, login pgapi.PgLogin) error {
+	logger := log.FromContext(ctx)
+	owner := script.ToNamespacedName()
+
+	records, err := pgApi.GetAppliedScripts(database.Name, owner)
+	if err != nil {
+		return err
+	}
+
+	reason, message := "ScriptsApplied", "-"
+	var applyErr error
+	for _, step := range script.Spec.Scripts {
+		sql, err := step.SQL.GetPropertyValue(ctx, r, script.Namespace, "sql")
+		if err != nil {
+			reason, message = "SourceMissing", fmt.Sprintf("The script %s cannot be read: %s", step.Name, err.Error())
+			break
+		}
+		checksum := pgapi.ScriptChecksum(sql)
+		if record, ok := records[step.Name]; ok {
+			if record.Checksum == checksum {
+				continue
+			}
+			if !step.RerunOnChange {
+				reason, message = "ChecksumMismatch", fmt.Sprintf("The script %s was changed after it was applied with checksum %s", step.Name, record.Checksum)
+				break
+			}
+		}
+		if err := pgApi.ApplyScript(database.Name, owner, step.Name, sql, login); err != nil {
+			var privilegedErr *pgapi.PrivilegedRoleError
+			if errors.As(err, &privilegedErr) {
+				reason, message = "RoleNotAllowed", err.Error()
+				break
+			}
+			reason, message, applyErr = "ScriptFailed", err.Error(), err
+			break
+		}
+		logger.Info("Applied script "+step.Name+" in database "+database.Name, "script", owner, "checksum", checksum)
+	}
+
+	// Update status with the recorded scripts
+	records, err = pgApi.GetAppliedScripts(database.Name, owner)
+	if err != nil {
+		return err
+	}
+	script.Status.Scripts = nil
+	for _, step := range script.Spec.Scripts {
+		if record, ok := records[step.Name]; ok {
+			script.Status.Scripts = append(script.Status.Scripts, apiV1.PgScriptStepStatus{
+				Name:        record.Name,
+				Checksum:    record.Checksum,
+				AppliedTime: metaV1.NewTime(record.AppliedAt),
+			})
+		}
+	}
+	putCondition(script, apiV1.PgScriptAppliedConditionType, reason == "ScriptsApplied", reason, message)
+	if err := r.Status().Update(ctx, script); err != nil {
+		return err
+	}
+	// Failed scripts are retried, while changed or missing scripts and privileged roles wait for an update
+	return applyErr
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type pgScriptMock struct {
+	*pgDatabaseMock
+	records          map[string]pgapi.PgScriptRecord
+	appliedScripts   []string
+	appliedLogins    []pgapi.PgLogin
+	failingScript    string
+	callsApplyScript int
+}
+
+func (m *pgScriptMock) GetAppliedScripts(databaseName string, owner string) (map[string]pgapi.PgScriptRecord, error) {
+	return m.records, nil
+}
+
+func (m *pgScriptMock) ApplyScript(databaseName string, owner string, name string, script string, login pgapi.PgLogin) error {
+	m.callsApplyScript += 1
+	if name == m.failingScript {
+		return errors.New("syntax error")
+	}
+	m.appliedScripts = append(m.appliedScripts, name)
+	m.appliedLogins = append(m.appliedLogins, login)
+	m.records[name] = pgapi.PgScriptRecord{Name: name, Checksum: pgapi.ScriptChecksum(script), AppliedAt: time.Now()}
+	return nil
+}
+
+var _ = Describe("PgScriptReconciler", func() {
+
+	var pgApiMock *pgScriptMock
+	var reconciler *PgScriptReconciler
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "migrations",
+		},
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pgApiMock = &pgScriptMock{
+			pgDatabaseMock: &pgDatabaseMock{databases: map[string]dummyDB{"dummy": {owner: "pgadmin"}}},
+			records:        make(map[string]pgapi.PgScriptRecord),
+		}
+
+		// Create Reconciler
+		reconciler = &PgScriptReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgScriptAPI, error) {
+				return pgApiMock, nil
+			},
+		}
+		createJobTestFixtures(ctx)
+
+		script := apiV1.PgScript{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "migrations",
+			},
+			Spec: apiV1.PgScriptSpec{
+				Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "dummy"},
+				Role:     "dummy-owner",
+				Scripts: []apiV1.PgScriptStep{
+					{Name: "001-orders", SQL: apiV1.PgProperty{Value: "create table orders (id integer);"}},
+					{Name: "002-items", SQL: apiV1.PgProperty{Value: "create table items (id integer);"}},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, &script)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		deleteJobTestFixtures(ctx)
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("applies the scripts in order", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.appliedScripts).To(Equal([]string{"001-orders", "002-items"}))
+		login := pgapi.PgLogin{Username: "dummy-owner", Password: "secret"}
+		Expect(pgApiMock.appliedLogins).To(Equal([]pgapi.PgLogin{login, login}))
+
+		// and
+		script := apiV1.PgScript{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		Expect(script.Status.Scripts).To(HaveLen(2))
+		Expect(script.Status.Scripts[0].Checksum).To(Equal(pgapi.ScriptChecksum("create table orders (id integer);")))
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("applies every script only once", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsApplyScript).To(Equal(2))
+	})
+
+	It("refuses to run a changed script", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		script := apiV1.PgScript{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		script.Spec.Scripts[0].SQL.Value = "create table orders (id bigint);"
+		err = k8sClient.Update(ctx, &script)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsApplyScript).To(Equal(2))
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("ChecksumMismatch"))
+	})
+
+	It("runs a changed script again if allowed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		script := apiV1.PgScript{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		script.Spec.Scripts[0].SQL.Value = "create table orders (id bigint);"
+		script.Spec.Scripts[0].RerunOnChange = true
+		err = k8sClient.Update(ctx, &script)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.appliedScripts).To(Equal([]string{"001-orders", "002-items", "001-orders"}))
+	})
+
+	It("stops at a failing script", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		pgApiMock.failingScript = "001-orders"
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		Expect(pgApiMock.callsApplyScript).To(Equal(1))
+		script := apiV1.PgScript{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		Expect(script.Status.Scripts).To(BeEmpty())
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Reason).To(Equal("ScriptFailed"))
+	})
+
+	It("refuses a database in another namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		script := apiV1.PgScript{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		script.Spec.Database.Namespace = "tenant"
+		err = k8sClient.Update(ctx, &script)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.callsApplyScript).To(BeZero())
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("DatabaseNotAllowed"))
+	})
+
+	It("refuses a user which can create roles", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "dummy-owner"}, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Attributes = &apiV1.PgUserAttributes{CreateRole: &cTrue}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		Expect(pgApiMock.callsApplyScript).To(BeZero())
+		script := apiV1.PgScript{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Reason).To(Equal("RoleNotAllowed"))
+	})
+
+	It("reports a missing user", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		script := apiV1.PgScript{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		script.Spec.Role = "missing"
+		err = k8sClient.Update(ctx, &script)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(pgApiMock.callsApplyScript).To(BeZero())
+		err = k8sClient.Get(ctx, request.NamespacedName, &script)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(script.Status.Conditions, apiV1.PgScriptAppliedConditionType)
+		Expect(condition.Reason).To(Equal("RoleMissing"))
+	})
+})
@@ -0,0 +1,423 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
+)
+
+// subscriptionTableStates maps the synchronization states of pg_subscription_rel to readable names
+var subscriptionTableStates = map[string]string{
+	"i": "Initializing",
+	"d": "CopyingData",
+	"f": "FinishedCopy",
+	"s": "Synchronized",
+	"r": "Ready",
+}
+
+// PgSubscriptionReconciler reconciles a PgSubscription object
+type PgSubscriptionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	PgReplicationAPIFactory
+}
+
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgsubscriptions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgsubscriptions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgsubscriptions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (r *PgSubscriptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger := log.FromContext(ctx)
+
+	var subscription apiV1.PgSubscription
+	exists, err := getResource(ctx, r, req.NamespacedName, &subscription)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgSubscription", "subscription", req.NamespacedName.String())
+		return ctrl.Result{}, err
+	}
+	// Handle deleted
+	if !exists {
+		logger.Info("Deleted PgSubscription", "subscription", req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	// Databases of other namespaces cannot be used, they may belong to another tenant of the instance
+	if subscription.Spec.Database.Namespace != subscription.Namespace {
+		if subscription.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &subscription)
+		}
+		message := "The PgDatabase " + subscription.GetDatabaseIdString() + " is not in the namespace " + subscription.Namespace
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionExistsConditionType, false, "DatabaseNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch Database
+	var database apiV1.PgDatabase
+	exists, err = getResource(ctx, r, subscription.GetDatabaseId(), &database)
+	if err != nil {
+		logger.Error(err, "Unable to fetch PgDatabase", "database", subscription.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		// Nothing is left to clean up, if the database resource is gone
+		if subscription.DeletionTimestamp != nil {
+			return ctrl.Result{}, r.removeFinalizer(ctx, &subscription)
+		}
+		message := "The PgDatabase " + subscription.GetDatabaseIdString() + " does not exist"
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		logger.Info("Referenced PgDatabase does not exist", "subscription", subscription.ToNamespacedName(), "database", subscription.GetDatabaseIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Create PgServerApi from instance
+	pgApi, err := r.createPgApi(ctx, &subscription, &database)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Handle finalizing
+	if subscription.DeletionTimestamp != nil {
+		if err := r.finalize(ctx, &subscription, &database, pgApi); err != nil {
+			logger.Info("Unable to finalize", "subscription", req.NamespacedName.String(), "database", subscription.GetDatabaseIdString())
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		// Exit and do not reconcile anymore
+		return ctrl.Result{}, nil
+	}
+
+	// Check if database exists on the instance
+	exists, err = pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if !exists {
+		message := "The database " + database.Name + " does not exist on the instance"
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionExistsConditionType, false, "DatabaseMissing", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Databases of other namespaces cannot be subscribed
+	if source := subscription.Spec.Source.Database; source != nil && source.Namespace != subscription.Namespace {
+		message := "The source PgDatabase " + source.ToNamespacedName().String() + " is not in the namespace " + subscription.Namespace
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionSourceConditionType, false, "SourceNotAllowed", message); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Connect to the publisher
+	sourceApi, sourceInstance, sourceDatabase, err := r.createSourceApi(ctx, &subscription)
+	if err != nil {
+		logger.Error(err, "Unable to connect to the publisher", "subscription", subscription.ToNamespacedName())
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionSourceConditionType, false, "SourceUnavailable", err.Error()); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Read the credentials of the role, which connects to the publisher
+	user, login, reason, err := readUserLogin(ctx, r, subscription.GetUserId())
+	if err == nil && !isSameInstance(&user.Spec.Instance, sourceInstance) {
+		reason = "RoleNotAllowed"
+		err = errors.New("The PgUser " + subscription.GetUserId().String() + " is not a role of the instance of the publisher")
+	}
+	if err != nil {
+		logger.Error(err, "Unable to read the credentials of the role", "subscription", subscription.ToNamespacedName(), "role", subscription.Spec.Source.Role)
+		if reason != "" {
+			if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionSourceConditionType, false, reason, err.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Minute}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionSourceConditionType, true, "SourceAvailable", "-"); err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Create or update Subscription
+	options := pgapi.PgSubscriptionOptions{
+		Connection:   toConnInfo(sourceApi.ConnectionString(), login, sourceDatabase, subscriptionTLSFiles(&subscription)),
+		Publications: subscription.Spec.Publications,
+		Enabled:      subscription.IsEnabled(),
+		CopyData:     subscription.IsCopyData(),
+		CreateSlot:   subscription.IsCreateSlot(),
+		SlotName:     subscription.GetSlotName(),
+	}
+	if err := r.createOrUpdateSubscription(ctx, pgApi, &subscription, &database, options); err != nil {
+		logger.Error(err, "Unable to update subscription", "subscription", subscription.GetSubscriptionName(), "database", database.Name)
+		if err := setCondition(ctx, r.Status(), &subscription, apiV1.PgSubscriptionExistsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update state and lag
+	if err := r.updateStatus(ctx, pgApi, sourceApi, &subscription, &database); err != nil {
+		logger.Error(err, "Unable to update status", "subscription", subscription.ToNamespacedName())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Check if finalizer exists
+	if !controllerutil.ContainsFinalizer(&subscription, apiV1.DefaultFinalizerPgSubscription) {
+		controllerutil.AddFinalizer(&subscription, apiV1.DefaultFinalizerPgSubscription)
+		err = r.Update(ctx, &subscription)
+		if err != nil {
+			logger.Error(err, "Failed to update finalizers", "subscription", subscription.ToNamespacedName())
+			return ctrl.Result{RequeueAfter: time.Second}, err
+		}
+	}
+
+	logger.Info("Processed subscription", "subscription", subscription.ToNamespacedName(), "database", subscription.GetDatabaseIdString())
+
+	// Refresh the state and lag of the replication periodically
+	return ctrl.Result{RequeueAfter: time.Minute}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PgSubscriptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Register Factory Method
+	r.PgReplicationAPIFactory = func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgReplicationAPI, error) {
+		return services.NewPgInstanceAPI(ctx, r, instance)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiV1.PgSubscription{}).
+		Complete(r)
+}
+
+func (r *PgSubscriptionReconciler) createPgApi(ctx context.Context, subscription *apiV1.PgSubscription, database *apiV1.PgDatabase) (PgReplicationAPI, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch Instance
+	instance, err := getInstance(ctx, r, r.Status(), subscription, database.Spec.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to Instance
+	pgApi, err := r.PgReplicationAPIFactory(ctx, r, instance)
+	if err != nil {
+		logger.Error(err, "Unable to connect", "instance", database.GetInstanceIdString())
+		// Update connection status
+		if err := setCondition(ctx, r.Status(), subscription, apiV1.PgConnectedConditionType, false, apiV1.PgConnectedConditionReasonConFailed, err.Error()); err != nil {
+			logger.Error(err, "Unable to update condition", "subscription", subscription.ToNamespacedName())
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Update connection status
+	if err := setCondition(ctx, r.Status(), subscription, apiV1.PgConnectedConditionType, true, apiV1.PgConnectedConditionReasonConSucceeded, "-"); err != nil {
+		logger.Error(err, "Unable to update condition", "subscription", subscription.ToNamespacedName())
+		return nil, err
+	}
+	return pgApi, nil
+}
+
+// createSourceApi connects to the instance of the publisher and returns the instance and the name of the published database
+func (r *PgSubscriptionReconciler) createSourceApi(ctx context.Context, subscription *apiV1.PgSubscription) (PgReplicationAPI, *apiV1.PgInstanceRef, string, error) {
+	source := subscription.Spec.Source
+	instanceRef := source.Instance
+	databaseName := source.DatabaseName
+	if source.Database != nil {
+		var database apiV1.PgDatabase
+		exists, err := getResource(ctx, r, source.Database.ToNamespacedName(), &database)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if !exists {
+			return nil, nil, "", errors.New("The source PgDatabase " + source.Database.ToNamespacedName().String() + " does not exist")
+		}
+		instanceRef = &database.Spec.Instance
+		databaseName = database.Name
+	}
+	if instanceRef == nil {
+		return nil, nil, "", errors.New("The source of the subscription is not specified")
+	}
+
+	// The namespace of the subscription has to be allowed to use the instance of the publisher
+	instance, err := getInstance(ctx, r, r.Status(), subscription, *instanceRef)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	sourceApi, err := r.PgReplicationAPIFactory(ctx, r, instance)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return sourceApi, instanceRef, databaseName, nil
+}
+
+// subscriptionTLSFiles returns the paths of the certificates on the server of the subscriber,
+// which are used to verify the publisher
+func subscriptionTLSFiles(subscription *apiV1.PgSubscription) map[string]string {
+	files := map[string]string{}
+	if subscription.Spec.Source.SSLRootCert != "" {
+		files["sslrootcert"] = subscription.Spec.Source.SSLRootCert
+	}
+	if subscription.Spec.Source.SSLCRL != "" {
+		files["sslcrl"] = subscription.Spec.Source.SSLCRL
+	}
+	return files
+}
+
+func (r *PgSubscriptionReconciler) finalize(ctx context.Context, subscription *apiV1.PgSubscription, database *apiV1.PgDatabase, pgApi PgReplicationAPI) error {
+	logger := log.FromContext(ctx)
+	subscriptionName := subscription.GetSubscriptionName()
+
+	exists, err := pgApi.IsDatabaseExisting(database.Name)
+	if err != nil {
+		logger.Error(err, "Unable to query database", "database", database.Name, "instance", database.GetInstanceIdString())
+		return err
+	}
+	if exists {
+		exists, err = pgApi.IsSubscriptionExisting(database.Name, subscriptionName)
+		if err != nil {
+			logger.Error(err, "Unable to query subscription", "subscription", subscriptionName, "database", database.Name)
+			return err
+		}
+	}
+	if exists {
+		// Dropping the replication slot requires a connection to the publisher
+		if err := pgApi.DeleteSubscription(database.Name, subscriptionName, !subscription.Spec.Slot.Retain); err != nil {
+			logger.Error(err, "Unable to remove subscription", "subscription", subscriptionName, "database", database.Name)
+			if err := setCondition(ctx, r.Status(), subscription, apiV1.PgSubscriptionExistsConditionType, true, "DeletionFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	return r.removeFinalizer(ctx, subscription)
+}
+
+func (r *PgSubscriptionReconciler) removeFinalizer(ctx context.Context, subscription *apiV1.PgSubscription) error {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(subscription, apiV1.DefaultFinalizerPgSubscription) {
+		return nil
+	}
+	controllerutil.RemoveFinalizer(subscription, apiV1.DefaultFinalizerPgSubscription)
+	if err := r.Update(ctx, subscription); err != nil {
+		logger.Error(err, "Failed to update finalizers")
+		return err
+	}
+	logger.Info("Removed finalizer, subscription resource can now be deleted")
+	return nil
+}
+
+// createOrUpdateSubscription creates the subscription or aligns the connection, publications and state of an existing subscription
+func (r *PgSubscriptionReconciler) createOrUpdateSubscription(ctx context.Context, pgApi PgReplicationAPI, subscription *apiV1.PgSubscription, database *apiV1.PgDatabase, options pgapi.PgSubscriptionOptions) error {
+	logger := log.FromContext(ctx)
+	subscriptionName := subscription.GetSubscriptionName()
+
+	exists, err := pgApi.IsSubscriptionExisting(database.Name, subscriptionName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := pgApi.CreateSubscription(database.Name, subscriptionName, options); err != nil {
+			return err
+		}
+		logger.Info("Created subscription " + subscriptionName + " in database " + database.Name)
+	} else if err := pgApi.UpdateSubscription(database.Name, subscriptionName, options); err != nil {
+		return err
+	}
+	return setCondition(ctx, r.Status(), subscription, apiV1.PgSubscriptionExistsConditionType, true, "SubscriptionExists", "-")
+}
+
+// updateStatus reports the state of the subscription and the lag of its replication slot
+func (r *PgSubscriptionReconciler) updateStatus(ctx context.Context, pgApi PgReplicationAPI, sourceApi PgReplicationAPI, subscription *apiV1.PgSubscription, database *apiV1.PgDatabase) error {
+	logger := log.FromContext(ctx)
+	state, err := pgApi.GetSubscriptionState(database.Name, subscription.GetSubscriptionName())
+	if err != nil {
+		return err
+	}
+
+	status := &subscription.Status
+	status.Enabled = state.Enabled
+	status.ReceivedLsn = state.ReceivedLsn
+	status.LatestEndLsn = state.LatestEndLsn
+	status.LatestEndTime = nil
+	if state.LatestEndTime != nil {
+		latestEndTime := metaV1.NewTime(*state.LatestEndTime)
+		status.LatestEndTime = &latestEndTime
+	}
+	status.Tables = []apiV1.PgSubscriptionTableStatus{}
+	ready := true
+	for table, code := range state.Tables {
+		tableState, found := subscriptionTableStates[code]
+		if !found {
+			tableState = code
+		}
+		ready = ready && code == "r"
+		status.Tables = append(status.Tables, apiV1.PgSubscriptionTableStatus{Name: table, State: tableState})
+	}
+	sort.Slice(status.Tables, func(i, j int) bool { return status.Tables[i].Name < status.Tables[j].Name })
+
+	// The lag is only known to the publisher
+	status.LagBytes = nil
+	if state.SlotName != "" {
+		lag, exists, err := sourceApi.GetReplicationSlotLag(state.SlotName)
+		if err != nil {
+			logger.Error(err, "Unable to query the replication slot", "slot", state.SlotName)
+		} else if exists {
+			status.LagBytes = &lag
+		}
+	}
+
+	switch {
+	case !state.Enabled:
+		putCondition(subscription, apiV1.PgSubscriptionReplicatingConditionType, false, "Disabled", "The subscription is disabled")
+	case !ready:
+		putCondition(subscription, apiV1.PgSubscriptionReplicatingConditionType, false, "Synchronizing", "The initial synchronization of tables is running")
+	default:
+		putCondition(subscription, apiV1.PgSubscriptionReplicatingConditionType, true, "Replicating", "-")
+	}
+	return r.Status().Update(ctx, subscription)
+}
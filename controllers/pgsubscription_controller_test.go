@@ -0,0 +1,264 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("PgSubscriptionReconciler", func() {
+
+	var pgApiMock *pgReplicationMock
+	var reconciler *PgSubscriptionReconciler
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "orders",
+		},
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pgApiMock = newPgReplicationMock("dummy", "source")
+
+		// Create Reconciler
+		reconciler = &PgSubscriptionReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgReplicationAPI, error) {
+				return pgApiMock, nil
+			},
+		}
+		createJobTestFixtures(ctx)
+
+		source := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "source",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, &source)
+		Expect(err).To(BeNil())
+		subscription := apiV1.PgSubscription{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "orders",
+			},
+			Spec: apiV1.PgSubscriptionSpec{
+				Database: apiV1.PgDatabaseRef{Namespace: "default", Name: "dummy"},
+				Source: apiV1.PgSubscriptionSource{
+					Database:    &apiV1.PgDatabaseRef{Namespace: "default", Name: "source"},
+					Role:        "dummy-owner",
+					SSLRootCert: "/etc/postgresql/root.crt",
+				},
+				Publications: []string{"orders"},
+			},
+		}
+		err = k8sClient.Create(ctx, &subscription)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		deleteJobTestFixtures(ctx)
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("creates the subscription and reports its state", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		pgApiMock.subscriptionTables["public.orders"] = "r"
+		pgApiMock.slotLag = 42
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		options := pgApiMock.subscriptions["orders"]
+		Expect(options.Connection).To(ContainSubstring("dbname='source'"))
+		Expect(options.Connection).To(ContainSubstring("user='dummy-owner' password='secret'"))
+		Expect(options.Connection).To(ContainSubstring("sslrootcert='/etc/postgresql/root.crt'"))
+		Expect(options.Publications).To(Equal([]string{"orders"}))
+		Expect(options.Enabled).To(BeTrue())
+		Expect(options.CreateSlot).To(BeTrue())
+		Expect(options.SlotName).To(Equal("orders"))
+
+		// and
+		subscription := apiV1.PgSubscription{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		Expect(subscription.Finalizers).To(HaveLen(1))
+		Expect(subscription.Status.Enabled).To(BeTrue())
+		Expect(*subscription.Status.LagBytes).To(Equal(int64(42)))
+		Expect(subscription.Status.Tables).To(Equal([]apiV1.PgSubscriptionTableStatus{{Name: "public.orders", State: "Ready"}}))
+		condition := meta.FindStatusCondition(subscription.Status.Conditions, apiV1.PgSubscriptionReplicatingConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("refuses a database in another namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		subscription := apiV1.PgSubscription{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		subscription.Spec.Database.Namespace = "tenant"
+		err = k8sClient.Update(ctx, &subscription)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(pgApiMock.subscriptions).To(BeEmpty())
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(subscription.Status.Conditions, apiV1.PgSubscriptionExistsConditionType)
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("DatabaseNotAllowed"))
+	})
+
+	It("reports a missing source database", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		subscription := apiV1.PgSubscription{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		subscription.Spec.Source.Database.Name = "missing"
+		err = k8sClient.Update(ctx, &subscription)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(pgApiMock.subscriptions).To(BeEmpty())
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(subscription.Status.Conditions, apiV1.PgSubscriptionSourceConditionType)
+		Expect(condition.Reason).To(Equal("SourceUnavailable"))
+	})
+
+	It("refuses a role of another instance", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "other-user"},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "other"},
+				Secret:   &apiV1.PgUserSecret{Name: "dummy-owner-credentials"},
+			},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+		subscription := apiV1.PgSubscription{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		subscription.Spec.Source.Role = "other-user"
+		err = k8sClient.Update(ctx, &subscription)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+		Expect(pgApiMock.subscriptions).To(BeEmpty())
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(subscription.Status.Conditions, apiV1.PgSubscriptionSourceConditionType)
+		Expect(condition.Reason).To(Equal("RoleNotAllowed"))
+	})
+
+	It("disables the subscription", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		subscription := apiV1.PgSubscription{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		subscription.Spec.Enabled = &cFalse
+		err = k8sClient.Update(ctx, &subscription)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsUpdateSubscription).To(Equal(1))
+		Expect(pgApiMock.subscriptions["orders"].Enabled).To(BeFalse())
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		condition := meta.FindStatusCondition(subscription.Status.Conditions, apiV1.PgSubscriptionReplicatingConditionType)
+		Expect(condition.Reason).To(Equal("Disabled"))
+	})
+
+	It("retains the replication slot on finalize", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		subscription := apiV1.PgSubscription{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &subscription)
+		Expect(err).To(BeNil())
+		subscription.Spec.Slot.Retain = true
+		err = k8sClient.Update(ctx, &subscription)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &subscription)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.callsDeleteSubscription).To(Equal(1))
+		Expect(pgApiMock.dropSlot).To(BeFalse())
+	})
+})
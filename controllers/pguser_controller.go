@@ -20,18 +20,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	coreV1 "k8s.io/api/core/v1"
 	kErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
 	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
@@ -41,16 +47,24 @@ import (
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// passwordSecretIndexField is the field index of PgUsers by the namespaced name of their password Secret
+const passwordSecretIndexField = "spec.passwordSecretRef"
+
 // PgUserReconciler reconciles a PgUser object
 type PgUserReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	PgRoleAPIFactory
+	// DefaultPasswordPolicy is the operator-wide password policy, unset fields are taken from security.DefaultPasswordPolicy
+	DefaultPasswordPolicy security.PasswordPolicy
 }
 
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgusers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=clusterpginstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=postgres.brose.bike,resources=pgdatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 
@@ -112,8 +126,25 @@ func (r *PgUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	// update login role with password in postgres instance
-	if err := pgApi.UpdateUserPassword(user.Name, password); err != nil {
-		logger.Error(err, "Unable to update role password for role "+user.Name+" on instance "+user.GetInstanceIdString())
+	if err := pgApi.UpdateUserPassword(user.ActiveRoleName(), password); err != nil {
+		logger.Error(err, "Unable to update role password for role "+user.ActiveRoleName()+" on instance "+user.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// rotate password if the rotation is due
+	rotationRequeueAfter, err := r.handleRotation(ctx, pgApi, &user)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// update role attributes in postgres instance
+	if err := r.updateAttributes(ctx, pgApi, &user); err != nil {
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
+	// Update memberships in group roles
+	if err := updateMemberships(ctx, r.Client, &user, pgApi, user.Name, &user.Spec.Instance, user.Spec.MemberOf, apiV1.PgUserMembershipsConditionType); err != nil {
+		logger.Error(err, "Unable to update memberships", "user", user.ToNamespacedName(), "instance", user.GetInstanceIdString())
 		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
@@ -131,6 +162,12 @@ func (r *PgUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{RequeueAfter: time.Minute}, err
 	}
 
+	// update settings in all databases and in the listed databases
+	if err := r.handleSettings(ctx, pgApi, &user); err != nil {
+		logger.Error(err, "Unable to update settings", "user", user.ToNamespacedName(), "instance", user.GetInstanceIdString())
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
 	// Check if finalizer exists
 	if !controllerutil.ContainsFinalizer(&user, apiV1.DefaultFinalizerPgUser) {
 		controllerutil.AddFinalizer(&user, apiV1.DefaultFinalizerPgUser)
@@ -142,7 +179,7 @@ func (r *PgUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	logger.Info("Processed user", "user", user.ToNamespacedName(), "instance", user.GetInstanceIdString())
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: rotationRequeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -152,25 +189,53 @@ func (r *PgUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return services.NewPgInstanceAPI(ctx, r, instance)
 	}
 
+	// Index the password Secrets, so Secret events do not list all PgUsers
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &apiV1.PgUser{}, passwordSecretIndexField, indexPasswordSecret); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&apiV1.PgUser{}).
+		// Changed passwords in referenced Secrets are applied immediately
+		Watches(&source.Kind{Type: &coreV1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.mapPasswordSecret)).
 		Complete(r)
 }
 
+// indexPasswordSecret returns the namespaced name of the password Secret of the given PgUser
+func indexPasswordSecret(obj client.Object) []string {
+	user, ok := obj.(*apiV1.PgUser)
+	if !ok || user.Spec.PasswordSecretRef == nil {
+		return nil
+	}
+	return []string{user.GetPasswordSecretId().String()}
+}
+
+// mapPasswordSecret maps a Secret to the PgUsers which read their password from it
+func (r *PgUserReconciler) mapPasswordSecret(obj client.Object) []reconcile.Request {
+	secretId := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	var users apiV1.PgUserList
+	if err := r.List(context.Background(), &users, client.MatchingFields{passwordSecretIndexField: secretId.String()}); err != nil {
+		log.Log.Error(err, "Unable to list PgUsers", "secret", secretId.String())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, user := range users.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: user.Namespace, Name: user.Name}})
+	}
+	return requests
+}
+
 func (r *PgUserReconciler) createPgApi(ctx context.Context, user *apiV1.PgUser) (PgRoleAPI, error) {
 	logger := log.FromContext(ctx)
 
 	// Fetch Instance
-	instanceId := user.GetInstanceId()
-	var instance apiV1.PgInstance
-	exists, err := getResource(ctx, r, instanceId, &instance)
-	if !exists || err != nil {
-		logger.Error(err, "Unable to fetch PgInstance", "instance", instanceId.String())
+	instance, err := getInstance(ctx, r, r.Status(), user, user.Spec.Instance)
+	if err != nil {
 		return nil, err
 	}
 
 	// Connect to Instance
-	pgApi, err := r.PgRoleAPIFactory(ctx, r, &instance)
+	pgApi, err := r.PgRoleAPIFactory(ctx, r, instance)
 	if err != nil {
 		logger.Error(err, "Unable to connect", "instance", instance.Namespace+"/"+instance.Name)
 		// Update connection status
@@ -189,40 +254,50 @@ func (r *PgUserReconciler) createPgApi(ctx context.Context, user *apiV1.PgUser)
 	return pgApi, nil
 }
 
-func (r *PgUserReconciler) finalize(ctx context.Context, user *apiV1.PgUser, pgApi pgapi.PgRoleAPI) error {
+func (r *PgUserReconciler) finalize(ctx context.Context, user *apiV1.PgUser, pgApi PgRoleAPI) error {
 	logger := log.FromContext(ctx)
+	deletion := user.Spec.DeletionBehavior
 
-	// Delete only if user exists
-	exists, err := pgApi.IsRoleExisting(user.Name)
-	if err != nil {
-		logger.Error(err, fmt.Sprintf("Unable to check user`s existence %s from %s", user.Name, user.GetInstanceIdString()))
-		return err
+	// The owned objects are only reassigned to an unprivileged role managed in the namespace of the user
+	if deletion.GetPolicy() == apiV1.ReassignUserDeletionPolicy {
+		if err := r.checkReassignTo(ctx, pgApi, user); err != nil {
+			return err
+		}
 	}
 
-	if exists {
-		if err := pgApi.DeleteRole(user.Name); err != nil {
-			logger.Error(err, fmt.Sprintf("Unable to remove login role %s from %s", user.Name, user.GetInstanceIdString()))
+	// Finalize the login role and the alternate login role of the password rotation
+	roleNames := []string{user.Name}
+	if user.Spec.Rotation != nil || user.Status.ActiveRole != "" {
+		roleNames = append(roleNames, user.AlternateRoleName())
+	}
+	for _, roleName := range roleNames {
+		if err := r.finalizeRole(ctx, pgApi, user, roleName); err != nil {
 			return err
 		}
 	}
 
 	// Update Login Role Exists Condition
-	if err := setCondition(ctx, r.Status(), user, apiV1.PgUserExistsConditionType, false, "MissingUser", "-"); err != nil {
-		logger.Error(err, "Unable to update condition")
-		return err
+	policy := deletion.GetPolicy()
+	if policy == apiV1.DropUserDeletionPolicy || policy == apiV1.ReassignUserDeletionPolicy {
+		if err := setCondition(ctx, r.Status(), user, apiV1.PgUserExistsConditionType, false, "MissingUser", "-"); err != nil {
+			logger.Error(err, "Unable to update condition")
+			return err
+		}
 	}
 
 	// Delete Secret if exists
-	roleSecret := coreV1.Secret{}
-	exists, err = getResource(ctx, r, types.NamespacedName{Namespace: user.Namespace, Name: user.Spec.Secret.Name}, &roleSecret)
-	if err != nil {
-		return err
-	}
-	if exists {
-		if err := r.Delete(ctx, &roleSecret); err != nil {
-			logger.Error(err, "Unable to delete Secret")
+	if user.Spec.Secret != nil && !deletion.RetainSecret {
+		roleSecret := coreV1.Secret{}
+		exists, err := getResource(ctx, r, types.NamespacedName{Namespace: user.Namespace, Name: user.Spec.Secret.Name}, &roleSecret)
+		if err != nil {
 			return err
 		}
+		if exists {
+			if err := r.Delete(ctx, &roleSecret); err != nil {
+				logger.Error(err, "Unable to delete Secret")
+				return err
+			}
+		}
 	}
 
 	// Remove finalizer
@@ -235,9 +310,107 @@ func (r *PgUserReconciler) finalize(ctx context.Context, user *apiV1.PgUser, pgA
 	return nil
 }
 
-func (r *PgUserReconciler) createLoginRoleIfNotExists(ctx context.Context, pgApi pgapi.PgRoleAPI, user *apiV1.PgUser) error {
+// checkReassignTo returns an error if the owned objects of the user must not be reassigned to the configured role,
+// which is the case for the role of the operator, superusers, predefined roles and roles of other namespaces
+func (r *PgUserReconciler) checkReassignTo(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) error {
+	reassignTo := user.Spec.DeletionBehavior.ReassignTo
+	message, err := checkPrivilegedRole(pgApi, reassignTo)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		managed, err := isRoleManagedInNamespace(ctx, r, user.Namespace, &user.Spec.Instance, reassignTo)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			message = "The role " + reassignTo + " is not managed by a PgRole or PgUser in the namespace " + user.Namespace
+		}
+	}
+	if message == "" {
+		return nil
+	}
+	err = errors.New(message + ", the owned objects cannot be reassigned to it")
+	log.FromContext(ctx).Error(err, "Unable to reassign the owned objects", "user", user.ToNamespacedName())
+	if err := setCondition(ctx, r.Status(), user, apiV1.PgUserDeletionConditionType, false, "ReassignNotAllowed", err.Error()); err != nil {
+		return err
+	}
+	return err
+}
+
+// finalizeRole applies the deletion policy of the user to the given login role
+func (r *PgUserReconciler) finalizeRole(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser, roleName string) error {
+	logger := log.FromContext(ctx)
+	deletion := user.Spec.DeletionBehavior
+
+	// Finalize only if role exists
+	exists, err := pgApi.IsRoleExisting(roleName)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to check user`s existence %s from %s", roleName, user.GetInstanceIdString()))
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	// The role of the operator and superusers are kept, even if they were adopted before they were refused
+	message, err := checkPrivilegedRole(pgApi, roleName)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to query login role %s", roleName))
+		return err
+	}
+	if message != "" {
+		logger.Info(fmt.Sprintf("Keeping login role %s: %s", roleName, message))
+		return nil
+	}
+
+	// The alternate login role is only removed, if it was created or adopted by the user
+	comment, err := pgApi.GetRoleComment(roleName)
+	if err != nil {
+		return err
+	}
+	if roleName == user.AlternateRoleName() && !isAlternateRoleManaged(user, comment) {
+		logger.Info(fmt.Sprintf("Keeping login role %s, which is not managed by the user", roleName))
+		return nil
+	}
+
+	switch deletion.GetPolicy() {
+	case apiV1.RetainUserDeletionPolicy:
+		logger.Info(fmt.Sprintf("Retaining login role %s on %s", roleName, user.GetInstanceIdString()))
+	case apiV1.DisableUserDeletionPolicy:
+		if err := pgApi.DisableRoleLogin(roleName); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to disable login role %s on %s", roleName, user.GetInstanceIdString()))
+			return err
+		}
+	case apiV1.ReassignUserDeletionPolicy:
+		if err := pgApi.ReassignAndDeleteRole(roleName, deletion.ReassignTo); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to remove login role %s from %s", roleName, user.GetInstanceIdString()))
+			return reportRoleCleanup(ctx, r.Status(), user, apiV1.PgUserDeletionConditionType, err)
+		}
+		return nil
+	default:
+		if err := pgApi.DeleteRole(roleName); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to remove login role %s from %s", roleName, user.GetInstanceIdString()))
+			return reportRoleCleanup(ctx, r.Status(), user, apiV1.PgUserDeletionConditionType, err)
+		}
+		return nil
+	}
+
+	// Release the retained role, so it can be adopted by another resource
+	if apiV1.ParseOwnershipMarker(comment) == user.UID {
+		if err := pgApi.UpdateRoleComment(roleName, apiV1.SetOwnershipMarker(comment, "")); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to release login role %s on %s", roleName, user.GetInstanceIdString()))
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PgUserReconciler) createLoginRoleIfNotExists(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) error {
 	logger := log.FromContext(ctx)
 	roleName := user.Name
+	policy := user.Spec.GetAdoptionPolicy()
+	description := "Role " + roleName
 
 	exists, err := pgApi.IsRoleExisting(roleName)
 	if err != nil {
@@ -246,14 +419,50 @@ func (r *PgUserReconciler) createLoginRoleIfNotExists(ctx context.Context, pgApi
 	}
 
 	// create roles
+	comment := ""
 	if !exists {
+		if reason, err := checkMissingOwnership(policy, description); err != nil {
+			return refuseOwnership(ctx, r.Status(), user, apiV1.PgUserOwnershipConditionType, reason, err)
+		}
 		if err := pgApi.CreateRole(roleName); err != nil {
 			logger.Error(err, fmt.Sprintf("Unable to create login role %s", roleName))
 			return err
 		}
 		logger.Info(fmt.Sprintf("Created login role %s", roleName))
+	} else {
+		// The role of the operator and superusers are never managed by a resource, even if it is marked as their owner
+		message, err := checkPrivilegedRole(pgApi, roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query login role %s", roleName))
+			return err
+		}
+		if message != "" {
+			err := errors.New(message + " and cannot be managed by a PgUser")
+			logger.Error(err, fmt.Sprintf("Refused to manage login role %s", roleName))
+			return refuseOwnership(ctx, r.Status(), user, apiV1.PgUserOwnershipConditionType, "PrivilegedRole", err)
+		}
+		comment, err = pgApi.GetRoleComment(roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query comment of login role %s", roleName))
+			return err
+		}
+		adopt, reason, err := checkOwnership(user, policy, comment, description, apiV1.PgUserExistsConditionType)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Refused to manage login role %s", roleName))
+			return refuseOwnership(ctx, r.Status(), user, apiV1.PgUserOwnershipConditionType, reason, err)
+		}
+		if !adopt {
+			return acceptOwnership(ctx, r.Status(), user, apiV1.PgUserOwnershipConditionType)
+		}
+		logger.Info(fmt.Sprintf("Adopting existing login role %s", roleName))
 	}
-	return nil
+
+	// Mark role as managed by this resource and keep the existing comment
+	if err := pgApi.UpdateRoleComment(roleName, apiV1.SetOwnershipMarker(comment, user.UID)); err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to mark login role %s", roleName))
+		return err
+	}
+	return acceptOwnership(ctx, r.Status(), user, apiV1.PgUserOwnershipConditionType)
 }
 
 func (r *PgUserReconciler) createOrUpdateSecret(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) (string, error) {
@@ -261,17 +470,33 @@ func (r *PgUserReconciler) createOrUpdateSecret(ctx context.Context, pgApi PgRol
 	roleName := user.Name
 	password := ""
 
+	// Read the password from the referenced Secret instead of generating one
+	externalPassword, err := r.readPasswordSecret(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
 	var roleSecret coreV1.Secret
 	secretKey := types.NamespacedName{
 		Namespace: user.Namespace,
 		Name:      user.Spec.Secret.Name,
 	}
-	err := r.Get(ctx, secretKey, &roleSecret)
+	err = r.Get(ctx, secretKey, &roleSecret)
 	if err != nil && !kErrors.IsNotFound(err) {
 		logger.Error(err, fmt.Sprintf("Unable to fetch role secret for login role %s", roleName))
 		return "", err
 	} else if err != nil && kErrors.IsNotFound(err) { // Create Secret
-		password = security.GeneratePassword()
+		password = externalPassword
+		if user.Spec.PasswordSecretRef == nil {
+			password, err = r.generatePassword(ctx, user)
+			if err != nil {
+				return "", err
+			}
+		}
+		data, err := r.generateSecretData(ctx, pgApi, user, user.ActiveRoleName(), password)
+		if err != nil {
+			return "", err
+		}
 		roleSecret = coreV1.Secret{
 			ObjectMeta: metaV1.ObjectMeta{
 				Namespace: secretKey.Namespace,
@@ -287,7 +512,7 @@ func (r *PgUserReconciler) createOrUpdateSecret(ctx context.Context, pgApi PgRol
 					},
 				},
 			},
-			Data: r.generateSecretData(pgApi, user, password),
+			Data: data,
 		}
 		if err := r.Create(ctx, &roleSecret); err != nil {
 			logger.Error(err, fmt.Sprintf("Unable to create role secret for login role %s", roleName))
@@ -307,7 +532,14 @@ func (r *PgUserReconciler) createOrUpdateSecret(ctx context.Context, pgApi PgRol
 		}
 		// Update Data
 		password = string(roleSecret.Data["password"])
-		roleSecret.Data = r.generateSecretData(pgApi, user, password)
+		if user.Spec.PasswordSecretRef != nil {
+			password = externalPassword
+		}
+		data, err := r.generateSecretData(ctx, pgApi, user, user.ActiveRoleName(), password)
+		if err != nil {
+			return "", err
+		}
+		roleSecret.Data = data
 		err = r.Update(ctx, &roleSecret)
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("Unable to update role secret for login role %s", roleName))
@@ -317,25 +549,86 @@ func (r *PgUserReconciler) createOrUpdateSecret(ctx context.Context, pgApi PgRol
 	return password, nil
 }
 
-func (r *PgUserReconciler) generateSecretData(pgApi PgRoleAPI, user *apiV1.PgUser, password string) map[string][]byte {
+// readPasswordSecret reads the password from the Secret referenced by the user, it returns an empty password
+// if no Secret is referenced. A Secret in another namespace has to allow the namespace of the user.
+func (r *PgUserReconciler) readPasswordSecret(ctx context.Context, user *apiV1.PgUser) (string, error) {
+	logger := log.FromContext(ctx)
+	if user.Spec.PasswordSecretRef == nil {
+		if meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordSecretConditionType) != nil {
+			return "", removeCondition(ctx, r.Status(), user, apiV1.PgUserPasswordSecretConditionType)
+		}
+		return "", nil
+	}
+	secretId := user.GetPasswordSecretId()
+	key := user.Spec.PasswordSecretRef.GetKey()
+
+	var secret coreV1.Secret
+	exists, err := getResource(ctx, r, secretId, &secret)
+	if err != nil {
+		logger.Error(err, "Unable to fetch password Secret", "secret", secretId.String())
+		return "", err
+	}
+	reason := ""
+	if !exists {
+		reason, err = "SecretMissing", errors.New("Secret "+secretId.String()+" does not exist")
+	} else if secretId.Namespace != user.Namespace && !isNamespaceAllowedBySecret(&secret, user.Namespace) {
+		reason, err = "NamespaceNotAllowed", errors.New("Namespace "+user.Namespace+" is not allowed to use the Secret "+secretId.String())
+	} else if len(secret.Data[key]) == 0 {
+		reason, err = "KeyMissing", errors.New("Secret "+secretId.String()+" does not contain the key "+key)
+	}
+	if err != nil {
+		logger.Error(err, "Unable to read password Secret", "user", user.ToNamespacedName())
+		if err := setCondition(ctx, r.Status(), user, apiV1.PgUserPasswordSecretConditionType, false, reason, err.Error()); err != nil {
+			return "", err
+		}
+		return "", err
+	}
+	if err := setCondition(ctx, r.Status(), user, apiV1.PgUserPasswordSecretConditionType, true, "SecretRead", "-"); err != nil {
+		return "", err
+	}
+	return string(secret.Data[key]), nil
+}
+
+func (r *PgUserReconciler) generateSecretData(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser, roleName string, password string) (map[string][]byte, error) {
 	data := map[string]string{}
 	connStr := pgApi.ConnectionString()
 	portStr := strconv.Itoa(connStr.Port())
+	// Render the layout of the preset and templates
+	if user.Spec.Secret.HasLayout() {
+		database := user.Spec.Secret.Database
+		if database == "" && len(user.Spec.Databases) > 0 {
+			database = user.Spec.Databases[0].Name
+		}
+		templateData := newSecretTemplateData(connStr.Hostname(), portStr, roleName, password, database, connStr.SSLMode())
+		binaryData, err := renderSecretTemplates(user.Spec.Secret.Preset, user.Spec.Secret.Templates, templateData)
+		if err != nil {
+			if err := setCondition(ctx, r.Status(), user, apiV1.PgUserSecretConditionType, false, "TemplateFailed", err.Error()); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+		// The password is always stored, because it is read from the secret on every reconciliation
+		binaryData["password"] = []byte(password)
+		if err := setCondition(ctx, r.Status(), user, apiV1.PgUserSecretConditionType, true, "TemplatesRendered", "-"); err != nil {
+			return nil, err
+		}
+		return binaryData, nil
+	}
 	data["host"] = connStr.Hostname()
 	data["port"] = portStr
-	data["user"] = user.Name
+	data["user"] = roleName
 	data["password"] = password
 	// Generate Connection Strings for Databases
 	for _, database := range user.Spec.Databases {
 		data["database."+database.Name+".uri"] = connStr.Hostname() + ":" + portStr + "/" + database.Name + "?sslmode=" + connStr.SSLMode()
-		data["database."+database.Name+".connection_string"] = "postgres://" + user.Name + ":" + password + "@" + connStr.Hostname() + ":" + portStr + "/" + database.Name + "?sslmode=" + connStr.SSLMode()
+		data["database."+database.Name+".connection_string"] = "postgres://" + urlEscape(roleName) + ":" + urlEscape(password) + "@" + connStr.Hostname() + ":" + portStr + "/" + database.Name + "?sslmode=" + connStr.SSLMode()
 		data["database."+database.Name+".jdbc_connection_string"] = "jdbc:postgresql://" + connStr.Hostname() + ":" + portStr + "/" + database.Name + "?sslmode=" + connStr.SSLMode()
 	}
 	binaryData := map[string][]byte{}
 	for key, element := range data {
 		binaryData[key] = []byte(element)
 	}
-	return binaryData
+	return binaryData, nil
 }
 
 func (r *PgUserReconciler) checkIfDatabasesExist(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) (bool, error) {
@@ -382,7 +675,12 @@ func (r *PgUserReconciler) updateDatabaseOwnershipAndPrivileges(ctx context.Cont
 			return err
 		}
 
-		// Update ownership
+		// Update ownership, unless the owner is set by a PgDatabase
+		ownerManaged, err := r.isDatabaseOwnerManaged(ctx, user, database.Name)
+		if err != nil {
+			logger.Error(err, "Unable to list PgDatabases")
+			return err
+		}
 		currentOwner, err := pgApi.GetDatabaseOwner(database.Name)
 		if err != nil {
 			logger.Error(err, "Unable to query for the database "+database.Name)
@@ -390,7 +688,9 @@ func (r *PgUserReconciler) updateDatabaseOwnershipAndPrivileges(ctx context.Cont
 		}
 		// Case 1: Login Role should be owner of database and is currently owner of database  => Do nothing
 		// Case 2: Login Role should not be owner of database and is currently not owner of database => Do nothing
-		if currentOwner != user.Name && database.IsOwner() { // Case 3: Login Role should be owner of database and is currently not owner of database
+		if ownerManaged {
+			logger.Info("Skipped owner of database "+database.Name+", because it is set by a PgDatabase", "user", user.ToNamespacedName())
+		} else if currentOwner != user.Name && database.IsOwner() { // Case 3: Login Role should be owner of database and is currently not owner of database
 			if err := pgApi.UpdateDatabaseOwner(database.Name, user.Name); err != nil {
 				logger.Error(err, "Unable to update database owner")
 				return err
@@ -417,6 +717,483 @@ func (r *PgUserReconciler) updateDatabaseOwnershipAndPrivileges(ctx context.Cont
 				return err
 			}
 		}
+
+		// Update schema and object privileges
+		if err := r.updateSchemaPrivileges(ctx, pgApi, user, &database); err != nil {
+			return err
+		}
+	}
+	return r.revokeRemovedSchemaPrivileges(ctx, pgApi, user)
+}
+
+func (r *PgUserReconciler) updateSchemaPrivileges(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser, database *apiV1.PgUserDatabase) error {
+	logger := log.FromContext(ctx)
+	for _, schema := range database.Schemas {
+		exists, err := pgApi.IsSchemaInDatabase(database.Name, schema.Name)
+		if err != nil {
+			logger.Error(err, "Unable to query for the schema "+schema.Name+" in database "+database.Name)
+			return err
+		}
+		if !exists {
+			err = errors.New("Schema " + schema.Name + " does not exist in database " + database.Name)
+			logger.Error(err, "Schema "+schema.Name+" does not exist in database "+database.Name)
+			return err
+		}
+		// Update schema privileges
+		if err := pgApi.UpdateSchemaPrivileges(database.Name, schema.Name, user.Name, schema.PrivilegesStr()); err != nil {
+			logger.Error(err, "Unable to update schema privileges")
+			return err
+		}
+		// Update privileges on all tables, sequences and functions
+		if err := pgApi.UpdatePrivilegesOnAllObjects(database.Name, schema.Name, user.Name, "TABLES", schema.TablePrivilegesStr()); err != nil {
+			logger.Error(err, "Unable to update table privileges")
+			return err
+		}
+		if err := pgApi.UpdatePrivilegesOnAllObjects(database.Name, schema.Name, user.Name, "SEQUENCES", schema.SequencePrivilegesStr()); err != nil {
+			logger.Error(err, "Unable to update sequence privileges")
+			return err
+		}
+		if err := pgApi.UpdatePrivilegesOnAllObjects(database.Name, schema.Name, user.Name, "FUNCTIONS", schema.FunctionPrivilegesStr()); err != nil {
+			logger.Error(err, "Unable to update function privileges")
+			return err
+		}
+		// Update privileges on specific tables, which extend the privileges on all tables
+		for _, table := range schema.Tables {
+			privileges := append(schema.TablePrivilegesStr(), table.PrivilegesStr()...)
+			if err := pgApi.UpdateTablePrivileges(database.Name, schema.Name, table.Name, user.Name, privileges); err != nil {
+				logger.Error(err, "Unable to update privileges on table "+table.Name)
+				return err
+			}
+		}
 	}
 	return nil
 }
+
+// isDatabaseOwnerManaged returns true if a PgDatabase on the instance of the user sets the owner of the database
+// with the given name, in this case the owner flag of the user is ignored
+func (r *PgUserReconciler) isDatabaseOwnerManaged(ctx context.Context, user *apiV1.PgUser, databaseName string) (bool, error) {
+	var databases apiV1.PgDatabaseList
+	if err := r.List(ctx, &databases); err != nil {
+		return false, err
+	}
+	for _, database := range databases.Items {
+		if database.Name == databaseName && database.Spec.Owner != "" && isSameInstance(&database.Spec.Instance, &user.Spec.Instance) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// revokeRemovedSchemaPrivileges revokes the privileges on the schemas, which were removed from the spec,
+// and persists the names of the schemas in the spec
+func (r *PgUserReconciler) revokeRemovedSchemaPrivileges(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) error {
+	logger := log.FromContext(ctx)
+	desired := make(map[string]bool)
+	var applied []apiV1.PgUserSchemaStatus
+	for _, database := range user.Spec.Databases {
+		names := make([]string, 0, len(database.Schemas))
+		for _, schema := range database.Schemas {
+			names = append(names, schema.Name)
+			desired[database.Name+"/"+schema.Name] = true
+		}
+		if len(names) > 0 {
+			applied = append(applied, apiV1.PgUserSchemaStatus{Database: database.Name, Names: names})
+		}
+	}
+
+	for _, previous := range user.Status.Schemas {
+		for _, schemaName := range previous.Names {
+			if desired[previous.Database+"/"+schemaName] {
+				continue
+			}
+			exists, err := pgApi.IsDatabaseExisting(previous.Database)
+			if err != nil {
+				return err
+			}
+			if exists {
+				exists, err = pgApi.IsSchemaInDatabase(previous.Database, schemaName)
+				if err != nil {
+					return err
+				}
+			}
+			// The privileges were removed together with the database or schema
+			if !exists {
+				continue
+			}
+			if err := pgApi.UpdateSchemaPrivileges(previous.Database, schemaName, user.Name, []string{}); err != nil {
+				logger.Error(err, "Unable to revoke schema privileges")
+				return err
+			}
+			for _, typeName := range []string{"TABLES", "SEQUENCES", "FUNCTIONS"} {
+				if err := pgApi.UpdatePrivilegesOnAllObjects(previous.Database, schemaName, user.Name, typeName, []string{}); err != nil {
+					logger.Error(err, "Unable to revoke privileges on "+strings.ToLower(typeName))
+					return err
+				}
+			}
+		}
+	}
+
+	// Persist the names of the schemas
+	if !reflect.DeepEqual(user.Status.Schemas, applied) {
+		user.Status.Schemas = applied
+		return r.Status().Update(ctx, user)
+	}
+	return nil
+}
+
+func (r *PgUserReconciler) updateAttributes(ctx context.Context, pgApi pgapi.PgRoleAPI, user *apiV1.PgUser) error {
+	logger := log.FromContext(ctx)
+	// Attributes are not managed for this user
+	if user.Spec.Attributes == nil {
+		return nil
+	}
+
+	// The login roles used during a password rotation act as the user and need the same attributes
+	roleNames := []string{user.Name}
+	for _, roleName := range []string{user.Status.ActiveRole, user.Status.PreviousRole} {
+		if roleName != "" && roleName != user.Name {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+	attributes := newRoleAttributes(user.Spec.Attributes)
+	for _, roleName := range roleNames {
+		if err := pgApi.UpdateRoleAttributes(roleName, attributes); err != nil {
+			logger.Error(err, "Unable to update role attributes for role "+roleName+" on instance "+user.GetInstanceIdString())
+			if err := setCondition(ctx, r.Status(), user, apiV1.PgUserAttributesConditionType, false, "UpdateFailed", err.Error()); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	return setCondition(ctx, r.Status(), user, apiV1.PgUserAttributesConditionType, true, "AttributesApplied", "-")
+}
+
+// newRoleAttributes converts the attributes of a PgUser into the attributes of its login roles
+func newRoleAttributes(spec *apiV1.PgUserAttributes) pgapi.PgRoleAttributes {
+	attributes := pgapi.PgRoleAttributes{
+		CreateDatabase:  spec.CreateDatabase,
+		CreateRole:      spec.CreateRole,
+		Replication:     spec.Replication,
+		BypassRLS:       spec.BypassRLS,
+		ConnectionLimit: spec.ConnectionLimit,
+	}
+	if spec.ValidUntil != nil {
+		attributes.ValidUntil = &spec.ValidUntil.Time
+	}
+	return attributes
+}
+
+// handleSettings sets the configuration settings of the user in all databases and in the listed databases
+// and resets the settings which were removed
+func (r *PgUserReconciler) handleSettings(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) error {
+	// Collect the settings by database, the empty database name selects all databases
+	desired := make(map[string]map[string]string)
+	if len(user.Spec.Settings) > 0 {
+		desired[""] = user.Spec.Settings
+	}
+	for _, database := range user.Spec.Databases {
+		if len(database.Settings) > 0 {
+			desired[database.Name] = database.Settings
+		}
+	}
+	previous := make(map[string][]string)
+	for _, settings := range user.Status.Settings {
+		previous[settings.Database] = settings.Names
+	}
+	// Settings are not managed for this user
+	if len(desired) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	// Settings are bound to the login role, therefore the alternate login role needs them as well
+	roleNames := []string{user.Name}
+	exists, err := pgApi.IsRoleExisting(user.AlternateRoleName())
+	if err != nil {
+		return err
+	}
+	if exists {
+		comment, err := pgApi.GetRoleComment(user.AlternateRoleName())
+		if err != nil {
+			return err
+		}
+		if isAlternateRoleManaged(user, comment) {
+			roleNames = append(roleNames, user.AlternateRoleName())
+		}
+	}
+
+	databaseNames := make([]string, 0)
+	for databaseName := range desired {
+		databaseNames = append(databaseNames, databaseName)
+	}
+	for databaseName := range previous {
+		if _, ok := desired[databaseName]; !ok {
+			databaseNames = append(databaseNames, databaseName)
+		}
+	}
+	sort.Strings(databaseNames)
+
+	var applied []apiV1.PgUserSettingsStatus
+	for _, databaseName := range databaseNames {
+		var names []string
+		for _, roleName := range roleNames {
+			names, err = updateSettings(ctx, pgApi, databaseName, roleName, desired[databaseName], previous[databaseName])
+			if err != nil {
+				if err := setCondition(ctx, r.Status(), user, apiV1.PgUserSettingsConditionType, false, "UpdateFailed", err.Error()); err != nil {
+					return err
+				}
+				return err
+			}
+		}
+		if len(names) > 0 {
+			applied = append(applied, apiV1.PgUserSettingsStatus{Database: databaseName, Names: names})
+		}
+	}
+
+	// Persist the names of the settings
+	if !reflect.DeepEqual(user.Status.Settings, applied) {
+		user.Status.Settings = applied
+		if err := r.Status().Update(ctx, user); err != nil {
+			return err
+		}
+	}
+	return setCondition(ctx, r.Status(), user, apiV1.PgUserSettingsConditionType, true, "SettingsApplied", "-")
+}
+
+// handleRotation rotates the password of the user if the interval elapsed or a rotation was requested via annotation
+// and disables the previous login role after the grace period. It returns the duration after which
+// the user has to be reconciled again, zero if no further reconciliation is required.
+func (r *PgUserReconciler) handleRotation(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+	rotation := user.Spec.Rotation
+	// Rotation is not managed for this user
+	if rotation == nil {
+		return 0, nil
+	}
+	now := time.Now()
+
+	// Disable the previous login role after the grace period
+	if user.Status.PreviousRole != "" && user.Status.LastRotationTime != nil {
+		graceEnd := user.Status.LastRotationTime.Add(r.gracePeriod(rotation))
+		if !now.Before(graceEnd) {
+			if err := pgApi.DisableRoleLogin(user.Status.PreviousRole); err != nil {
+				logger.Error(err, "Unable to disable login role "+user.Status.PreviousRole+" on instance "+user.GetInstanceIdString())
+				if err := setCondition(ctx, r.Status(), user, apiV1.PgUserRotationConditionType, false, "DisableFailed", err.Error()); err != nil {
+					return 0, err
+				}
+				return 0, err
+			}
+			user.Status.PreviousRole = ""
+			if err := r.Status().Update(ctx, user); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// The interval starts when the rotation is enabled
+	if user.Status.LastRotationTime == nil {
+		user.Status.LastRotationTime = &metaV1.Time{Time: now}
+		if err := r.Status().Update(ctx, user); err != nil {
+			return 0, err
+		}
+	}
+
+	// Check if the rotation is due
+	request := user.Annotations[apiV1.PgUserRotatePasswordAnnotation]
+	requested := request != "" && request != user.Status.LastRotationRequest
+	elapsed := rotation.Interval != nil && rotation.Interval.Duration > 0 &&
+		!now.Before(user.Status.LastRotationTime.Add(rotation.Interval.Duration))
+	if requested || elapsed {
+		// A refused alternate login role reports the reason of the refusal in the rotation condition
+		if rotation.HasGracePeriod() {
+			if err := r.createAlternateRoleIfNotExists(ctx, pgApi, user); err != nil {
+				return 0, err
+			}
+		}
+		if err := r.rotatePassword(ctx, pgApi, user, request, now); err != nil {
+			if err := setCondition(ctx, r.Status(), user, apiV1.PgUserRotationConditionType, false, "RotationFailed", err.Error()); err != nil {
+				return 0, err
+			}
+			return 0, err
+		}
+	}
+
+	// Calculate when the next rotation or the end of the grace period is due
+	requeueAfter := time.Duration(0)
+	if rotation.Interval != nil && rotation.Interval.Duration > 0 {
+		requeueAfter = time.Until(user.Status.LastRotationTime.Add(rotation.Interval.Duration))
+	}
+	if user.Status.PreviousRole != "" {
+		graceRemaining := time.Until(user.Status.LastRotationTime.Add(r.gracePeriod(rotation)))
+		if requeueAfter == 0 || graceRemaining < requeueAfter {
+			requeueAfter = graceRemaining
+		}
+	}
+	if requeueAfter < 0 {
+		requeueAfter = time.Second
+	}
+	return requeueAfter, setCondition(ctx, r.Status(), user, apiV1.PgUserRotationConditionType, true, "RotationApplied", "-")
+}
+
+// rotatePassword generates a new password and stores it in the secret of the user.
+// With a grace period the new password is set on the inactive login role, which becomes the active one,
+// and the previous credentials stay valid until the grace period ends.
+func (r *PgUserReconciler) rotatePassword(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser, request string, now time.Time) error {
+	logger := log.FromContext(ctx)
+	previousRole := user.ActiveRoleName()
+	targetRole := previousRole
+	if user.Spec.Rotation.HasGracePeriod() {
+		if previousRole == user.Name {
+			targetRole = user.AlternateRoleName()
+		} else {
+			targetRole = user.Name
+		}
+	}
+
+	// Update the password in the instance at first, the secret is updated afterwards in a single update
+	password, err := r.generatePassword(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := pgApi.UpdateUserPassword(targetRole, password); err != nil {
+		logger.Error(err, "Unable to update role password for role "+targetRole+" on instance "+user.GetInstanceIdString())
+		return err
+	}
+	var roleSecret coreV1.Secret
+	secretKey := types.NamespacedName{
+		Namespace: user.Namespace,
+		Name:      user.Spec.Secret.Name,
+	}
+	if err := r.Get(ctx, secretKey, &roleSecret); err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to fetch role secret for login role %s", user.Name))
+		return err
+	}
+	data, err := r.generateSecretData(ctx, pgApi, user, targetRole, password)
+	if err != nil {
+		return err
+	}
+	roleSecret.Data = data
+	if err := r.Update(ctx, &roleSecret); err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to update role secret for login role %s", user.Name))
+		return err
+	}
+
+	// Persist the rotation
+	user.Status.LastRotationTime = &metaV1.Time{Time: now}
+	user.Status.LastRotationRequest = request
+	user.Status.ActiveRole = targetRole
+	user.Status.PreviousRole = ""
+	if targetRole != previousRole {
+		user.Status.PreviousRole = previousRole
+	}
+	if err := r.Status().Update(ctx, user); err != nil {
+		return err
+	}
+	logger.Info("Rotated password", "user", user.ToNamespacedName(), "role", targetRole)
+	return nil
+}
+
+// generatePassword generates a password with the password policy of the user,
+// whose unset fields are taken from the operator-wide password policy
+func (r *PgUserReconciler) generatePassword(ctx context.Context, user *apiV1.PgUser) (string, error) {
+	defaults := r.DefaultPasswordPolicy.WithDefaults(security.DefaultPasswordPolicy)
+	policy := user.Spec.PasswordPolicy.ToPasswordPolicy().WithDefaults(defaults)
+	password, err := security.GeneratePasswordWithPolicy(policy)
+	if err != nil {
+		if err := setCondition(ctx, r.Status(), user, apiV1.PgUserPasswordPolicyConditionType, false, "Unsatisfiable", err.Error()); err != nil {
+			return "", err
+		}
+		return "", err
+	}
+	if user.Spec.PasswordPolicy != nil {
+		err = setCondition(ctx, r.Status(), user, apiV1.PgUserPasswordPolicyConditionType, true, "Satisfiable", "-")
+	} else if meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordPolicyConditionType) != nil {
+		err = removeCondition(ctx, r.Status(), user, apiV1.PgUserPasswordPolicyConditionType)
+	}
+	return password, err
+}
+
+// createAlternateRoleIfNotExists creates the alternate login role, which acts as the user after login,
+// and applies the attributes of the user to it
+func (r *PgUserReconciler) createAlternateRoleIfNotExists(ctx context.Context, pgApi PgRoleAPI, user *apiV1.PgUser) error {
+	logger := log.FromContext(ctx)
+	roleName := user.AlternateRoleName()
+	exists, err := pgApi.IsRoleExisting(roleName)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to query login role %s", roleName))
+		return err
+	}
+	comment := ""
+	if !exists {
+		if err := pgApi.CreateRole(roleName); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to create login role %s", roleName))
+			return err
+		}
+		logger.Info(fmt.Sprintf("Created login role %s", roleName))
+	} else {
+		message, err := checkPrivilegedRole(pgApi, roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query login role %s", roleName))
+			return err
+		}
+		if message != "" {
+			err := errors.New(message + " and cannot be used as alternate login role")
+			return refuseOwnership(ctx, r.Status(), user, apiV1.PgUserRotationConditionType, "PrivilegedRole", err)
+		}
+		comment, err = pgApi.GetRoleComment(roleName)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to query comment of login role %s", roleName))
+			return err
+		}
+		if !isAlternateRoleManaged(user, comment) {
+			// Existing roles are only adopted according to the adoption policy, the exists condition of the user does not apply
+			_, reason, err := checkOwnership(user, user.Spec.GetAdoptionPolicy(), comment, "Role "+roleName, "")
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Refused to manage login role %s", roleName))
+				return refuseOwnership(ctx, r.Status(), user, apiV1.PgUserRotationConditionType, reason, err)
+			}
+			logger.Info(fmt.Sprintf("Adopting existing login role %s", roleName))
+		}
+	}
+	if apiV1.ParseOwnershipMarker(comment) != user.UID {
+		// The alternate role switches to the user after login, so created objects are owned by the user
+		if err := pgApi.GrantRoleMembership(roleName, user.Name, false, true); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to grant role %s to login role %s", user.Name, roleName))
+			return err
+		}
+		if err := pgApi.UpdateRoleSessionRole(roleName, user.Name); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to update session role of login role %s", roleName))
+			return err
+		}
+		// Mark role as managed by this resource and keep the existing comment
+		if err := pgApi.UpdateRoleComment(roleName, apiV1.SetOwnershipMarker(comment, user.UID)); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to mark login role %s", roleName))
+			return err
+		}
+	}
+	// Attributes like REPLICATION or CONNECTION LIMIT are not inherited from the user
+	if user.Spec.Attributes != nil {
+		if err := pgApi.UpdateRoleAttributes(roleName, newRoleAttributes(user.Spec.Attributes)); err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to update role attributes of login role %s", roleName))
+			return err
+		}
+	}
+	return nil
+}
+
+// isAlternateRoleManaged returns true if the alternate login role with the given comment is managed by the user.
+// Alternate roles created by a version of the operator without ownership markers are identified by the status of the user.
+func isAlternateRoleManaged(user *apiV1.PgUser, comment string) bool {
+	owner := apiV1.ParseOwnershipMarker(comment)
+	if owner == "" {
+		roleName := user.AlternateRoleName()
+		return user.Status.ActiveRole == roleName || user.Status.PreviousRole == roleName
+	}
+	return owner == user.UID
+}
+
+func (r *PgUserReconciler) gracePeriod(rotation *apiV1.PgUserRotation) time.Duration {
+	if !rotation.HasGracePeriod() {
+		return 0
+	}
+	return rotation.GracePeriod.Duration
+}
@@ -19,9 +19,12 @@ package controllers
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
 	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+	"github.com/brose-ebike/postgres-operator/pkg/security"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	coreV1 "k8s.io/api/core/v1"
@@ -33,8 +36,10 @@ import (
 )
 
 type pgRoleMock struct {
+	pgSettingsMock
 	databases                       map[string]dummyDB
 	roles                           map[string]bool
+	superusers                      map[string]bool
 	callsIsRoleExisting             int
 	callsCreateRole                 int
 	callsDeleteRole                 int
@@ -51,6 +56,26 @@ type pgRoleMock struct {
 	callsUpdateDatabasePrivileges   int
 	callsIsDatabaseExtensionPresent int
 	callsCreateDatabaseExtension    int
+	memberships                     map[string][]pgapi.PgRoleMembership
+	callsCreateGroupRole            int
+	callsGetRoleMemberships         int
+	callsGrantRoleMembership        int
+	callsRevokeRoleMembership       int
+	attributes                      map[string]pgapi.PgRoleAttributes
+	callsGetRoleAttributes          int
+	callsUpdateRoleAttributes       int
+	schemaPrivileges                map[string][]string
+	tablePrivileges                 map[string][]string
+	callsIsSchemaInDatabase         int
+	callsUpdateSchemaPrivileges     int
+	callsUpdatePrivilegesOnAll      int
+	callsUpdateTablePrivileges      int
+	passwords                       map[string]string
+	sessionRoles                    map[string]string
+	comments                        map[string]string
+	callsReassignAndDeleteRole      int
+	deleteRoleErr                   error
+	callsDisableRoleLogin           int
 }
 
 func (r *pgRoleMock) IsRoleExisting(roleName string) (bool, error) {
@@ -59,6 +84,10 @@ func (r *pgRoleMock) IsRoleExisting(roleName string) (bool, error) {
 	return exists, nil
 }
 
+func (r *pgRoleMock) IsRoleSuperuser(roleName string) (bool, error) {
+	return r.superusers[roleName], nil
+}
+
 func (r *pgRoleMock) CreateRole(name string) error {
 	r.callsCreateRole += 1
 	return nil
@@ -66,17 +95,91 @@ func (r *pgRoleMock) CreateRole(name string) error {
 
 func (r *pgRoleMock) DeleteRole(name string) error {
 	r.callsDeleteRole += 1
+	return r.deleteRoleErr
+}
+
+func (r *pgRoleMock) ReassignAndDeleteRole(name string, newOwner string) error {
+	r.callsReassignAndDeleteRole += 1
+	return nil
+}
+
+func (r *pgRoleMock) TerminateRoleSessions(name string) error {
 	return nil
 }
 
 func (r *pgRoleMock) UpdateUserPassword(name string, password string) error {
 	r.callsUpdateUserPassword += 1
+	if r.passwords == nil {
+		r.passwords = make(map[string]string)
+	}
+	r.passwords[name] = password
+	return nil
+}
+
+func (r *pgRoleMock) DisableRoleLogin(name string) error {
+	r.callsDisableRoleLogin += 1
+	delete(r.passwords, name)
+	return nil
+}
+
+func (r *pgRoleMock) UpdateRoleSessionRole(name string, sessionRole string) error {
+	if r.sessionRoles == nil {
+		r.sessionRoles = make(map[string]string)
+	}
+	r.sessionRoles[name] = sessionRole
+	return nil
+}
+
+func (r *pgRoleMock) CreateGroupRole(name string) error {
+	r.callsCreateGroupRole += 1
+	r.roles[name] = true
+	return nil
+}
+
+func (r *pgRoleMock) GetRoleMemberships(roleName string) ([]pgapi.PgRoleMembership, error) {
+	r.callsGetRoleMemberships += 1
+	return r.memberships[roleName], nil
+}
+
+func (r *pgRoleMock) GrantRoleMembership(roleName string, groupName string, admin bool, inherit bool) error {
+	r.callsGrantRoleMembership += 1
+	if r.memberships == nil {
+		r.memberships = make(map[string][]pgapi.PgRoleMembership)
+	}
+	r.memberships[roleName] = append(r.memberships[roleName], pgapi.PgRoleMembership{Group: groupName, Admin: admin, Inherit: inherit})
+	return nil
+}
+
+func (r *pgRoleMock) RevokeRoleMembership(roleName string, groupName string) error {
+	r.callsRevokeRoleMembership += 1
+	memberships := []pgapi.PgRoleMembership{}
+	for _, membership := range r.memberships[roleName] {
+		if membership.Group != groupName {
+			memberships = append(memberships, membership)
+		}
+	}
+	r.memberships[roleName] = memberships
+	return nil
+}
+
+func (r *pgRoleMock) GetRoleAttributes(name string) (pgapi.PgRoleAttributes, error) {
+	r.callsGetRoleAttributes += 1
+	return r.attributes[name], nil
+}
+
+func (r *pgRoleMock) UpdateRoleAttributes(name string, attributes pgapi.PgRoleAttributes) error {
+	r.callsUpdateRoleAttributes += 1
+	if r.attributes == nil {
+		r.attributes = make(map[string]pgapi.PgRoleAttributes)
+	}
+	r.attributes[name] = attributes
 	return nil
 }
 
 func (r *pgRoleMock) ConnectionString() pgapi.PgConnectionString {
 	r.callsConnectionString += 1
-	return pgapi.PgConnectionString{}
+	connStr, _ := pgapi.NewPgConnectionString("", 0, "admin", "", "", "")
+	return *connStr
 }
 
 func (r *pgRoleMock) TestConnection() error {
@@ -84,6 +187,10 @@ func (r *pgRoleMock) TestConnection() error {
 	return nil
 }
 
+func (r *pgRoleMock) GetServerInfo() (pgapi.PgServerInfo, error) {
+	return pgapi.PgServerInfo{}, nil
+}
+
 func (r *pgRoleMock) IsConnected() bool {
 	r.callsIsConnected += 1
 	return false
@@ -100,16 +207,65 @@ func (r *pgRoleMock) CreateDatabase(databaseName string) error {
 	return nil
 }
 
+func (r *pgRoleMock) CreateDatabaseWithOptions(databaseName string, options pgapi.PgDatabaseOptions) error {
+	return r.CreateDatabase(databaseName)
+}
+
+func (r *pgRoleMock) GetEncodingName(encoding string) (string, error) {
+	return encoding, nil
+}
+
+func (r *pgRoleMock) GetDatabaseOptions(databaseName string) (pgapi.PgDatabaseOptions, error) {
+	return pgapi.PgDatabaseOptions{}, nil
+}
+
+func (r *pgRoleMock) UpdateDatabaseOptions(databaseName string, options pgapi.PgDatabaseOptions) error {
+	return nil
+}
+
 func (r *pgRoleMock) DeleteDatabase(name string) error {
 	r.callsDeleteDatabase += 1
 	return nil
 }
 
+func (r *pgRoleMock) ForceDeleteDatabase(name string) error {
+	r.callsDeleteDatabase += 1
+	return nil
+}
+
+func (r *pgRoleMock) GetDatabaseSessionCount(name string) (int, error) {
+	return 0, nil
+}
+
+func (r *pgRoleMock) TerminateDatabaseSessions(name string) error {
+	return nil
+}
+
 func (r *pgRoleMock) GetDatabaseOwner(name string) (string, error) {
 	r.callsGetDatabaseOwner += 1
 	return "", nil
 }
 
+func (r *pgRoleMock) GetDatabaseComment(name string) (string, error) {
+	return "", nil
+}
+
+func (r *pgRoleMock) UpdateDatabaseComment(name string, comment string) error {
+	return nil
+}
+
+func (r *pgRoleMock) GetRoleComment(name string) (string, error) {
+	return r.comments[name], nil
+}
+
+func (r *pgRoleMock) UpdateRoleComment(name string, comment string) error {
+	if r.comments == nil {
+		r.comments = map[string]string{}
+	}
+	r.comments[name] = comment
+	return nil
+}
+
 func (r *pgRoleMock) IsDatabaseExisting(databaseName string) (bool, error) {
 	r.callsIsDatabaseExisting += 1
 	_, exists := r.databases[databaseName]
@@ -141,6 +297,124 @@ func (m *pgRoleMock) CreateDatabaseExtension(databaseName string, extension stri
 	return nil
 }
 
+func (m *pgRoleMock) GetDatabaseExtensionVersion(databaseName string, extension string) (string, error) {
+	return "", nil
+}
+
+func (m *pgRoleMock) IsDatabaseExtensionAvailable(databaseName string, extension string, version string) (bool, error) {
+	return true, nil
+}
+
+func (m *pgRoleMock) CreateDatabaseExtensionWithOptions(databaseName string, extension string, version string, schema string, cascade bool) error {
+	return nil
+}
+
+func (m *pgRoleMock) UpdateDatabaseExtension(databaseName string, extension string, version string) error {
+	return nil
+}
+
+func (m *pgRoleMock) DeleteDatabaseExtension(databaseName string, extension string, cascade bool) error {
+	return nil
+}
+
+func (r *pgRoleMock) IsSchemaInDatabase(databaseName string, schemaName string) (bool, error) {
+	r.callsIsSchemaInDatabase += 1
+	database, exists := r.databases[databaseName]
+	if !exists {
+		return false, errors.New("Database does not exist")
+	}
+	_, exists = database.schemas[schemaName]
+	return exists, nil
+}
+
+func (r *pgRoleMock) CreateSchema(databaseName string, schemaName string) error {
+	return nil
+}
+
+func (r *pgRoleMock) DeleteSchema(databaseName string, schemaName string) error {
+	return nil
+}
+
+func (r *pgRoleMock) DeleteSchemaCascade(databaseName string, schemaName string) error {
+	return nil
+}
+
+func (r *pgRoleMock) UpdateSchemaOwner(databaseName string, schemaName string, roleName string) error {
+	return nil
+}
+
+func (r *pgRoleMock) UpdateSchemaPrivileges(databaseName string, schemaName string, roleName string, privileges []string) error {
+	r.callsUpdateSchemaPrivileges += 1
+	if r.schemaPrivileges == nil {
+		r.schemaPrivileges = make(map[string][]string)
+	}
+	r.schemaPrivileges[databaseName+"."+schemaName+"/"+roleName] = privileges
+	return nil
+}
+
+func (r *pgRoleMock) UpdatePrivilegesOnAllObjects(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
+	r.callsUpdatePrivilegesOnAll += 1
+	return nil
+}
+
+func (r *pgRoleMock) UpdateTablePrivileges(databaseName string, schemaName string, tableName string, roleName string, privileges []string) error {
+	r.callsUpdateTablePrivileges += 1
+	if r.tablePrivileges == nil {
+		r.tablePrivileges = make(map[string][]string)
+	}
+	r.tablePrivileges[databaseName+"."+schemaName+"."+tableName+"/"+roleName] = privileges
+	return nil
+}
+
+func (r *pgRoleMock) UpdateDefaultPrivileges(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
+	return nil
+}
+
+func (r *pgRoleMock) DeleteAllPrivilegesOnSchema(databaseName string, schemaName string, role string) error {
+	return nil
+}
+
+func (r *pgRoleMock) IsSchemaUsable(databaseName string, schemaName string) (bool, error) {
+	return true, nil
+}
+
+func (r *pgRoleMock) MakeSchemaUseable(databaseName string, schemaName string) error {
+	return nil
+}
+
+func (r *pgRoleMock) GetSchemaOwner(databaseName string, schemaName string) (string, error) {
+	return "", nil
+}
+
+// passwordSecretIndexClient resolves the field index of password Secrets like the cache of the manager,
+// because the API server does not support field selectors on custom resources
+type passwordSecretIndexClient struct {
+	client.Client
+}
+
+func (c *passwordSecretIndexClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	users, ok := list.(*apiV1.PgUserList)
+	if !ok || listOptions.FieldSelector == nil {
+		return c.Client.List(ctx, list, opts...)
+	}
+	value, _ := listOptions.FieldSelector.RequiresExactMatch(passwordSecretIndexField)
+	if err := c.Client.List(ctx, users); err != nil {
+		return err
+	}
+	var items []apiV1.PgUser
+	for _, user := range users.Items {
+		for _, indexed := range indexPasswordSecret(&user) {
+			if indexed == value {
+				items = append(items, user)
+			}
+		}
+	}
+	users.Items = items
+	return nil
+}
+
 var _ = Describe("PgUserReconciler", func() {
 
 	var pgApiMock PgRoleAPI
@@ -170,6 +444,7 @@ var _ = Describe("PgUserReconciler", func() {
 				}
 				return pgApiMock, nil
 			},
+			security.PasswordPolicy{},
 		}
 
 		// Create dummy
@@ -286,28 +561,63 @@ var _ = Describe("PgUserReconciler", func() {
 		Expect(secret.ObjectMeta.OwnerReferences).To(HaveLen(1))
 	})
 
-	It("reconciles on delete of PgDatabase", func() {
+	It("rotates the password of PgUser with grace period", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		// given
 		request := reconcile.Request{
 			NamespacedName: types.NamespacedName{
 				Namespace: "default",
-				Name:      "missing",
+				Name:      "dummy",
 			},
 		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgRoleMock)
+		oldPassword := mock.passwords["dummy"]
+
+		// and
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Annotations = map[string]string{apiV1.PgUserRotatePasswordAnnotation: "1"}
+		user.Spec.Rotation = &apiV1.PgUserRotation{
+			GracePeriod: &v1.Duration{Duration: time.Hour},
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
 		// when
 		result, err := reconciler.Reconcile(ctx, request)
 
 		// then
 		Expect(err).To(BeNil())
-		Expect(result.RequeueAfter).To(BeZero())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(mock.sessionRoles["dummy_alt"]).To(Equal("dummy"))
+		Expect(mock.passwords["dummy"]).To(Equal(oldPassword))
+		Expect(mock.passwords["dummy_alt"]).ToNot(BeEmpty())
 
 		// and
-		Expect(nil).To(BeNil())
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		Expect(user.Status.LastRotationTime).ToNot(BeNil())
+		Expect(user.Status.LastRotationRequest).To(Equal("1"))
+		Expect(user.Status.ActiveRole).To(Equal("dummy_alt"))
+		Expect(user.Status.PreviousRole).To(Equal("dummy"))
+		rotationCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserRotationConditionType)
+		Expect(rotationCondition.Status).To(Equal(v1.ConditionTrue))
+		Expect(mock.comments["dummy_alt"]).To(Equal(apiV1.NewOwnershipMarker(user.UID)))
+
+		// and
+		secret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data["user"])).To(Equal("dummy_alt"))
+		Expect(string(secret.Data["password"])).To(Equal(mock.passwords["dummy_alt"]))
 	})
 
-	It("reconciles on finalize of PgUser", func() {
+	It("refuses an existing alternate role which was not created by PgUser", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		// given
@@ -319,103 +629,957 @@ var _ = Describe("PgUserReconciler", func() {
 		}
 		_, err := reconciler.Reconcile(ctx, request)
 		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["dummy_alt"] = true
 
 		// and
 		user := apiV1.PgUser{}
 		err = k8sClient.Get(ctx, request.NamespacedName, &user)
 		Expect(err).To(BeNil())
-		err = k8sClient.Delete(ctx, &user)
+		user.Annotations = map[string]string{apiV1.PgUserRotatePasswordAnnotation: "1"}
+		user.Spec.Rotation = &apiV1.PgUserRotation{
+			GracePeriod: &v1.Duration{Duration: time.Hour},
+		}
+		err = k8sClient.Update(ctx, &user)
 		Expect(err).To(BeNil())
 
 		// when
-		result, err := reconciler.Reconcile(ctx, request)
+		_, err = reconciler.Reconcile(ctx, request)
 
 		// then
+		Expect(err).ToNot(BeNil())
+		Expect(mock.passwords).ToNot(HaveKey("dummy_alt"))
+		Expect(mock.sessionRoles).ToNot(HaveKey("dummy_alt"))
+		Expect(mock.comments["dummy_alt"]).To(BeEmpty())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
 		Expect(err).To(BeNil())
-		Expect(result.RequeueAfter).To(BeZero())
+		Expect(user.Status.ActiveRole).To(BeEmpty())
+		rotationCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserRotationConditionType)
+		Expect(rotationCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(rotationCondition.Reason).To(Equal("ExistsUnmanaged"))
 	})
-})
-
-var _ = Describe("PgUserReconciler finalize", func() {
-
-	var pgApiMock PgRoleAPI
-	var reconciler *PgUserReconciler
 
-	BeforeEach(func() {
-		// Create ApiMock
-		pgApiMock = &pgRoleMock{
-			databases: map[string]dummyDB{
-				"testdb": {
-					owner:   "pgadmin",
-					schemas: make(map[string]string),
-				},
+	It("applies the attributes of PgUser to the alternate role", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
 			},
-			roles: make(map[string]bool),
 		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
 
-		// Create Reconciler
-		reconciler = &PgUserReconciler{
-			k8sClient,
-			nil,
-			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgRoleAPI, error) {
-				if instance.Name == "failure" {
-					return nil, errors.New("Connection Failure")
-				}
-				return pgApiMock, nil
-			},
+		// and
+		cTrue := true
+		connectionLimit := 10
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Annotations = map[string]string{apiV1.PgUserRotatePasswordAnnotation: "1"}
+		user.Spec.Rotation = &apiV1.PgUserRotation{
+			GracePeriod: &v1.Duration{Duration: time.Hour},
 		}
-	})
+		user.Spec.Attributes = &apiV1.PgUserAttributes{
+			Replication:     &cTrue,
+			ConnectionLimit: &connectionLimit,
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
 
-	AfterEach(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
 		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgRoleMock)
+		Expect(*mock.attributes["dummy_alt"].Replication).To(BeTrue())
+		Expect(*mock.attributes["dummy_alt"].ConnectionLimit).To(Equal(10))
+		Expect(*mock.attributes["dummy"].Replication).To(BeTrue())
 	})
 
-	It("handles user deletion", func() {
+	It("rotates the password of PgUser without grace period", func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		// given
-		user := apiV1.PgUser{
-			TypeMeta: v1.TypeMeta{
-				APIVersion: "postgres.brose.bike/v1",
-				Kind:       "PgUser",
-			},
-			ObjectMeta: v1.ObjectMeta{
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
 				Namespace: "default",
-				Name:      "existing",
-			},
-			Spec: apiV1.PgUserSpec{
-				Instance: apiV1.PgInstanceRef{
-					Namespace: "default",
-					Name:      "instance",
-				},
-				Secret: &apiV1.PgUserSecret{
-					Name: "credentials",
-				},
-				Databases: []apiV1.PgUserDatabase{},
+				Name:      "dummy",
 			},
-			Status: apiV1.PgUserStatus{},
 		}
-		err := k8sClient.Create(ctx, &user)
+		_, err := reconciler.Reconcile(ctx, request)
 		Expect(err).To(BeNil())
+		mock := pgApiMock.(*pgRoleMock)
+		oldPassword := mock.passwords["dummy"]
 
 		// and
-		pgApiMock.(*pgRoleMock).roles["existing"] = true
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Annotations = map[string]string{apiV1.PgUserRotatePasswordAnnotation: "now"}
+		user.Spec.Rotation = &apiV1.PgUserRotation{}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
 
 		// when
-		err = reconciler.finalize(ctx, &user, pgApiMock)
+		result, err := reconciler.Reconcile(ctx, request)
 
 		// then
 		Expect(err).To(BeNil())
-		Expect(pgApiMock.(*pgRoleMock).callsIsRoleExisting).To(Equal(1))
-		Expect(pgApiMock.(*pgRoleMock).callsDeleteRole).To(Equal(1))
-	})
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(mock.passwords["dummy"]).ToNot(Equal(oldPassword))
+		Expect(mock.passwords).ToNot(HaveKey("dummy_alt"))
 
-	It("handles missing user", func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		Expect(user.Status.ActiveRole).To(Equal("dummy"))
+		Expect(user.Status.PreviousRole).To(BeEmpty())
+
+		// and
+		secret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data["password"])).To(Equal(mock.passwords["dummy"]))
+	})
+
+	It("renders the secret layout of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Secret.Preset = apiV1.SpringSecretPreset
+		user.Spec.Secret.Database = "service_db"
+		user.Spec.Secret.Templates = map[string]string{
+			"DATABASE_URL": "postgres://{{.UserURL}}:{{.PasswordURL}}@{{.Host}}:{{.Port}}/{{.DatabaseURL}}",
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		password := mock.passwords["dummy"]
+		secret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(secret.Data).To(HaveLen(5))
+		Expect(string(secret.Data["SPRING_DATASOURCE_URL"])).To(Equal("jdbc:postgresql://:0/service_db?sslmode="))
+		Expect(string(secret.Data["SPRING_DATASOURCE_USERNAME"])).To(Equal("dummy"))
+		Expect(string(secret.Data["SPRING_DATASOURCE_PASSWORD"])).To(Equal(password))
+		Expect(string(secret.Data["DATABASE_URL"])).To(Equal("postgres://dummy:" + password + "@:0/service_db"))
+		Expect(string(secret.Data["password"])).To(Equal(password))
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		secretCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserSecretConditionType)
+		Expect(secretCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("quotes the values of the dotnet secret preset", func() {
+		// given
+		data := newSecretTemplateData("localhost", "5432", "dummy", `pa;ss="word`, "service_db", "verify-full")
+
+		// when
+		rendered, err := renderSecretTemplates(apiV1.DotnetSecretPreset, nil, data)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(string(rendered["ConnectionStrings__DefaultConnection"])).To(Equal(
+			`Host=localhost;Port=5432;Database="service_db";Username="dummy";Password="pa;ss=""word";SSL Mode=verifyfull`))
+	})
+
+	It("generates passwords with the password policy of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		reconciler.DefaultPasswordPolicy = security.PasswordPolicy{ExcludedCharacters: "0O1lI"}
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.PasswordPolicy = &apiV1.PgPasswordPolicy{
+			MinLength:  40,
+			Symbols:    "#!",
+			MinSymbols: 4,
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		password := mock.passwords["dummy"]
+		Expect(password).To(HaveLen(40))
+		Expect(strings.Count(password, "#") + strings.Count(password, "!")).To(BeNumerically(">=", 4))
+		Expect(strings.ContainsAny(password, "0O1lI")).To(BeFalse())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		policyCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordPolicyConditionType)
+		Expect(policyCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("fails on an unsatisfiable password policy of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		reconciler.DefaultPasswordPolicy = security.PasswordPolicy{ExcludedCharacters: "0123456789"}
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.PasswordPolicy = &apiV1.PgPasswordPolicy{
+			CharacterClasses: []apiV1.PgPasswordCharacterClass{apiV1.DigitsPasswordCharacterClass},
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).NotTo(BeNil())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		policyCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordPolicyConditionType)
+		Expect(policyCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(policyCondition.Reason).To(Equal("Unsatisfiable"))
+	})
+
+	It("reads the password of PgUser from a referenced secret", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		passwordSecret := coreV1.Secret{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "vendor-password"},
+			Data:       map[string][]byte{"pw": []byte("vendor's p@ssword")},
+		}
+		err := k8sClient.Create(ctx, &passwordSecret)
+		Expect(err).To(BeNil())
+		defer k8sClient.Delete(ctx, &passwordSecret)
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.PasswordSecretRef = &apiV1.PgUserPasswordSecretRef{Name: "vendor-password", Key: "pw"}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		reconciler.Client = &passwordSecretIndexClient{k8sClient}
+		Expect(reconciler.mapPasswordSecret(&passwordSecret)).To(ConsistOf(request))
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		Expect(mock.passwords["dummy"]).To(Equal("vendor's p@ssword"))
+		secret := coreV1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data["password"])).To(Equal("vendor's p@ssword"))
+
+		// when
+		passwordSecret.Data["pw"] = []byte("changed-password")
+		err = k8sClient.Update(ctx, &passwordSecret)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.passwords["dummy"]).To(Equal("changed-password"))
+		secret = coreV1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data["password"])).To(Equal("changed-password"))
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		secretCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordSecretConditionType)
+		Expect(secretCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("refuses a password secret of PgUser in a namespace which is not allowed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		passwordSecret := coreV1.Secret{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace:   "kube-public",
+				Name:        "vendor-password",
+				Annotations: map[string]string{apiV1.PgPasswordSecretAllowedNamespacesAnnotation: "team-a, team-b"},
+			},
+			Data: map[string][]byte{"password": []byte("vendor-password")},
+		}
+		err := k8sClient.Create(ctx, &passwordSecret)
+		Expect(err).To(BeNil())
+		defer k8sClient.Delete(ctx, &passwordSecret)
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.PasswordSecretRef = &apiV1.PgUserPasswordSecretRef{Namespace: "kube-public", Name: "vendor-password"}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(pgApiMock.(*pgRoleMock).passwords["dummy"]).To(BeEmpty())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		secretCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserPasswordSecretConditionType)
+		Expect(secretCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(secretCondition.Reason).To(Equal("NamespaceNotAllowed"))
+	})
+
+	It("reconciles attributes of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		cTrue := true
+		connectionLimit := 10
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Attributes = &apiV1.PgUserAttributes{
+			CreateDatabase:  &cTrue,
+			ConnectionLimit: &connectionLimit,
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		attributesCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserAttributesConditionType)
+		Expect(attributesCondition.Status).To(Equal(v1.ConditionTrue))
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		Expect(mock.callsUpdateRoleAttributes).To(Equal(1))
+		Expect(*mock.attributes["dummy"].CreateDatabase).To(BeTrue())
+		Expect(*mock.attributes["dummy"].ConnectionLimit).To(Equal(10))
+		Expect(mock.attributes["dummy"].CreateRole).To(BeNil())
+	})
+
+	It("leaves the owner of a database to the PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		database := apiV1.PgDatabase{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "testdb",
+			},
+			Spec: apiV1.PgDatabaseSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Owner: "pgadmin",
+			},
+		}
+		err := k8sClient.Create(ctx, &database)
+		Expect(err).To(BeNil())
+		cTrue := true
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Databases[0].Owner = &cTrue
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+		mock := pgApiMock.(*pgRoleMock)
+		Expect(mock.callsUpdateDatabaseOwner).To(BeZero())
+		Expect(mock.databases["testdb"].owner).To(Equal("pgadmin"))
+	})
+
+	It("reconciles settings of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Settings = map[string]string{"search_path": "service, public", "statement_timeout": "30s"}
+		user.Spec.Databases[0].Settings = map[string]string{"work_mem": "64MB"}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// when
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Settings = map[string]string{"search_path": "service"}
+		user.Spec.Databases[0].Settings = nil
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		Expect(mock.settings["/dummy"]).To(Equal(map[string]string{"search_path": "service"}))
+		Expect(mock.settings["testdb/dummy"]).To(BeEmpty())
+		Expect(mock.callsResetSetting).To(Equal(2))
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		Expect(user.Status.Settings).To(Equal([]apiV1.PgUserSettingsStatus{{Names: []string{"search_path"}}}))
+		settingsCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserSettingsConditionType)
+		Expect(settingsCondition.Status).To(Equal(v1.ConditionTrue))
+	})
+
+	It("reconciles schema privileges of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgRoleMock)
+		mock.databases["testdb"].schemas["service"] = "pgadmin"
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Databases[0].Schemas = []apiV1.PgUserSchema{
+			{
+				Name:            "service",
+				Privileges:      []apiV1.SchemaPrivilege{"USAGE"},
+				TablePrivileges: []apiV1.TablePrivilege{"SELECT"},
+				Tables: []apiV1.PgUserTable{
+					{Name: "bikes", Privileges: []apiV1.TablePrivilege{"INSERT", "UPDATE"}},
+				},
+			},
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		Expect(mock.callsUpdateSchemaPrivileges).To(Equal(1))
+		Expect(mock.callsUpdatePrivilegesOnAll).To(Equal(3))
+		Expect(mock.schemaPrivileges["testdb.service/dummy"]).To(Equal([]string{"USAGE"}))
+		Expect(mock.tablePrivileges["testdb.service.bikes/dummy"]).To(Equal([]string{"SELECT", "INSERT", "UPDATE"}))
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		Expect(user.Status.Schemas).To(Equal([]apiV1.PgUserSchemaStatus{{Database: "testdb", Names: []string{"service"}}}))
+
+		// when
+		user.Spec.Databases[0].Schemas = nil
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsUpdateSchemaPrivileges).To(Equal(2))
+		Expect(mock.callsUpdatePrivilegesOnAll).To(Equal(6))
+		Expect(mock.schemaPrivileges["testdb.service/dummy"]).To(BeEmpty())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		Expect(user.Status.Schemas).To(BeEmpty())
+	})
+
+	It("fails on missing schema of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Databases[0].Schemas = []apiV1.PgUserSchema{
+			{Name: "missing", Privileges: []apiV1.SchemaPrivilege{"USAGE"}},
+		}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(pgApiMock.(*pgRoleMock).callsUpdateSchemaPrivileges).To(BeZero())
+	})
+
+	It("adopts an existing role of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["dummy"] = true
+		mock.comments = map[string]string{"dummy": "Service account"}
+
+		// and
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		_, err = reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsCreateRole).To(BeZero())
+		Expect(mock.comments["dummy"]).To(Equal("Service account\n" + apiV1.NewOwnershipMarker(user.UID)))
+	})
+
+	It("refuses an existing role which was not created by PgUser by default", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["dummy"] = true
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(mock.comments["dummy"]).To(BeEmpty())
+
+		// and
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("ExistsUnmanaged"))
+	})
+
+	It("refuses to adopt a superuser with PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["dummy"] = true
+		mock.superusers = map[string]bool{"dummy": true}
+
+		// and
+		user := apiV1.PgUser{}
+		err := k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.AdoptionPolicy = apiV1.CreateAdoptionPolicy
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(mock.comments["dummy"]).To(BeEmpty())
+		Expect(mock.callsUpdateUserPassword).To(BeZero())
+
+		// and
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("PrivilegedRole"))
+	})
+
+	It("refuses a role managed by another PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["dummy"] = true
+		mock.comments = map[string]string{"dummy": apiV1.NewOwnershipMarker("other-uid")}
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(mock.callsUpdateUserPassword).To(BeZero())
+
+		// and
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		ownershipCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserOwnershipConditionType)
+		Expect(ownershipCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(ownershipCondition.Reason).To(Equal("ManagedByOtherResource"))
+	})
+
+	It("refuses instances which are not allowed in the namespace", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		instance := apiV1.ClusterPgInstance{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "restricted",
+			},
+			Spec: apiV1.ClusterPgInstanceSpec{
+				PgInstanceSpec: apiV1.PgInstanceSpec{
+					Hostname: apiV1.PgProperty{Value: "localhost"},
+					AllowedNamespaces: &v1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					},
+				},
+				Namespace: "default",
+			},
+		}
+		err := k8sClient.Create(ctx, &instance)
+		Expect(err).To(BeNil())
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		user.Spec.Instance = apiV1.PgInstanceRef{Kind: apiV1.ClusterPgInstanceKind, Name: "restricted"}
+		err = k8sClient.Update(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+
+		// and
+		user = apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		accessCondition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgInstanceAccessConditionType)
+		Expect(accessCondition.Status).To(Equal(v1.ConditionFalse))
+		Expect(accessCondition.Reason).To(Equal("NamespaceNotAllowed"))
+		Expect(pgApiMock.(*pgRoleMock).callsCreateRole).To(BeZero())
+	})
+
+	It("reconciles on delete of PgDatabase", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "missing",
+			},
+		}
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		// and
+		Expect(nil).To(BeNil())
+	})
+
+	It("reconciles on finalize of PgUser", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "dummy",
+			},
+		}
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).To(BeNil())
+
+		// and
+		user := apiV1.PgUser{}
+		err = k8sClient.Get(ctx, request.NamespacedName, &user)
+		Expect(err).To(BeNil())
+		err = k8sClient.Delete(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// when
+		result, err := reconciler.Reconcile(ctx, request)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeZero())
+	})
+})
+
+var _ = Describe("PgUserReconciler finalize", func() {
+
+	var pgApiMock PgRoleAPI
+	var reconciler *PgUserReconciler
+
+	BeforeEach(func() {
+		// Create ApiMock
+		pgApiMock = &pgRoleMock{
+			databases: map[string]dummyDB{
+				"testdb": {
+					owner:   "pgadmin",
+					schemas: make(map[string]string),
+				},
+			},
+			roles: make(map[string]bool),
+		}
+
+		// Create Reconciler
+		reconciler = &PgUserReconciler{
+			k8sClient,
+			nil,
+			func(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (PgRoleAPI, error) {
+				if instance.Name == "failure" {
+					return nil, errors.New("Connection Failure")
+				}
+				return pgApiMock, nil
+			},
+			security.PasswordPolicy{},
+		}
+	})
+
+	AfterEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := deleteAllCustomResources(ctx, k8sClient, "default")
+		Expect(err).To(BeNil())
+	})
+
+	It("handles user deletion", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			TypeMeta: v1.TypeMeta{
+				APIVersion: "postgres.brose.bike/v1",
+				Kind:       "PgUser",
+			},
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Secret: &apiV1.PgUserSecret{
+					Name: "credentials",
+				},
+				Databases: []apiV1.PgUserDatabase{},
+			},
+			Status: apiV1.PgUserStatus{},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		pgApiMock.(*pgRoleMock).roles["existing"] = true
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(pgApiMock.(*pgRoleMock).callsIsRoleExisting).To(Equal(1))
+		Expect(pgApiMock.(*pgRoleMock).callsDeleteRole).To(Equal(1))
+	})
+
+	It("keeps an alternate role which is not managed by the user on deletion", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Secret: &apiV1.PgUserSecret{
+					Name: "credentials",
+				},
+				Rotation: &apiV1.PgUserRotation{
+					GracePeriod: &v1.Duration{Duration: time.Hour},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["existing"] = true
+		mock.roles["existing_alt"] = true
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsDeleteRole).To(Equal(1))
+		Expect(mock.roles["existing_alt"]).To(BeTrue())
+	})
+
+	It("handles missing user", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 		// given
 		user := apiV1.PgUser{
 			TypeMeta: v1.TypeMeta{
@@ -502,4 +1666,206 @@ var _ = Describe("PgUserReconciler finalize", func() {
 		Expect(err).To(BeNil())
 		Expect(exists).To(BeFalse())
 	})
+	It("disables the role and retains the secret on user deletion", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Secret: &apiV1.PgUserSecret{
+					Name: "credentials",
+				},
+				Databases: []apiV1.PgUserDatabase{},
+				DeletionBehavior: apiV1.PgUserDeletion{
+					Policy:       apiV1.DisableUserDeletionPolicy,
+					RetainSecret: true,
+				},
+			},
+			Status: apiV1.PgUserStatus{},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["existing"] = true
+		mock.comments = map[string]string{"existing": apiV1.NewOwnershipMarker(user.UID)}
+
+		// and
+		secret := coreV1.Secret{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "credentials",
+			},
+			StringData: map[string]string{"key": "value"},
+		}
+		err = k8sClient.Create(ctx, &secret)
+		Expect(err).To(BeNil())
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsDeleteRole).To(BeZero())
+		Expect(mock.callsDisableRoleLogin).To(Equal(1))
+		Expect(mock.comments["existing"]).To(BeEmpty())
+
+		// and secret still exists
+		secret = coreV1.Secret{}
+		exists, err := getResource(ctx, k8sClient, types.NamespacedName{Namespace: "default", Name: "credentials"}, &secret)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+	})
+
+	It("reassigns the owned objects on user deletion", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Secret: &apiV1.PgUserSecret{
+					Name: "credentials",
+				},
+				Databases: []apiV1.PgUserDatabase{},
+				DeletionBehavior: apiV1.PgUserDeletion{
+					Policy:     apiV1.ReassignUserDeletionPolicy,
+					ReassignTo: "team",
+				},
+			},
+			Status: apiV1.PgUserStatus{},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["existing"] = true
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(mock.callsReassignAndDeleteRole).To(BeZero())
+		condition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserDeletionConditionType)
+		Expect(condition.Reason).To(Equal("ReassignNotAllowed"))
+
+		// and
+		team := apiV1.PgRole{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "team"},
+			Spec:       apiV1.PgRoleSpec{Instance: apiV1.PgInstanceRef{Namespace: "default", Name: "instance"}},
+		}
+		err = k8sClient.Create(ctx, &team)
+		Expect(err).To(BeNil())
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).To(BeNil())
+		Expect(mock.callsDeleteRole).To(BeZero())
+		Expect(mock.callsReassignAndDeleteRole).To(Equal(1))
+	})
+
+	It("does not reassign the owned objects to privileged roles", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Secret: &apiV1.PgUserSecret{
+					Name: "credentials",
+				},
+				DeletionBehavior: apiV1.PgUserDeletion{
+					Policy:     apiV1.ReassignUserDeletionPolicy,
+					ReassignTo: "admin",
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["existing"] = true
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("used by the operator"))
+		Expect(mock.callsReassignAndDeleteRole).To(BeZero())
+		Expect(mock.roles).To(HaveKey("existing"))
+	})
+
+	It("reports the progress of a stuck user deletion", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// given
+		user := apiV1.PgUser{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "existing",
+			},
+			Spec: apiV1.PgUserSpec{
+				Instance: apiV1.PgInstanceRef{
+					Namespace: "default",
+					Name:      "instance",
+				},
+				Databases: []apiV1.PgUserDatabase{},
+			},
+			Status: apiV1.PgUserStatus{},
+		}
+		err := k8sClient.Create(ctx, &user)
+		Expect(err).To(BeNil())
+
+		// and
+		mock := pgApiMock.(*pgRoleMock)
+		mock.roles["existing"] = true
+		mock.deleteRoleErr = &pgapi.RoleCleanupError{
+			Role:    "existing",
+			Cleaned: []string{"postgres"},
+			Failed:  map[string]error{"testdb": errors.New("permission denied")},
+		}
+
+		// when
+		err = reconciler.finalize(ctx, &user, pgApiMock)
+
+		// then
+		Expect(err).To(Equal(mock.deleteRoleErr))
+		Expect(mock.callsDeleteRole).To(Equal(1))
+		condition := meta.FindStatusCondition(user.Status.Conditions, apiV1.PgUserDeletionConditionType)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("CleanupIncomplete"))
+		Expect(condition.Message).To(ContainSubstring("cleaned up [postgres]"))
+		Expect(condition.Message).To(ContainSubstring("testdb: permission denied"))
+		Expect(user.Finalizers).To(BeEmpty())
+	})
 })
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// reportRoleCleanup writes the progress of a stuck role cleanup to the condition with the given type.
+// The condition is updated on every attempt, since the cleaned and failed databases may change.
+// The given error is returned, so the deletion is retried.
+func reportRoleCleanup(ctx context.Context, r client.StatusWriter, obj ObjectWithConditions, conditionType string, err error) error {
+	var cleanupErr *pgapi.RoleCleanupError
+	if !errors.As(err, &cleanupErr) {
+		return err
+	}
+	putCondition(obj, conditionType, false, "CleanupIncomplete", cleanupErr.Error())
+	if err := r.Update(ctx, obj); err != nil {
+		return err
+	}
+	return err
+}
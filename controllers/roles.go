@@ -0,0 +1,78 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// privilegedRoleChecker provides the information to decide if a role is privileged
+type privilegedRoleChecker interface {
+	ConnectionString() pgapi.PgConnectionString
+	IsRoleSuperuser(roleName string) (bool, error)
+}
+
+// checkPrivilegedRole returns a message explaining why the given role must not be used by the resources of tenants,
+// which applies to the role of the operator, to superusers and to the predefined roles starting with pg_.
+// An empty message is returned for all other roles.
+func checkPrivilegedRole(pgApi privilegedRoleChecker, roleName string) (string, error) {
+	connStr := pgApi.ConnectionString()
+	if roleName == connStr.Username() {
+		return "The role " + roleName + " is used by the operator", nil
+	}
+	if strings.HasPrefix(roleName, "pg_") {
+		return "The role " + roleName + " is a predefined role", nil
+	}
+	superuser, err := pgApi.IsRoleSuperuser(roleName)
+	if err != nil {
+		return "", err
+	}
+	if superuser {
+		return "The role " + roleName + " is a superuser", nil
+	}
+	return "", nil
+}
+
+// isRoleManagedInNamespace returns true if a PgRole or a PgUser in the given namespace manages the role
+// with the given name on the given instance
+func isRoleManagedInNamespace(ctx context.Context, c client.Reader, namespace string, instance *apiV1.PgInstanceRef, roleName string) (bool, error) {
+	var roles apiV1.PgRoleList
+	if err := c.List(ctx, &roles, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	for _, role := range roles.Items {
+		if role.Name == roleName && isSameInstance(&role.Spec.Instance, instance) {
+			return true, nil
+		}
+	}
+	var users apiV1.PgUserList
+	if err := c.List(ctx, &users, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	for _, user := range users.Items {
+		if user.Name == roleName && isSameInstance(&user.Spec.Instance, instance) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"text/template"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// secretPresets contains the templates of the built-in secret layouts
+var secretPresets = map[apiV1.PgUserSecretPreset]map[string]string{
+	apiV1.SpringSecretPreset: {
+		"SPRING_DATASOURCE_URL":      "jdbc:postgresql://{{.Host}}:{{.Port}}/{{.Database}}?sslmode={{.SSLMode}}",
+		"SPRING_DATASOURCE_USERNAME": "{{.User}}",
+		"SPRING_DATASOURCE_PASSWORD": "{{.Password}}",
+	},
+	apiV1.DjangoSecretPreset: {
+		"DATABASE_URL": "postgres://{{.UserURL}}:{{.PasswordURL}}@{{.Host}}:{{.Port}}/{{.DatabaseURL}}?sslmode={{.SSLMode}}",
+	},
+	apiV1.DotnetSecretPreset: {
+		"ConnectionStrings__DefaultConnection": "Host={{.Host}};Port={{.Port}};Database={{quote .Database}};Username={{quote .User}};Password={{quote .Password}};SSL Mode={{replace .SSLMode \"-\" \"\"}}",
+	},
+}
+
+// secretTemplateData contains the fields, which are available in secret templates
+type secretTemplateData struct {
+	Host        string
+	Port        string
+	User        string
+	Password    string
+	Database    string
+	SSLMode     string
+	UserURL     string
+	PasswordURL string
+	DatabaseURL string
+}
+
+func newSecretTemplateData(host string, port string, user string, password string, database string, sslMode string) secretTemplateData {
+	return secretTemplateData{
+		Host:        host,
+		Port:        port,
+		User:        user,
+		Password:    password,
+		Database:    database,
+		SSLMode:     sslMode,
+		UserURL:     urlEscape(user),
+		PasswordURL: urlEscape(password),
+		DatabaseURL: urlEscape(database),
+	}
+}
+
+// renderSecretTemplates renders the templates of the preset and the given templates,
+// the given templates override keys of the preset
+func renderSecretTemplates(preset apiV1.PgUserSecretPreset, templates map[string]string, data secretTemplateData) (map[string][]byte, error) {
+	combined := make(map[string]string)
+	for key, text := range secretPresets[preset] {
+		combined[key] = text
+	}
+	for key, text := range templates {
+		combined[key] = text
+	}
+	rendered := make(map[string][]byte)
+	for key, text := range combined {
+		tmpl, err := template.New(key).Funcs(apiV1.PgUserSecretTemplateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return nil, err
+		}
+		rendered[key] = buffer.Bytes()
+	}
+	return rendered, nil
+}
+
+// urlEscape escapes the given value to be used in the user info or path of an URL
+func urlEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// isNamespaceAllowedBySecret returns true if the allowed namespaces annotation of the Secret contains the namespace
+func isNamespaceAllowedBySecret(secret *coreV1.Secret, namespace string) bool {
+	return isNamespaceAllowedByAnnotation(secret, apiV1.PgPasswordSecretAllowedNamespacesAnnotation, namespace)
+}
+
+// isNamespaceAllowedByAnnotation returns true if the comma separated namespaces in the annotation of the object contain the namespace
+func isNamespaceAllowedByAnnotation(obj metaV1.Object, annotation string, namespace string) bool {
+	for _, allowed := range strings.Split(obj.GetAnnotations()[annotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// readUserLogin fetches the PgUser with the given id and reads its credentials from the Secret of the PgUser.
+// If the PgUser or its password is missing, the reason RoleMissing or CredentialsMissing is returned with the error.
+func readUserLogin(ctx context.Context, c client.Reader, userId types.NamespacedName) (*apiV1.PgUser, pgapi.PgLogin, string, error) {
+	var user apiV1.PgUser
+	exists, err := getResource(ctx, c, userId, &user)
+	if err != nil {
+		return nil, pgapi.PgLogin{}, "", err
+	}
+	if !exists {
+		return nil, pgapi.PgLogin{}, "RoleMissing", errors.New("The PgUser " + userId.String() + " does not exist")
+	}
+	var secret coreV1.Secret
+	secretId := types.NamespacedName{Namespace: user.Namespace, Name: user.Spec.Secret.Name}
+	exists, err = getResource(ctx, c, secretId, &secret)
+	if err != nil {
+		return nil, pgapi.PgLogin{}, "", err
+	}
+	if !exists || len(secret.Data["password"]) == 0 {
+		message := "The Secret " + secretId.String() + " of the PgUser " + userId.String() + " does not contain a password"
+		return nil, pgapi.PgLogin{}, "CredentialsMissing", errors.New(message)
+	}
+	return &user, pgapi.PgLogin{Username: user.ActiveRoleName(), Password: string(secret.Data["password"])}, "", nil
+}
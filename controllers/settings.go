@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/brose-ebike/postgres-operator/pkg/pgapi"
+)
+
+// updateSettings sets the desired configuration settings for the given database and role and resets all settings,
+// which were set by the operator before, but are not desired anymore. Settings which were set manually are not touched.
+// An empty database name selects the settings of the role in all databases,
+// an empty role name selects the settings of the database for all roles.
+// It returns the sorted names of the settings which are set by the operator.
+func updateSettings(
+	ctx context.Context,
+	pgApi pgapi.PgSettingsAPI,
+	databaseName string,
+	roleName string,
+	desired map[string]string,
+	previous []string,
+) ([]string, error) {
+	logger := log.FromContext(ctx)
+	currentSettings, err := pgApi.GetSettings(databaseName, roleName)
+	if err != nil {
+		return nil, err
+	}
+	// Names of settings are case insensitive, e.g. TimeZone is reported for timezone
+	current := make(map[string]string)
+	for name, value := range currentSettings {
+		current[strings.ToLower(name)] = value
+	}
+
+	// Set desired settings, which are missing or have a different value
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, found := current[strings.ToLower(name)]
+		if found && pgapi.NormalizeSettingValue(name, value) == pgapi.NormalizeSettingValue(name, desired[name]) {
+			continue
+		}
+		if err := pgApi.UpdateSetting(databaseName, roleName, name, desired[name]); err != nil {
+			logger.Error(err, "Unable to set "+name, "database", databaseName, "role", roleName)
+			return nil, err
+		}
+		logger.Info("Set "+name+" to "+desired[name], "database", databaseName, "role", roleName)
+	}
+
+	// Reset settings which are not desired anymore
+	for _, name := range previous {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		if _, found := current[strings.ToLower(name)]; !found {
+			continue
+		}
+		if err := pgApi.ResetSetting(databaseName, roleName, name); err != nil {
+			logger.Error(err, "Unable to reset "+name, "database", databaseName, "role", roleName)
+			return nil, err
+		}
+		logger.Info("Reset "+name, "database", databaseName, "role", roleName)
+	}
+	return names, nil
+}
@@ -43,6 +43,29 @@ func getResource(ctx context.Context, r client.Reader, name client.ObjectKey, ob
 	return false, err
 }
 
+// hasElement checks if a given element e is contained in the slice s
+func hasElement(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// equalElements checks if both slices contain the same elements in the same order
+func equalElements(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 type ObjectWithConditions interface {
 	client.Object
 	GetConditions() []metaV1.Condition
@@ -80,6 +103,24 @@ func setCondition(
 	return r.Update(ctx, obj)
 }
 
+// putCondition sets the condition with the given type on the given object without writing the status,
+// the transition time is only changed if the status of the condition changed
+func putCondition(obj ObjectWithConditions, conditionType string, status bool, reason string, message string) {
+	statusString := metaV1.ConditionFalse
+	if status {
+		statusString = metaV1.ConditionTrue
+	}
+	conditions := obj.GetConditions()
+	meta.SetStatusCondition(&conditions, metaV1.Condition{
+		Type:               conditionType,
+		Status:             statusString,
+		ObservedGeneration: obj.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+	obj.SetConditions(conditions)
+}
+
 // removeCondition removes the condition with the given type from the given object
 func removeCondition(
 	ctx context.Context,
@@ -93,7 +134,7 @@ func removeCondition(
 	return r.Update(ctx, obj)
 }
 
-// deleteAllCustomResources force deletes all custom resources (PgUser, PgDatabase and PgInstance)
+// deleteAllCustomResources force deletes all custom resources (PgBackup, PgRestore, PgSchema, PgUser, PgRole, PgDatabase, PgInstance and ClusterPgInstance)
 // without executing the finalizers.
 // THIS METHOD SHOULD ONLY BE USED FOR TESTING
 func deleteAllCustomResources(ctx context.Context, c client.Client, namespace string) error {
@@ -101,10 +142,36 @@ func deleteAllCustomResources(ctx context.Context, c client.Client, namespace st
 		client.InNamespace(namespace),
 		client.GracePeriodSeconds(5),
 	}
+	// Delete all backups and restores
+	if err := c.DeleteAllOf(ctx, &apiV1.PgBackup{}, opts...); err != nil {
+		return err
+	}
+	if err := c.DeleteAllOf(ctx, &apiV1.PgRestore{}, opts...); err != nil {
+		return err
+	}
+	// Delete all scripts
+	if err := c.DeleteAllOf(ctx, &apiV1.PgScript{}, opts...); err != nil {
+		return err
+	}
+	// Delete all publications and subscriptions
+	if err := deleteAllPgPublications(ctx, c, opts); err != nil {
+		return err
+	}
+	if err := deleteAllPgSubscriptions(ctx, c, opts); err != nil {
+		return err
+	}
+	// Delete all schemas
+	if err := deleteAllPgSchemas(ctx, c, opts); err != nil {
+		return err
+	}
 	// Delete all users
 	if err := deleteAllPgUsers(ctx, c, opts); err != nil {
 		return err
 	}
+	// Delete all roles
+	if err := deleteAllPgRoles(ctx, c, opts); err != nil {
+		return err
+	}
 	// Delete all databases
 	if err := deleteAllPgDatabases(ctx, c, opts); err != nil {
 		return err
@@ -113,6 +180,73 @@ func deleteAllCustomResources(ctx context.Context, c client.Client, namespace st
 	if err := deleteAllPgInstances(ctx, c, opts); err != nil {
 		return err
 	}
+	// Delete all cluster instances
+	if err := deleteAllClusterPgInstances(ctx, c, []client.DeleteAllOfOption{client.GracePeriodSeconds(5)}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// THIS METHOD SHOULD ONLY BE USED FOR TESTING
+func deleteAllPgSchemas(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
+	schemas := apiV1.PgSchemaList{}
+	if err := c.List(ctx, &schemas); err != nil {
+		return nil
+	}
+	// Remove the finalizers from all resource objects to ensure no logic gets executed before deletion
+	for i := range schemas.Items {
+		schemaPtr := &schemas.Items[i]
+		schemaPtr.Finalizers = []string{}
+		if err := c.Update(ctx, schemaPtr); err != nil {
+			return err
+		}
+	}
+	schema := apiV1.PgSchema{}
+	if err := c.DeleteAllOf(ctx, &schema, opts...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// THIS METHOD SHOULD ONLY BE USED FOR TESTING
+func deleteAllPgPublications(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
+	publications := apiV1.PgPublicationList{}
+	if err := c.List(ctx, &publications); err != nil {
+		return nil
+	}
+	// Remove the finalizers from all resource objects to ensure no logic gets executed before deletion
+	for i := range publications.Items {
+		publicationPtr := &publications.Items[i]
+		publicationPtr.Finalizers = []string{}
+		if err := c.Update(ctx, publicationPtr); err != nil {
+			return err
+		}
+	}
+	publication := apiV1.PgPublication{}
+	if err := c.DeleteAllOf(ctx, &publication, opts...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// THIS METHOD SHOULD ONLY BE USED FOR TESTING
+func deleteAllPgSubscriptions(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
+	subscriptions := apiV1.PgSubscriptionList{}
+	if err := c.List(ctx, &subscriptions); err != nil {
+		return nil
+	}
+	// Remove the finalizers from all resource objects to ensure no logic gets executed before deletion
+	for i := range subscriptions.Items {
+		subscriptionPtr := &subscriptions.Items[i]
+		subscriptionPtr.Finalizers = []string{}
+		if err := c.Update(ctx, subscriptionPtr); err != nil {
+			return err
+		}
+	}
+	subscription := apiV1.PgSubscription{}
+	if err := c.DeleteAllOf(ctx, &subscription, opts...); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -137,6 +271,27 @@ func deleteAllPgUsers(ctx context.Context, c client.Client, opts []client.Delete
 	return nil
 }
 
+// THIS METHOD SHOULD ONLY BE USED FOR TESTING
+func deleteAllPgRoles(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
+	roles := apiV1.PgRoleList{}
+	if err := c.List(ctx, &roles); err != nil {
+		return nil
+	}
+	// Remove the finalizers from all resource objects to ensure no logic gets executed before deletion
+	for i := range roles.Items {
+		rolePtr := &roles.Items[i]
+		rolePtr.Finalizers = []string{}
+		if err := c.Update(ctx, rolePtr); err != nil {
+			return err
+		}
+	}
+	role := apiV1.PgRole{}
+	if err := c.DeleteAllOf(ctx, &role, opts...); err != nil {
+		return err
+	}
+	return nil
+}
+
 // THIS METHOD SHOULD ONLY BE USED FOR TESTING
 func deleteAllPgDatabases(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
 	databases := apiV1.PgDatabaseList{}
@@ -178,3 +333,24 @@ func deleteAllPgInstances(ctx context.Context, c client.Client, opts []client.De
 	}
 	return nil
 }
+
+// THIS METHOD SHOULD ONLY BE USED FOR TESTING
+func deleteAllClusterPgInstances(ctx context.Context, c client.Client, opts []client.DeleteAllOfOption) error {
+	instances := apiV1.ClusterPgInstanceList{}
+	if err := c.List(ctx, &instances); err != nil {
+		return nil
+	}
+	// Remove the finalizers from all resource objects to ensure no logic gets executed before deletion
+	for i := range instances.Items {
+		instancePtr := &instances.Items[i]
+		instancePtr.Finalizers = []string{}
+		if err := c.Update(ctx, instancePtr); err != nil {
+			return err
+		}
+	}
+	instance := apiV1.ClusterPgInstance{}
+	if err := c.DeleteAllOf(ctx, &instance, opts...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,6 +35,8 @@ import (
 
 	postgresv1 "github.com/brose-ebike/postgres-operator/api/v1"
 	"github.com/brose-ebike/postgres-operator/controllers"
+	"github.com/brose-ebike/postgres-operator/pkg/security"
+	"github.com/brose-ebike/postgres-operator/pkg/services"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -57,6 +61,23 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	var passwordPolicy security.PasswordPolicy
+	var passwordCharacterClasses string
+	flag.IntVar(&passwordPolicy.MinLength, "password-min-length", security.DefaultPasswordPolicy.MinLength,
+		"The minimum length of generated passwords.")
+	flag.IntVar(&passwordPolicy.MaxLength, "password-max-length", security.DefaultPasswordPolicy.MaxLength,
+		"The maximum length of generated passwords.")
+	flag.StringVar(&passwordCharacterClasses, "password-character-classes", "Lowercase,Uppercase,Digits",
+		"The comma separated character classes (Lowercase, Uppercase, Digits, Symbols) of generated passwords.")
+	flag.StringVar(&passwordPolicy.Symbols, "password-symbols", security.DefaultSymbols,
+		"The characters of the character class Symbols.")
+	flag.IntVar(&passwordPolicy.MinSymbols, "password-min-symbols", 0,
+		"The minimum number of symbols in generated passwords.")
+	flag.StringVar(&passwordPolicy.ExcludedCharacters, "password-excluded-characters", "",
+		"The characters which are never used in generated passwords.")
+	var credentialExecConfig string
+	flag.StringVar(&credentialExecConfig, "credential-exec-config", "",
+		"The JSON file with the rules for the command lines which can be run by exec credential providers of instances.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +86,28 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	for _, class := range strings.Split(passwordCharacterClasses, ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			passwordPolicy.CharacterClasses = append(passwordPolicy.CharacterClasses, security.CharacterClass(class))
+		}
+	}
+	if err := passwordPolicy.Validate(); err != nil {
+		setupLog.Error(err, "invalid password policy")
+		os.Exit(1)
+	}
+	if credentialExecConfig != "" {
+		var rules []services.ExecCommandRule
+		data, err := os.ReadFile(credentialExecConfig)
+		if err == nil {
+			err = json.Unmarshal(data, &rules)
+		}
+		if err != nil {
+			setupLog.Error(err, "invalid credential exec config")
+			os.Exit(1)
+		}
+		services.SetAllowedExecCommands(rules)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -103,12 +146,116 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "PgDatabase")
 	}
 	if err = (&controllers.PgUserReconciler{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		DefaultPasswordPolicy: passwordPolicy,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgUser")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgSchemaReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PgUser")
+		setupLog.Error(err, "unable to create controller", "controller", "PgSchema")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgRoleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgRole")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterPgInstanceReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterPgInstance")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgBackupReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgBackup")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgRestoreReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgRestore")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgPublicationReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgPublication")
 		os.Exit(1)
 	}
+	if err = (&controllers.PgSubscriptionReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgSubscription")
+		os.Exit(1)
+	}
+	if err = (&controllers.PgScriptReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PgScript")
+		os.Exit(1)
+	}
+	// Webhooks can be disabled to run the manager locally without certificates
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&postgresv1.PgInstance{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgInstance")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.ClusterPgInstance{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterPgInstance")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgDatabase{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgDatabase")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgUser{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgUser")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgRole{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgRole")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgSchema{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgSchema")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgPublication{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgPublication")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgSubscription{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgSubscription")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgScript{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgScript")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgBackup{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgBackup")
+			os.Exit(1)
+		}
+		if err = (&postgresv1.PgRestore{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PgRestore")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
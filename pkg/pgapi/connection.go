@@ -19,6 +19,7 @@ package pgapi
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -36,6 +37,27 @@ type PgConnector interface {
 	// If the connection cannot be established, or the server does not communicate
 	// as expected, an error is returned
 	TestConnection() error
+	// GetServerInfo queries the version, the privileges of the connected role
+	// and the current load of the Postgres instance
+	GetServerInfo() (PgServerInfo, error)
+}
+
+// PgServerInfo describes the state of a Postgres instance
+type PgServerInfo struct {
+	// Version is the human readable server version, e.g. 14.5
+	Version string
+	// Superuser is true if the connected role is a superuser
+	Superuser bool
+	// CreateRole is true if the connected role is allowed to create roles
+	CreateRole bool
+	// CreateDB is true if the connected role is allowed to create databases
+	CreateDB bool
+	// StartTime is the time at which the server was started
+	StartTime time.Time
+	// Connections is the number of currently open client connections
+	Connections int
+	// MaxConnections is the maximum number of concurrent connections
+	MaxConnections int
 }
 
 func (s *pgInstanceAPIImpl) ConnectionString() PgConnectionString {
@@ -46,25 +68,41 @@ func (s *pgInstanceAPIImpl) connect() error {
 	if s.instance != nil {
 		return nil
 	}
+	// Check the server certificates before any credentials are sent
+	if err := s.checkServerRevocation(); err != nil {
+		return err
+	}
+	// Write certificates and keys for the driver
+	tlsFiles, err := writeTLSFiles(s.connectionString.tls)
+	if err != nil {
+		return err
+	}
+
 	// Start SQL Database
-	db, err := sql.Open("postgres", s.connectionString.toString())
+	db, err := sql.Open("postgres", s.connectionString.toString()+tlsFiles.toString())
 	if err != nil {
+		_ = tlsFiles.remove()
 		return err
 	}
 
 	// Connect to Database Server
 	con, err := db.Conn(s.ctx)
 	if err != nil {
+		_ = db.Close()
+		_ = tlsFiles.remove()
 		return err
 	}
 
 	err = con.Close()
 	if err != nil {
+		_ = db.Close()
+		_ = tlsFiles.remove()
 		return err
 	}
 
 	// Connection established
 	s.instance = db
+	s.tlsFiles = tlsFiles
 	return nil
 }
 
@@ -75,6 +113,11 @@ func (s *pgInstanceAPIImpl) disconnect() error {
 
 	err := s.instance.Close()
 	s.instance = nil
+	// Remove certificates and keys after the connections were closed
+	if removeErr := s.tlsFiles.remove(); removeErr != nil && err == nil {
+		err = removeErr
+	}
+	s.tlsFiles = nil
 	return err
 }
 
@@ -104,3 +147,28 @@ func (s *pgInstanceAPIImpl) newConnection() (*sql.Conn, error) {
 	// Connect to Database Server
 	return s.instance.Conn(s.ctx)
 }
+
+func (s *pgInstanceAPIImpl) GetServerInfo() (PgServerInfo, error) {
+	// Reconnect if the connection was closed by a previous connection test
+	if err := s.connect(); err != nil {
+		return PgServerInfo{}, err
+	}
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return PgServerInfo{}, err
+	}
+	defer conn.Close()
+
+	info := PgServerInfo{}
+	const query = "select current_setting('server_version'), r.rolsuper, r.rolcreaterole, r.rolcreatedb, pg_postmaster_start_time(), " +
+		"(select count(*) from pg_stat_activity where backend_type = 'client backend'), current_setting('max_connections')::integer " +
+		"from pg_roles r where r.rolname = current_user;"
+	err = conn.QueryRowContext(s.ctx, query).Scan(
+		&info.Version, &info.Superuser, &info.CreateRole, &info.CreateDB, &info.StartTime, &info.Connections, &info.MaxConnections,
+	)
+	if err != nil {
+		return PgServerInfo{}, WrapSqlExecutionError(err, query)
+	}
+	return info, nil
+}
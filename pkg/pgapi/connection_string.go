@@ -17,11 +17,24 @@ limitations under the License.
 package pgapi
 
 import (
+	"crypto/tls"
 	"errors"
 	"strconv"
 	"strings"
 )
 
+// PgTLSConfig contains the PEM encoded certificates and keys for a TLS connection
+type PgTLSConfig struct {
+	// RootCert contains the certificates of the authorities which are used to verify the server
+	RootCert string
+	// Cert contains the client certificate which is used to authenticate the user
+	Cert string
+	// Key contains the private key of the client certificate
+	Key string
+	// CRL contains the revocation list against which the server certificates are checked
+	CRL string
+}
+
 type PgConnectionString struct {
 	hostname string
 	port     int
@@ -29,6 +42,7 @@ type PgConnectionString struct {
 	password string
 	database string
 	sslMode  string
+	tls      PgTLSConfig
 }
 
 func NewPgConnectionString(
@@ -49,32 +63,68 @@ func NewPgConnectionString(
 		password,
 		database,
 		sslMode,
+		PgTLSConfig{},
 	}, nil
 }
 
+// SetTLSConfig sets the certificates and keys which should be used for TLS connections
+func (pgcs *PgConnectionString) SetTLSConfig(config PgTLSConfig) error {
+	if (config.Cert == "") != (config.Key == "") {
+		return errors.New("the client certificate and its key have to be set together")
+	}
+	if config.RootCert != "" {
+		if _, err := ParseCertificates(config.RootCert); err != nil {
+			return err
+		}
+	}
+	if config.Cert != "" {
+		if _, err := tls.X509KeyPair([]byte(config.Cert), []byte(config.Key)); err != nil {
+			return err
+		}
+	}
+	if config.CRL != "" {
+		if _, err := parseRevocationList(config.CRL); err != nil {
+			return err
+		}
+	}
+	pgcs.tls = config
+	return nil
+}
+
 func (pgcs *PgConnectionString) toString() string {
 	result := ""
 	if pgcs.hostname != "" {
-		result += "host=" + pgcs.hostname + " "
+		result += "host=" + quoteConnectionValue(pgcs.hostname) + " "
 	}
 	if pgcs.port != 5432 {
 		result += "port=" + strconv.Itoa(pgcs.port) + " "
 	}
 	if pgcs.username != "" {
-		result += "user=" + pgcs.username + " "
+		result += "user=" + quoteConnectionValue(pgcs.username) + " "
 	}
 	if pgcs.password != "" {
-		result += "password=" + pgcs.password + " "
+		result += "password=" + quoteConnectionValue(pgcs.password) + " "
 	}
 	if pgcs.database != "" {
-		result += "dbname=" + pgcs.database + " "
+		result += "dbname=" + quoteConnectionValue(pgcs.database) + " "
 	}
 	if pgcs.sslMode != "" {
-		result += "sslmode=" + pgcs.sslMode + " "
+		result += "sslmode=" + quoteConnectionValue(pgcs.sslMode) + " "
 	}
 	return strings.TrimSpace(result)
 }
 
+// quoteConnectionValue quotes a value of a key/value connection string if it contains
+// whitespace, quotes or backslashes
+func quoteConnectionValue(value string) string {
+	if !strings.ContainsAny(value, " \t\n\r\v\f'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "'", "\\'")
+	return "'" + value + "'"
+}
+
 func (pgcs *PgConnectionString) Hostname() string {
 	return pgcs.hostname
 }
@@ -91,6 +141,11 @@ func (pgcs *PgConnectionString) Password() string {
 	return pgcs.password
 }
 
+// Login returns the credentials of the administrator
+func (pgcs *PgConnectionString) Login() PgLogin {
+	return PgLogin{Username: pgcs.username, Password: pgcs.password}
+}
+
 func (pgcs *PgConnectionString) Database() string {
 	return pgcs.database
 }
@@ -99,6 +154,10 @@ func (pgcs *PgConnectionString) SSLMode() string {
 	return pgcs.sslMode
 }
 
+func (pgcs *PgConnectionString) TLSConfig() PgTLSConfig {
+	return pgcs.tls
+}
+
 func (pgcs *PgConnectionString) copy() *PgConnectionString {
 	return &PgConnectionString{
 		hostname: pgcs.hostname,
@@ -107,5 +166,6 @@ func (pgcs *PgConnectionString) copy() *PgConnectionString {
 		password: pgcs.password,
 		database: pgcs.database,
 		sslMode:  pgcs.sslMode,
+		tls:      pgcs.tls,
 	}
 }
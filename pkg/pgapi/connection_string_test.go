@@ -81,3 +81,14 @@ func TestPgConnectionStringToString(t *testing.T) {
 		t.Errorf("Postgres Connection String: %s", actual)
 	}
 }
+
+func TestPgConnectionStringToStringQuotesValues(t *testing.T) {
+	pgCS, err := NewPgConnectionString("hostname", 1234, "username", "it's a \\secret", "database", "none")
+	if err != nil {
+		t.Errorf("Create connection string failed")
+	}
+	actual := pgCS.toString()
+	if actual != "host=hostname port=1234 user=username password='it\\'s a \\\\secret' dbname=database sslmode=none" {
+		t.Errorf("Postgres Connection String: %s", actual)
+	}
+}
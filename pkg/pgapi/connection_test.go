@@ -50,6 +50,18 @@ var _ = Describe("PostgresAPI Connection Handling", func() {
 
 	})
 
+	It("server info contains the version and privileges of the connected role", func() {
+		// when
+		info, err := pgApi.GetServerInfo()
+		// then
+		Expect(err).To(BeNil())
+		Expect(info.Version).To(HavePrefix("14."))
+		Expect(info.Superuser).To(BeTrue())
+		Expect(info.StartTime).ToNot(BeZero())
+		Expect(info.Connections).To(BeNumerically(">=", 1))
+		Expect(info.MaxConnections).To(BeNumerically(">=", info.Connections))
+	})
+
 	It("connection string returns the current connection string", func() {
 		// Test Server Connection
 		cs := pgApi.ConnectionString()
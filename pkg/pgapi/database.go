@@ -19,12 +19,39 @@ package pgapi
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
 	_ "github.com/lib/pq"
 )
 
+// PgDatabaseOptions contains the options of a database.
+// Empty strings and nil values are not set explicitly and use the server defaults.
+type PgDatabaseOptions struct {
+	// Encoding is the character set encoding of the database
+	Encoding string
+	// LcCollate is the collation order (LC_COLLATE) of the database
+	LcCollate string
+	// LcCtype is the character classification (LC_CTYPE) of the database
+	LcCtype string
+	// IcuLocale is the ICU locale of the database, setting it selects the ICU locale provider
+	IcuLocale string
+	// Template is the name of the template from which the database is created,
+	// it is only used for the creation and not returned by GetDatabaseOptions
+	Template string
+	// Tablespace is the name of the default tablespace of the database
+	Tablespace string
+	// Owner is the name of the role which owns the database,
+	// it is only used for the creation and not returned by GetDatabaseOptions
+	Owner string
+	// ConnectionLimit limits the concurrent connections to the database, -1 means no limit
+	ConnectionLimit *int
+	// AllowConnections is false if no connections to the database are allowed
+	AllowConnections *bool
+}
+
 // PgDatabaseAPI provides functionality to check and manipulate
 // databases, database ownership and privileges on databases
 type PgDatabaseAPI interface {
@@ -33,12 +60,33 @@ type PgDatabaseAPI interface {
 	IsDatabaseExisting(databaseName string) (bool, error)
 	// CreateDatabase creates a new database on the connected instance
 	CreateDatabase(databaseName string) error
+	// CreateDatabaseWithOptions creates a new database with the given options on the connected instance
+	CreateDatabaseWithOptions(databaseName string, options PgDatabaseOptions) error
+	// GetDatabaseOptions returns the current options of the database with the given name
+	GetDatabaseOptions(databaseName string) (PgDatabaseOptions, error)
+	// GetEncodingName returns the name of the given encoding as used by the connected instance, e.g. UTF8 for UTF-8,
+	// or an empty string if the encoding is unknown
+	GetEncodingName(encoding string) (string, error)
+	// UpdateDatabaseOptions alters the mutable options (connection limit and allow connections)
+	// of the database with the given name, if they differ from the given options
+	UpdateDatabaseOptions(databaseName string, options PgDatabaseOptions) error
 	// DeleteDatabase drops the database with the given name on the connected instance
 	DeleteDatabase(databaseName string) error
+	// GetDatabaseSessionCount returns the number of other sessions connected to the database with the given name
+	GetDatabaseSessionCount(databaseName string) (int, error)
+	// TerminateDatabaseSessions terminates all other sessions connected to the database with the given name
+	TerminateDatabaseSessions(databaseName string) error
+	// ForceDeleteDatabase terminates all sessions connected to the database with the given name
+	// and drops the database on the connected instance
+	ForceDeleteDatabase(databaseName string) error
 	// GetDatabaseOwner returns the owner of the database with the given name on the connected instance
 	GetDatabaseOwner(databaseName string) (string, error)
 	// UpdateDatabaseOwner changes the owner of the database with the given name to the role with the given name
 	UpdateDatabaseOwner(databaseName string, roleName string) error
+	// GetDatabaseComment returns the comment of the database with the given name or an empty string if it has none
+	GetDatabaseComment(databaseName string) (string, error)
+	// UpdateDatabaseComment changes the comment of the database with the given name
+	UpdateDatabaseComment(databaseName string, comment string) error
 	// ResetDatabaseOwner changes the owner of the database with the given name to the role with which the client is connected
 	ResetDatabaseOwner(databaseName string) error
 	// UpdateDatabasePrivileges changes the given privileges on the given database for the given role
@@ -47,6 +95,20 @@ type PgDatabaseAPI interface {
 	IsDatabaseExtensionPresent(databaseName string, extension string) (bool, error)
 	// CreateDatabaseExtension creates the given extension in the database
 	CreateDatabaseExtension(databaseName string, extension string) error
+	// GetDatabaseExtensionVersion returns the installed version of the given extension
+	// or an empty string if the extension is not installed in the database
+	GetDatabaseExtensionVersion(databaseName string, extension string) (string, error)
+	// IsDatabaseExtensionAvailable checks if the given version of the extension can be installed in the database,
+	// an empty version checks if any version is available
+	IsDatabaseExtensionAvailable(databaseName string, extension string, version string) (bool, error)
+	// CreateDatabaseExtensionWithOptions creates the given extension in the database
+	// with the given version in the given schema, empty values use the defaults of the extension
+	CreateDatabaseExtensionWithOptions(databaseName string, extension string, version string, schema string, cascade bool) error
+	// UpdateDatabaseExtension updates the given extension to the given version,
+	// an empty version updates to the default version
+	UpdateDatabaseExtension(databaseName string, extension string, version string) error
+	// DeleteDatabaseExtension drops the given extension from the database
+	DeleteDatabaseExtension(databaseName string, extension string, cascade bool) error
 }
 
 func (s *pgInstanceAPIImpl) IsDatabaseExisting(databaseName string) (bool, error) {
@@ -76,6 +138,135 @@ func (s *pgInstanceAPIImpl) CreateDatabase(databaseName string) error {
 	return WrapSqlExecutionError(err, query, databaseName)
 }
 
+func (s *pgInstanceAPIImpl) CreateDatabaseWithOptions(databaseName string, options PgDatabaseOptions) error {
+	// Collect all options which are set
+	clauses := make([]string, 0)
+	if options.Template != "" {
+		clauses = append(clauses, formatQueryObj("template %s", options.Template))
+	}
+	if options.Encoding != "" {
+		clauses = append(clauses, "encoding "+escapeQueryValue(options.Encoding))
+	}
+	if options.LcCollate != "" {
+		clauses = append(clauses, "lc_collate "+escapeQueryValue(options.LcCollate))
+	}
+	if options.LcCtype != "" {
+		clauses = append(clauses, "lc_ctype "+escapeQueryValue(options.LcCtype))
+	}
+	if options.IcuLocale != "" {
+		clauses = append(clauses, "locale_provider icu icu_locale "+escapeQueryValue(options.IcuLocale))
+	}
+	if options.Tablespace != "" {
+		clauses = append(clauses, formatQueryObj("tablespace %s", options.Tablespace))
+	}
+	if options.ConnectionLimit != nil {
+		clauses = append(clauses, "connection limit "+strconv.Itoa(*options.ConnectionLimit))
+	}
+	if options.AllowConnections != nil {
+		clauses = append(clauses, "allow_connections "+strconv.FormatBool(*options.AllowConnections))
+	}
+	if options.Owner != "" {
+		clauses = append(clauses, formatQueryObj("owner %s", options.Owner))
+	}
+	if len(clauses) == 0 {
+		return s.CreateDatabase(databaseName)
+	}
+
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	// The clauses are appended after formatting, because they are escaped already
+	joinedClauses := strings.Join(clauses, " ")
+	query := "create database %s with " + joinedClauses + ";"
+	create := func() error {
+		_, err := conn.ExecContext(s.ctx, formatQueryObj("create database %s with ", databaseName)+joinedClauses+";")
+		return WrapSqlExecutionError(err, query, databaseName)
+	}
+	if options.Owner == "" {
+		return create()
+	}
+	// The current role has to be a member of the owner
+	return s.runAs(conn, options.Owner, create)
+}
+
+func (s *pgInstanceAPIImpl) GetDatabaseOptions(databaseName string) (PgDatabaseOptions, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return PgDatabaseOptions{}, err
+	}
+	version, err := s.serverVersion(conn)
+	if err != nil {
+		return PgDatabaseOptions{}, err
+	}
+	// The ICU locale is available since Postgres 15 and was renamed in Postgres 17
+	icuLocaleColumn := "''"
+	if version >= 170000 {
+		icuLocaleColumn = "coalesce(d.datlocale, '')"
+	} else if version >= 150000 {
+		icuLocaleColumn = "coalesce(d.daticulocale, '')"
+	}
+	options := PgDatabaseOptions{}
+	var connectionLimit int
+	var allowConnections bool
+	query := "select pg_catalog.pg_encoding_to_char(d.encoding), d.datcollate, d.datctype, " + icuLocaleColumn + ", t.spcname, d.datconnlimit, d.datallowconn from pg_catalog.pg_database d join pg_catalog.pg_tablespace t on d.dattablespace = t.oid where d.datname = $1;"
+	err = conn.QueryRowContext(s.ctx, query, databaseName).Scan(
+		&options.Encoding, &options.LcCollate, &options.LcCtype, &options.IcuLocale, &options.Tablespace, &connectionLimit, &allowConnections,
+	)
+	if err != nil {
+		return PgDatabaseOptions{}, WrapSqlExecutionError(err, query, databaseName)
+	}
+	options.ConnectionLimit = &connectionLimit
+	options.AllowConnections = &allowConnections
+	return options, nil
+}
+
+func (s *pgInstanceAPIImpl) GetEncodingName(encoding string) (string, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return "", err
+	}
+	var name string
+	const query = "select pg_catalog.pg_encoding_to_char(pg_catalog.pg_char_to_encoding($1));"
+	err = conn.QueryRowContext(s.ctx, query, encoding).Scan(&name)
+	return name, WrapSqlExecutionError(err, query, encoding)
+}
+
+func (s *pgInstanceAPIImpl) UpdateDatabaseOptions(databaseName string, options PgDatabaseOptions) error {
+	current, err := s.GetDatabaseOptions(databaseName)
+	if err != nil {
+		return err
+	}
+	// Collect all options which differ
+	clauses := make([]string, 0)
+	if options.ConnectionLimit != nil && *options.ConnectionLimit != *current.ConnectionLimit {
+		clauses = append(clauses, "connection limit "+strconv.Itoa(*options.ConnectionLimit))
+	}
+	if options.AllowConnections != nil && *options.AllowConnections != *current.AllowConnections {
+		clauses = append(clauses, "allow_connections "+strconv.FormatBool(*options.AllowConnections))
+	}
+	// Nothing to do
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	// The clauses are appended after formatting, because they are escaped already
+	joinedClauses := strings.Join(clauses, " ")
+	query := "alter database %s with " + joinedClauses + ";"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj("alter database %s with ", databaseName)+joinedClauses+";")
+	return WrapSqlExecutionError(err, query, databaseName)
+}
+
 func (s *pgInstanceAPIImpl) DeleteDatabase(databaseName string) error {
 	// Connect to Database Server
 	conn, err := s.newConnection()
@@ -90,6 +281,79 @@ func (s *pgInstanceAPIImpl) DeleteDatabase(databaseName string) error {
 	})
 }
 
+func (s *pgInstanceAPIImpl) GetDatabaseSessionCount(databaseName string) (int, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return 0, err
+	}
+	// Execute Query
+	const query = "select count(*) from pg_catalog.pg_stat_activity where datname = $1 and pid <> pg_catalog.pg_backend_pid();"
+	var count int
+	err = conn.QueryRowContext(s.ctx, query, databaseName).Scan(&count)
+	return count, WrapSqlExecutionError(err, query, databaseName)
+}
+
+func (s *pgInstanceAPIImpl) TerminateDatabaseSessions(databaseName string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "select pg_catalog.pg_terminate_backend(pid) from pg_catalog.pg_stat_activity where datname = $1 and pid <> pg_catalog.pg_backend_pid();"
+	_, err = conn.ExecContext(s.ctx, query, databaseName)
+	return WrapSqlExecutionError(err, query, databaseName)
+}
+
+func (s *pgInstanceAPIImpl) ForceDeleteDatabase(databaseName string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	version, err := s.serverVersion(conn)
+	if err != nil {
+		return err
+	}
+	return s.runAs(conn, s.connectionString.username, func() error {
+		// The force option is available since Postgres 13
+		if version >= 130000 {
+			const query = "drop database %s with (force);"
+			_, err = conn.ExecContext(s.ctx, formatQueryObj(query, databaseName))
+			return WrapSqlExecutionError(err, query, databaseName)
+		}
+		// Prevent new sessions, before the existing sessions are terminated
+		const queryAlter = "alter database %s allow_connections false;"
+		_, err = conn.ExecContext(s.ctx, formatQueryObj(queryAlter, databaseName))
+		if err != nil {
+			return WrapSqlExecutionError(err, queryAlter, databaseName)
+		}
+		const queryTerminate = "select pg_catalog.pg_terminate_backend(pid) from pg_catalog.pg_stat_activity where datname = $1 and pid <> pg_catalog.pg_backend_pid();"
+		_, err = conn.ExecContext(s.ctx, queryTerminate, databaseName)
+		if err != nil {
+			return s.allowConnectionsAfterFailure(conn, databaseName, WrapSqlExecutionError(err, queryTerminate, databaseName))
+		}
+		const queryDrop = "drop database %s;"
+		_, err = conn.ExecContext(s.ctx, formatQueryObj(queryDrop, databaseName))
+		if err != nil {
+			return s.allowConnectionsAfterFailure(conn, databaseName, WrapSqlExecutionError(err, queryDrop, databaseName))
+		}
+		return nil
+	})
+}
+
+// allowConnectionsAfterFailure allows connections to the database again, which could not be dropped,
+// so the database is not left unusable. The given error is returned together with a failed restore.
+func (s *pgInstanceAPIImpl) allowConnectionsAfterFailure(conn *sql.Conn, databaseName string, err error) error {
+	const queryAllow = "alter database %s allow_connections true;"
+	_, allowErr := conn.ExecContext(s.ctx, formatQueryObj(queryAllow, databaseName))
+	if allowErr != nil {
+		return fmt.Errorf("%w, connections to the database remain disabled: %s", err, WrapSqlExecutionError(allowErr, queryAllow, databaseName).Error())
+	}
+	return err
+}
+
 func (s *pgInstanceAPIImpl) UpdateDatabaseOwner(databaseName string, roleName string) error {
 	// Connect to Database Server
 	conn, err := s.newConnection()
@@ -161,6 +425,33 @@ func (s *pgInstanceAPIImpl) GetDatabaseOwner(databaseName string) (string, error
 	return databaseOwner, nil
 }
 
+func (s *pgInstanceAPIImpl) GetDatabaseComment(databaseName string) (string, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return "", err
+	}
+	var comment string
+	const query = "select coalesce(pg_catalog.shobj_description(d.oid, 'pg_database'), '') from pg_catalog.pg_database as d where d.datname = $1;"
+	err = conn.QueryRowContext(s.ctx, query, databaseName).Scan(&comment)
+	if err != nil {
+		return "", WrapSqlExecutionError(err, query, databaseName)
+	}
+	return comment, nil
+}
+
+func (s *pgInstanceAPIImpl) UpdateDatabaseComment(databaseName string, comment string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	query := formatQueryObj("comment on database %s is ", databaseName) + escapeQueryValue(comment) + ";"
+	_, err = conn.ExecContext(s.ctx, query)
+	return WrapSqlExecutionError(err, query)
+}
+
 func (s *pgInstanceAPIImpl) ResetDatabaseOwner(databaseName string) error {
 	// Connect to Database Server
 	conn, err := s.newConnection()
@@ -197,3 +488,73 @@ func (s *pgInstanceAPIImpl) CreateDatabaseExtension(databaseName string, extensi
 		return WrapSqlExecutionError(err, query, extension)
 	})
 }
+
+func (s *pgInstanceAPIImpl) GetDatabaseExtensionVersion(databaseName string, extension string) (string, error) {
+	var version string
+	// Execute Query
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "select coalesce((select extversion from pg_extension where extname = $1), '');"
+		err := conn.QueryRowContext(s.ctx, query, extension).Scan(&version)
+		return WrapSqlExecutionError(err, query, extension)
+	})
+	return version, err
+}
+
+func (s *pgInstanceAPIImpl) IsDatabaseExtensionAvailable(databaseName string, extension string, version string) (bool, error) {
+	var available bool
+	// Execute Query
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "select exists(SELECT * FROM pg_available_extension_versions where name = $1 and ($2 = '' or version = $2));"
+		err := conn.QueryRowContext(s.ctx, query, extension, version).Scan(&available)
+		return WrapSqlExecutionError(err, query, extension, version)
+	})
+	return available, err
+}
+
+func (s *pgInstanceAPIImpl) CreateDatabaseExtensionWithOptions(databaseName string, extension string, version string, schema string, cascade bool) error {
+	// Collect all options which are set
+	clauses := make([]string, 0)
+	if schema != "" {
+		clauses = append(clauses, formatQueryObj("schema %s", schema))
+	}
+	if version != "" {
+		clauses = append(clauses, "version "+escapeQueryValue(version))
+	}
+	if cascade {
+		clauses = append(clauses, "cascade")
+	}
+	// Execute Query
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		// The clauses are appended after formatting, because they are escaped already
+		joinedClauses := strings.Join(clauses, " ")
+		query := "create extension %s " + joinedClauses + ";"
+		_, err := conn.ExecContext(s.ctx, formatQueryObj("create extension %s ", extension)+joinedClauses+";")
+		return WrapSqlExecutionError(err, query, extension)
+	})
+}
+
+func (s *pgInstanceAPIImpl) UpdateDatabaseExtension(databaseName string, extension string, version string) error {
+	// The version is appended after formatting, because it is escaped already
+	versionClause := ""
+	if version != "" {
+		versionClause = " to " + escapeQueryValue(version)
+	}
+	// Execute Query
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		query := "alter extension %s update" + versionClause + ";"
+		_, err := conn.ExecContext(s.ctx, formatQueryObj("alter extension %s update", extension)+versionClause+";")
+		return WrapSqlExecutionError(err, query, extension)
+	})
+}
+
+func (s *pgInstanceAPIImpl) DeleteDatabaseExtension(databaseName string, extension string, cascade bool) error {
+	// Execute Query
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		query := "drop extension %s;"
+		if cascade {
+			query = "drop extension %s cascade;"
+		}
+		_, err := conn.ExecContext(s.ctx, formatQueryObj(query, extension))
+		return WrapSqlExecutionError(err, query, extension)
+	})
+}
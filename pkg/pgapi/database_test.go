@@ -17,6 +17,8 @@ limitations under the License.
 package pgapi
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -48,6 +50,86 @@ var _ = Describe("PostgresAPI Database Handling", func() {
 		Expect(err).To(BeNil())
 	})
 
+	It("can delete database with connected sessions", func() {
+		// Create new database
+		err := pgApi.CreateDatabase("dummy_db_25")
+		Expect(err).To(BeNil())
+		// Connect to the database
+		conStr := pgApi.(*pgInstanceAPIImpl).connectionString.copy()
+		conStr.database = "dummy_db_25"
+		db, err := sql.Open("postgres", conStr.toString())
+		Expect(err).To(BeNil())
+		defer db.Close()
+		err = db.Ping()
+		Expect(err).To(BeNil())
+		// Delete database
+		err = pgApi.ForceDeleteDatabase("dummy_db_25")
+		Expect(err).To(BeNil())
+		exists, err := pgApi.IsDatabaseExisting("dummy_db_25")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("can clone a database after its sessions are closed", func() {
+		// Create new database with a table
+		err := pgApi.CreateDatabase("dummy_db_26")
+		Expect(err).To(BeNil())
+		err = pgApi.(*pgInstanceAPIImpl).runIn("dummy_db_26", func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table public.bikes (id integer);")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Connect to the database
+		conStr := pgApi.(*pgInstanceAPIImpl).connectionString.copy()
+		conStr.database = "dummy_db_26"
+		db, err := sql.Open("postgres", conStr.toString())
+		Expect(err).To(BeNil())
+		err = db.Ping()
+		Expect(err).To(BeNil())
+		// A database with connected sessions cannot be used as template
+		count, err := pgApi.GetDatabaseSessionCount("dummy_db_26")
+		Expect(err).To(BeNil())
+		Expect(count).To(BeNumerically(">=", 1))
+		err = db.Close()
+		Expect(err).To(BeNil())
+		Eventually(func() (int, error) {
+			return pgApi.GetDatabaseSessionCount("dummy_db_26")
+		}).Should(BeZero())
+		// Clone database
+		err = pgApi.CreateDatabaseWithOptions("dummy_db_27", PgDatabaseOptions{Template: "dummy_db_26"})
+		Expect(err).To(BeNil())
+		// Check the cloned table
+		var exists bool
+		err = pgApi.(*pgInstanceAPIImpl).runIn("dummy_db_27", func(ctx context.Context, conn *sql.Conn) error {
+			return conn.QueryRowContext(ctx, "select exists(select * from pg_catalog.pg_tables where tablename = 'bikes');").Scan(&exists)
+		})
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+	})
+
+	It("can terminate the sessions of a database", func() {
+		// Create new database
+		err := pgApi.CreateDatabase("dummy_db_35")
+		Expect(err).To(BeNil())
+		// Connect to the database
+		conStr := pgApi.(*pgInstanceAPIImpl).connectionString.copy()
+		conStr.database = "dummy_db_35"
+		db, err := sql.Open("postgres", conStr.toString())
+		Expect(err).To(BeNil())
+		defer db.Close()
+		err = db.Ping()
+		Expect(err).To(BeNil())
+		count, err := pgApi.GetDatabaseSessionCount("dummy_db_35")
+		Expect(err).To(BeNil())
+		Expect(count).To(BeNumerically(">=", 1))
+		// Terminate the sessions
+		err = pgApi.TerminateDatabaseSessions("dummy_db_35")
+		Expect(err).To(BeNil())
+		Eventually(func() (int, error) {
+			return pgApi.GetDatabaseSessionCount("dummy_db_35")
+		}).Should(BeZero())
+	})
+
 	It("can update database owner", func() {
 		newOwnerName := "dummy_db_2_owner"
 		databaseName := "dummy_db_2"
@@ -115,6 +197,38 @@ var _ = Describe("PostgresAPI Database Handling", func() {
 		Expect(err).To(BeNil())
 	})
 
+	It("can update and drop extensions", func() {
+		databaseName := "dummy_db_21"
+		// Create new database
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Check available versions
+		available, err := pgApi.IsDatabaseExtensionAvailable(databaseName, "pg_trgm", "1.5")
+		Expect(err).To(BeNil())
+		Expect(available).To(BeTrue())
+		available, err = pgApi.IsDatabaseExtensionAvailable(databaseName, "pg_trgm", "0.1")
+		Expect(err).To(BeNil())
+		Expect(available).To(BeFalse())
+		// Create Extension in older version
+		err = pgApi.CreateDatabaseExtensionWithOptions(databaseName, "pg_trgm", "1.5", "public", false)
+		Expect(err).To(BeNil())
+		version, err := pgApi.GetDatabaseExtensionVersion(databaseName, "pg_trgm")
+		Expect(err).To(BeNil())
+		Expect(version).To(Equal("1.5"))
+		// Update Extension
+		err = pgApi.UpdateDatabaseExtension(databaseName, "pg_trgm", "1.6")
+		Expect(err).To(BeNil())
+		version, err = pgApi.GetDatabaseExtensionVersion(databaseName, "pg_trgm")
+		Expect(err).To(BeNil())
+		Expect(version).To(Equal("1.6"))
+		// Drop Extension
+		err = pgApi.DeleteDatabaseExtension(databaseName, "pg_trgm", false)
+		Expect(err).To(BeNil())
+		version, err = pgApi.GetDatabaseExtensionVersion(databaseName, "pg_trgm")
+		Expect(err).To(BeNil())
+		Expect(version).To(BeEmpty())
+	})
+
 	It("cannot create a database twice", func() {
 		// Create new database
 		err := pgApi.CreateDatabase("dummy_db_6")
@@ -129,4 +243,85 @@ var _ = Describe("PostgresAPI Database Handling", func() {
 		Expect(err.Error()).To(Equal("Unable to execute query 'create database %s;' with arguments 'dummy_db_6'\npq: database \"dummy_db_6\" already exists"))
 		Expect(errors.Unwrap(err).Error()).To(Equal("pq: database \"dummy_db_6\" already exists"))
 	})
+
+	It("can create database with options", func() {
+		connectionLimit := 10
+		// Create new database
+		err := pgApi.CreateDatabaseWithOptions("dummy_db_19", PgDatabaseOptions{
+			Encoding:        "UTF8",
+			LcCollate:       "C",
+			LcCtype:         "C",
+			Template:        "template0",
+			ConnectionLimit: &connectionLimit,
+		})
+		Expect(err).To(BeNil())
+		// Check database options
+		options, err := pgApi.GetDatabaseOptions("dummy_db_19")
+		Expect(err).To(BeNil())
+		Expect(options.Encoding).To(Equal("UTF8"))
+		Expect(options.LcCollate).To(Equal("C"))
+		Expect(options.LcCtype).To(Equal("C"))
+		Expect(options.Tablespace).To(Equal("pg_default"))
+		Expect(*options.ConnectionLimit).To(Equal(10))
+		Expect(*options.AllowConnections).To(BeTrue())
+	})
+
+	It("can create database with owner", func() {
+		roleName := "dummy_role_26"
+		// Create new role
+		err := pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		// Create new database
+		err = pgApi.CreateDatabaseWithOptions("dummy_db_33", PgDatabaseOptions{Owner: roleName})
+		Expect(err).To(BeNil())
+		// Check database owner
+		owner, err := pgApi.GetDatabaseOwner("dummy_db_33")
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(roleName))
+	})
+
+	It("can normalize encoding names", func() {
+		name, err := pgApi.GetEncodingName("utf-8")
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("UTF8"))
+		// Unknown encodings have no name
+		name, err = pgApi.GetEncodingName("unknown")
+		Expect(err).To(BeNil())
+		Expect(name).To(BeEmpty())
+	})
+
+	It("can update database options", func() {
+		connectionLimit := 5
+		allowConnections := false
+		// Create new database
+		err := pgApi.CreateDatabase("dummy_db_20")
+		Expect(err).To(BeNil())
+		// Update database options
+		err = pgApi.UpdateDatabaseOptions("dummy_db_20", PgDatabaseOptions{
+			ConnectionLimit:  &connectionLimit,
+			AllowConnections: &allowConnections,
+		})
+		Expect(err).To(BeNil())
+		// Check database options
+		options, err := pgApi.GetDatabaseOptions("dummy_db_20")
+		Expect(err).To(BeNil())
+		Expect(*options.ConnectionLimit).To(Equal(5))
+		Expect(*options.AllowConnections).To(BeFalse())
+	})
+
+	It("can update the comment of a database", func() {
+		// Create new database
+		err := pgApi.CreateDatabase("dummy_db_22")
+		Expect(err).To(BeNil())
+		// Check empty comment
+		comment, err := pgApi.GetDatabaseComment("dummy_db_22")
+		Expect(err).To(BeNil())
+		Expect(comment).To(BeEmpty())
+		// Update comment
+		err = pgApi.UpdateDatabaseComment("dummy_db_22", "team's database")
+		Expect(err).To(BeNil())
+		comment, err = pgApi.GetDatabaseComment("dummy_db_22")
+		Expect(err).To(BeNil())
+		Expect(comment).To(Equal("team's database"))
+	})
 })
@@ -15,7 +15,14 @@ limitations under the License.
 
 package pgapi
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
 
 type SqlExecutionError struct {
 	msg   string
@@ -38,3 +45,31 @@ func WrapSqlExecutionError(err error, query string, args ...string) error {
 
 func (e *SqlExecutionError) Error() string { return e.msg }
 func (e *SqlExecutionError) Unwrap() error { return e.err }
+
+// RoleCleanupError reports the progress of the cleanup of a role, which failed in at least one database
+type RoleCleanupError struct {
+	// Role is the name of the role which should be cleaned up
+	Role string
+	// Cleaned contains the databases in which the role was cleaned up
+	Cleaned []string
+	// Failed contains the errors of the databases in which the cleanup failed
+	Failed map[string]error
+}
+
+func (e *RoleCleanupError) Error() string {
+	failed := []string{}
+	for database, err := range e.Failed {
+		failed = append(failed, database+": "+err.Error())
+	}
+	sort.Strings(failed)
+	return fmt.Sprintf(
+		"Unable to clean up role %s in %d of %d databases, cleaned up [%s], failed [%s]",
+		e.Role, len(e.Failed), len(e.Failed)+len(e.Cleaned), strings.Join(e.Cleaned, ", "), strings.Join(failed, "; "),
+	)
+}
+
+// IsAuthenticationError returns true if the server refused the credentials (SQLSTATE class 28)
+func IsAuthenticationError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "28"
+}
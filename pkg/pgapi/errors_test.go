@@ -18,7 +18,9 @@ package pgapi
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -35,3 +37,29 @@ var _ = Describe("PostgresAPI SqlExecutionError", func() {
 		Expect(err).To(BeNil())
 	})
 })
+
+var _ = Describe("PostgresAPI RoleCleanupError", func() {
+
+	It("reports the cleaned and failed databases", func() {
+		err := &RoleCleanupError{
+			Role:    "service",
+			Cleaned: []string{"postgres"},
+			Failed:  map[string]error{"b": errors.New("second"), "a": errors.New("first")},
+		}
+		Expect(err.Error()).To(Equal("Unable to clean up role service in 2 of 3 databases, cleaned up [postgres], failed [a: first; b: second]"))
+	})
+})
+
+var _ = Describe("PostgresAPI IsAuthenticationError", func() {
+
+	It("detects refused credentials", func() {
+		err := fmt.Errorf("unable to connect: %w", &pq.Error{Code: "28P01"})
+		Expect(IsAuthenticationError(err)).To(BeTrue())
+	})
+
+	It("ignores other errors", func() {
+		Expect(IsAuthenticationError(&pq.Error{Code: "42P01"})).To(BeFalse())
+		Expect(IsAuthenticationError(errors.New("test"))).To(BeFalse())
+		Expect(IsAuthenticationError(nil)).To(BeFalse())
+	})
+})
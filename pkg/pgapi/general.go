@@ -27,12 +27,15 @@ import (
 // PgInstanceAPI represents the full functionality of the API to a postgres instance of a cluster
 // The implementation for this interface can be created by NewPgInstanceAPI
 // Instead of using this interface directly a client should implement its own interfaces or use one of the provided interfaces like
-// PgConnector, PgRoleAPI, PgDatabaseAPI or PgSchemaAPI
+// PgConnector, PgRoleAPI, PgDatabaseAPI, PgSchemaAPI, PgReplicationAPI, PgScriptAPI or PgSettingsAPI
 type PgInstanceAPI interface {
 	PgConnector
 	PgRoleAPI
 	PgDatabaseAPI
 	PgSchemaAPI
+	PgReplicationAPI
+	PgScriptAPI
+	PgSettingsAPI
 }
 
 // NewPgInstanceAPI creates an implementation for the PgInstanceAPI interface
@@ -43,6 +46,7 @@ func NewPgInstanceAPI(ctx context.Context, name string, connectionString *PgConn
 		*connectionString,
 		ctx,
 		nil,
+		nil,
 	}
 	if err := api.connect(); err != nil {
 		logger.Error(err, "Unable to connect")
@@ -70,6 +74,8 @@ type pgInstanceAPIImpl struct {
 	// If the clients need to set other contexts we need to refactor this struct and all methods!
 	ctx      context.Context
 	instance *sql.DB
+	// tlsFiles contains the certificates and keys which are used by the driver while connected
+	tlsFiles *pgTLSFiles
 }
 
 // isMember determines if roleA is a member of roleB
@@ -83,6 +89,17 @@ func (s *pgInstanceAPIImpl) isMember(con *sql.Conn, roleA, roleB string) (bool,
 	return result, nil
 }
 
+// serverVersion returns the version number of the connected server, e.g. 140005 for 14.5
+func (s *pgInstanceAPIImpl) serverVersion(con *sql.Conn) (int, error) {
+	var result int
+	const query = "select current_setting('server_version_num')::integer;"
+	sqlRow := con.QueryRowContext(s.ctx, query)
+	if err := sqlRow.Scan(&result); err != nil {
+		return 0, WrapSqlExecutionError(err, query)
+	}
+	return result, nil
+}
+
 func (s *pgInstanceAPIImpl) runAs(con *sql.Conn, role string, runner func() error) error {
 	myRole := s.connectionString.username
 	isMember, err := s.isMember(con, myRole, role)
@@ -110,6 +127,21 @@ func (s *pgInstanceAPIImpl) runAs(con *sql.Conn, role string, runner func() erro
 	return err
 }
 
+// runInTransaction executes the runner in a transaction on the given connection,
+// the transaction is committed if the runner succeeds and rolled back otherwise
+func runInTransaction(ctx context.Context, conn *sql.Conn, runner func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful commit
+	defer tx.Rollback()
+	if err := runner(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *pgInstanceAPIImpl) runIn(database string, runner func(ctx context.Context, conn *sql.Conn) error) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -119,10 +151,47 @@ func (s *pgInstanceAPIImpl) runIn(database string, runner func(ctx context.Conte
 	conStr.database = database
 
 	// Start SQL Database
-	db, err := sql.Open("postgres", conStr.toString())
+	db, err := sql.Open("postgres", conStr.toString()+s.tlsFiles.toString())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Connect to Database Server
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Execute commands
+	err = runner(ctx, conn)
+
+	// Close connection
+	if err := conn.Close(); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// runInWithLogin executes the runner in the given database on a connection, which is logged in with the given
+// credentials instead of the credentials of the instance. The client certificate of the instance is not used.
+func (s *pgInstanceAPIImpl) runInWithLogin(database string, login PgLogin, runner func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Use new connection string
+	conStr := s.connectionString.copy()
+	conStr.database = database
+	conStr.username = login.Username
+	conStr.password = login.Password
+
+	// Start SQL Database
+	db, err := sql.Open("postgres", conStr.toString()+s.tlsFiles.withoutClientCert().toString())
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
 	// Connect to Database Server
 	conn, err := db.Conn(ctx)
@@ -150,10 +219,11 @@ func (s *pgInstanceAPIImpl) runInAs(database string, role string, runner func(ct
 	conStr.database = database
 
 	// Start SQL Database
-	db, err := sql.Open("postgres", conStr.toString())
+	db, err := sql.Open("postgres", conStr.toString()+s.tlsFiles.toString())
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
 	// Connect to Database Server
 	conn, err := db.Conn(ctx)
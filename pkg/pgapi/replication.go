@@ -0,0 +1,424 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
+	"github.com/lib/pq"
+)
+
+var pgPublicationOperations = []string{"insert", "update", "delete", "truncate"}
+
+// PgPublicationOptions contains the options of a publication
+type PgPublicationOptions struct {
+	// AllTables publishes all tables of the database, including tables created in the future
+	AllTables bool
+	// Tables contains the schema qualified names of the published tables, e.g. public.orders
+	Tables []string
+	// Operations contains the published operations, all operations are published if empty
+	Operations []string
+}
+
+// PgSubscriptionOptions contains the options of a subscription
+type PgSubscriptionOptions struct {
+	// Connection contains the libpq connection string of the publisher
+	Connection string
+	// Publications contains the names of the subscribed publications
+	Publications []string
+	// Enabled specifies if the subscription replicates
+	Enabled bool
+	// CopyData copies the existing data of the published tables on creation
+	CopyData bool
+	// CreateSlot creates the replication slot on the publisher on creation
+	CreateSlot bool
+	// SlotName contains the name of the replication slot on the publisher, defaults to the subscription name
+	SlotName string
+}
+
+// PgSubscriptionState contains the observed state of a subscription
+type PgSubscriptionState struct {
+	// Enabled is true if the subscription replicates
+	Enabled bool
+	// SlotName contains the name of the replication slot on the publisher
+	SlotName string
+	// ReceivedLsn contains the last write-ahead log location received from the publisher
+	ReceivedLsn string
+	// LatestEndLsn contains the last write-ahead log location reported to the publisher
+	LatestEndLsn string
+	// LatestEndTime contains the time of the last location reported to the publisher
+	LatestEndTime *time.Time
+	// Tables contains the synchronization state of every subscribed table by its schema qualified name
+	Tables map[string]string
+}
+
+// PgReplicationAPI provides functionality to manage publications and subscriptions for logical replication
+type PgReplicationAPI interface {
+	// IsPublicationExisting returns true if a publication with the given name exists in the given database
+	IsPublicationExisting(databaseName string, publicationName string) (bool, error)
+	// GetPublicationOptions returns the options of the given publication
+	GetPublicationOptions(databaseName string, publicationName string) (PgPublicationOptions, error)
+	// CreatePublication creates a new publication with the given options in the given database
+	CreatePublication(databaseName string, publicationName string, options PgPublicationOptions) error
+	// UpdatePublication adds and removes tables and changes the operations of the given publication
+	UpdatePublication(databaseName string, publicationName string, options PgPublicationOptions) error
+	// DeletePublication drops the given publication from the given database
+	DeletePublication(databaseName string, publicationName string) error
+	// IsSubscriptionExisting returns true if a subscription with the given name exists in the given database
+	IsSubscriptionExisting(databaseName string, subscriptionName string) (bool, error)
+	// CreateSubscription creates a new subscription with the given options in the given database
+	CreateSubscription(databaseName string, subscriptionName string, options PgSubscriptionOptions) error
+	// UpdateSubscription changes the connection, publications and the enabled state of the given subscription
+	UpdateSubscription(databaseName string, subscriptionName string, options PgSubscriptionOptions) error
+	// DeleteSubscription drops the given subscription from the given database,
+	// the replication slot on the publisher is only dropped if dropSlot is true
+	DeleteSubscription(databaseName string, subscriptionName string, dropSlot bool) error
+	// GetSubscriptionState returns the observed state of the given subscription
+	GetSubscriptionState(databaseName string, subscriptionName string) (PgSubscriptionState, error)
+	// GetReplicationSlotLag returns the number of bytes the given replication slot is behind the current
+	// write-ahead log location of the connected instance and false if the slot does not exist
+	GetReplicationSlotLag(slotName string) (int64, bool, error)
+}
+
+func validateOperations(operations []string) error {
+	for _, operation := range operations {
+		if !hasElementString(pgPublicationOperations, operation) {
+			return brose_errors.NewIllegalArgumentError("operations", operation, nil)
+		}
+	}
+	return nil
+}
+
+// qualifyTableName prefixes the given table name with the public schema, if it contains no schema
+func qualifyTableName(name string) string {
+	if !strings.Contains(name, ".") {
+		return "public." + name
+	}
+	return name
+}
+
+// formatQualifiedNames quotes the given schema qualified names and joins them to a list
+func formatQualifiedNames(names []string) string {
+	quoted := []string{}
+	for _, name := range names {
+		schemaName, tableName, _ := strings.Cut(qualifyTableName(name), ".")
+		quoted = append(quoted, pq.QuoteIdentifier(schemaName)+"."+pq.QuoteIdentifier(tableName))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// formatIdentifiers quotes the given identifiers and joins them to a list
+func formatIdentifiers(names []string) string {
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, pq.QuoteIdentifier(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// publishedOperations returns the value of the publish parameter, defaulting to all operations
+func publishedOperations(operations []string) string {
+	if len(operations) == 0 {
+		return strings.Join(pgPublicationOperations, ", ")
+	}
+	return strings.Join(operations, ", ")
+}
+
+func (s *pgInstanceAPIImpl) IsPublicationExisting(databaseName string, publicationName string) (bool, error) {
+	var exists bool
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "select exists(select * from pg_catalog.pg_publication where pubname = $1);"
+		err := conn.QueryRowContext(ctx, query, publicationName).Scan(&exists)
+		return WrapSqlExecutionError(err, query, publicationName)
+	})
+	return exists, err
+}
+
+func (s *pgInstanceAPIImpl) GetPublicationOptions(databaseName string, publicationName string) (PgPublicationOptions, error) {
+	options := PgPublicationOptions{}
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		var insert, update, delete, truncate bool
+		const query = "select puballtables, pubinsert, pubupdate, pubdelete, pubtruncate from pg_catalog.pg_publication where pubname = $1;"
+		err := conn.QueryRowContext(ctx, query, publicationName).Scan(&options.AllTables, &insert, &update, &delete, &truncate)
+		if err != nil {
+			return WrapSqlExecutionError(err, query, publicationName)
+		}
+		for i, published := range []bool{insert, update, delete, truncate} {
+			if published {
+				options.Operations = append(options.Operations, pgPublicationOperations[i])
+			}
+		}
+		// All tables are listed for publications of all tables
+		if options.AllTables {
+			return nil
+		}
+		const queryT = "select schemaname || '.' || tablename from pg_catalog.pg_publication_tables where pubname = $1 order by 1;"
+		rows, err := conn.QueryContext(ctx, queryT, publicationName)
+		if err != nil {
+			return WrapSqlExecutionError(err, queryT, publicationName)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				return WrapSqlExecutionError(err, queryT, publicationName)
+			}
+			options.Tables = append(options.Tables, table)
+		}
+		return WrapSqlExecutionError(rows.Err(), queryT, publicationName)
+	})
+	return options, err
+}
+
+func (s *pgInstanceAPIImpl) CreatePublication(databaseName string, publicationName string, options PgPublicationOptions) error {
+	if err := validateOperations(options.Operations); err != nil {
+		return err
+	}
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		query := "create publication " + pq.QuoteIdentifier(publicationName)
+		if options.AllTables {
+			query += " for all tables"
+		} else if len(options.Tables) > 0 {
+			query += " for table " + formatQualifiedNames(options.Tables)
+		}
+		query += " with (publish = " + escapeQueryValue(publishedOperations(options.Operations)) + ");"
+		_, err := conn.ExecContext(ctx, query)
+		return WrapSqlExecutionError(err, query)
+	})
+}
+
+func (s *pgInstanceAPIImpl) UpdatePublication(databaseName string, publicationName string, options PgPublicationOptions) error {
+	if err := validateOperations(options.Operations); err != nil {
+		return err
+	}
+	current, err := s.GetPublicationOptions(databaseName, publicationName)
+	if err != nil {
+		return err
+	}
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		publication := pq.QuoteIdentifier(publicationName)
+		// Tables cannot be added to or removed from publications of all tables
+		if !options.AllTables {
+			desired := []string{}
+			for _, table := range options.Tables {
+				desired = append(desired, qualifyTableName(table))
+			}
+			added := []string{}
+			for _, table := range desired {
+				if !hasElementString(current.Tables, table) {
+					added = append(added, table)
+				}
+			}
+			removed := []string{}
+			for _, table := range current.Tables {
+				if !hasElementString(desired, table) {
+					removed = append(removed, table)
+				}
+			}
+			if len(added) > 0 {
+				query := "alter publication " + publication + " add table " + formatQualifiedNames(added) + ";"
+				if _, err := conn.ExecContext(ctx, query); err != nil {
+					return WrapSqlExecutionError(err, query)
+				}
+			}
+			if len(removed) > 0 {
+				query := "alter publication " + publication + " drop table " + formatQualifiedNames(removed) + ";"
+				if _, err := conn.ExecContext(ctx, query); err != nil {
+					return WrapSqlExecutionError(err, query)
+				}
+			}
+		}
+		if publishedOperations(options.Operations) != publishedOperations(current.Operations) {
+			query := "alter publication " + publication + " set (publish = " + escapeQueryValue(publishedOperations(options.Operations)) + ");"
+			if _, err := conn.ExecContext(ctx, query); err != nil {
+				return WrapSqlExecutionError(err, query)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *pgInstanceAPIImpl) DeletePublication(databaseName string, publicationName string) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "drop publication if exists %s;"
+		_, err := conn.ExecContext(ctx, formatQueryObj(query, publicationName))
+		return WrapSqlExecutionError(err, query, publicationName)
+	})
+}
+
+func (s *pgInstanceAPIImpl) IsSubscriptionExisting(databaseName string, subscriptionName string) (bool, error) {
+	var exists bool
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "select exists(select * from pg_catalog.pg_subscription s join pg_catalog.pg_database d on d.oid = s.subdbid where d.datname = current_database() and s.subname = $1);"
+		err := conn.QueryRowContext(ctx, query, subscriptionName).Scan(&exists)
+		return WrapSqlExecutionError(err, query, subscriptionName)
+	})
+	return exists, err
+}
+
+func (s *pgInstanceAPIImpl) CreateSubscription(databaseName string, subscriptionName string, options PgSubscriptionOptions) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		parameters := []string{
+			"enabled = " + formatBool(options.Enabled),
+			"copy_data = " + formatBool(options.CopyData),
+			"create_slot = " + formatBool(options.CreateSlot),
+		}
+		if options.SlotName != "" {
+			parameters = append(parameters, "slot_name = "+escapeQueryValue(options.SlotName))
+		}
+		query := "create subscription " + pq.QuoteIdentifier(subscriptionName) +
+			" connection " + escapeQueryValue(options.Connection) +
+			" publication " + formatIdentifiers(options.Publications) +
+			" with (" + strings.Join(parameters, ", ") + ");"
+		_, err := conn.ExecContext(ctx, query)
+		// The query contains the password of the publisher
+		return WrapSqlExecutionError(err, "create subscription %s connection ... publication ...", subscriptionName)
+	})
+}
+
+func (s *pgInstanceAPIImpl) UpdateSubscription(databaseName string, subscriptionName string, options PgSubscriptionOptions) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		var connection string
+		var publications []string
+		var enabled bool
+		const query = "select subconninfo, subpublications, subenabled from pg_catalog.pg_subscription s join pg_catalog.pg_database d on d.oid = s.subdbid where d.datname = current_database() and s.subname = $1;"
+		err := conn.QueryRowContext(ctx, query, subscriptionName).Scan(&connection, pq.Array(&publications), &enabled)
+		if err != nil {
+			return WrapSqlExecutionError(err, query, subscriptionName)
+		}
+		subscription := pq.QuoteIdentifier(subscriptionName)
+		if connection != options.Connection {
+			query := "alter subscription " + subscription + " connection " + escapeQueryValue(options.Connection) + ";"
+			if _, err := conn.ExecContext(ctx, query); err != nil {
+				return WrapSqlExecutionError(err, "alter subscription %s connection ...", subscriptionName)
+			}
+		}
+		desired := append([]string{}, options.Publications...)
+		sort.Strings(desired)
+		sort.Strings(publications)
+		if strings.Join(desired, ",") != strings.Join(publications, ",") {
+			// Refreshing the tables of the publications requires an enabled subscription
+			query := "alter subscription " + subscription + " set publication " + formatIdentifiers(options.Publications) +
+				" with (refresh = " + formatBool(enabled) + ");"
+			if _, err := conn.ExecContext(ctx, query); err != nil {
+				return WrapSqlExecutionError(err, query)
+			}
+		}
+		if enabled != options.Enabled {
+			query := "alter subscription " + subscription + " disable;"
+			if options.Enabled {
+				query = "alter subscription " + subscription + " enable;"
+			}
+			if _, err := conn.ExecContext(ctx, query); err != nil {
+				return WrapSqlExecutionError(err, query)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *pgInstanceAPIImpl) DeleteSubscription(databaseName string, subscriptionName string, dropSlot bool) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		// Detach the replication slot to keep it on the publisher
+		if !dropSlot {
+			const queryD = "alter subscription %s disable;"
+			if _, err := conn.ExecContext(ctx, formatQueryObj(queryD, subscriptionName)); err != nil {
+				return WrapSqlExecutionError(err, queryD, subscriptionName)
+			}
+			const queryS = "alter subscription %s set (slot_name = none);"
+			if _, err := conn.ExecContext(ctx, formatQueryObj(queryS, subscriptionName)); err != nil {
+				return WrapSqlExecutionError(err, queryS, subscriptionName)
+			}
+		}
+		const query = "drop subscription %s;"
+		_, err := conn.ExecContext(ctx, formatQueryObj(query, subscriptionName))
+		return WrapSqlExecutionError(err, query, subscriptionName)
+	})
+}
+
+func (s *pgInstanceAPIImpl) GetSubscriptionState(databaseName string, subscriptionName string) (PgSubscriptionState, error) {
+	state := PgSubscriptionState{Tables: map[string]string{}}
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		var slotName, receivedLsn, latestEndLsn sql.NullString
+		var latestEndTime sql.NullTime
+		const query = "select s.subenabled, s.subslotname, st.received_lsn::text, st.latest_end_lsn::text, st.latest_end_time " +
+			"from pg_catalog.pg_subscription s join pg_catalog.pg_database d on d.oid = s.subdbid " +
+			"left join pg_catalog.pg_stat_subscription st on st.subid = s.oid and st.relid is null " +
+			"where d.datname = current_database() and s.subname = $1;"
+		err := conn.QueryRowContext(ctx, query, subscriptionName).Scan(&state.Enabled, &slotName, &receivedLsn, &latestEndLsn, &latestEndTime)
+		if err != nil {
+			return WrapSqlExecutionError(err, query, subscriptionName)
+		}
+		state.SlotName = slotName.String
+		state.ReceivedLsn = receivedLsn.String
+		state.LatestEndLsn = latestEndLsn.String
+		if latestEndTime.Valid {
+			state.LatestEndTime = &latestEndTime.Time
+		}
+		const queryT = "select n.nspname || '.' || c.relname, r.srsubstate " +
+			"from pg_catalog.pg_subscription_rel r join pg_catalog.pg_subscription s on s.oid = r.srsubid " +
+			"join pg_catalog.pg_class c on c.oid = r.srrelid join pg_catalog.pg_namespace n on n.oid = c.relnamespace " +
+			"where s.subname = $1 and s.subdbid = (select oid from pg_catalog.pg_database where datname = current_database());"
+		rows, err := conn.QueryContext(ctx, queryT, subscriptionName)
+		if err != nil {
+			return WrapSqlExecutionError(err, queryT, subscriptionName)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table, tableState string
+			if err := rows.Scan(&table, &tableState); err != nil {
+				return WrapSqlExecutionError(err, queryT, subscriptionName)
+			}
+			state.Tables[table] = tableState
+		}
+		return WrapSqlExecutionError(rows.Err(), queryT, subscriptionName)
+	})
+	return state, err
+}
+
+func (s *pgInstanceAPIImpl) GetReplicationSlotLag(slotName string) (int64, bool, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	var lag sql.NullInt64
+	const query = "select pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn)::bigint from pg_catalog.pg_replication_slots where slot_name = $1;"
+	err = conn.QueryRowContext(s.ctx, query, slotName).Scan(&lag)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, WrapSqlExecutionError(err, query, slotName)
+	}
+	return lag.Int64, true, nil
+}
+
+// formatBool returns the given value as boolean literal
+func formatBool(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"context"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PostgresAPI Replication Handling", func() {
+	It("can create, update and delete a publication", func() {
+		databaseName := "dummy_db_28"
+		publicationName := "orders"
+		// Create new database with tables
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		err = pgApi.CreateSchema(databaseName, "sales")
+		Expect(err).To(BeNil())
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table orders (id integer primary key); create table sales.orders (id integer primary key);")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Check if publication exists
+		exists, err := pgApi.IsPublicationExisting(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+		// Create publication
+		err = pgApi.CreatePublication(databaseName, publicationName, PgPublicationOptions{
+			Tables:     []string{"orders"},
+			Operations: []string{"insert"},
+		})
+		Expect(err).To(BeNil())
+		exists, err = pgApi.IsPublicationExisting(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+		options, err := pgApi.GetPublicationOptions(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		Expect(options.AllTables).To(BeFalse())
+		Expect(options.Tables).To(Equal([]string{"public.orders"}))
+		Expect(options.Operations).To(Equal([]string{"insert"}))
+		// Update publication
+		err = pgApi.UpdatePublication(databaseName, publicationName, PgPublicationOptions{
+			Tables: []string{"sales.orders"},
+		})
+		Expect(err).To(BeNil())
+		options, err = pgApi.GetPublicationOptions(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		Expect(options.Tables).To(Equal([]string{"sales.orders"}))
+		Expect(options.Operations).To(Equal([]string{"insert", "update", "delete", "truncate"}))
+		// Delete publication
+		err = pgApi.DeletePublication(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		exists, err = pgApi.IsPublicationExisting(databaseName, publicationName)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("refuses unknown operations of a publication", func() {
+		err := pgApi.CreatePublication("postgres", "invalid", PgPublicationOptions{
+			Operations: []string{"select"},
+		})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("reports a missing replication slot", func() {
+		_, exists, err := pgApi.GetReplicationSlotLag("missing_slot")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+	})
+})
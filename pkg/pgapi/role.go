@@ -17,22 +17,87 @@ limitations under the License.
 package pgapi
 
 import (
+	"context"
+	"database/sql"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
+	"github.com/brose-ebike/postgres-operator/pkg/security"
 	_ "github.com/lib/pq"
 )
 
+// PgRoleMembership describes the membership of a role in a group role
+type PgRoleMembership struct {
+	// Group contains the name of the group role
+	Group string
+	// Admin is true if the member is allowed to grant the group role to other roles
+	Admin bool
+	// Inherit is true if the member inherits the privileges of the group role
+	Inherit bool
+}
+
+// PgRoleAttributes describes the attributes of a role.
+// Attributes which are nil are not managed and kept as they are.
+type PgRoleAttributes struct {
+	// CreateDatabase allows the role to create databases (CREATEDB)
+	CreateDatabase *bool
+	// CreateRole allows the role to create, alter and drop other roles (CREATEROLE)
+	CreateRole *bool
+	// Replication allows the role to initiate streaming replication (REPLICATION)
+	Replication *bool
+	// BypassRLS allows the role to bypass every row level security policy (BYPASSRLS)
+	BypassRLS *bool
+	// ConnectionLimit limits the concurrent connections of the role, -1 means no limit
+	ConnectionLimit *int
+	// ValidUntil is the time after which the password of the role is no longer valid
+	ValidUntil *time.Time
+}
+
 // PgRoleAPI provides functionality to check and manipulate login roles (role with login)
+// and group roles (role without login)
 type PgRoleAPI interface {
 	// IsRoleExisting returns true if a role
 	// with the given name exists on the connected instance and false if not.
 	IsRoleExisting(roleName string) (bool, error)
+	// IsRoleSuperuser returns true if a role with the given name exists and is a superuser
+	IsRoleSuperuser(roleName string) (bool, error)
 	// CreateRole creates the given role on the connected instance
 	CreateRole(name string) error
-	// DeleteRole drops the given role from the connected instance
+	// CreateGroupRole creates the given role without login on the connected instance
+	CreateGroupRole(name string) error
+	// GetRoleMemberships returns all group roles in which the given role is a member
+	GetRoleMemberships(roleName string) ([]PgRoleMembership, error)
+	// GrantRoleMembership makes the given role a member of the given group role
+	GrantRoleMembership(roleName string, groupName string, admin bool, inherit bool) error
+	// RevokeRoleMembership removes the given role from the given group role
+	RevokeRoleMembership(roleName string, groupName string) error
+	// DeleteRole drops the given role from the connected instance,
+	// the objects owned by the role are reassigned to the connected role.
+	// If the role cannot be cleaned up in all databases, a RoleCleanupError is returned.
 	DeleteRole(name string) error
+	// ReassignAndDeleteRole terminates the sessions of the given role,
+	// reassigns the objects owned by the given role in all databases to the new owner
+	// and drops the given role from the connected instance.
+	// If the role cannot be cleaned up in all databases, a RoleCleanupError is returned.
+	ReassignAndDeleteRole(name string, newOwner string) error
+	// TerminateRoleSessions terminates all sessions of the given role
+	TerminateRoleSessions(name string) error
 	// UpdateUserPassword changes the password for the given role
 	UpdateUserPassword(name string, password string) error
+	// DisableRoleLogin removes the password and the login attribute from the given role
+	DisableRoleLogin(name string) error
+	// UpdateRoleSessionRole changes the role which the given role assumes after login
+	UpdateRoleSessionRole(name string, sessionRole string) error
+	// GetRoleComment returns the comment of the given role or an empty string if it has none
+	GetRoleComment(name string) (string, error)
+	// UpdateRoleComment changes the comment of the given role
+	UpdateRoleComment(name string, comment string) error
+	// GetRoleAttributes returns the current attributes of the given role
+	GetRoleAttributes(name string) (PgRoleAttributes, error)
+	// UpdateRoleAttributes alters all attributes of the given role, which differ from the given attributes
+	UpdateRoleAttributes(name string, attributes PgRoleAttributes) error
 }
 
 func (s *pgInstanceAPIImpl) IsRoleExisting(roleName string) (bool, error) {
@@ -42,7 +107,7 @@ func (s *pgInstanceAPIImpl) IsRoleExisting(roleName string) (bool, error) {
 		return false, err
 	}
 	var exists bool
-	const query = "select exists(select * from pg_catalog.pg_user where usename = $1);"
+	const query = "select exists(select * from pg_catalog.pg_roles where rolname = $1);"
 	err = conn.QueryRowContext(s.ctx, query, roleName).Scan(&exists)
 	if err != nil {
 		return false, WrapSqlExecutionError(err, query, roleName)
@@ -50,6 +115,21 @@ func (s *pgInstanceAPIImpl) IsRoleExisting(roleName string) (bool, error) {
 	return exists, nil
 }
 
+func (s *pgInstanceAPIImpl) IsRoleSuperuser(roleName string) (bool, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return false, err
+	}
+	var superuser bool
+	const query = "select exists(select * from pg_catalog.pg_roles where rolname = $1 and rolsuper);"
+	err = conn.QueryRowContext(s.ctx, query, roleName).Scan(&superuser)
+	if err != nil {
+		return false, WrapSqlExecutionError(err, query, roleName)
+	}
+	return superuser, nil
+}
+
 func (s *pgInstanceAPIImpl) CreateRole(name string) error {
 	// Connect to Database Server
 	conn, err := s.newConnection()
@@ -62,24 +142,147 @@ func (s *pgInstanceAPIImpl) CreateRole(name string) error {
 	return WrapSqlExecutionError(err, query, name)
 }
 
+func (s *pgInstanceAPIImpl) CreateGroupRole(name string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "create role %s nologin;"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, name))
+	return WrapSqlExecutionError(err, query, name)
+}
+
+func (s *pgInstanceAPIImpl) GetRoleMemberships(roleName string) ([]PgRoleMembership, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.serverVersion(conn)
+	if err != nil {
+		return nil, err
+	}
+	// Before Postgres 16 inherit is an attribute of the member and not of the membership
+	query := "select g.rolname, m.admin_option, r.rolinherit from pg_catalog.pg_auth_members m join pg_catalog.pg_roles g on m.roleid = g.oid join pg_catalog.pg_roles r on m.member = r.oid where r.rolname = $1;"
+	if version >= 160000 {
+		query = "select g.rolname, m.admin_option, m.inherit_option from pg_catalog.pg_auth_members m join pg_catalog.pg_roles g on m.roleid = g.oid join pg_catalog.pg_roles r on m.member = r.oid where r.rolname = $1;"
+	}
+	rows, err := conn.QueryContext(s.ctx, query, roleName)
+	if err != nil {
+		return nil, WrapSqlExecutionError(err, query, roleName)
+	}
+	defer rows.Close()
+	memberships := []PgRoleMembership{}
+	for rows.Next() {
+		membership := PgRoleMembership{}
+		if err := rows.Scan(&membership.Group, &membership.Admin, &membership.Inherit); err != nil {
+			return nil, WrapSqlExecutionError(err, query, roleName)
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, WrapSqlExecutionError(rows.Err(), query, roleName)
+}
+
+func (s *pgInstanceAPIImpl) GrantRoleMembership(roleName string, groupName string, admin bool, inherit bool) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	version, err := s.serverVersion(conn)
+	if err != nil {
+		return err
+	}
+	query := "grant %s to %s"
+	if version >= 160000 {
+		if admin {
+			query += " with admin true"
+		} else {
+			query += " with admin false"
+		}
+		if inherit {
+			query += ", inherit true"
+		} else {
+			query += ", inherit false"
+		}
+	} else if !inherit {
+		// Before Postgres 16 inherit cannot be set per membership
+		return brose_errors.NewIllegalArgumentError("inherit", inherit, nil)
+	} else if admin {
+		query += " with admin option"
+	}
+	query += ";"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, groupName, roleName))
+	return WrapSqlExecutionError(err, query, groupName, roleName)
+}
+
+func (s *pgInstanceAPIImpl) RevokeRoleMembership(roleName string, groupName string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "revoke %s from %s;"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, groupName, roleName))
+	return WrapSqlExecutionError(err, query, groupName, roleName)
+}
+
 func (s *pgInstanceAPIImpl) DeleteRole(name string) error {
+	return s.ReassignAndDeleteRole(name, s.connectionString.username)
+}
+
+func (s *pgInstanceAPIImpl) ReassignAndDeleteRole(name string, newOwner string) error {
+	// Terminate sessions, which would hold locks on the owned objects
+	if err := s.TerminateRoleSessions(name); err != nil {
+		return err
+	}
+
 	// Connect to Database Server
 	conn, err := s.newConnection()
 	if err != nil {
 		return err
 	}
 
+	// The owned objects and privileges are stored per database
+	databases, err := s.getConnectableDatabases(conn)
+	if err != nil {
+		return err
+	}
+	cleanup := func() error {
+		cleanupErr := &RoleCleanupError{Role: name, Cleaned: []string{}, Failed: map[string]error{}}
+		for _, database := range databases {
+			err := s.runIn(database, func(ctx context.Context, dbConn *sql.Conn) error {
+				// reassign owned objects
+				const queryReassign = "reassign owned by %s to %s;"
+				_, err := dbConn.ExecContext(ctx, formatQueryObj(queryReassign, name, newOwner))
+				if err != nil {
+					return WrapSqlExecutionError(err, queryReassign, name, newOwner)
+				}
+				// drop all existing privileges
+				const queryDrop = "drop owned by %s;"
+				_, err = dbConn.ExecContext(ctx, formatQueryObj(queryDrop, name))
+				return WrapSqlExecutionError(err, queryDrop, name)
+			})
+			if err != nil {
+				cleanupErr.Failed[database] = err
+			} else {
+				cleanupErr.Cleaned = append(cleanupErr.Cleaned, database)
+			}
+		}
+		if len(cleanupErr.Failed) > 0 {
+			return cleanupErr
+		}
+		return nil
+	}
+	// The connected role has to be a member of the role and the new owner
 	err = s.runAs(conn, name, func() error {
-		// reassign owned objects
-		const queryReassign = "reassign owned by %s to %s;"
-		_, err = conn.ExecContext(s.ctx, formatQueryObj(queryReassign, name, s.connectionString.username))
-		if err != nil {
-			return WrapSqlExecutionError(err, queryReassign, name)
+		if newOwner != s.connectionString.username {
+			return s.runAs(conn, newOwner, cleanup)
 		}
-		// drop all existing privileges
-		const queryDrop = "drop owned by %s;"
-		_, err = conn.ExecContext(s.ctx, formatQueryObj(queryDrop, name))
-		return WrapSqlExecutionError(err, queryDrop, name)
+		return cleanup()
 	})
 	if err != nil {
 		return err
@@ -91,15 +294,172 @@ func (s *pgInstanceAPIImpl) DeleteRole(name string) error {
 	return WrapSqlExecutionError(err, queryDrop, name)
 }
 
+func (s *pgInstanceAPIImpl) TerminateRoleSessions(name string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "select pg_catalog.pg_terminate_backend(pid) from pg_catalog.pg_stat_activity where usename = $1 and pid <> pg_catalog.pg_backend_pid();"
+	_, err = conn.ExecContext(s.ctx, query, name)
+	return WrapSqlExecutionError(err, query, name)
+}
+
+// getConnectableDatabases returns the names of all databases which allow connections
+func (s *pgInstanceAPIImpl) getConnectableDatabases(conn *sql.Conn) ([]string, error) {
+	const query = "select datname from pg_catalog.pg_database where datallowconn order by datname;"
+	rows, err := conn.QueryContext(s.ctx, query)
+	if err != nil {
+		return nil, WrapSqlExecutionError(err, query)
+	}
+	defer rows.Close()
+	databases := []string{}
+	for rows.Next() {
+		var database string
+		if err := rows.Scan(&database); err != nil {
+			return nil, WrapSqlExecutionError(err, query)
+		}
+		databases = append(databases, database)
+	}
+	return databases, rows.Err()
+}
+
 func (s *pgInstanceAPIImpl) UpdateUserPassword(name string, password string) error {
 	// Connect to Database Server
 	conn, err := s.newConnection()
 	if err != nil {
 		return err
 	}
-	// Escape Password manually because its not an object identifier
-	password = strings.ReplaceAll(password, "'", "\\'")
-	query := "alter user %s with password '" + password + "' login;"
+	defer conn.Close()
+	// Only send the SCRAM verifier, so the plaintext never reaches the server logs
+	verifier, err := security.ScramSha256Verifier(password)
+	if err != nil {
+		return err
+	}
+	const query = "alter user %s with password "
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, name)+escapeQueryValue(verifier)+" login;")
+	return WrapSqlExecutionError(err, query, name)
+}
+
+func (s *pgInstanceAPIImpl) DisableRoleLogin(name string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "alter role %s with password null nologin;"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, name))
+	return WrapSqlExecutionError(err, query, name)
+}
+
+func (s *pgInstanceAPIImpl) UpdateRoleSessionRole(name string, sessionRole string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	const query = "alter role %s set role to %s;"
+	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, name, sessionRole))
+	return WrapSqlExecutionError(err, query, name, sessionRole)
+}
+
+func (s *pgInstanceAPIImpl) GetRoleComment(name string) (string, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return "", err
+	}
+	var comment string
+	const query = "select coalesce(pg_catalog.shobj_description(r.oid, 'pg_authid'), '') from pg_catalog.pg_roles as r where r.rolname = $1;"
+	err = conn.QueryRowContext(s.ctx, query, name).Scan(&comment)
+	if err != nil {
+		return "", WrapSqlExecutionError(err, query, name)
+	}
+	return comment, nil
+}
+
+func (s *pgInstanceAPIImpl) UpdateRoleComment(name string, comment string) error {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	query := formatQueryObj("comment on role %s is ", name) + escapeQueryValue(comment) + ";"
+	_, err = conn.ExecContext(s.ctx, query)
+	return WrapSqlExecutionError(err, query)
+}
+
+func (s *pgInstanceAPIImpl) GetRoleAttributes(name string) (PgRoleAttributes, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return PgRoleAttributes{}, err
+	}
+	var createDatabase, createRole, replication, bypassRLS bool
+	var connectionLimit int
+	var validUntil sql.NullTime
+	// A role without expiration has either no value or infinity as valid until
+	const query = "select rolcreatedb, rolcreaterole, rolreplication, rolbypassrls, rolconnlimit, case when rolvaliduntil = 'infinity' then null else rolvaliduntil end from pg_catalog.pg_roles where rolname = $1;"
+	err = conn.QueryRowContext(s.ctx, query, name).Scan(&createDatabase, &createRole, &replication, &bypassRLS, &connectionLimit, &validUntil)
+	if err != nil {
+		return PgRoleAttributes{}, WrapSqlExecutionError(err, query, name)
+	}
+	attributes := PgRoleAttributes{
+		CreateDatabase:  &createDatabase,
+		CreateRole:      &createRole,
+		Replication:     &replication,
+		BypassRLS:       &bypassRLS,
+		ConnectionLimit: &connectionLimit,
+	}
+	if validUntil.Valid {
+		attributes.ValidUntil = &validUntil.Time
+	}
+	return attributes, nil
+}
+
+func (s *pgInstanceAPIImpl) UpdateRoleAttributes(name string, attributes PgRoleAttributes) error {
+	current, err := s.GetRoleAttributes(name)
+	if err != nil {
+		return err
+	}
+	// Collect all attributes which differ
+	options := make([]string, 0)
+	appendFlag := func(desired *bool, actual *bool, option string) {
+		if desired == nil || *desired == *actual {
+			return
+		}
+		if *desired {
+			options = append(options, option)
+		} else {
+			options = append(options, "no"+option)
+		}
+	}
+	appendFlag(attributes.CreateDatabase, current.CreateDatabase, "createdb")
+	appendFlag(attributes.CreateRole, current.CreateRole, "createrole")
+	appendFlag(attributes.Replication, current.Replication, "replication")
+	appendFlag(attributes.BypassRLS, current.BypassRLS, "bypassrls")
+	if attributes.ConnectionLimit != nil && *attributes.ConnectionLimit != *current.ConnectionLimit {
+		options = append(options, "connection limit "+strconv.Itoa(*attributes.ConnectionLimit))
+	}
+	if attributes.ValidUntil != nil && (current.ValidUntil == nil || !attributes.ValidUntil.Equal(*current.ValidUntil)) {
+		options = append(options, formatQueryValue("valid until %s", attributes.ValidUntil.UTC().Format(time.RFC3339)))
+	}
+	// Nothing to do
+	if len(options) == 0 {
+		return nil
+	}
+
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	// Execute Query
+	query := "alter role %s with " + strings.Join(options, " ") + ";"
 	_, err = conn.ExecContext(s.ctx, formatQueryObj(query, name))
 	return WrapSqlExecutionError(err, query, name)
 }
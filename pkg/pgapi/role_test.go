@@ -17,6 +17,10 @@ limitations under the License.
 package pgapi
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -59,6 +63,42 @@ var _ = Describe("PostgresAPI Login Role Handling", func() {
 		Expect(err).To(BeNil())
 	})
 
+	It("can login with updated passwords containing special characters", func() {
+		// Create new role
+		err := pgApi.CreateRole("dummy_role_22")
+		Expect(err).To(BeNil())
+		passwords := []string{
+			"super-secret-password",
+			"it's a 'quoted' password",
+			"back\\slash\\' or 1=1; --",
+			"$$dollar$$ %s %d",
+			"p\u00e4ssw\u00f6rd \u2168 \u5bc6\u7801",
+		}
+		for _, password := range passwords {
+			// Update Password
+			err = pgApi.UpdateUserPassword("dummy_role_22", password)
+			Expect(err).To(BeNil())
+			// Check that only a verifier is stored
+			var verifier string
+			err = pgApi.(*pgInstanceAPIImpl).runIn("postgres", func(ctx context.Context, conn *sql.Conn) error {
+				return conn.QueryRowContext(ctx, "select rolpassword from pg_catalog.pg_authid where rolname = 'dummy_role_22';").Scan(&verifier)
+			})
+			Expect(err).To(BeNil())
+			Expect(verifier).To(HavePrefix("SCRAM-SHA-256$4096:"))
+			Expect(verifier).NotTo(ContainSubstring(password))
+			// Login with the new password
+			conStr := pgApi.(*pgInstanceAPIImpl).connectionString.copy()
+			conStr.username = "dummy_role_22"
+			conStr.password = password
+			conStr.database = "postgres"
+			db, err := sql.Open("postgres", conStr.toString())
+			Expect(err).To(BeNil())
+			err = db.Ping()
+			db.Close()
+			Expect(err).To(BeNil(), "login with password %q failed", password)
+		}
+	})
+
 	It("can check if a role exists", func() {
 		// Check if role exists
 		exists, err := pgApi.IsRoleExisting("dummy_role_3")
@@ -72,4 +112,165 @@ var _ = Describe("PostgresAPI Login Role Handling", func() {
 		Expect(err).To(BeNil())
 		Expect(exists).To(BeTrue())
 	})
+
+	It("can check if a role is a superuser", func() {
+		// The role of the test container is a superuser
+		connStr := pgApi.ConnectionString()
+		superuser, err := pgApi.IsRoleSuperuser(connStr.Username())
+		Expect(err).To(BeNil())
+		Expect(superuser).To(BeTrue())
+		// Create new role
+		err = pgApi.CreateRole("dummy_role_27")
+		Expect(err).To(BeNil())
+		superuser, err = pgApi.IsRoleSuperuser("dummy_role_27")
+		Expect(err).To(BeNil())
+		Expect(superuser).To(BeFalse())
+		// Missing roles are no superusers
+		superuser, err = pgApi.IsRoleSuperuser("dummy_role_missing")
+		Expect(err).To(BeNil())
+		Expect(superuser).To(BeFalse())
+	})
+
+	It("can create group role", func() {
+		// Create new group role
+		err := pgApi.CreateGroupRole("dummy_group_0")
+		Expect(err).To(BeNil())
+		// Check if role exists
+		exists, err := pgApi.IsRoleExisting("dummy_group_0")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+	})
+
+	It("can grant and revoke role memberships", func() {
+		// Create new roles
+		err := pgApi.CreateGroupRole("dummy_group_1")
+		Expect(err).To(BeNil())
+		err = pgApi.CreateRole("dummy_role_13")
+		Expect(err).To(BeNil())
+		// Grant membership
+		err = pgApi.GrantRoleMembership("dummy_role_13", "dummy_group_1", true, true)
+		Expect(err).To(BeNil())
+		// Check memberships
+		memberships, err := pgApi.GetRoleMemberships("dummy_role_13")
+		Expect(err).To(BeNil())
+		Expect(memberships).To(Equal([]PgRoleMembership{{Group: "dummy_group_1", Admin: true, Inherit: true}}))
+		// Revoke membership
+		err = pgApi.RevokeRoleMembership("dummy_role_13", "dummy_group_1")
+		Expect(err).To(BeNil())
+		// Check memberships
+		memberships, err = pgApi.GetRoleMemberships("dummy_role_13")
+		Expect(err).To(BeNil())
+		Expect(memberships).To(BeEmpty())
+	})
+
+	It("can update role attributes", func() {
+		cTrue := true
+		connectionLimit := 5
+		validUntil := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+		// Create new role
+		err := pgApi.CreateRole("dummy_role_14")
+		Expect(err).To(BeNil())
+		// Check default attributes
+		attributes, err := pgApi.GetRoleAttributes("dummy_role_14")
+		Expect(err).To(BeNil())
+		Expect(*attributes.CreateDatabase).To(BeFalse())
+		Expect(*attributes.ConnectionLimit).To(Equal(-1))
+		Expect(attributes.ValidUntil).To(BeNil())
+		// Update attributes
+		err = pgApi.UpdateRoleAttributes("dummy_role_14", PgRoleAttributes{
+			CreateDatabase:  &cTrue,
+			ConnectionLimit: &connectionLimit,
+			ValidUntil:      &validUntil,
+		})
+		Expect(err).To(BeNil())
+		// Check updated attributes
+		attributes, err = pgApi.GetRoleAttributes("dummy_role_14")
+		Expect(err).To(BeNil())
+		Expect(*attributes.CreateDatabase).To(BeTrue())
+		Expect(*attributes.CreateRole).To(BeFalse())
+		Expect(*attributes.ConnectionLimit).To(Equal(5))
+		Expect(attributes.ValidUntil.Equal(validUntil)).To(BeTrue())
+	})
+
+	It("can disable the login of a role acting as another role", func() {
+		// Create new roles
+		err := pgApi.CreateRole("dummy_role_16")
+		Expect(err).To(BeNil())
+		err = pgApi.CreateRole("dummy_role_16_alt")
+		Expect(err).To(BeNil())
+		// Let the alternate role act as the role
+		err = pgApi.GrantRoleMembership("dummy_role_16_alt", "dummy_role_16", false, true)
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateRoleSessionRole("dummy_role_16_alt", "dummy_role_16")
+		Expect(err).To(BeNil())
+		// Set and remove the password
+		err = pgApi.UpdateUserPassword("dummy_role_16_alt", "super-secret-password")
+		Expect(err).To(BeNil())
+		err = pgApi.DisableRoleLogin("dummy_role_16_alt")
+		Expect(err).To(BeNil())
+	})
+
+	It("can update the comment of a role", func() {
+		// Create new role
+		err := pgApi.CreateRole("dummy_role_17")
+		Expect(err).To(BeNil())
+		// Check empty comment
+		comment, err := pgApi.GetRoleComment("dummy_role_17")
+		Expect(err).To(BeNil())
+		Expect(comment).To(BeEmpty())
+		// Update comment
+		err = pgApi.UpdateRoleComment("dummy_role_17", "team's role")
+		Expect(err).To(BeNil())
+		comment, err = pgApi.GetRoleComment("dummy_role_17")
+		Expect(err).To(BeNil())
+		Expect(comment).To(Equal("team's role"))
+	})
+
+	It("can reassign the owned objects of a deleted role", func() {
+		// Create new roles and a database owned by the first role
+		err := pgApi.CreateRole("dummy_role_18")
+		Expect(err).To(BeNil())
+		err = pgApi.CreateGroupRole("dummy_role_19")
+		Expect(err).To(BeNil())
+		err = pgApi.CreateDatabase("dummy_db_23")
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateDatabaseOwner("dummy_db_23", "dummy_role_18")
+		Expect(err).To(BeNil())
+		// Delete role
+		err = pgApi.ReassignAndDeleteRole("dummy_role_18", "dummy_role_19")
+		Expect(err).To(BeNil())
+		// Check role and database owner
+		exists, err := pgApi.IsRoleExisting("dummy_role_18")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+		owner, err := pgApi.GetDatabaseOwner("dummy_db_23")
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal("dummy_role_19"))
+	})
+
+	It("can delete a role owning objects in another database", func() {
+		// Create new role and a table owned by the role
+		err := pgApi.CreateRole("dummy_role_20")
+		Expect(err).To(BeNil())
+		err = pgApi.CreateDatabase("dummy_db_24")
+		Expect(err).To(BeNil())
+		err = pgApi.(*pgInstanceAPIImpl).runIn("dummy_db_24", func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table public.bikes (id integer); alter table public.bikes owner to dummy_role_20;")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Delete role
+		err = pgApi.DeleteRole("dummy_role_20")
+		Expect(err).To(BeNil())
+		// Check role and table owner
+		exists, err := pgApi.IsRoleExisting("dummy_role_20")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+		var owner string
+		err = pgApi.(*pgInstanceAPIImpl).runIn("dummy_db_24", func(ctx context.Context, conn *sql.Conn) error {
+			return conn.QueryRowContext(ctx, "select tableowner from pg_catalog.pg_tables where tablename = 'bikes';").Scan(&owner)
+		})
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal("pgtest"))
+	})
 })
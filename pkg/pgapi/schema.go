@@ -19,6 +19,7 @@ package pgapi
 import (
 	"context"
 	"database/sql"
+	"sort"
 	"strings"
 
 	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
@@ -26,6 +27,19 @@ import (
 )
 
 var pgTypes = []string{"TABLES", "SEQUENCES", "FUNCTIONS", "ROUTINES", "TYPES", "SCHEMAS"}
+
+// pgObjectTypes maps the object types of the grants on all objects in a schema to the object type of a single object
+var pgObjectTypes = map[string]string{"TABLES": "table", "SEQUENCES": "sequence", "FUNCTIONS": "function", "ROUTINES": "routine"}
+
+// pgObjectQueries contains the queries for the qualified names and the owners of the objects in a schema,
+// which are affected by the grants on all objects of a type
+var pgObjectQueries = map[string]string{
+	"TABLES":    "select quote_ident(n.nspname) || '.' || quote_ident(c.relname), pg_catalog.pg_get_userbyid(c.relowner) from pg_catalog.pg_class c join pg_catalog.pg_namespace n on n.oid = c.relnamespace where n.nspname = $1 and c.relkind in ('r', 'p', 'v', 'm', 'f');",
+	"SEQUENCES": "select quote_ident(n.nspname) || '.' || quote_ident(c.relname), pg_catalog.pg_get_userbyid(c.relowner) from pg_catalog.pg_class c join pg_catalog.pg_namespace n on n.oid = c.relnamespace where n.nspname = $1 and c.relkind = 'S';",
+	"FUNCTIONS": "select quote_ident(n.nspname) || '.' || quote_ident(p.proname) || '(' || pg_catalog.pg_get_function_identity_arguments(p.oid) || ')', pg_catalog.pg_get_userbyid(p.proowner) from pg_catalog.pg_proc p join pg_catalog.pg_namespace n on n.oid = p.pronamespace where n.nspname = $1 and p.prokind <> 'p';",
+	"ROUTINES":  "select quote_ident(n.nspname) || '.' || quote_ident(p.proname) || '(' || pg_catalog.pg_get_function_identity_arguments(p.oid) || ')', pg_catalog.pg_get_userbyid(p.proowner) from pg_catalog.pg_proc p join pg_catalog.pg_namespace n on n.oid = p.pronamespace where n.nspname = $1;",
+}
+
 var pgPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "USAGE", "CONNECT", "CREATE", "EXECUTE", "ALL"}
 
 func validateTypeName(typeName string) error {
@@ -54,10 +68,18 @@ type PgSchemaAPI interface {
 	CreateSchema(databaseName string, schemaName string) error
 	// DeleteSchema drops the given schema from the given database
 	DeleteSchema(databaseName string, schemaName string) error
-	// UpdateSchemaPrivileges updates the privileges for the given schema
+	// DeleteSchemaCascade drops the given schema and all objects contained in it from the given database
+	DeleteSchemaCascade(databaseName string, schemaName string) error
+	// UpdateSchemaOwner changes the owner of the given schema to the role with the given name
+	UpdateSchemaOwner(databaseName string, schemaName string, roleName string) error
+	// UpdateSchemaPrivileges revokes all privileges of the given role on the given schema and grants the given privileges
 	UpdateSchemaPrivileges(databaseName string, schemaName string, roleName string, privileges []string) error
-	// UpdatePrivilegesOnAllObjects updates the privileges according to the given parameters
+	// UpdatePrivilegesOnAllObjects revokes all privileges of the given role on the existing objects of the given type
+	// in the given schema and grants the given privileges
 	UpdatePrivilegesOnAllObjects(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error
+	// UpdateTablePrivileges revokes all privileges of the given role on the given table in the given schema
+	// and grants the given privileges
+	UpdateTablePrivileges(databaseName string, schemaName string, tableName string, roleName string, privileges []string) error
 	// UpdateDefaultPrivileges updates the default privileges in the given schema
 	// for the given role on the given type to the given privileges
 	UpdateDefaultPrivileges(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error
@@ -97,50 +119,153 @@ func (s *pgInstanceAPIImpl) DeleteSchema(databaseName string, schemaName string)
 	})
 }
 
-func (s *pgInstanceAPIImpl) UpdateSchemaPrivileges(databaseName string, schemaName string, roleName string, privileges []string) error {
-	if len(privileges) == 0 {
-		return nil
-	}
+func (s *pgInstanceAPIImpl) DeleteSchemaCascade(databaseName string, schemaName string) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "drop schema %s cascade;"
+		_, err := conn.ExecContext(ctx, formatQueryObj(query, schemaName))
+		return WrapSqlExecutionError(err, query, schemaName)
+	})
+}
 
-	dbOwner, err := s.GetDatabaseOwner(databaseName)
+func (s *pgInstanceAPIImpl) UpdateSchemaOwner(databaseName string, schemaName string, roleName string) error {
+	oldOwner, err := s.GetSchemaOwner(databaseName, schemaName)
 	if err != nil {
 		return err
 	}
+	// The current role has to be a member of the old and the new owner
+	return s.runInAs(databaseName, oldOwner, func(ctx context.Context, conn *sql.Conn) error {
+		return s.runAs(conn, roleName, func() error {
+			const query = "alter schema %s owner to %s;"
+			_, err := conn.ExecContext(ctx, formatQueryObj(query, schemaName, roleName))
+			return WrapSqlExecutionError(err, query, schemaName, roleName)
+		})
+	})
+}
 
+func (s *pgInstanceAPIImpl) UpdateSchemaPrivileges(databaseName string, schemaName string, roleName string, privileges []string) error {
 	if err := validatePrivileges(privileges); err != nil {
 		return err
 	}
-	// Execute Grants
-	return s.runInAs(databaseName, dbOwner, func(ctx context.Context, conn *sql.Conn) error {
-		// This gets executed on the database `databaseName`
-		joinedPrivileges := strings.Join(privileges, ", ")
-		var queryB = "GRANT " + joinedPrivileges + " ON SCHEMA %s TO %s;"
-		_, err := conn.ExecContext(ctx, formatQueryObj(queryB, schemaName, roleName))
-		return WrapSqlExecutionError(err, queryB, schemaName, roleName)
+	// Privileges granted by another role than the owner can not be revoked by the owner
+	schemaOwner, err := s.GetSchemaOwner(databaseName, schemaName)
+	if err != nil {
+		return err
+	}
+	return s.runInAs(databaseName, schemaOwner, func(ctx context.Context, conn *sql.Conn) error {
+		// The role never loses the privileges, which are granted again, while they are updated
+		return runInTransaction(ctx, conn, func(tx *sql.Tx) error {
+			// This gets executed on the database `databaseName`
+			const queryRevoke = "revoke all on schema %s from %s;"
+			if _, err := tx.ExecContext(ctx, formatQueryObj(queryRevoke, schemaName, roleName)); err != nil {
+				return WrapSqlExecutionError(err, queryRevoke, schemaName, roleName)
+			}
+			// no privileges need to be granted
+			if len(privileges) == 0 {
+				return nil
+			}
+			joinedPrivileges := strings.Join(privileges, ", ")
+			queryGrant := "grant " + joinedPrivileges + " on schema %s to %s;"
+			_, err := tx.ExecContext(ctx, formatQueryObj(queryGrant, schemaName, roleName))
+			return WrapSqlExecutionError(err, queryGrant, schemaName, roleName)
+		})
 	})
 }
 
 func (s *pgInstanceAPIImpl) UpdatePrivilegesOnAllObjects(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
-	if len(privileges) == 0 {
-		return nil
+	objectType, ok := pgObjectTypes[typeName]
+	if !ok {
+		return brose_errors.NewIllegalArgumentError("typeName", typeName, nil)
 	}
-	if err := validateTypeName(typeName); err != nil {
+	if err := validatePrivileges(privileges); err != nil {
+		return err
+	}
+	objects, err := s.getSchemaObjectsByOwner(databaseName, schemaName, typeName)
+	if err != nil {
 		return err
 	}
+	owners := make([]string, 0, len(objects))
+	for owner := range objects {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	// Grants on objects of other owners have no effect, therefore the privileges are updated as the owner of each object
+	// in one transaction per owner
+	for _, owner := range owners {
+		err := s.runInAs(databaseName, owner, func(ctx context.Context, conn *sql.Conn) error {
+			return runInTransaction(ctx, conn, func(tx *sql.Tx) error {
+				for _, object := range objects[owner] {
+					if err := updateObjectPrivileges(ctx, tx, objectType, object, roleName, privileges); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pgInstanceAPIImpl) UpdateTablePrivileges(databaseName string, schemaName string, tableName string, roleName string, privileges []string) error {
 	if err := validatePrivileges(privileges); err != nil {
 		return err
 	}
-	dbOwner, err := s.GetDatabaseOwner(databaseName)
+	var tableOwner string
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const query = "select pg_catalog.pg_get_userbyid(c.relowner) from pg_catalog.pg_class c join pg_catalog.pg_namespace n on n.oid = c.relnamespace where n.nspname = $1 and c.relname = $2;"
+		err := conn.QueryRowContext(ctx, query, schemaName, tableName).Scan(&tableOwner)
+		return WrapSqlExecutionError(err, query, schemaName, tableName)
+	})
 	if err != nil {
 		return err
 	}
-	// Execute Grants
-	return s.runInAs(databaseName, dbOwner, func(ctx context.Context, conn *sql.Conn) error {
-		joinedPrivileges := strings.Join(privileges, ", ")
-		query := "GRANT " + joinedPrivileges + " ON ALL " + typeName + " IN SCHEMA %s TO  %s;"
-		_, err := conn.ExecContext(ctx, formatQueryObj(query, schemaName, roleName))
-		return WrapSqlExecutionError(err, query, schemaName, roleName)
+	return s.runInAs(databaseName, tableOwner, func(ctx context.Context, conn *sql.Conn) error {
+		return runInTransaction(ctx, conn, func(tx *sql.Tx) error {
+			return updateObjectPrivileges(ctx, tx, "table", formatQueryObj("%s.%s", schemaName, tableName), roleName, privileges)
+		})
+	})
+}
+
+// getSchemaObjectsByOwner returns the qualified names of the objects of the given type in the given schema
+// grouped by the name of their owner
+func (s *pgInstanceAPIImpl) getSchemaObjectsByOwner(databaseName string, schemaName string, typeName string) (map[string][]string, error) {
+	objects := make(map[string][]string)
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		query := pgObjectQueries[typeName]
+		rows, err := conn.QueryContext(ctx, query, schemaName)
+		if err != nil {
+			return WrapSqlExecutionError(err, query, schemaName)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var object, owner string
+			if err := rows.Scan(&object, &owner); err != nil {
+				return WrapSqlExecutionError(err, query, schemaName)
+			}
+			objects[owner] = append(objects[owner], object)
+		}
+		return WrapSqlExecutionError(rows.Err(), query, schemaName)
 	})
+	return objects, err
+}
+
+// updateObjectPrivileges revokes all privileges of the given role on the given object
+// and grants the given privileges afterwards in the given transaction, the object name has to be quoted already
+func updateObjectPrivileges(ctx context.Context, tx *sql.Tx, objectType string, objectName string, roleName string, privileges []string) error {
+	queryRevoke := "revoke all on " + objectType + " " + objectName + " from " + formatQueryObj("%s", roleName) + ";"
+	if _, err := tx.ExecContext(ctx, queryRevoke); err != nil {
+		return WrapSqlExecutionError(err, queryRevoke)
+	}
+	// no privileges need to be granted
+	if len(privileges) == 0 {
+		return nil
+	}
+	joinedPrivileges := strings.Join(privileges, ", ")
+	queryGrant := "grant " + joinedPrivileges + " on " + objectType + " " + objectName + " to " + formatQueryObj("%s", roleName) + ";"
+	_, err := tx.ExecContext(ctx, queryGrant)
+	return WrapSqlExecutionError(err, queryGrant)
 }
 
 func (s *pgInstanceAPIImpl) UpdateDefaultPrivileges(databaseName string, schemaName string, roleName string, typeName string, privileges []string) error {
@@ -17,6 +17,9 @@ limitations under the License.
 package pgapi
 
 import (
+	"context"
+	"database/sql"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -119,4 +122,150 @@ var _ = Describe("PostgresAPI Schema Handling", func() {
 		err = pgApi.UpdatePrivilegesOnAllObjects(databaseName, schemaName, roleName, "TABLES", []string{"SELECT"})
 		Expect(err).To(BeNil())
 	})
+
+	It("can delete schema with contained objects", func() {
+		databaseName := "dummy_db_16"
+		schemaName := "service"
+		// Create new database
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Create Schema
+		err = pgApi.CreateSchema(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Create Table in Schema
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table service.dummy (id integer);")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Delete Schema without cascade
+		err = pgApi.DeleteSchema(databaseName, schemaName)
+		Expect(err).ToNot(BeNil())
+		// Delete Schema with cascade
+		err = pgApi.DeleteSchemaCascade(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Check if schema exists
+		exists, err := pgApi.IsSchemaInDatabase(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("can update schema owner", func() {
+		roleName := "dummy_role_12"
+		databaseName := "dummy_db_17"
+		schemaName := "service"
+		// Create new role
+		err := pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		// Create new database
+		err = pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Create Schema
+		err = pgApi.CreateSchema(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Update Schema Owner
+		err = pgApi.UpdateSchemaOwner(databaseName, schemaName, roleName)
+		Expect(err).To(BeNil())
+		// Check Schema Owner
+		owner, err := pgApi.GetSchemaOwner(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(roleName))
+	})
+
+	It("can update table privileges", func() {
+		roleName := "dummy_role_15"
+		databaseName := "dummy_db_18"
+		schemaName := "service"
+		// Create new role
+		err := pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		// Create new database
+		err = pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Create Schema
+		err = pgApi.CreateSchema(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Create Table in Schema
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table service.bikes (id integer);")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Update Table Privileges
+		err = pgApi.UpdateTablePrivileges(databaseName, schemaName, "bikes", roleName, []string{"SELECT", "INSERT"})
+		Expect(err).To(BeNil())
+		// Check Table Privileges
+		var granted bool
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			return conn.QueryRowContext(ctx, "select has_table_privilege($1, 'service.bikes', 'INSERT');", roleName).Scan(&granted)
+		})
+		Expect(err).To(BeNil())
+		Expect(granted).To(BeTrue())
+	})
+
+	It("keeps the privileges on the schema if the grant fails", func() {
+		roleName := "dummy_role_28"
+		databaseName := "dummy_db_36"
+		schemaName := "service"
+		// Create new role
+		err := pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		// Create new database
+		err = pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Create Schema
+		err = pgApi.CreateSchema(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Update Schema Privileges
+		err = pgApi.UpdateSchemaPrivileges(databaseName, schemaName, roleName, []string{"USAGE"})
+		Expect(err).To(BeNil())
+		// SELECT cannot be granted on a schema, so the revoke is rolled back
+		err = pgApi.UpdateSchemaPrivileges(databaseName, schemaName, roleName, []string{"SELECT"})
+		Expect(err).ToNot(BeNil())
+		// Check Schema Privileges
+		var granted bool
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			return conn.QueryRowContext(ctx, "select has_schema_privilege($1, 'service', 'USAGE');", roleName).Scan(&granted)
+		})
+		Expect(err).To(BeNil())
+		Expect(granted).To(BeTrue())
+	})
+
+	It("revokes privileges on all objects which were removed", func() {
+		roleName := "dummy_role_24"
+		ownerName := "dummy_role_25"
+		databaseName := "dummy_db_32"
+		schemaName := "service"
+		// Create new roles
+		err := pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		err = pgApi.CreateRole(ownerName)
+		Expect(err).To(BeNil())
+		// Create new database
+		err = pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// Create Schema
+		err = pgApi.CreateSchema(databaseName, schemaName)
+		Expect(err).To(BeNil())
+		// Create Table in Schema, which is owned by another role than the database owner
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "create table service.bikes (id integer); alter table service.bikes owner to dummy_role_25;")
+			return err
+		})
+		Expect(err).To(BeNil())
+		// Update Privileges
+		err = pgApi.UpdatePrivilegesOnAllObjects(databaseName, schemaName, roleName, "TABLES", []string{"SELECT", "INSERT"})
+		Expect(err).To(BeNil())
+		err = pgApi.UpdatePrivilegesOnAllObjects(databaseName, schemaName, roleName, "TABLES", []string{"SELECT"})
+		Expect(err).To(BeNil())
+		// Check Table Privileges
+		var selectGranted, insertGranted bool
+		err = pgApi.(*pgInstanceAPIImpl).runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+			const query = "select has_table_privilege($1, 'service.bikes', 'SELECT'), has_table_privilege($1, 'service.bikes', 'INSERT');"
+			return conn.QueryRowContext(ctx, query, roleName).Scan(&selectGranted, &insertGranted)
+		})
+		Expect(err).To(BeNil())
+		Expect(selectGranted).To(BeTrue())
+		Expect(insertGranted).To(BeFalse())
+	})
 })
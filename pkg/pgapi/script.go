@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// PgScriptRecord contains the tracking record of an applied script
+type PgScriptRecord struct {
+	// Name identifies the script within its owner
+	Name string
+	// Checksum contains the SHA-256 checksum of the applied script
+	Checksum string
+	// AppliedAt contains the time at which the script was applied
+	AppliedAt time.Time
+}
+
+// PgLogin contains the credentials of a role, which are used to log in instead of the credentials of the instance
+type PgLogin struct {
+	Username string
+	Password string
+}
+
+// PrivilegedRoleError is returned if a script should be executed as a role with the superuser or createrole attribute
+type PrivilegedRoleError struct {
+	Role string
+}
+
+func (e *PrivilegedRoleError) Error() string {
+	return "the role " + e.Role + " has the superuser or createrole attribute and cannot execute scripts"
+}
+
+// PgScriptAPI provides functionality to execute tracked SQL scripts in a database
+// The scripts are tracked in the table postgres_operator.script_history of the database
+type PgScriptAPI interface {
+	// GetAppliedScripts returns the tracking records of all scripts of the given owner by name
+	GetAppliedScripts(databaseName string, owner string) (map[string]PgScriptRecord, error)
+	// ApplyScript executes the given script in a transaction on a connection logged in with the given credentials
+	// and records its checksum, roles with the superuser or createrole attribute are refused
+	ApplyScript(databaseName string, owner string, name string, script string, login PgLogin) error
+}
+
+// ScriptChecksum returns the hex encoded SHA-256 checksum of the given script
+func ScriptChecksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *pgInstanceAPIImpl) GetAppliedScripts(databaseName string, owner string) (map[string]PgScriptRecord, error) {
+	records := make(map[string]PgScriptRecord)
+	err := s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		// The tracking table is created with the first applied script
+		var exists bool
+		const queryE = "select to_regclass('postgres_operator.script_history') is not null;"
+		if err := conn.QueryRowContext(ctx, queryE).Scan(&exists); err != nil {
+			return WrapSqlExecutionError(err, queryE)
+		}
+		if !exists {
+			return nil
+		}
+		const query = "select name, checksum, applied_at from postgres_operator.script_history where owner = $1;"
+		rows, err := conn.QueryContext(ctx, query, owner)
+		if err != nil {
+			return WrapSqlExecutionError(err, query, owner)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			record := PgScriptRecord{}
+			if err := rows.Scan(&record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+				return WrapSqlExecutionError(err, query, owner)
+			}
+			records[record.Name] = record
+		}
+		return WrapSqlExecutionError(rows.Err(), query, owner)
+	})
+	return records, err
+}
+
+func (s *pgInstanceAPIImpl) ApplyScript(databaseName string, owner string, name string, script string, login PgLogin) error {
+	return s.runIn(databaseName, func(ctx context.Context, conn *sql.Conn) error {
+		const queryT = "create schema if not exists postgres_operator; " +
+			"create table if not exists postgres_operator.script_history (" +
+			"owner text not null, name text not null, checksum text not null, " +
+			"applied_at timestamptz not null default now(), applied_by text not null, " +
+			"primary key (owner, name));"
+		if _, err := conn.ExecContext(ctx, queryT); err != nil {
+			return WrapSqlExecutionError(err, queryT)
+		}
+		// The record is written by the user of the instance, so the script cannot change the tracking table.
+		// It is committed after the script, the lock on the record prevents a concurrent execution of the script.
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		// Rollback is a no-op after a successful commit
+		defer tx.Rollback()
+		checksum := ScriptChecksum(script)
+		const queryH = "insert into postgres_operator.script_history (owner, name, checksum, applied_by) values ($1, $2, $3, $4) " +
+			"on conflict (owner, name) do update set checksum = excluded.checksum, applied_at = now(), applied_by = excluded.applied_by;"
+		if _, err := tx.ExecContext(ctx, queryH, owner, name, checksum, login.Username); err != nil {
+			return WrapSqlExecutionError(err, queryH, owner, name, checksum, login.Username)
+		}
+		if err := s.runInWithLogin(databaseName, login, func(ctx context.Context, conn *sql.Conn) error {
+			return executeScript(ctx, conn, name, script)
+		}); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("the script %s was executed, but cannot be recorded: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// executeScript executes the script in a transaction, if the current user is not privileged
+func executeScript(ctx context.Context, conn *sql.Conn, name string, script string) error {
+	var username string
+	var privileged bool
+	const queryP = "select current_user, rolsuper or rolcreaterole from pg_roles where rolname = current_user;"
+	if err := conn.QueryRowContext(ctx, queryP).Scan(&username, &privileged); err != nil {
+		return WrapSqlExecutionError(err, queryP)
+	}
+	if privileged {
+		return &PrivilegedRoleError{Role: username}
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful commit
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("unable to execute script %s: %w", name, err)
+	}
+	return tx.Commit()
+}
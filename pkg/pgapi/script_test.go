@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PostgresAPI Script Handling", func() {
+	It("applies and records a script", func() {
+		databaseName := "dummy_db_29"
+		owner := "default/migrations"
+		login := PgLogin{Username: "dummy_role_23", Password: "super-secret-password"}
+		script := "create table orders (id integer primary key); insert into orders values (1);"
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		err = pgApi.CreateRole(login.Username)
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateUserPassword(login.Username, login.Password)
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateDatabaseOwner(databaseName, login.Username)
+		Expect(err).To(BeNil())
+		// No scripts are recorded before the first script was applied
+		records, err := pgApi.GetAppliedScripts(databaseName, owner)
+		Expect(err).To(BeNil())
+		Expect(records).To(BeEmpty())
+		// Apply script
+		err = pgApi.ApplyScript(databaseName, owner, "001-orders", script, login)
+		Expect(err).To(BeNil())
+		records, err = pgApi.GetAppliedScripts(databaseName, owner)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records["001-orders"].Checksum).To(Equal(ScriptChecksum(script)))
+		// A failing script is rolled back and not recorded
+		err = pgApi.ApplyScript(databaseName, owner, "002-items", "create table items (id integer); select * from missing;", login)
+		Expect(err).NotTo(BeNil())
+		records, err = pgApi.GetAppliedScripts(databaseName, owner)
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		// The tracking table cannot be changed by the script
+		err = pgApi.ApplyScript(databaseName, owner, "002-items", "delete from postgres_operator.script_history;", login)
+		Expect(err).NotTo(BeNil())
+		err = pgApi.ApplyScript(databaseName, owner, "002-items", "create table items (id integer);", login)
+		Expect(err).To(BeNil())
+	})
+
+	It("refuses privileged roles", func() {
+		databaseName := "dummy_db_31"
+		connStr := pgApi.ConnectionString()
+		login := PgLogin{Username: connStr.Username(), Password: connStr.Password()}
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		// when
+		err = pgApi.ApplyScript(databaseName, "default/privileged", "001-orders", "select 1;", login)
+		// then
+		var privilegedErr *PrivilegedRoleError
+		Expect(errors.As(err, &privilegedErr)).To(BeTrue())
+		records, err := pgApi.GetAppliedScripts(databaseName, "default/privileged")
+		Expect(err).To(BeNil())
+		Expect(records).To(BeEmpty())
+	})
+})
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/brose-ebike/postgres-operator/pkg/brose_errors"
+)
+
+// settingNamePattern matches the names of configuration settings including custom settings like app.tenant
+var settingNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// listSettingNames contains the settings, which postgres flags with GUC_LIST_INPUT and which take a list of values
+var listSettingNames = []string{
+	"datestyle",
+	"listen_addresses",
+	"local_preload_libraries",
+	"log_destination",
+	"search_path",
+	"session_preload_libraries",
+	"shared_preload_libraries",
+	"synchronous_standby_names",
+	"temp_tablespaces",
+	"unix_socket_directories",
+}
+
+// PgSettingsAPI provides functionality to manage the configuration settings of databases and roles,
+// which are applied at the start of every session and are stored in pg_db_role_setting.
+// An empty database name selects the settings of the role in all databases,
+// an empty role name selects the settings of the database for all roles.
+type PgSettingsAPI interface {
+	// GetSettings returns the configuration settings for the given database and role by name
+	GetSettings(databaseName string, roleName string) (map[string]string, error)
+	// UpdateSetting sets the configuration setting for the given database and role,
+	// comma separated values of list settings are passed as list, e.g. for search_path.
+	// Only settings, which can be changed by every user, are accepted.
+	UpdateSetting(databaseName string, roleName string, name string, value string) error
+	// ResetSetting removes the configuration setting for the given database and role
+	ResetSetting(databaseName string, roleName string, name string) error
+}
+
+// IsValidSettingName returns true if the given name can be used as name of a configuration setting
+func IsValidSettingName(name string) bool {
+	return settingNamePattern.MatchString(name)
+}
+
+// IsListSetting returns true if the setting with the given name takes a list of values
+func IsListSetting(name string) bool {
+	return hasElementString(listSettingNames, strings.ToLower(name))
+}
+
+// NormalizeSettingValue converts the given value of the given setting into the form in which postgres reports list values,
+// so desired and current values can be compared
+func NormalizeSettingValue(name string, value string) string {
+	if !IsListSetting(name) {
+		return value
+	}
+	elements := strings.Split(value, ",")
+	for i, element := range elements {
+		elements[i] = strings.Trim(strings.TrimSpace(element), "\"")
+	}
+	return strings.Join(elements, ", ")
+}
+
+// alterSettingTarget returns the alter statement which selects the settings of the given database and role
+func alterSettingTarget(databaseName string, roleName string) (string, error) {
+	if roleName == "" && databaseName == "" {
+		return "", brose_errors.NewIllegalArgumentError("databaseName", databaseName, nil)
+	}
+	if roleName == "" {
+		return formatQueryObj("alter database %s", databaseName), nil
+	}
+	if databaseName == "" {
+		return formatQueryObj("alter role %s", roleName), nil
+	}
+	return formatQueryObj("alter role %s in database %s", roleName, databaseName), nil
+}
+
+func (s *pgInstanceAPIImpl) GetSettings(databaseName string, roleName string) (map[string]string, error) {
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// pg_db_role_setting is shared by all databases of the instance
+	const query = "select unnest(s.setconfig) from pg_catalog.pg_db_role_setting s " +
+		"where s.setdatabase = case when $1 = '' then 0::oid else (select d.oid from pg_catalog.pg_database d where d.datname = $1) end " +
+		"and s.setrole = case when $2 = '' then 0::oid else (select r.oid from pg_catalog.pg_roles r where r.rolname = $2) end;"
+	rows, err := conn.QueryContext(s.ctx, query, databaseName, roleName)
+	if err != nil {
+		return nil, WrapSqlExecutionError(err, query, databaseName, roleName)
+	}
+	defer rows.Close()
+	settings := make(map[string]string)
+	for rows.Next() {
+		var setting string
+		if err := rows.Scan(&setting); err != nil {
+			return nil, WrapSqlExecutionError(err, query, databaseName, roleName)
+		}
+		name, value, _ := strings.Cut(setting, "=")
+		settings[name] = value
+	}
+	return settings, WrapSqlExecutionError(rows.Err(), query, databaseName, roleName)
+}
+
+func (s *pgInstanceAPIImpl) UpdateSetting(databaseName string, roleName string, name string, value string) error {
+	if !IsValidSettingName(name) {
+		return brose_errors.NewIllegalArgumentError("name", name, nil)
+	}
+	target, err := alterSettingTarget(databaseName, roleName)
+	if err != nil {
+		return err
+	}
+	// Every element of a list is passed as separate literal, other values as a single literal
+	literal := escapeQueryValue(value)
+	if IsListSetting(name) {
+		elements := strings.Split(value, ",")
+		for i, element := range elements {
+			elements[i] = escapeQueryValue(strings.TrimSpace(element))
+		}
+		literal = strings.Join(elements, ", ")
+	}
+	query := target + " set " + name + " = " + literal + ";"
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// The operator connects as superuser, settings which require more privileges than a user has are refused
+	const contextQuery = "select context from pg_catalog.pg_settings where lower(name) = lower($1);"
+	var settingContext string
+	err = conn.QueryRowContext(s.ctx, contextQuery, name).Scan(&settingContext)
+	if err == sql.ErrNoRows {
+		// Custom settings like app.tenant are unknown until they are used
+		if !strings.Contains(name, ".") {
+			return brose_errors.NewIllegalArgumentError("name", name, nil)
+		}
+		settingContext = "user"
+	} else if err != nil {
+		return WrapSqlExecutionError(err, contextQuery, name)
+	}
+	if settingContext != "user" {
+		return brose_errors.NewIllegalArgumentError("name", name, fmt.Errorf("setting %s has the context %s, only settings with the context user can be managed", name, settingContext))
+	}
+	_, err = conn.ExecContext(s.ctx, query)
+	return WrapSqlExecutionError(err, query)
+}
+
+func (s *pgInstanceAPIImpl) ResetSetting(databaseName string, roleName string, name string) error {
+	if !IsValidSettingName(name) {
+		return brose_errors.NewIllegalArgumentError("name", name, nil)
+	}
+	target, err := alterSettingTarget(databaseName, roleName)
+	if err != nil {
+		return err
+	}
+	query := target + " reset " + name + ";"
+	// Connect to Database Server
+	conn, err := s.newConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(s.ctx, query)
+	return WrapSqlExecutionError(err, query)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PostgresAPI Settings Handling", func() {
+	It("sets and resets settings of databases and roles", func() {
+		databaseName := "dummy_db_30"
+		roleName := "dummy_role_21"
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		err = pgApi.CreateRole(roleName)
+		Expect(err).To(BeNil())
+		// Set settings of the database
+		err = pgApi.UpdateSetting(databaseName, "", "statement_timeout", "30s")
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateSetting(databaseName, "", "search_path", "app, public")
+		Expect(err).To(BeNil())
+		settings, err := pgApi.GetSettings(databaseName, "")
+		Expect(err).To(BeNil())
+		Expect(settings).To(HaveLen(2))
+		Expect(settings["statement_timeout"]).To(Equal("30s"))
+		Expect(NormalizeSettingValue("search_path", settings["search_path"])).To(Equal("app, public"))
+		// Set settings of the role in all databases and in the database
+		err = pgApi.UpdateSetting("", roleName, "work_mem", "64MB")
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateSetting(databaseName, roleName, "work_mem", "128MB")
+		Expect(err).To(BeNil())
+		settings, err = pgApi.GetSettings("", roleName)
+		Expect(err).To(BeNil())
+		Expect(settings).To(Equal(map[string]string{"work_mem": "64MB"}))
+		settings, err = pgApi.GetSettings(databaseName, roleName)
+		Expect(err).To(BeNil())
+		Expect(settings).To(Equal(map[string]string{"work_mem": "128MB"}))
+		// Reset settings
+		err = pgApi.ResetSetting(databaseName, "", "statement_timeout")
+		Expect(err).To(BeNil())
+		err = pgApi.ResetSetting(databaseName, roleName, "work_mem")
+		Expect(err).To(BeNil())
+		settings, err = pgApi.GetSettings(databaseName, "")
+		Expect(err).To(BeNil())
+		Expect(settings).To(HaveLen(1))
+		settings, err = pgApi.GetSettings(databaseName, roleName)
+		Expect(err).To(BeNil())
+		Expect(settings).To(BeEmpty())
+	})
+
+	It("passes values of settings which are no lists as single value", func() {
+		databaseName := "dummy_db_34"
+		err := pgApi.CreateDatabase(databaseName)
+		Expect(err).To(BeNil())
+		err = pgApi.UpdateSetting(databaseName, "", "application_name", "billing, reports")
+		Expect(err).To(BeNil())
+		settings, err := pgApi.GetSettings(databaseName, "")
+		Expect(err).To(BeNil())
+		Expect(settings["application_name"]).To(Equal("billing, reports"))
+	})
+
+	It("refuses settings which can only be changed by a superuser", func() {
+		err := pgApi.UpdateSetting("postgres", "", "session_preload_libraries", "auto_explain")
+		Expect(err).NotTo(BeNil())
+		err = pgApi.UpdateSetting("postgres", "", "log_statement", "all")
+		Expect(err).NotTo(BeNil())
+		settings, err := pgApi.GetSettings("postgres", "")
+		Expect(err).To(BeNil())
+		Expect(settings).NotTo(HaveKey("session_preload_libraries"))
+		Expect(settings).NotTo(HaveKey("log_statement"))
+	})
+
+	It("refuses invalid names of settings", func() {
+		err := pgApi.UpdateSetting("postgres", "", "work_mem = '1MB'; drop database postgres; --", "1MB")
+		Expect(err).NotTo(BeNil())
+	})
+})
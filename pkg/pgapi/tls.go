@@ -0,0 +1,226 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// sslRequestCode is sent by a client to request a TLS connection,
+// see https://www.postgresql.org/docs/current/protocol-message-formats.html
+const sslRequestCode = 80877103
+
+// ParseCertificates parses all PEM encoded certificates of the given string
+func ParseCertificates(data string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certificates, nil
+}
+
+// parseRevocationList parses the PEM encoded certificate revocation list
+func parseRevocationList(data string) (*x509.RevocationList, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, errors.New("no PEM encoded certificate revocation list found")
+	}
+	return x509.ParseRevocationList(block.Bytes)
+}
+
+// checkRevocation returns an error if one of the certificates was revoked by the revocation list.
+// If issuers are given, the revocation list has to be signed by one of them.
+func checkRevocation(certificates []*x509.Certificate, crl *x509.RevocationList, issuers []*x509.Certificate) error {
+	if len(issuers) > 0 {
+		signed := false
+		for _, issuer := range issuers {
+			if crl.CheckSignatureFrom(issuer) == nil {
+				signed = true
+				break
+			}
+		}
+		if !signed {
+			return errors.New("the certificate revocation list is not signed by any of the root certificates")
+		}
+	}
+	for _, certificate := range certificates {
+		if !bytes.Equal(certificate.RawIssuer, crl.RawIssuer) {
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(certificate.SerialNumber) == 0 {
+				return fmt.Errorf("the certificate %s with serial number %s was revoked", certificate.Subject, certificate.SerialNumber)
+			}
+		}
+	}
+	return nil
+}
+
+// fetchServerCertificates requests a TLS connection like a Postgres client and returns the certificates presented by the server.
+// The certificates are not verified, this is done by the driver when the actual connection is established.
+func fetchServerCertificates(ctx context.Context, hostname string, port int) ([]*x509.Certificate, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(hostname, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Request TLS and wait for the confirmation of the server
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], sslRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+	response := make([]byte, 1)
+	if _, err := conn.Read(response); err != nil {
+		return nil, err
+	}
+	if response[0] != 'S' {
+		return nil, errors.New("the server does not support TLS connections")
+	}
+
+	client := tls.Client(conn, &tls.Config{ServerName: hostname, InsecureSkipVerify: true})
+	if err := client.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return client.ConnectionState().PeerCertificates, nil
+}
+
+// pgTLSFiles references the files into which the certificates and keys of a PgTLSConfig were written,
+// because the driver only accepts paths to certificate and key files.
+type pgTLSFiles struct {
+	directory string
+	rootCert  string
+	cert      string
+	key       string
+}
+
+// writeTLSFiles writes the certificates and keys of the given config into a new directory,
+// which is only accessible by the current user. If no certificates are configured nil is returned.
+func writeTLSFiles(config PgTLSConfig) (*pgTLSFiles, error) {
+	if config.RootCert == "" && config.Cert == "" {
+		return nil, nil
+	}
+	directory, err := os.MkdirTemp("", "pgapi-tls-")
+	if err != nil {
+		return nil, err
+	}
+	files := &pgTLSFiles{directory: directory}
+	write := func(name string, content string) (string, error) {
+		if content == "" {
+			return "", nil
+		}
+		path := filepath.Join(directory, name)
+		return path, os.WriteFile(path, []byte(content), 0600)
+	}
+	if files.rootCert, err = write("root.crt", config.RootCert); err != nil {
+		_ = files.remove()
+		return nil, err
+	}
+	if files.cert, err = write("postgresql.crt", config.Cert); err != nil {
+		_ = files.remove()
+		return nil, err
+	}
+	if files.key, err = write("postgresql.key", config.Key); err != nil {
+		_ = files.remove()
+		return nil, err
+	}
+	return files, nil
+}
+
+func (f *pgTLSFiles) toString() string {
+	if f == nil {
+		return ""
+	}
+	result := ""
+	if f.rootCert != "" {
+		result += " sslrootcert=" + f.rootCert
+	}
+	if f.cert != "" {
+		result += " sslcert=" + f.cert + " sslkey=" + f.key
+	}
+	return result
+}
+
+// withoutClientCert returns the files without the client certificate and its key,
+// for connections which are not logged in as the user of the instance
+func (f *pgTLSFiles) withoutClientCert() *pgTLSFiles {
+	if f == nil {
+		return nil
+	}
+	return &pgTLSFiles{directory: f.directory, rootCert: f.rootCert}
+}
+
+func (f *pgTLSFiles) remove() error {
+	if f == nil {
+		return nil
+	}
+	return os.RemoveAll(f.directory)
+}
+
+// checkServerRevocation checks the certificates of the server against the configured revocation list
+func (s *pgInstanceAPIImpl) checkServerRevocation() error {
+	config := s.connectionString.tls
+	if config.CRL == "" || s.connectionString.sslMode == "disable" {
+		return nil
+	}
+	crl, err := parseRevocationList(config.CRL)
+	if err != nil {
+		return err
+	}
+	var issuers []*x509.Certificate
+	if config.RootCert != "" {
+		if issuers, err = ParseCertificates(config.RootCert); err != nil {
+			return err
+		}
+	}
+	certificates, err := fetchServerCertificates(s.ctx, s.connectionString.hostname, s.connectionString.port)
+	if err != nil {
+		return err
+	}
+	return checkRevocation(certificates, crl, issuers)
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPEM     string
+	keyPEM      string
+}
+
+func newTestCertificate(t *testing.T, name string, serial int64, issuer *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  issuer == nil,
+	}
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.certificate, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPEM:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:      string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+	}
+}
+
+func newTestRevocationList(t *testing.T, issuer *testCertificate, revoked ...*x509.Certificate) string {
+	entries := []x509.RevocationListEntry{}
+	for _, certificate := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: certificate.SerialNumber, RevocationTime: time.Now()})
+	}
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                time.Now().Add(24 * time.Hour),
+		RevokedCertificateEntries: entries,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, issuer.certificate, issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func TestParseCertificatesReturnsAllCertificates(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	client := newTestCertificate(t, "client", 2, ca)
+	certificates, err := ParseCertificates(ca.certPEM + client.certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certificates) != 2 || certificates[1].Subject.CommonName != "client" {
+		t.Errorf("Certificates were not parsed")
+	}
+}
+
+func TestParseCertificatesWithoutCertificate(t *testing.T) {
+	if _, err := ParseCertificates("no certificate"); err == nil {
+		t.Errorf("Parsing succeeded without certificate")
+	}
+}
+
+func TestSetTLSConfigRequiresCertAndKey(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	client := newTestCertificate(t, "client", 2, ca)
+	pgCS, _ := NewPgConnectionString("hostname", 5432, "username", "password", "database", "verify-full")
+	if err := pgCS.SetTLSConfig(PgTLSConfig{Cert: client.certPEM}); err == nil {
+		t.Errorf("Certificate without key was accepted")
+	}
+	if err := pgCS.SetTLSConfig(PgTLSConfig{Cert: client.certPEM, Key: ca.keyPEM}); err == nil {
+		t.Errorf("Certificate with wrong key was accepted")
+	}
+	config := PgTLSConfig{RootCert: ca.certPEM, Cert: client.certPEM, Key: client.keyPEM}
+	if err := pgCS.SetTLSConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if pgCS.copy().TLSConfig() != config {
+		t.Errorf("TLS config was not copied")
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	revoked := newTestCertificate(t, "revoked", 2, ca)
+	valid := newTestCertificate(t, "valid", 3, ca)
+	crl, err := parseRevocationList(newTestRevocationList(t, ca, revoked.certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuers := []*x509.Certificate{ca.certificate}
+	if err := checkRevocation([]*x509.Certificate{valid.certificate, ca.certificate}, crl, issuers); err != nil {
+		t.Errorf("Valid certificate was refused: %s", err)
+	}
+	if err := checkRevocation([]*x509.Certificate{revoked.certificate, ca.certificate}, crl, issuers); err == nil {
+		t.Errorf("Revoked certificate was accepted")
+	}
+}
+
+func TestCheckRevocationRequiresSignatureOfIssuer(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	other := newTestCertificate(t, "other", 2, nil)
+	crl, err := parseRevocationList(newTestRevocationList(t, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkRevocation([]*x509.Certificate{}, crl, []*x509.Certificate{ca.certificate}); err == nil {
+		t.Errorf("Revocation list of another issuer was accepted")
+	}
+}
+
+func TestWriteTLSFiles(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	client := newTestCertificate(t, "client", 2, ca)
+	files, err := writeTLSFiles(PgTLSConfig{RootCert: ca.certPEM, Cert: client.certPEM, Key: client.keyPEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(files.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Key file is accessible by other users: %s", info.Mode().Perm())
+	}
+	if !strings.Contains(files.toString(), "sslrootcert="+files.rootCert) {
+		t.Errorf("Root certificate is missing in connection string")
+	}
+	if err := files.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(files.directory); !os.IsNotExist(err) {
+		t.Errorf("Directory was not removed")
+	}
+}
+
+func TestWriteTLSFilesWithoutCertificates(t *testing.T) {
+	files, err := writeTLSFiles(PgTLSConfig{})
+	if err != nil || files != nil {
+		t.Errorf("Files were written without certificates")
+	}
+	if files.toString() != "" {
+		t.Errorf("Connection string contains certificates")
+	}
+}
@@ -16,7 +16,10 @@ limitations under the License.
 
 package pgapi
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func formatQueryObj(query string, args ...string) string {
 	escaped := []any{}
@@ -34,6 +37,11 @@ func formatQueryValue(query string, args ...string) string {
 	return fmt.Sprintf(query, escaped...)
 }
 
+// escapeQueryValue quotes the given value as string literal and escapes all contained quotes
+func escapeQueryValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
 // hasElementString checks if a given element e is contained in the slice s
 func hasElementString(s []string, e string) bool {
 	for _, a := range s {
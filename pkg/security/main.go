@@ -21,8 +21,6 @@ import (
 	"math/big"
 )
 
-const letterBytes = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-
 func randomInt(max int) int {
 	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
 	if err != nil {
@@ -31,11 +29,11 @@ func randomInt(max int) int {
 	return int(n.Int64())
 }
 
+// GeneratePassword generates a random password with the DefaultPasswordPolicy
 func GeneratePassword() string {
-	length := 24 + randomInt(8)
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = letterBytes[randomInt(len(letterBytes))]
+	password, err := GeneratePasswordWithPolicy(DefaultPasswordPolicy)
+	if err != nil {
+		panic(err)
 	}
-	return string(b)
+	return password
 }
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CharacterClass is a class of characters which can be contained in generated passwords
+type CharacterClass string
+
+const (
+	LowercaseCharacters CharacterClass = "Lowercase"
+	UppercaseCharacters CharacterClass = "Uppercase"
+	DigitCharacters     CharacterClass = "Digits"
+	SymbolCharacters    CharacterClass = "Symbols"
+)
+
+// DefaultSymbols are the symbols which are used if a policy does not define its own,
+// quotes, backslashes and spaces are omitted to keep passwords usable in connection strings and shells
+const DefaultSymbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+var characterClassAlphabets = map[CharacterClass]string{
+	LowercaseCharacters: "abcdefghijklmnopqrstuvwxyz",
+	UppercaseCharacters: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	DigitCharacters:     "0123456789",
+}
+
+// DefaultPasswordPolicy generates 24 to 31 alphanumeric characters
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:        24,
+	MaxLength:        31,
+	CharacterClasses: []CharacterClass{LowercaseCharacters, UppercaseCharacters, DigitCharacters},
+	Symbols:          DefaultSymbols,
+}
+
+// PasswordPolicy describes the passwords which are generated by GeneratePasswordWithPolicy.
+// Every character class is contained at least once, MinSymbols greater than zero implies the class Symbols.
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	CharacterClasses   []CharacterClass
+	Symbols            string
+	MinSymbols         int
+	ExcludedCharacters string
+}
+
+// WithDefaults returns a copy of the policy, whose unset fields are taken from the given defaults.
+// If only one length is set, the other one is adjusted to keep the range valid.
+func (p PasswordPolicy) WithDefaults(defaults PasswordPolicy) PasswordPolicy {
+	result := p
+	if result.MinLength == 0 {
+		result.MinLength = defaults.MinLength
+		if p.MaxLength != 0 && result.MinLength > p.MaxLength {
+			result.MinLength = p.MaxLength
+		}
+	}
+	if result.MaxLength == 0 {
+		result.MaxLength = defaults.MaxLength
+		if result.MaxLength < result.MinLength {
+			result.MaxLength = result.MinLength
+		}
+	}
+	if len(result.CharacterClasses) == 0 {
+		result.CharacterClasses = append([]CharacterClass{}, defaults.CharacterClasses...)
+	}
+	if result.Symbols == "" {
+		result.Symbols = defaults.Symbols
+	}
+	if result.MinSymbols == 0 {
+		result.MinSymbols = defaults.MinSymbols
+	}
+	if result.ExcludedCharacters == "" {
+		result.ExcludedCharacters = defaults.ExcludedCharacters
+	}
+	return result
+}
+
+// alphabets returns the allowed characters of every character class of the policy
+func (p PasswordPolicy) alphabets() (map[CharacterClass]string, error) {
+	classes := append([]CharacterClass{}, p.CharacterClasses...)
+	if p.MinSymbols > 0 {
+		classes = append(classes, SymbolCharacters)
+	}
+	result := make(map[CharacterClass]string)
+	for _, class := range classes {
+		alphabet, ok := characterClassAlphabets[class]
+		if class == SymbolCharacters {
+			alphabet, ok = p.Symbols, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown character class %s", class)
+		}
+		allowed := ""
+		for _, c := range alphabet {
+			if !strings.ContainsRune(p.ExcludedCharacters, c) && !strings.ContainsRune(allowed, c) {
+				allowed += string(c)
+			}
+		}
+		if allowed == "" {
+			return nil, fmt.Errorf("no characters of the class %s remain after excluding %q", class, p.ExcludedCharacters)
+		}
+		result[class] = allowed
+	}
+	return result, nil
+}
+
+// requiredCharacters returns the number of characters which are required by the character classes
+func (p PasswordPolicy) requiredCharacters(alphabets map[CharacterClass]string) int {
+	required := len(alphabets)
+	if p.MinSymbols > 1 {
+		required += p.MinSymbols - 1
+	}
+	return required
+}
+
+// Validate checks that passwords can be generated with the policy
+func (p PasswordPolicy) Validate() error {
+	if p.MinLength < 1 {
+		return fmt.Errorf("the minimum length must be positive, got %d", p.MinLength)
+	}
+	if p.MaxLength < p.MinLength {
+		return fmt.Errorf("the maximum length %d is less than the minimum length %d", p.MaxLength, p.MinLength)
+	}
+	if p.MinSymbols < 0 {
+		return fmt.Errorf("the minimum number of symbols cannot be negative, got %d", p.MinSymbols)
+	}
+	for _, c := range p.Symbols {
+		if c < '!' || c > '~' || strings.ContainsRune(characterClassAlphabets[LowercaseCharacters]+characterClassAlphabets[UppercaseCharacters]+characterClassAlphabets[DigitCharacters], c) {
+			return fmt.Errorf("the symbols can only contain printable ASCII characters, which are neither letters nor digits, got %q", p.Symbols)
+		}
+	}
+	if len(p.CharacterClasses) == 0 && p.MinSymbols == 0 {
+		return fmt.Errorf("at least one character class is required")
+	}
+	alphabets, err := p.alphabets()
+	if err != nil {
+		return err
+	}
+	if required := p.requiredCharacters(alphabets); required > p.MaxLength {
+		return fmt.Errorf("the character classes require %d characters, but the maximum length is %d", required, p.MaxLength)
+	}
+	return nil
+}
+
+// GeneratePasswordWithPolicy generates a random password, which satisfies the policy
+func GeneratePasswordWithPolicy(policy PasswordPolicy) (string, error) {
+	if err := policy.Validate(); err != nil {
+		return "", err
+	}
+	alphabets, _ := policy.alphabets()
+	required := policy.requiredCharacters(alphabets)
+	minLength := policy.MinLength
+	if minLength < required {
+		minLength = required
+	}
+	length := minLength + randomInt(policy.MaxLength-minLength+1)
+	// Add the required characters of every class at first
+	password := make([]byte, 0, length)
+	all := ""
+	for _, class := range []CharacterClass{LowercaseCharacters, UppercaseCharacters, DigitCharacters, SymbolCharacters} {
+		alphabet, ok := alphabets[class]
+		if !ok {
+			continue
+		}
+		count := 1
+		if class == SymbolCharacters && policy.MinSymbols > 1 {
+			count = policy.MinSymbols
+		}
+		for i := 0; i < count; i++ {
+			password = append(password, alphabet[randomInt(len(alphabet))])
+		}
+		all += alphabet
+	}
+	// Fill the password with characters of all classes and shuffle it afterwards
+	for len(password) < length {
+		password = append(password, all[randomInt(len(all))])
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j := randomInt(i + 1)
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePasswordWithPolicy(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:          12,
+		MaxLength:          16,
+		CharacterClasses:   []CharacterClass{LowercaseCharacters, DigitCharacters},
+		Symbols:            "#!",
+		MinSymbols:         3,
+		ExcludedCharacters: "0o1l",
+	}
+	for i := 0; i < 100; i++ {
+		password, err := GeneratePasswordWithPolicy(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) < 12 || len(password) > 16 {
+			t.Errorf("Password has an unexpected length, got: '%s'", password)
+		}
+		if !strings.ContainsAny(password, "abcdefghijkmnpqrstuvwxyz") || !strings.ContainsAny(password, "23456789") {
+			t.Errorf("Password does not contain every character class, got: '%s'", password)
+		}
+		if strings.Count(password, "#")+strings.Count(password, "!") < 3 {
+			t.Errorf("Password does not contain enough symbols, got: '%s'", password)
+		}
+		if strings.ContainsAny(password, "0o1lABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			t.Errorf("Password contains excluded characters, got: '%s'", password)
+		}
+	}
+}
+
+func TestGeneratePasswordWithPolicyRequiredLength(t *testing.T) {
+	// The minimum length is raised to the number of required characters
+	policy := PasswordPolicy{
+		MinLength:        1,
+		MaxLength:        5,
+		CharacterClasses: []CharacterClass{LowercaseCharacters, UppercaseCharacters},
+		Symbols:          DefaultSymbols,
+		MinSymbols:       3,
+	}
+	password, err := GeneratePasswordWithPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != 5 {
+		t.Errorf("Password has an unexpected length, got: '%s'", password)
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	cases := map[string]PasswordPolicy{
+		"zero length":       {MinLength: 0, MaxLength: 8, CharacterClasses: []CharacterClass{DigitCharacters}},
+		"inverted lengths":  {MinLength: 8, MaxLength: 4, CharacterClasses: []CharacterClass{DigitCharacters}},
+		"no classes":        {MinLength: 8, MaxLength: 8},
+		"unknown class":     {MinLength: 8, MaxLength: 8, CharacterClasses: []CharacterClass{"Emoji"}},
+		"excluded class":    {MinLength: 8, MaxLength: 8, CharacterClasses: []CharacterClass{DigitCharacters}, ExcludedCharacters: "0123456789"},
+		"no symbols":        {MinLength: 8, MaxLength: 8, CharacterClasses: []CharacterClass{SymbolCharacters}},
+		"invalid symbols":   {MinLength: 8, MaxLength: 8, CharacterClasses: []CharacterClass{SymbolCharacters}, Symbols: "a!"},
+		"negative symbols":  {MinLength: 8, MaxLength: 8, CharacterClasses: []CharacterClass{DigitCharacters}, MinSymbols: -1},
+		"too many required": {MinLength: 2, MaxLength: 4, CharacterClasses: []CharacterClass{DigitCharacters}, Symbols: "!", MinSymbols: 4},
+	}
+	for name, policy := range cases {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Policy '%s' is unsatisfiable, but passed the validation", name)
+		}
+		if _, err := GeneratePasswordWithPolicy(policy); err == nil {
+			t.Errorf("Policy '%s' is unsatisfiable, but generated a password", name)
+		}
+	}
+	if err := DefaultPasswordPolicy.Validate(); err != nil {
+		t.Errorf("Default policy is invalid: %s", err)
+	}
+}
+
+func TestPasswordPolicyWithDefaults(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 40, ExcludedCharacters: "0O"}.WithDefaults(DefaultPasswordPolicy)
+	if policy.MinLength != 40 || policy.MaxLength != 40 {
+		t.Errorf("Unexpected lengths, got: %d to %d", policy.MinLength, policy.MaxLength)
+	}
+	if len(policy.CharacterClasses) != 3 || policy.Symbols != DefaultSymbols || policy.ExcludedCharacters != "0O" {
+		t.Errorf("Unexpected policy, got: %+v", policy)
+	}
+	policy = PasswordPolicy{MaxLength: 12}.WithDefaults(DefaultPasswordPolicy)
+	if policy.MinLength != 12 || policy.MaxLength != 12 {
+		t.Errorf("Unexpected lengths, got: %d to %d", policy.MinLength, policy.MaxLength)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ScramIterations is the number of iterations used for new verifiers, it matches the default of postgres
+const ScramIterations = 4096
+
+// scramSaltLength is the length of the random salt in bytes, it matches the default of postgres
+const scramSaltLength = 16
+
+// ScramSha256Verifier computes the SCRAM-SHA-256 verifier of the given password with a random salt.
+// The verifier has the same format as the ones stored by postgres in pg_authid,
+// so it can be passed to ALTER ROLE ... PASSWORD without sending the password to the server.
+func ScramSha256Verifier(password string) (string, error) {
+	salt := make([]byte, scramSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return scramSha256Verifier(password, salt, ScramIterations), nil
+}
+
+// scramSha256Verifier computes the SCRAM-SHA-256 verifier as defined by RFC 5802 and RFC 7677
+func scramSha256Verifier(password string, salt []byte, iterations int) string {
+	saltedPassword := pbkdf2Sha256([]byte(saslPrep(password)), salt, iterations)
+	clientKey := hmacSha256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSha256(saltedPassword, []byte("Server Key"))
+	encode := base64.StdEncoding.EncodeToString
+	return "SCRAM-SHA-256$" + strconv.Itoa(iterations) + ":" + encode(salt) + "$" + encode(storedKey[:]) + ":" + encode(serverKey)
+}
+
+func hmacSha256(key []byte, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// pbkdf2Sha256 computes the function Hi of RFC 5802, which is PBKDF2 with HMAC-SHA-256
+// and a key length of a single SHA-256 block
+func pbkdf2Sha256(password []byte, salt []byte, iterations int) []byte {
+	block := make([]byte, 4)
+	binary.BigEndian.PutUint32(block, 1)
+	u := hmacSha256(password, append(append([]byte{}, salt...), block...))
+	result := append([]byte{}, u...)
+	for i := 1; i < iterations; i++ {
+		u = hmacSha256(password, u)
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
+
+// saslPrepSpaces contains the non-ASCII space characters (RFC 3454 C.1.2), which are mapped to a space
+var saslPrepSpaces = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00A0, Hi: 0x00A0, Stride: 1},
+		{Lo: 0x1680, Hi: 0x1680, Stride: 1},
+		{Lo: 0x2000, Hi: 0x200B, Stride: 1},
+		{Lo: 0x202F, Hi: 0x202F, Stride: 1},
+		{Lo: 0x205F, Hi: 0x205F, Stride: 1},
+		{Lo: 0x3000, Hi: 0x3000, Stride: 1},
+	},
+}
+
+// saslPrepNothing contains the characters which are commonly mapped to nothing (RFC 3454 B.1)
+var saslPrepNothing = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00AD, Hi: 0x00AD, Stride: 1},
+		{Lo: 0x034F, Hi: 0x034F, Stride: 1},
+		{Lo: 0x1806, Hi: 0x1806, Stride: 1},
+		{Lo: 0x180B, Hi: 0x180D, Stride: 1},
+		{Lo: 0x200B, Hi: 0x200D, Stride: 1},
+		{Lo: 0x2060, Hi: 0x2060, Stride: 1},
+		{Lo: 0xFE00, Hi: 0xFE0F, Stride: 1},
+		{Lo: 0xFEFF, Hi: 0xFEFF, Stride: 1},
+	},
+}
+
+// saslPrepProhibited contains the prohibited characters (RFC 3454 C.1.2 to C.9)
+var saslPrepProhibited = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x0000, Hi: 0x001F, Stride: 1},
+		{Lo: 0x007F, Hi: 0x009F, Stride: 1},
+		{Lo: 0x00A0, Hi: 0x00A0, Stride: 1},
+		{Lo: 0x0340, Hi: 0x0341, Stride: 1},
+		{Lo: 0x06DD, Hi: 0x06DD, Stride: 1},
+		{Lo: 0x070F, Hi: 0x070F, Stride: 1},
+		{Lo: 0x1680, Hi: 0x1680, Stride: 1},
+		{Lo: 0x180E, Hi: 0x180E, Stride: 1},
+		{Lo: 0x2000, Hi: 0x200F, Stride: 1},
+		{Lo: 0x2028, Hi: 0x202F, Stride: 1},
+		{Lo: 0x205F, Hi: 0x2063, Stride: 1},
+		{Lo: 0x206A, Hi: 0x206F, Stride: 1},
+		{Lo: 0x2FF0, Hi: 0x2FFB, Stride: 1},
+		{Lo: 0x3000, Hi: 0x3000, Stride: 1},
+		{Lo: 0xD800, Hi: 0xF8FF, Stride: 1},
+		{Lo: 0xFDD0, Hi: 0xFDEF, Stride: 1},
+		{Lo: 0xFEFF, Hi: 0xFEFF, Stride: 1},
+		{Lo: 0xFFF9, Hi: 0xFFFF, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1D173, Hi: 0x1D17A, Stride: 1},
+		{Lo: 0xE0001, Hi: 0xE0001, Stride: 1},
+		{Lo: 0xE0020, Hi: 0xE007F, Stride: 1},
+		{Lo: 0xF0000, Hi: 0x10FFFF, Stride: 1},
+	},
+}
+
+// isSaslPrepProhibited returns true if the character is prohibited or a non-character
+func isSaslPrepProhibited(r rune) bool {
+	return unicode.Is(saslPrepProhibited, r) || r&0xFFFE == 0xFFFE
+}
+
+// saslPrep normalizes the password with the SASLprep profile (RFC 4013) in the same way as postgres.
+// Like postgres the password is used unchanged, if it is pure ASCII, not valid UTF-8 or contains prohibited characters.
+// Unassigned code points are determined with the unicode version of Go instead of Unicode 3.2.
+func saslPrep(password string) string {
+	ascii := true
+	for i := 0; i < len(password); i++ {
+		if password[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii || !utf8.ValidString(password) {
+		return password
+	}
+
+	// Map spaces to a space and remove the characters which are mapped to nothing
+	mapped := make([]rune, 0, len(password))
+	for _, r := range password {
+		if unicode.Is(saslPrepSpaces, r) {
+			mapped = append(mapped, ' ')
+		} else if !unicode.Is(saslPrepNothing, r) {
+			mapped = append(mapped, r)
+		}
+	}
+	normalized := []rune(norm.NFKC.String(string(mapped)))
+	if len(normalized) == 0 {
+		return password
+	}
+
+	// Check prohibited and unassigned characters and bidirectional strings
+	hasRandAL, hasL := false, false
+	for _, r := range normalized {
+		if isSaslPrepProhibited(r) || !isAssigned(r) {
+			return password
+		}
+		switch bidiClass(r) {
+		case bidi.R, bidi.AL:
+			hasRandAL = true
+		case bidi.L:
+			hasL = true
+		}
+	}
+	if hasRandAL {
+		first, last := bidiClass(normalized[0]), bidiClass(normalized[len(normalized)-1])
+		if hasL || (first != bidi.R && first != bidi.AL) || (last != bidi.R && last != bidi.AL) {
+			return password
+		}
+	}
+	return string(normalized)
+}
+
+func isAssigned(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+func bidiClass(r rune) bidi.Class {
+	properties, _ := bidi.LookupRune(r)
+	return properties.Class()
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScramSha256VerifierKnownValue(t *testing.T) {
+	verifier := scramSha256Verifier("pencil", []byte("0123456789abcdef"), 4096)
+	expected := "SCRAM-SHA-256$4096:MDEyMzQ1Njc4OWFiY2RlZg==$nQpbZ77WudtqufPwikHXGRt6g2QJ4zns8bZLw273DRM=:jn2amWP1q1h+jgjy0YTO14S6/F02SV7taipOeB7ef20="
+	if verifier != expected {
+		t.Errorf("Unexpected verifier, got: '%s'", verifier)
+	}
+}
+
+func TestScramSha256VerifierSpecialCharacters(t *testing.T) {
+	verifier := scramSha256Verifier("it's a \\secret", []byte("0123456789abcdef"), 4096)
+	expected := "SCRAM-SHA-256$4096:MDEyMzQ1Njc4OWFiY2RlZg==$iFyHcAuue30uD38Gz9oqY1zjEsd/IY/Bhm7xIEd4qsk=:Pt3amcTHvBcOG6rp9+SWDO+2ud0A8YqhgRQkuYWcC34="
+	if verifier != expected {
+		t.Errorf("Unexpected verifier, got: '%s'", verifier)
+	}
+}
+
+func TestScramSha256VerifierRandomSalt(t *testing.T) {
+	verifier0, err := ScramSha256Verifier("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier1, err := ScramSha256Verifier("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(verifier0, "SCRAM-SHA-256$4096:") {
+		t.Errorf("Unexpected verifier format, got: '%s'", verifier0)
+	}
+	if verifier0 == verifier1 {
+		t.Errorf("Two verifiers use the same salt, got: '%s'", verifier0)
+	}
+}
+
+func TestSaslPrep(t *testing.T) {
+	cases := map[string]string{
+		// ASCII is never changed
+		"pass word\t": "pass word\t",
+		// Compatibility characters are normalized
+		"\u2168": "IX",
+		// Non-ASCII spaces are mapped to a space
+		"pass\u00a0word": "pass word",
+		// Soft hyphens are mapped to nothing
+		"pass\u00adword": "password",
+		// Prohibited characters keep the password unchanged
+		"p\u00e4ssword\u0007": "p\u00e4ssword\u0007",
+		// Mixed bidirectional strings keep the password unchanged
+		"\u05d0abc": "\u05d0abc",
+		// Invalid UTF-8 keeps the password unchanged
+		"pass\xffword": "pass\xffword",
+	}
+	for password, expected := range cases {
+		if actual := saslPrep(password); actual != expected {
+			t.Errorf("Unexpected result of %q, got: %q, expected: %q", password, actual, expected)
+		}
+	}
+}
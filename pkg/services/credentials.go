@@ -0,0 +1,285 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// credentialExpiryMargin is the time before the expiry at which cached credentials are renewed
+const credentialExpiryMargin = 30 * time.Second
+
+// defaultExecTimeout is the time after which a credential command is cancelled if the instance defines no timeout
+const defaultExecTimeout = 30 * time.Second
+
+// PgCredentials contains the password of the Administrator User of an instance
+type PgCredentials struct {
+	// Password is used to authenticate the Administrator User, e.g. a static password or a token
+	Password string
+	// ExpiresAt is the time at which the password expires, zero if it does not expire
+	ExpiresAt time.Time
+}
+
+// PgCredentialProvider provides the credentials of the Administrator User of an instance
+type PgCredentialProvider interface {
+	// GetCredentials returns the current credentials, which are obtained again after they expired
+	GetCredentials(ctx context.Context) (PgCredentials, error)
+	// Invalidate discards the current credentials after the authentication failed.
+	// It returns true if calling GetCredentials again may return different credentials.
+	Invalidate() bool
+}
+
+// NewPgCredentialProvider returns the provider of the credentials of the instance,
+// the static password property is used if no credential provider is configured
+func NewPgCredentialProvider(r client.Reader, instance *apiV1.PgInstance) PgCredentialProvider {
+	if instance.Spec.CredentialProvider != nil && instance.Spec.CredentialProvider.Exec != nil {
+		return newExecCredentialProvider(r, instance)
+	}
+	return &staticCredentialProvider{r, instance}
+}
+
+// staticCredentialProvider reads the password property of the instance
+type staticCredentialProvider struct {
+	reader   client.Reader
+	instance *apiV1.PgInstance
+}
+
+func (p *staticCredentialProvider) GetCredentials(ctx context.Context) (PgCredentials, error) {
+	password, err := p.instance.Spec.GetPassword(ctx, p.reader, p.instance.Namespace)
+	if err != nil {
+		return PgCredentials{}, err
+	}
+	return PgCredentials{Password: password}, nil
+}
+
+func (p *staticCredentialProvider) Invalidate() bool {
+	// The property is read on every call, so there is nothing to discard
+	return false
+}
+
+// credentialCacheRetention is the time after which cached credentials, which were not used anymore, are removed,
+// e.g. because the instance was deleted. Instances are probed more often, so the credentials of existing instances are kept.
+const credentialCacheRetention = time.Hour
+
+// cachedCredentials contains the credentials of an exec provider, which are shared between
+// the short-lived instance APIs of all reconciliations
+type cachedCredentials struct {
+	sync.Mutex
+	credentials PgCredentials
+	valid       bool
+	// config is the exec provider configuration which returned the credentials
+	config string
+	// lastUsed is guarded by the lock of the credentialCache
+	lastUsed time.Time
+}
+
+// credentialCache maps the instance to the cached credentials
+var credentialCache = struct {
+	sync.Mutex
+	entries map[string]*cachedCredentials
+}{entries: make(map[string]*cachedCredentials)}
+
+// ExecCommandRule allows the exec credential providers of the instances in the given namespaces to run a command line.
+// The command and its arguments have to match exactly, only the listed environment variables can be set by the instance.
+type ExecCommandRule struct {
+	// Namespaces of the instances which can run the command,
+	// a ClusterPgInstance is located in the namespace of its properties
+	Namespaces []string `json:"namespaces"`
+	// Command is the executable which is run
+	Command string `json:"command"`
+	// Args are the arguments which are passed to the command
+	Args []string `json:"args,omitempty"`
+	// Env contains the names of the environment variables which can be set by the instance
+	Env []string `json:"env,omitempty"`
+}
+
+// allowedExecCommands contains the rules for the command lines which can be run by exec credential providers
+var allowedExecCommands = struct {
+	sync.RWMutex
+	rules []ExecCommandRule
+}{}
+
+// SetAllowedExecCommands sets the rules for the command lines which can be run by exec credential providers.
+// No command is allowed by default, because the commands run with the privileges of the operator.
+func SetAllowedExecCommands(rules []ExecCommandRule) {
+	allowedExecCommands.Lock()
+	defer allowedExecCommands.Unlock()
+	allowedExecCommands.rules = append([]ExecCommandRule{}, rules...)
+}
+
+// isExecCommandAllowed returns true if a rule allows instances in the given namespace to run the given command line
+func isExecCommandAllowed(namespace string, spec *apiV1.PgExecCredentialProvider) bool {
+	allowedExecCommands.RLock()
+	defer allowedExecCommands.RUnlock()
+	for _, rule := range allowedExecCommands.rules {
+		if rule.Command != spec.Command || !equalStrings(rule.Args, spec.Args) || !containsString(rule.Namespaces, namespace) {
+			continue
+		}
+		allowed := true
+		for _, env := range spec.Env {
+			allowed = allowed && containsString(rule.Env, env.Name)
+		}
+		if allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// execCredentialProvider runs an external command and caches the returned token until it expires
+type execCredentialProvider struct {
+	reader   client.Reader
+	instance *apiV1.PgInstance
+	cache    *cachedCredentials
+}
+
+func newExecCredentialProvider(r client.Reader, instance *apiV1.PgInstance) *execCredentialProvider {
+	key := instance.Namespace + "/" + instance.Name + "/" + string(instance.UID)
+	now := time.Now()
+
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	// Remove the credentials of instances which were deleted or renamed
+	for k, entry := range credentialCache.entries {
+		if now.Sub(entry.lastUsed) > credentialCacheRetention {
+			delete(credentialCache.entries, k)
+		}
+	}
+	cache, ok := credentialCache.entries[key]
+	if !ok {
+		cache = &cachedCredentials{}
+		credentialCache.entries[key] = cache
+	}
+	cache.lastUsed = now
+	return &execCredentialProvider{r, instance, cache}
+}
+
+func (p *execCredentialProvider) GetCredentials(ctx context.Context) (PgCredentials, error) {
+	config, _ := json.Marshal(p.instance.Spec.CredentialProvider.Exec)
+	p.cache.Lock()
+	defer p.cache.Unlock()
+	// Changes of the configuration discard the cached credentials
+	expiresAt := p.cache.credentials.ExpiresAt
+	if p.cache.valid && p.cache.config == string(config) && (expiresAt.IsZero() || time.Now().Add(credentialExpiryMargin).Before(expiresAt)) {
+		return p.cache.credentials, nil
+	}
+	credentials, err := p.run(ctx)
+	if err != nil {
+		p.cache.valid = false
+		return PgCredentials{}, err
+	}
+	p.cache.credentials = credentials
+	p.cache.config = string(config)
+	p.cache.valid = true
+	return credentials, nil
+}
+
+func (p *execCredentialProvider) Invalidate() bool {
+	p.cache.Lock()
+	defer p.cache.Unlock()
+	p.cache.valid = false
+	return true
+}
+
+// run executes the command with the configured arguments and environment and parses its output
+func (p *execCredentialProvider) run(ctx context.Context) (PgCredentials, error) {
+	spec := p.instance.Spec.CredentialProvider.Exec
+	if !isExecCommandAllowed(p.instance.Namespace, spec) {
+		return PgCredentials{}, fmt.Errorf("the credential command %s with the given arguments and environment is not allowed by the operator in the namespace %s", spec.Command, p.instance.Namespace)
+	}
+	timeout := defaultExecTimeout
+	if spec.Timeout != nil && spec.Timeout.Duration > 0 {
+		timeout = spec.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, spec.Command, spec.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range spec.Env {
+		value, err := env.Value.GetPropertyValue(ctx, p.reader, p.instance.Namespace, env.Name)
+		if err != nil {
+			return PgCredentials{}, err
+		}
+		cmd.Env = append(cmd.Env, env.Name+"="+value)
+	}
+	// The error output is discarded, because errors are reported in the status of the instance
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return PgCredentials{}, fmt.Errorf("the credential command %s failed: %w", spec.Command, err)
+	}
+	return parseExecCredentials(stdout.Bytes())
+}
+
+// execCredentialOutput is the output of a credential command, the fields can be nested in status
+// like in the ExecCredential returned by exec plugins of kubeconfig files
+type execCredentialOutput struct {
+	Token               string                `json:"token"`
+	ExpirationTimestamp *time.Time            `json:"expirationTimestamp,omitempty"`
+	Status              *execCredentialOutput `json:"status,omitempty"`
+}
+
+// parseExecCredentials parses the JSON output of a credential command
+func parseExecCredentials(data []byte) (PgCredentials, error) {
+	var output execCredentialOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return PgCredentials{}, fmt.Errorf("unable to parse the output of the credential command: %w", err)
+	}
+	if output.Status != nil {
+		output = *output.Status
+	}
+	if output.Token == "" {
+		return PgCredentials{}, fmt.Errorf("the credential command returned no token")
+	}
+	credentials := PgCredentials{Password: output.Token}
+	if output.ExpirationTimestamp != nil {
+		credentials.ExpiresAt = *output.ExpirationTimestamp
+	}
+	return credentials, nil
+}
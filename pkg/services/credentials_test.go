@@ -0,0 +1,233 @@
+/*
+Copyright 2023 Brose Fahrzeugteile SE & Co. KG, Bamberg.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	apiV1 "github.com/brose-ebike/postgres-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// execScript counts the calls of the credential command and prints the output
+const execScript = "echo call >> \"$COUNT_FILE\" && echo \"$OUTPUT\""
+
+var _ = Describe("PgCredentialProvider", func() {
+
+	var countFile string
+
+	// newExecInstance returns an instance whose credential command counts its calls and prints the given output
+	newExecInstance := func(name string, output string) *apiV1.PgInstance {
+		return &apiV1.PgInstance{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: apiV1.PgInstanceSpec{
+				CredentialProvider: &apiV1.PgCredentialProvider{
+					Exec: &apiV1.PgExecCredentialProvider{
+						Command: "sh",
+						Args:    []string{"-c", execScript},
+						Env: []apiV1.PgExecEnvVar{
+							{Name: "COUNT_FILE", Value: apiV1.PgProperty{Value: countFile}},
+							{Name: "OUTPUT", Value: apiV1.PgProperty{Value: output}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	countCalls := func() int {
+		data, err := os.ReadFile(countFile)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		Expect(err).To(BeNil())
+		return strings.Count(string(data), "call")
+	}
+
+	BeforeEach(func() {
+		dir, err := os.MkdirTemp("", "credentials")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.RemoveAll, dir)
+		countFile = filepath.Join(dir, "count")
+		SetAllowedExecCommands([]ExecCommandRule{{
+			Namespaces: []string{"default"},
+			Command:    "sh",
+			Args:       []string{"-c", execScript},
+			Env:        []string{"COUNT_FILE", "OUTPUT"},
+		}})
+		DeferCleanup(SetAllowedExecCommands, []ExecCommandRule{})
+	})
+
+	It("reads the static password", func() {
+		// given:
+		instance := &apiV1.PgInstance{Spec: apiV1.PgInstanceSpec{Password: apiV1.PgProperty{Value: "secret"}}}
+		provider := NewPgCredentialProvider(&mockReader{}, instance)
+		// when:
+		credentials, err := provider.GetCredentials(context.TODO())
+		// then:
+		Expect(err).To(BeNil())
+		Expect(credentials.Password).To(Equal("secret"))
+		Expect(credentials.ExpiresAt.IsZero()).To(BeTrue())
+		Expect(provider.Invalidate()).To(BeFalse())
+	})
+
+	It("caches the token of an exec provider until it expires", func() {
+		// given:
+		expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		instance := newExecInstance("exec-cached", `{"token": "token-1", "expirationTimestamp": "`+expiry+`"}`)
+		// when:
+		credentials0, err0 := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+		credentials1, err1 := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+		// then:
+		Expect(err0).To(BeNil())
+		Expect(err1).To(BeNil())
+		Expect(credentials0.Password).To(Equal("token-1"))
+		Expect(credentials1).To(Equal(credentials0))
+		Expect(credentials0.ExpiresAt.Format(time.RFC3339)).To(Equal(expiry))
+		Expect(countCalls()).To(Equal(1))
+	})
+
+	It("renews expiring tokens of an exec provider", func() {
+		// given:
+		expiry := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+		instance := newExecInstance("exec-expiring", `{"status": {"token": "token-2", "expirationTimestamp": "`+expiry+`"}}`)
+		provider := NewPgCredentialProvider(&mockReader{}, instance)
+		// when:
+		_, err0 := provider.GetCredentials(context.TODO())
+		credentials, err1 := provider.GetCredentials(context.TODO())
+		// then:
+		Expect(err0).To(BeNil())
+		Expect(err1).To(BeNil())
+		Expect(credentials.Password).To(Equal("token-2"))
+		Expect(countCalls()).To(Equal(2))
+	})
+
+	It("obtains a new token of an exec provider after invalidation", func() {
+		// given:
+		instance := newExecInstance("exec-invalidated", `{"token": "token-3"}`)
+		provider := NewPgCredentialProvider(&mockReader{}, instance)
+		_, err := provider.GetCredentials(context.TODO())
+		Expect(err).To(BeNil())
+		// when:
+		invalidated := provider.Invalidate()
+		_, err = provider.GetCredentials(context.TODO())
+		// then:
+		Expect(err).To(BeNil())
+		Expect(invalidated).To(BeTrue())
+		Expect(countCalls()).To(Equal(2))
+	})
+
+	It("refuses commands which are not allowed", func() {
+		// given:
+		SetAllowedExecCommands([]ExecCommandRule{{Namespaces: []string{"default"}, Command: "/usr/local/bin/pg-token"}})
+		instance := newExecInstance("exec-refused", `{"token": "token-4"}`)
+		// when:
+		_, err := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+		// then:
+		Expect(err).ToNot(BeNil())
+		Expect(countCalls()).To(BeZero())
+	})
+
+	It("refuses arguments, environment variables and namespaces which are not allowed", func() {
+		// given:
+		arguments := newExecInstance("exec-refused-args", `{"token": "token-5"}`)
+		arguments.Spec.CredentialProvider.Exec.Args = []string{"-c", "id >&2; exit 1"}
+		environment := newExecInstance("exec-refused-env", `{"token": "token-5"}`)
+		environment.Spec.CredentialProvider.Exec.Env = append(environment.Spec.CredentialProvider.Exec.Env,
+			apiV1.PgExecEnvVar{Name: "LD_PRELOAD", Value: apiV1.PgProperty{Value: "/tmp/library.so"}})
+		namespace := newExecInstance("exec-refused-namespace", `{"token": "token-5"}`)
+		namespace.Namespace = "tenant"
+		for _, instance := range []*apiV1.PgInstance{arguments, environment, namespace} {
+			// when:
+			_, err := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+			// then:
+			Expect(err).ToNot(BeNil())
+		}
+		Expect(countCalls()).To(BeZero())
+	})
+
+	It("does not report the error output of the command", func() {
+		// given:
+		SetAllowedExecCommands([]ExecCommandRule{{Namespaces: []string{"default"}, Command: "sh", Args: []string{"-c", "echo secret-output >&2; exit 1"}}})
+		instance := newExecInstance("exec-stderr", `{"token": "token-6"}`)
+		instance.Spec.CredentialProvider.Exec.Args = []string{"-c", "echo secret-output >&2; exit 1"}
+		instance.Spec.CredentialProvider.Exec.Env = nil
+		// when:
+		_, err := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+		// then:
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).ToNot(ContainSubstring("secret-output"))
+	})
+
+	It("removes cached credentials which were not used anymore", func() {
+		// given:
+		instance := newExecInstance("exec-removed", `{"token": "token-7"}`)
+		provider := NewPgCredentialProvider(&mockReader{}, instance).(*execCredentialProvider)
+		credentialCache.Lock()
+		provider.cache.lastUsed = time.Now().Add(-2 * credentialCacheRetention)
+		credentialCache.Unlock()
+		// when:
+		NewPgCredentialProvider(&mockReader{}, newExecInstance("exec-other", `{"token": "token-8"}`))
+		// then:
+		credentialCache.Lock()
+		defer credentialCache.Unlock()
+		Expect(credentialCache.entries).ToNot(ContainElement(provider.cache))
+	})
+
+	It("refuses output without token", func() {
+		// given:
+		instance := newExecInstance("exec-invalid", `{"expirationTimestamp": "2030-01-01T00:00:00Z"}`)
+		// when:
+		_, err := NewPgCredentialProvider(&mockReader{}, instance).GetCredentials(context.TODO())
+		// then:
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("re-authenticates after the server refused cached credentials", func() {
+		// given:
+		ctx := context.TODO()
+		hostname, _ := container.Hostname(ctx)
+		port, _ := container.Port(ctx)
+		instance := newExecInstance("exec-reauth", `{"token": "`+container.Password()+`"}`)
+		instance.Spec.Hostname = apiV1.PgProperty{Value: hostname}
+		instance.Spec.Port = apiV1.PgProperty{Value: strconv.Itoa(port)}
+		instance.Spec.Username = apiV1.PgProperty{Value: container.Username()}
+		instance.Spec.Database = apiV1.PgProperty{Value: container.Database()}
+		instance.Spec.SSLMode = apiV1.PgProperty{Value: "disable"}
+		// and: a revoked token is cached
+		provider := NewPgCredentialProvider(&mockReader{}, instance).(*execCredentialProvider)
+		provider.cache.credentials = PgCredentials{Password: "revoked-token", ExpiresAt: time.Now().Add(time.Hour)}
+		provider.cache.valid = true
+
+		// when:
+		pgApi, err := NewPgInstanceAPI(ctx, &mockReader{}, instance)
+
+		// then:
+		Expect(err).To(BeNil())
+		Expect(pgApi.IsConnected()).To(BeTrue())
+		connectionString := pgApi.ConnectionString()
+		Expect(connectionString.Password()).To(Equal(container.Password()))
+		Expect(countCalls()).To(Equal(1))
+	})
+})
@@ -25,7 +25,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// NewPgInstanceAPI connects to the instance with the credentials of its credential provider
 func NewPgInstanceAPI(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgInstanceAPI, error) {
+	return NewPgInstanceAPIWithCredentials(ctx, r, instance, NewPgCredentialProvider(r, instance))
+}
+
+// NewPgInstanceAPIWithCredentials connects to the instance with the credentials of the given provider.
+// If the server refuses the credentials, they are invalidated and the connection is retried once with new credentials.
+func NewPgInstanceAPIWithCredentials(ctx context.Context, r client.Reader, instance *apiV1.PgInstance, provider PgCredentialProvider) (pgapi.PgInstanceAPI, error) {
 	logger := log.FromContext(ctx)
 	namespace := instance.Namespace
 	hostname, err := instance.Spec.GetHostname(ctx, r, namespace)
@@ -46,12 +53,6 @@ func NewPgInstanceAPI(ctx context.Context, r client.Reader, instance *apiV1.PgIn
 		return nil, err
 	}
 
-	password, err := instance.Spec.GetPassword(ctx, r, namespace)
-	if err != nil {
-		logger.Error(err, "Unable to read the value for the password property")
-		return nil, err
-	}
-
 	database, err := instance.Spec.GetDatabase(ctx, r, namespace)
 	if err != nil {
 		logger.Error(err, "Unable to read the value for the database property")
@@ -64,24 +65,84 @@ func NewPgInstanceAPI(ctx context.Context, r client.Reader, instance *apiV1.PgIn
 		return nil, err
 	}
 
-	connectionString, err := pgapi.NewPgConnectionString(
-		hostname,
-		port,
-		username,
-		password,
-		database,
-		sslMode,
-	)
-
+	tlsConfig, err := getTLSConfig(ctx, r, instance)
 	if err != nil {
-		logger.Error(err, "Unable to create the postgresql connection string")
 		return nil, err
 	}
 
-	pgApi, err := pgapi.NewPgInstanceAPI(ctx, instance.Name, connectionString)
+	connect := func() (pgapi.PgInstanceAPI, error) {
+		credentials, err := provider.GetCredentials(ctx)
+		if err != nil {
+			logger.Error(err, "Unable to obtain the credentials")
+			return nil, err
+		}
+
+		connectionString, err := pgapi.NewPgConnectionString(
+			hostname,
+			port,
+			username,
+			credentials.Password,
+			database,
+			sslMode,
+		)
+
+		if err != nil {
+			logger.Error(err, "Unable to create the postgresql connection string")
+			return nil, err
+		}
+
+		if err := connectionString.SetTLSConfig(tlsConfig); err != nil {
+			logger.Error(err, "Unable to use the TLS certificates")
+			return nil, err
+		}
+
+		return pgapi.NewPgInstanceAPI(ctx, instance.Name, connectionString)
+	}
+
+	pgApi, err := connect()
+	// Obtain new credentials once, e.g. if a cached token was revoked before it expired
+	if pgapi.IsAuthenticationError(err) && provider.Invalidate() {
+		logger.Info("Authentication failed, retrying with new credentials", "instance", instance.Name)
+		pgApi, err = connect()
+	}
 	if err != nil {
 		logger.Error(err, "Unable to connect to the Postgres instance")
 		return nil, err
 	}
 	return pgApi, nil
 }
+
+func getTLSConfig(ctx context.Context, r client.Reader, instance *apiV1.PgInstance) (pgapi.PgTLSConfig, error) {
+	logger := log.FromContext(ctx)
+	namespace := instance.Namespace
+	rootCert, err := instance.Spec.GetSSLRootCert(ctx, r, namespace)
+	if err != nil {
+		logger.Error(err, "Unable to read the value for the sslRootCert property")
+		return pgapi.PgTLSConfig{}, err
+	}
+
+	cert, err := instance.Spec.GetSSLCert(ctx, r, namespace)
+	if err != nil {
+		logger.Error(err, "Unable to read the value for the sslCert property")
+		return pgapi.PgTLSConfig{}, err
+	}
+
+	key, err := instance.Spec.GetSSLKey(ctx, r, namespace)
+	if err != nil {
+		logger.Error(err, "Unable to read the value for the sslKey property")
+		return pgapi.PgTLSConfig{}, err
+	}
+
+	crl, err := instance.Spec.GetSSLCRL(ctx, r, namespace)
+	if err != nil {
+		logger.Error(err, "Unable to read the value for the sslCRL property")
+		return pgapi.PgTLSConfig{}, err
+	}
+
+	return pgapi.PgTLSConfig{
+		RootCert: rootCert,
+		Cert:     cert,
+		Key:      key,
+		CRL:      crl,
+	}, nil
+}